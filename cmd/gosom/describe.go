@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+)
+
+func runDescribe(args []string) error {
+	var input string
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	fs.StringVar(&input, "input", "", "Path to data set")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if input == "" {
+		return fmt.Errorf("invalid path to input data: %s", input)
+	}
+
+	ds, err := dataset.New(input, "")
+	if err != nil {
+		return err
+	}
+	stats, err := ds.Describe()
+	if err != nil {
+		return err
+	}
+	rows, _ := ds.Data.Dims()
+	fmt.Printf("%s: %d rows, %d columns\n\n", input, rows, len(stats))
+	// print column statistics table
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "column\tmin\tmax\tmean\tstdev\tnans\t")
+	for i, s := range stats {
+		fmt.Fprintf(w, "%d\t%.4g\t%.4g\t%.4g\t%.4g\t%d\t\n", i, s.Min, s.Max, s.Mean, s.Stdev, s.NaNs)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+	"github.com/milosgajdos/gosom/som"
+	"gonum.org/v1/gonum/mat"
+)
+
+// evalReport holds SOM evaluation report
+type evalReport struct {
+	Model       string     `json:"model"`
+	Input       string     `json:"input"`
+	Samples     int        `json:"samples"`
+	QuantError  float64    `json:"quant_error"`
+	TopoProduct float64    `json:"topo_product"`
+	TopoError   float64    `json:"topo_error"`
+	Estimates   *estimates `json:"estimates,omitempty"`
+	Hits        *hitStats  `json:"hits"`
+	Classes     *purity    `json:"classes,omitempty"`
+	Confusion   *confusion `json:"confusion,omitempty"`
+}
+
+// estimates holds confidence intervals of quality measures evaluated on a sample of data
+type estimates struct {
+	Samples    int        `json:"samples"`
+	Level      float64    `json:"level"`
+	QuantError [2]float64 `json:"quant_error"`
+	TopoError  [2]float64 `json:"topo_error"`
+}
+
+// hitStats holds SOM unit hit statistics
+type hitStats struct {
+	Units     []int   `json:"units"`
+	Max       int     `json:"max"`
+	Mean      float64 `json:"mean"`
+	DeadUnits int     `json:"dead_units"`
+}
+
+// purity holds SOM class separation statistics
+type purity struct {
+	Purity float64      `json:"purity"`
+	Units  []unitPurity `json:"units"`
+}
+
+// confusion holds classification results of test data classified by unit classes
+type confusion struct {
+	Classes    []int   `json:"classes"`
+	Counts     [][]int `json:"counts"`
+	Unassigned []int   `json:"unassigned"`
+	Accuracy   float64 `json:"accuracy"`
+	MacroF1    float64 `json:"macro_f1"`
+}
+
+// unitPurity holds class statistics of SOM unit
+type unitPurity struct {
+	Unit    int     `json:"unit"`
+	Class   int     `json:"class"`
+	Purity  float64 `json:"purity"`
+	Entropy float64 `json:"entropy"`
+}
+
+func runEvaluate(args []string) error {
+	var modelPath, input, classes string
+	var scale bool
+	var sample int
+	var level float64
+	var seed int64
+	fs := flag.NewFlagSet("evaluate", flag.ExitOnError)
+	fs.StringVar(&modelPath, "model", "", "Path to trained SOM model or model bundle")
+	fs.StringVar(&input, "input", "", "Path to test data set")
+	fs.StringVar(&classes, "classes", "", "Path to test data set classification file")
+	fs.BoolVar(&scale, "scale", false, "Request data scaling when the model has no fitted scaler")
+	fs.IntVar(&sample, "sample", 0, "Estimate quantization and topographic errors on given number of randomly sampled rows with bootstrap confidence intervals (default: all rows)")
+	fs.Float64Var(&level, "level", 0.95, "Confidence level of sampled error estimates")
+	fs.Int64Var(&seed, "seed", 0, "Seed of the row sampling (default: current time)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if modelPath == "" {
+		return fmt.Errorf("invalid path to model: %s", modelPath)
+	}
+	if input == "" {
+		return fmt.Errorf("invalid path to input data: %s", input)
+	}
+	if err := checkStdio(modelPath, input, classes); err != nil {
+		return err
+	}
+
+	log.Printf("Loading model %s", modelPath)
+	b, err := loadModel(modelPath)
+	if err != nil {
+		return err
+	}
+	m := b.Map
+	log.Printf("Loading data set %s", input)
+	ds, err := dataset.New(input, classes)
+	if err != nil {
+		return err
+	}
+	data := ds.Data
+	switch {
+	case b.Scaler != nil:
+		if data, err = b.Transform(ds.Data); err != nil {
+			return err
+		}
+	case scale:
+		log.Printf("Attempting feature scaling")
+		data = ds.Scale()
+	}
+
+	r := &evalReport{
+		Model: modelPath,
+		Input: input,
+	}
+	r.Samples, _ = data.Dims()
+	if sample > 0 {
+		if err := estimateErrors(r, m, data, &som.EstimateConfig{Samples: sample, Level: level, Seed: seed}); err != nil {
+			return err
+		}
+	} else {
+		if r.QuantError, err = m.QuantError(data); err != nil {
+			return err
+		}
+		if r.TopoError, err = m.TopoError(data); err != nil {
+			return err
+		}
+	}
+	if r.TopoProduct, err = m.TopoProduct(); err != nil {
+		return err
+	}
+	hits, err := m.Hits(data)
+	if err != nil {
+		return err
+	}
+	r.Hits = &hitStats{Units: hits}
+	for _, h := range hits {
+		if h > r.Hits.Max {
+			r.Hits.Max = h
+		}
+		if h == 0 {
+			r.Hits.DeadUnits++
+		}
+	}
+	r.Hits.Mean = float64(r.Samples) / float64(len(hits))
+	if classes != "" {
+		stats, err := m.ClassStats(data, ds.Classes)
+		if err != nil {
+			return err
+		}
+		r.Classes = &purity{Purity: stats.Purity()}
+		dominant := stats.Dominant()
+		for _, unit := range stats.Units() {
+			r.Classes.Units = append(r.Classes.Units, unitPurity{
+				Unit:    unit,
+				Class:   dominant[unit],
+				Purity:  stats.UnitPurity(unit),
+				Entropy: stats.UnitEntropy(unit),
+			})
+		}
+		// model bundles carry unit classes learnt from training data
+		if b.Classes != nil {
+			c, err := m.Confusion(data, ds.Classes, b.Classes)
+			if err != nil {
+				return err
+			}
+			r.Confusion = &confusion{
+				Classes:    c.Classes,
+				Counts:     c.Counts,
+				Unassigned: c.Unassigned,
+				Accuracy:   c.Accuracy(),
+				MacroF1:    c.MacroF1(),
+			}
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// estimateErrors estimates quantization and topographic errors of map m on data sampled as configured
+// by c and records them in report r along with their confidence intervals
+func estimateErrors(r *evalReport, m *som.Map, data *mat.Dense, c *som.EstimateConfig) error {
+	qe, err := m.SampledQuantError(data, c)
+	if err != nil {
+		return err
+	}
+	te, err := m.SampledTopoError(data, c)
+	if err != nil {
+		return err
+	}
+	r.QuantError, r.TopoError = qe.Value, te.Value
+	r.Estimates = &estimates{
+		Samples:    qe.Samples,
+		Level:      c.Level,
+		QuantError: [2]float64{qe.Lower, qe.Upper},
+		TopoError:  [2]float64{te.Lower, te.Upper},
+	}
+	return nil
+}
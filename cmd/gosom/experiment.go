@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/milosgajdos/gosom/pkg/experiment"
+)
+
+func runExperiment(args []string) error {
+	var config, outdir string
+	fs := flag.NewFlagSet("experiment", flag.ExitOnError)
+	fs.StringVar(&config, "config", "", "Path to JSON experiment configuration or manifest")
+	fs.StringVar(&outdir, "outdir", "", "Path to experiment output directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if config == "" {
+		return fmt.Errorf("invalid path to experiment configuration: %s", config)
+	}
+	if outdir == "" {
+		return fmt.Errorf("invalid path to output directory: %s", outdir)
+	}
+
+	c, err := loadExperimentConfig(config)
+	if err != nil {
+		return err
+	}
+	log.Printf("Running experiment %s", c.Name)
+	mf, err := experiment.Run(c, outdir)
+	if err != nil {
+		return err
+	}
+	log.Printf("Experiment completed. Config hash: %s", mf.ConfigHash)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(mf.Metrics)
+}
+
+// loadExperimentConfig loads experiment configuration from a file in path.
+// The file contains either the configuration or a manifest of an experiment run
+// in which case the configuration of the run is returned so the run can be reproduced.
+func loadExperimentConfig(path string) (*experiment.Config, error) {
+	mf, err := experiment.LoadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	if mf.Config != nil {
+		return mf.Config, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	c := &experiment.Config{}
+	if err := json.NewDecoder(file).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+	"github.com/milosgajdos/gosom/pkg/infer"
+	"github.com/milosgajdos/gosom/som"
+	"gonum.org/v1/gonum/mat"
+)
+
+func runExport(args []string) error {
+	var modelPath, input, classes, format, quantize, output string
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.StringVar(&modelPath, "model", "", "Path to trained SOM model or model bundle")
+	fs.StringVar(&input, "input", "", "Path to data set whose BMUs are exported along with the map")
+	fs.StringVar(&classes, "classes", "", "Path to data set classification file used to label geojson units")
+	fs.StringVar(&format, "format", "kohonen", "Export format: kohonen, somoclu, infer, inferbin, graph, graphml, geojson, index (input data rows mapped to each unit)")
+	fs.StringVar(&quantize, "quantize", "", "Quantize codebook of infer and inferbin format model: float16 or int8 (default: no quantization)")
+	fs.StringVar(&output, "output", "", "Path to exported map; somoclu format uses it as prefix of .wts and .bm files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if modelPath == "" {
+		return fmt.Errorf("invalid path to model: %s", modelPath)
+	}
+	if output == "" {
+		return fmt.Errorf("invalid path to output data: %s", output)
+	}
+	switch format {
+	case "kohonen", "somoclu", "infer", "inferbin", "graph", "graphml", "geojson", "index":
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+	if quantize != "" && format != "infer" && format != "inferbin" {
+		return fmt.Errorf("only infer and inferbin formats can be quantized")
+	}
+	if classes != "" && input == "" {
+		return fmt.Errorf("classes require input data set")
+	}
+	if format == "index" && input == "" {
+		return fmt.Errorf("index format requires input data set")
+	}
+	if err := checkStdio(modelPath, input, classes); err != nil {
+		return err
+	}
+	if format == "somoclu" && output == stdio {
+		return fmt.Errorf("somoclu format can't be written to %s", stdio)
+	}
+
+	log.Printf("Loading model %s", modelPath)
+	b, err := loadModel(modelPath)
+	if err != nil {
+		return err
+	}
+	// use unit classes stored in model bundle unless data set is supplied
+	stats := som.NewClassStats()
+	for unit, class := range b.Classes {
+		stats.Add(unit, class)
+	}
+	var raw, data *mat.Dense
+	if input != "" {
+		log.Printf("Loading data set %s", input)
+		ds, err := dataset.New(input, classes)
+		if err != nil {
+			return err
+		}
+		raw = ds.Data
+		if data, err = b.Transform(ds.Data); err != nil {
+			return err
+		}
+		if stats, err = b.Map.ClassStats(data, ds.Classes); err != nil {
+			return err
+		}
+	}
+
+	if format == "somoclu" {
+		return exportSomoclu(b.Map, data, output)
+	}
+
+	file, err := createFile(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	log.Printf("Exporting map to %s", output)
+	switch format {
+	case "infer", "inferbin":
+		im, err := b.Inference()
+		if err != nil {
+			return err
+		}
+		if quantize != "" {
+			if im, err = quantizeModel(im, quantize, raw); err != nil {
+				return err
+			}
+		}
+		if format == "inferbin" {
+			return im.EncodeBinary(file)
+		}
+		return im.Encode(file)
+	case "graph":
+		_, err := b.Map.Grid().MarshalGraph("json", file)
+		return err
+	case "graphml":
+		_, err := b.Map.Grid().MarshalGraph("graphml", file)
+		return err
+	case "geojson":
+		_, err := b.Map.MarshalFeatures(file, data, stats)
+		return err
+	case "index":
+		ix, err := b.Map.UnitIndex(data)
+		if err != nil {
+			return err
+		}
+		return ix.Encode(file)
+	}
+	return b.Map.ExportKohonen(file, data)
+}
+
+// quantizeModel returns inference model m with codebook quantized to given kind and logs
+// the codebook size reduction along with the BMU agreement on data if data is not nil
+func quantizeModel(m *infer.Model, kind string, data *mat.Dense) (*infer.Model, error) {
+	q, err := m.Quantize(kind)
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]float64
+	if data != nil {
+		r, _ := data.Dims()
+		for i := 0; i < r; i++ {
+			rows = append(rows, data.RawRowView(i))
+		}
+	}
+	r, err := infer.Compare(m, q, rows)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Quantized %s codebook: %d -> %d bytes, max value error: %g", r.Kind, r.Bytes, r.QuantizedBytes, r.MaxError)
+	if r.Rows > 0 {
+		log.Printf("BMU agreement: %.2f%% (%d of %d rows changed BMU), mean grid shift: %f",
+			100*r.Agreement, r.Changed, r.Rows, r.GridShift)
+	}
+	return q, nil
+}
+
+// exportSomoclu exports codebook of m to prefix.wts file and BMUs of data to prefix.bm file if data is not nil
+func exportSomoclu(m *som.Map, data *mat.Dense, prefix string) error {
+	log.Printf("Exporting codebook to %s.wts", prefix)
+	if err := writeFile(prefix+".wts", func(w io.Writer) error {
+		_, err := m.MarshalTo("somoclu", w)
+		return err
+	}); err != nil {
+		return err
+	}
+	if data == nil {
+		return nil
+	}
+	log.Printf("Exporting BMUs to %s.bm", prefix)
+	return writeFile(prefix+".bm", func(w io.Writer) error {
+		return m.WriteSomocluBMUs(w, data)
+	})
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+	"github.com/milosgajdos/gosom/pkg/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// generateFlags holds generate command flags
+type generateFlags struct {
+	// kind of generated data: clusters, moons, mixture, swissroll
+	kind string
+	// number of generated samples
+	rows int
+	// data dimension
+	cols int
+	// number of clusters or mixture components
+	clusters int
+	// bounds of cluster centres or component means
+	min, max float64
+	// cluster size, component standard deviation or noise
+	spread float64
+	// random seed
+	seed int64
+	// output data format: csv, lrn
+	format string
+	// path to generated data set
+	output string
+	// path to generated classification file
+	cls string
+}
+
+func runGenerate(args []string) error {
+	f := &generateFlags{}
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	fs.StringVar(&f.kind, "kind", "clusters", "Kind of generated data: clusters, moons, mixture, swissroll")
+	fs.IntVar(&f.rows, "rows", 1000, "Number of generated samples")
+	fs.IntVar(&f.cols, "cols", 2, "Data dimension of clusters and mixture data")
+	fs.IntVar(&f.clusters, "clusters", 3, "Number of clusters or mixture components")
+	fs.Float64Var(&f.min, "min", 0.0, "Minimum coordinate of cluster centres")
+	fs.Float64Var(&f.max, "max", 1.0, "Maximum coordinate of cluster centres")
+	fs.Float64Var(&f.spread, "spread", 0.1, "Cluster size, mixture component standard deviation or noise")
+	fs.Int64Var(&f.seed, "seed", 1, "Random seed")
+	fs.StringVar(&f.format, "format", "", "Output data format: csv, lrn (default: inferred from output)")
+	fs.StringVar(&f.output, "output", "", "Path to generated data set")
+	fs.StringVar(&f.cls, "cls", "", "Path to generated classification file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if f.output == "" {
+		return fmt.Errorf("invalid path to output data: %s", f.output)
+	}
+	if err := checkStdio(f.output, f.cls); err != nil {
+		return err
+	}
+	if f.rows <= 0 || f.cols <= 0 || f.clusters <= 0 {
+		return fmt.Errorf("invalid data dimensions: rows: %d, cols: %d, clusters: %d", f.rows, f.cols, f.clusters)
+	}
+	format := f.format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(f.output), ".")
+	}
+	if format != "csv" && format != "lrn" {
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+
+	// data and number of classes of generated data
+	var data *mat.Dense
+	var classes int
+	switch f.kind {
+	case "clusters":
+		data = utils.GenerateClusters(f.rows, f.cols, f.clusters, f.max, f.min, f.spread, f.seed)
+		classes = f.clusters
+	case "moons":
+		data = utils.GenerateMoons(f.rows, f.spread, f.seed)
+		classes = 2
+	case "mixture":
+		data = utils.GenerateGaussianMixture(f.rows, f.cols, f.clusters, f.max, f.min, f.spread, f.seed)
+		classes = f.clusters
+	case "swissroll":
+		data = utils.GenerateSwissRoll(f.rows, f.spread, f.seed)
+	default:
+		return fmt.Errorf("unsupported kind of data: %s", f.kind)
+	}
+
+	log.Printf("Saving %s data set to %s", f.kind, f.output)
+	if err := writeFile(f.output, func(file io.Writer) error {
+		if format == "lrn" {
+			return dataset.WriteLRN(file, data)
+		}
+		return dataset.WriteCSV(file, data)
+	}); err != nil {
+		return err
+	}
+
+	if f.cls != "" {
+		if classes == 0 {
+			return fmt.Errorf("%s data has no classes", f.kind)
+		}
+		// generated sample i belongs to class i % classes
+		cls := make(map[int]int, f.rows)
+		for i := 0; i < f.rows; i++ {
+			cls[i] = i % classes
+		}
+		log.Printf("Saving classification to %s", f.cls)
+		return writeFile(f.cls, func(file io.Writer) error {
+			return dataset.WriteCLS(file, cls)
+		})
+	}
+
+	return nil
+}
+
+// writeFile writes a file in path using write; path - writes to standard output
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return write(file)
+}
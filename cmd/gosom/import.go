@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/milosgajdos/gosom/som"
+	"gonum.org/v1/gonum/mat"
+)
+
+func runImport(args []string) error {
+	var input, format, grid, ushape, output string
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.StringVar(&input, "input", "", "Path to codebook trained by other tool")
+	fs.StringVar(&format, "format", "somoclu", "Import format: somoclu")
+	fs.StringVar(&grid, "grid", "planar", "Type of SOM grid the codebook was trained on: planar, toroid or cylinder")
+	fs.StringVar(&ushape, "ushape", "rectangle", "SOM map unit shape the codebook was trained with")
+	fs.StringVar(&output, "output", "", "Path to store imported SOM model; .zip path stores model bundle")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if input == "" {
+		return fmt.Errorf("invalid path to input data: %s", input)
+	}
+	if output == "" {
+		return fmt.Errorf("invalid path to output data: %s", output)
+	}
+	if format != "somoclu" {
+		return fmt.Errorf("unsupported import format: %s", format)
+	}
+
+	log.Printf("Loading codebook %s", input)
+	file, err := openFile(input)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	codebook, dims, err := som.ReadSomocluCodebook(file)
+	if err != nil {
+		return err
+	}
+	_, dim := codebook.Dims()
+	m, err := som.NewMap(&som.MapConfig{
+		Grid: &som.GridConfig{
+			Size:   dims,
+			Type:   grid,
+			UShape: ushape,
+		},
+		Cb: &som.CbConfig{
+			Dim: dim,
+			InitFunc: func(*mat.Dense, []int) (*mat.Dense, error) {
+				return codebook, nil
+			},
+		},
+	}, mat.NewDense(1, dim, nil))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Saving imported model to %s", output)
+	return saveModel(m, nil, nil, nil, -1, output)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+const (
+	cliname = "gosom"
+)
+
+// command is a gosom subcommand
+type command struct {
+	// desc is a short command description
+	desc string
+	// run runs the command with the supplied cli arguments
+	run func(args []string) error
+}
+
+// commands maps subcommand names to their implementations
+var commands = map[string]*command{
+	"train":      {desc: "train SOM on one or more data sets", run: runTrain},
+	"predict":    {desc: "project data set onto a trained SOM", run: runPredict},
+	"describe":   {desc: "print column statistics of a data set", run: runDescribe},
+	"evaluate":   {desc: "evaluate trained SOM on a test data set", run: runEvaluate},
+	"import":     {desc: "import SOM trained by other tools", run: runImport},
+	"generate":   {desc: "generate synthetic data set", run: runGenerate},
+	"export":     {desc: "export trained SOM to other tools", run: runExport},
+	"experiment": {desc: "run reproducible training experiment", run: runExperiment},
+	"umatrix":    {desc: "render u-matrix of a trained SOM", run: runUMatrix},
+	"sweep":      {desc: "trace BMUs of a single feature sweep", run: runSweep},
+}
+
+func init() {
+	// disable timestamps and set prefix
+	log.SetFlags(0)
+	log.SetPrefix("[ " + cliname + " ] ")
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", cliname)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].desc)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for command flags.\n", cliname)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "\nERROR: %s\n", err)
+		os.Exit(1)
+	}
+}
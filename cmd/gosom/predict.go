@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+	"github.com/milosgajdos/gosom/pkg/model"
+	"github.com/milosgajdos/gosom/som"
+)
+
+func runPredict(args []string) error {
+	var modelPath, input, confidence, output string
+	fs := flag.NewFlagSet("predict", flag.ExitOnError)
+	fs.StringVar(&modelPath, "model", "", "Path to trained SOM model or model bundle")
+	fs.StringVar(&input, "input", "", "Path to input data set")
+	fs.StringVar(&confidence, "confidence", "", "Add BMU confidence column computed by measure: ratio, softmax")
+	fs.StringVar(&output, "output", "", "Path to output CSV file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if modelPath == "" {
+		return fmt.Errorf("invalid path to model: %s", modelPath)
+	}
+	if input == "" {
+		return fmt.Errorf("invalid path to input data: %s", input)
+	}
+	if output == "" {
+		return fmt.Errorf("invalid path to output data: %s", output)
+	}
+	if err := checkStdio(modelPath, input); err != nil {
+		return err
+	}
+
+	log.Printf("Loading model %s", modelPath)
+	b, err := loadModel(modelPath)
+	if err != nil {
+		return err
+	}
+	m := b.Map
+	log.Printf("Loading data set %s", input)
+	ds, err := dataset.New(input, "")
+	if err != nil {
+		return err
+	}
+	data, err := b.Transform(ds.Data)
+	if err != nil {
+		return err
+	}
+	bmus, dists, err := m.BMUDistances(data)
+	if err != nil {
+		return err
+	}
+	var conf []float64
+	if confidence != "" {
+		if conf, err = m.Confidence(data, confidence); err != nil {
+			return err
+		}
+	}
+
+	file, err := createFile(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	log.Printf("Saving predictions to %s", output)
+	w := csv.NewWriter(file)
+	header := []string{"row", "bmu", "x", "y", "distance"}
+	if conf != nil {
+		header = append(header, "confidence")
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	coords := m.Grid().Coords()
+	for row, bmu := range bmus {
+		record := []string{
+			strconv.Itoa(row),
+			strconv.Itoa(bmu),
+			strconv.FormatFloat(coords.At(bmu, 0), 'f', -1, 64),
+			strconv.FormatFloat(coords.At(bmu, 1), 'f', -1, 64),
+			strconv.FormatFloat(dists[row], 'f', -1, 64),
+		}
+		if conf != nil {
+			record = append(record, strconv.FormatFloat(conf[row], 'f', -1, 64))
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+// loadModel loads trained map from a file in path.
+// If the path has .zip extension the map is loaded from model bundle,
+// otherwise it's decoded from som model format and returned in a bundle.
+// Path - reads either of the formats from standard input.
+func loadModel(path string) (*model.Bundle, error) {
+	if filepath.Ext(path) == ".zip" {
+		return model.LoadFile(path)
+	}
+
+	file, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	if path == stdio && isZip(r) {
+		return model.Load(r)
+	}
+	m := new(som.Map)
+	if _, err := m.UnmarshalFrom("som", r); err != nil {
+		return nil, err
+	}
+
+	return &model.Bundle{Map: m}, nil
+}
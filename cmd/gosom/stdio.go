@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// stdio is the path which refers to standard input or standard output
+const stdio = "-"
+
+// nopCloser is a writer whose Close does nothing
+type nopCloser struct {
+	io.Writer
+}
+
+// Close does not close the underlying writer
+func (nopCloser) Close() error { return nil }
+
+// openFile opens file in path for reading. Path - opens standard input.
+func openFile(path string) (io.ReadCloser, error) {
+	if path == stdio {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// createFile creates file in path for writing. Path - returns standard output
+// which is not closed when the returned writer is closed.
+func createFile(path string) (io.WriteCloser, error) {
+	if path == stdio {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// checkStdio checks that at most one of the paths refers to standard input or output
+func checkStdio(paths ...string) error {
+	n := 0
+	for _, path := range paths {
+		if path == stdio {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("only one path can be %s", stdio)
+	}
+	return nil
+}
+
+// zipMagic is the signature which starts zip archives such as model bundles
+var zipMagic = []byte("PK\x03\x04")
+
+// isZip reports whether the data read from r starts with zip archive signature
+func isZip(r *bufio.Reader) bool {
+	head, _ := r.Peek(len(zipMagic))
+	return bytes.Equal(head, zipMagic)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/milosgajdos/gosom/pkg/model"
+	"github.com/milosgajdos/gosom/som"
+	"gonum.org/v1/gonum/mat"
+)
+
+func runSweep(args []string) error {
+	var modelPath, values, format, title, output string
+	var feature, steps int
+	var from, to float64
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	fs.StringVar(&modelPath, "model", "", "Path to trained SOM model or model bundle")
+	fs.IntVar(&feature, "feature", -1, "Index of swept data set column")
+	fs.Float64Var(&from, "from", 0.0, "First value of swept feature")
+	fs.Float64Var(&to, "to", 1.0, "Last value of swept feature")
+	fs.IntVar(&steps, "steps", 20, "Number of evenly spaced values of swept feature")
+	fs.StringVar(&values, "values", "", "Comma-separated values the other features are held at (default: feature means)")
+	fs.StringVar(&format, "format", "", "Output format: svg (u-matrix with BMU path), csv, json (default: inferred from output)")
+	fs.StringVar(&title, "title", "Feature Sweep", "U-matrix title")
+	fs.StringVar(&output, "output", "", "Path to sweep output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if modelPath == "" {
+		return fmt.Errorf("invalid path to model: %s", modelPath)
+	}
+	if output == "" {
+		return fmt.Errorf("invalid path to output data: %s", output)
+	}
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(output), ".")
+	}
+	if format != "svg" && format != "csv" && format != "json" {
+		return fmt.Errorf("unsupported sweep format: %s", format)
+	}
+
+	log.Printf("Loading model %s", modelPath)
+	b, err := loadModel(modelPath)
+	if err != nil {
+		return err
+	}
+	base, err := sweepBase(b, values)
+	if err != nil {
+		return err
+	}
+	// the sweep is defined in data set units and projected in map units
+	samples, err := som.SweepData(base, feature, from, to, steps)
+	if err != nil {
+		return err
+	}
+	scaled, err := b.Transform(samples)
+	if err != nil {
+		return err
+	}
+	sweep, err := b.Map.FeatureSweep(scaled.RawRowView(0), feature, scaled.At(0, feature), scaled.At(steps-1, feature), steps)
+	if err != nil {
+		return err
+	}
+	for i := range sweep {
+		sweep[i].Value = samples.At(i, feature)
+	}
+
+	file, err := createFile(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	log.Printf("Saving sweep of feature %d to %s", feature, output)
+	switch format {
+	case "csv":
+		return writeSweepCSV(file, sweep)
+	case "json":
+		return json.NewEncoder(file).Encode(sweep)
+	}
+	stats := som.NewClassStats()
+	for unit, class := range b.Classes {
+		stats.Add(unit, class)
+	}
+	path := make([]int, len(sweep))
+	for i, s := range sweep {
+		path[i] = s.BMU
+	}
+	return b.Map.PathSVG(file, path, stats, title, &som.SVGConfig{Standalone: true})
+}
+
+// sweepBase returns base values of sweep samples parsed from comma-separated values.
+// If values is empty, the feature means of the bundle scaler are used or, if the bundle
+// has no scaler, the means of the codebook vectors.
+func sweepBase(b *model.Bundle, values string) ([]float64, error) {
+	units, dim := b.Map.Codebook().Dims()
+	if values == "" {
+		if b.Scaler != nil {
+			return append([]float64(nil), b.Scaler.Mean...), nil
+		}
+		base := make([]float64, dim)
+		for j := range base {
+			base[j] = mat.Sum(b.Map.Codebook().(*mat.Dense).ColView(j)) / float64(units)
+		}
+		return base, nil
+	}
+	fields := strings.Split(values, ",")
+	if len(fields) != dim {
+		return nil, fmt.Errorf("invalid number of values: %d != %d", len(fields), dim)
+	}
+	base := make([]float64, dim)
+	for j, field := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %s", field)
+		}
+		base[j] = v
+	}
+	return base, nil
+}
+
+// writeSweepCSV writes sweep steps to w in CSV format with header
+func writeSweepCSV(w io.Writer, sweep []som.SweepStep) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"value", "bmu", "dist"}); err != nil {
+		return err
+	}
+	for _, s := range sweep {
+		if err := cw.Write([]string{
+			strconv.FormatFloat(s.Value, 'g', -1, 64),
+			strconv.Itoa(s.BMU),
+			strconv.FormatFloat(s.Dist, 'g', -1, 64),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,671 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+	"github.com/milosgajdos/gosom/pkg/model"
+	"github.com/milosgajdos/gosom/pkg/utils"
+	"github.com/milosgajdos/gosom/som"
+	"gonum.org/v1/gonum/mat"
+)
+
+// dataExts lists data set file extensions picked up in batch mode
+var dataExts = map[string]bool{
+	".csv": true,
+	".lrn": true,
+}
+
+// trainFlags holds train command flags shared by all trained data sets
+type trainFlags struct {
+	// path to input data set
+	input string
+	// path to classification file for the data set
+	cls string
+	// path to directory which contains data sets
+	dir string
+	// path to manifest file which lists data sets
+	manifest string
+	// path to output directory used in batch mode
+	outdir string
+	// feature scaling flag
+	scale bool
+	// data set cache flag
+	cache bool
+	// input data set format: csv, tsv, lrn, auto
+	format string
+	// outlier detection method: zscore, iqr
+	outliers string
+	// outlier detection threshold
+	othresh float64
+	// coma separated map dimensions or scout
+	dims string
+	// scale of the estimated number of map units
+	sizeScale float64
+	// maximum estimated number of map units
+	maxUnits int
+	// map grid type: planar
+	grid string
+	// map unit shape: hexagon, rectangle
+	ushape string
+	// codebook initialization function: rand, lin, sample
+	init string
+	// initial unit neihbourhood radius
+	radius float64
+	// radius decay strategy: lin, exp
+	rdecay string
+	// neighbourhood func: gaussian, bubble, mexican
+	neighb string
+	// initial learning rate
+	lrate float64
+	// learning rate decay strategy: lin, exp
+	ldecay string
+	// training method: seq, batch
+	training string
+	// radius and learning rate schedule unit: iter, epoch
+	schedule string
+	// sequential training sampling: uniform, qerror
+	sampling string
+	// codebook movement threshold of unit freezing
+	freeze float64
+	// number of epochs below freeze threshold before units are frozen
+	patience int
+	// non-finite values check flag
+	checkFinite bool
+	// number of training iterations
+	iters int
+	// random seed of sequential training
+	seed int64
+	// number of batch training workers
+	workers int
+	// index of data column with row weights
+	weights int
+	// batch training update of empty units: keep, decay, reassign
+	empty string
+	// minimum neighbourhood sum of batch training updates
+	minNghb float64
+	// comma separated indices of data columns left out of BMU search
+	carry string
+	// comma separated indices of data columns which are not updated
+	fixed string
+	// comma separated weights of data columns in BMU search
+	fweights string
+	// distance metric used to find BMUs
+	metric string
+	// fraction of data rows sampled in each epoch
+	subsample float64
+	// number of samples whose sequential updates are applied at once
+	delay int
+	// minimum purity of unit classes kept by label smoothing
+	smooth float64
+	// path to saved model
+	output string
+	// path to umatrix visualization
+	umatrix string
+}
+
+// job is a single data set training job
+type job struct {
+	// name identifies the job in batch mode
+	name string
+	// input is path to data set
+	input string
+	// cls is path to classification file
+	cls string
+	// output is path to saved model
+	output string
+	// umatrix is path to umatrix visualization
+	umatrix string
+	// report is path to training report
+	report string
+}
+
+// report holds training report
+type report struct {
+	Input       string             `json:"input"`
+	Dims        []int              `json:"dims"`
+	UShape      string             `json:"ushape"`
+	Algorithm   string             `json:"algorithm"`
+	Iterations  int                `json:"iterations"`
+	Duration    string             `json:"duration"`
+	QuantError  float64            `json:"quant_error"`
+	TopoProduct float64            `json:"topo_product"`
+	TopoError   float64            `json:"topo_error"`
+	Outliers    []dataset.Outlier  `json:"outliers,omitempty"`
+	Schedule    []som.ScheduleStep `json:"schedule,omitempty"`
+	Seed        int64              `json:"seed,omitempty"`
+	Empty       string             `json:"empty,omitempty"`
+	MinNghb     float64            `json:"min_nghb,omitempty"`
+	Carry       []int              `json:"carry,omitempty"`
+	Fixed       []int              `json:"fixed,omitempty"`
+	FWeights    []float64          `json:"feature_weights,omitempty"`
+	Metric      string             `json:"metric"`
+	Subsample   float64            `json:"subsample,omitempty"`
+	Samples     int                `json:"samples,omitempty"`
+	Delay       int                `json:"delay,omitempty"`
+}
+
+func runTrain(args []string) error {
+	f := &trainFlags{}
+	fs := flag.NewFlagSet("train", flag.ExitOnError)
+	fs.StringVar(&f.input, "input", "", "Path to input data set or a glob pattern matching several data sets")
+	fs.StringVar(&f.cls, "cls", "", "Path to input data set classification file")
+	fs.StringVar(&f.dir, "dir", "", "Path to directory with data sets to train in batch mode")
+	fs.StringVar(&f.manifest, "manifest", "", "Path to manifest file listing data sets to train in batch mode")
+	fs.StringVar(&f.outdir, "outdir", ".", "Path to output directory used in batch mode")
+	fs.BoolVar(&f.scale, "scale", false, "Request data scaling")
+	fs.BoolVar(&f.cache, "cache", false, "Cache parsed data sets in binary files next to them")
+	fs.StringVar(&f.format, "format", "", "Input data set format: csv, tsv, lrn or auto to detect it from the data (default: inferred from file extension)")
+	fs.StringVar(&f.outliers, "outliers", "", "Remove outlier rows before training: zscore or iqr")
+	fs.Float64Var(&f.othresh, "othresh", 3.0, "Outlier threshold in standard deviations (zscore) or interquartile ranges (iqr)")
+	fs.StringVar(&f.dims, "dims", "", "comma-separated SOM grid dimensions or scout to pick them by training scout maps")
+	fs.Float64Var(&f.sizeScale, "sizescale", 0.0, "Scale of the number of map units estimated from data: scale*sqrt(rows) when -dims is not set (default: 5)")
+	fs.IntVar(&f.maxUnits, "maxunits", 0, "Maximum number of map units estimated from data when -dims is not set (default: no maximum)")
+	fs.StringVar(&f.grid, "grid", "planar", "Type of SOM grid: planar, toroid, cylinder or sphere")
+	fs.StringVar(&f.ushape, "ushape", "hexagon", "SOM map unit shape")
+	fs.StringVar(&f.init, "init", "rand", "Codebook initialization: "+strings.Join(som.CbInitFuncs(), ", "))
+	fs.Float64Var(&f.radius, "radius", 0.0, "SOM neighbourhood initial radius (default: half of the largest grid dimension)")
+	fs.StringVar(&f.rdecay, "rdecay", "lin", "Radius decay strategy")
+	fs.StringVar(&f.neighb, "neighb", "gaussian", "SOM neighbourhood function")
+	fs.Float64Var(&f.lrate, "lrate", 0.5, "SOM initial learning rate")
+	fs.StringVar(&f.ldecay, "ldecay", "lin", "Learning rate decay strategy")
+	fs.StringVar(&f.training, "training", "seq", "SOM training method")
+	fs.StringVar(&f.schedule, "schedule", "iter", "Radius and learning rate decay schedule: iter or epoch")
+	fs.StringVar(&f.sampling, "sampling", "uniform", "Sequential training row sampling: uniform or qerror (rows drawn by their quantization error)")
+	fs.Float64Var(&f.freeze, "freeze", 0.0, "Freeze units whose codebook vectors move less than given distance per epoch (default: no freezing)")
+	fs.IntVar(&f.patience, "patience", 3, "Number of consecutive epochs units must move less than -freeze distance to be frozen")
+	fs.BoolVar(&f.checkFinite, "checkfinite", false, "Stop training with an error identifying the data row, or the iteration and unit, when infinite values or NaN codebook values appear")
+	fs.IntVar(&f.iters, "iters", 0, "Number of training iterations (default: suggested from data and map size)")
+	fs.Int64Var(&f.seed, "seed", 0, "Random seed of sequential training row sampling and subsampling; runs with the same seed and data produce the same map (default: current time)")
+	fs.Float64Var(&f.subsample, "subsample", 0.0, "Fraction of data rows drawn at random in every epoch to train on, e.g. to try out configurations quickly on large data sets (default: all rows)")
+	fs.IntVar(&f.delay, "delay", 0, "Number of samples whose sequential training updates are accumulated and applied to the codebook at once, e.g. to train very large maps (default: every sample updates the codebook)")
+	fs.IntVar(&f.workers, "workers", 0, "Number of batch training workers (default: number of CPUs)")
+	fs.IntVar(&f.weights, "weights", -1, "Index of data column with row weights of batch training, e.g. counts of duplicate rows (default: no weights)")
+	fs.StringVar(&f.empty, "empty", "keep", "Batch training update of units outside of the radius of all BMUs: keep, decay (halfway towards adjacent units) or reassign (to the rows farthest from their BMUs)")
+	fs.Float64Var(&f.minNghb, "minnghb", 0.0, "Minimum neighbourhood sum of batch training updates; units below it keep part of their codebook vectors (default: no minimum)")
+	fs.StringVar(&f.carry, "carry", "", "Comma-separated indices of data columns left out of BMU search but still updated, e.g. IDs summarized by units (default: none)")
+	fs.StringVar(&f.fixed, "fixed", "", "Comma-separated indices of data columns used in BMU search but never updated (default: none)")
+	fs.StringVar(&f.fweights, "fweights", "", "Comma-separated weights of data columns in BMU search, so some features count more than others (default: all 1)")
+	fs.StringVar(&f.metric, "metric", "euclidean", "Distance metric used to find BMUs and measure map quality: euclidean, manhattan, chebyshev, mahalanobis (covariance estimated from the data) or tanimoto (binary data)")
+	fs.Float64Var(&f.smooth, "smooth", -1, "Minimum purity of unit classes of model bundles and u-matrix labels; empty and less pure units inherit the most frequent class of their neighbours (default: no smoothing)")
+	fs.StringVar(&f.output, "output", "", "Path to store trained SOM model; .zip path stores model bundle")
+	fs.StringVar(&f.umatrix, "umatrix", "", "Path to u-matrix output visualization; svg, html or png format is inferred from its extension")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	jobs, err := trainJobs(f)
+	if err != nil {
+		return err
+	}
+
+	for _, j := range jobs {
+		if err := train(f, j); err != nil {
+			return fmt.Errorf("%s: %w", j.input, err)
+		}
+	}
+
+	return nil
+}
+
+// trainJobs returns training jobs requested via cli flags.
+// It fails with error if the flags are incorrect or data sets could not be listed.
+func trainJobs(f *trainFlags) ([]*job, error) {
+	// number of iterations can't be negative; 0 requests suggested number
+	if f.iters < 0 {
+		return nil, fmt.Errorf("invalid number of training iterations: %d", f.iters)
+	}
+	if _, err := som.ParseNeighbFunc(f.neighb); err != nil {
+		return nil, err
+	}
+	if _, err := som.ParseCbInitFunc(f.init); err != nil {
+		return nil, err
+	}
+	if f.smooth > 1 {
+		return nil, fmt.Errorf("invalid minimum purity of smoothed classes: %f", f.smooth)
+	}
+	// outlier removal would drop rows by their weights
+	if f.weights >= 0 && f.outliers != "" {
+		return nil, fmt.Errorf("row weights can't be combined with outlier removal")
+	}
+	// cached data sets are loaded in the format inferred from file extension
+	if f.format != "" && f.cache {
+		return nil, fmt.Errorf("data set format can't be combined with caching")
+	}
+	// data sets read from standard input can't be cached
+	if f.input == stdio && f.cache {
+		return nil, fmt.Errorf("data set read from %s can't be cached", stdio)
+	}
+	if err := checkStdio(f.input, f.cls); err != nil {
+		return nil, err
+	}
+	if err := checkStdio(f.output, f.umatrix); err != nil {
+		return nil, err
+	}
+
+	var paths [][2]string
+	switch {
+	case f.dir != "":
+		files, err := ioutil.ReadDir(f.dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			// gzip compressed data sets are picked up, too
+			name := strings.TrimSuffix(file.Name(), ".gz")
+			ext := filepath.Ext(name)
+			if file.IsDir() || !dataExts[ext] {
+				continue
+			}
+			input := filepath.Join(f.dir, file.Name())
+			// pick classification file with the same name if it exists
+			cls := filepath.Join(f.dir, strings.TrimSuffix(name, ext)+".cls")
+			if _, err := os.Stat(cls); err != nil {
+				cls = ""
+			}
+			paths = append(paths, [2]string{input, cls})
+		}
+	case f.manifest != "":
+		var err error
+		paths, err = readManifest(f.manifest)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		// path to input data is mandatory
+		if f.input == "" {
+			return nil, fmt.Errorf("invalid path to input data: %s", f.input)
+		}
+		return []*job{{input: f.input, cls: f.cls, output: f.output, umatrix: f.umatrix}}, nil
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no data sets found")
+	}
+
+	jobs := make([]*job, len(paths))
+	for i, p := range paths {
+		base := strings.TrimSuffix(filepath.Base(p[0]), ".gz")
+		name := strings.TrimSuffix(base, filepath.Ext(base))
+		jobs[i] = &job{
+			name:    name,
+			input:   p[0],
+			cls:     p[1],
+			output:  filepath.Join(f.outdir, name+".som"),
+			umatrix: filepath.Join(f.outdir, name+".html"),
+			report:  filepath.Join(f.outdir, name+".json"),
+		}
+	}
+
+	return jobs, nil
+}
+
+// readManifest reads data set paths from manifest file.
+// Each non-empty manifest line contains path to data set optionally followed by
+// path to its classification file. Lines starting with # are ignored.
+// Relative paths are resolved relative to the manifest directory.
+func readManifest(path string) ([][2]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(filepath.Dir(path), p)
+	}
+
+	var paths [][2]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("invalid manifest line: %s", scanner.Text())
+		}
+		var cls string
+		if len(fields) == 2 {
+			cls = fields[1]
+		}
+		paths = append(paths, [2]string{resolve(fields[0]), resolve(cls)})
+	}
+
+	return paths, scanner.Err()
+}
+
+// train runs a single training job
+func train(f *trainFlags, j *job) error {
+	log.Printf("Loading data set %s", j.input)
+	// load input data set from a file in provided path
+	load := dataset.New
+	if f.cache {
+		load = dataset.NewCached
+	}
+	if f.format != "" {
+		load = func(dataPath, clsPath string) (*dataset.DataSet, error) {
+			return dataset.NewFormat(dataPath, clsPath, f.format)
+		}
+	}
+	// glob patterns load all matching data sets concatenated into one
+	if strings.ContainsAny(j.input, "*?[") {
+		load = func(pattern, _ string) (*dataset.DataSet, error) {
+			return dataset.NewGlob(pattern)
+		}
+	}
+	ds, err := load(j.input, j.cls)
+	if err != nil {
+		return err
+	}
+	// split row weights from the data set if requested
+	var weights []float64
+	if f.weights >= 0 {
+		if ds, weights, err = ds.SplitWeights(f.weights); err != nil {
+			return err
+		}
+	}
+	// remove outliers if requested
+	var outliers []dataset.Outlier
+	if f.outliers != "" {
+		if ds, outliers, err = ds.RemoveOutliers(f.outliers, f.othresh); err != nil {
+			return err
+		}
+		log.Printf("Removed %d outlier rows", len(outliers))
+		for _, o := range outliers {
+			log.Printf("Outlier row %d, columns: %v", o.Row, o.Cols)
+		}
+	}
+	// scale features in input data if requested
+	data := ds.Data
+	var scaler *dataset.Scaler
+	if f.scale {
+		log.Printf("Attempting feature scaling")
+		scaler = dataset.NewScaler(ds.Data)
+		if data, err = scaler.Transform(ds.Data); err != nil {
+			return err
+		}
+	}
+	neighbFn, err := som.ParseNeighbFunc(f.neighb)
+	if err != nil {
+		return err
+	}
+	// parse SOM grid dimensions or estimate them from data
+	var mdims []int
+	switch f.dims {
+	case "":
+		sizeCfg := &som.SizeConfig{Scale: f.sizeScale, MaxUnits: f.maxUnits}
+		// sphere grid size is given by its subdivision level
+		if f.grid == "sphere" {
+			level, err := som.SphereLevel(data, sizeCfg)
+			if err != nil {
+				return err
+			}
+			mdims = som.SphereSize(level)
+			break
+		}
+		est, err := som.EstimateGridSize(data, f.ushape, sizeCfg)
+		if err != nil {
+			return err
+		}
+		if len(est.Explained) > 0 {
+			log.Printf("Estimated %d SOM units, dimensions ratio: %f, explained variance ratios: %v",
+				est.Units, est.Ratio, est.Explained)
+		}
+		mdims = est.Dims
+	case "scout":
+		log.Printf("Scouting SOM grid dimensions")
+		if mdims, _, err = som.ScoutSize(data, &som.ScoutConfig{
+			Grid: &som.GridConfig{
+				Type:   f.grid,
+				UShape: f.ushape,
+			},
+			Train: &som.TrainConfig{
+				Algorithm: f.training,
+				RDecay:    f.rdecay,
+				NeighbFn:  neighbFn,
+				LRate:     f.lrate,
+				LDecay:    f.ldecay,
+				Workers:   f.workers,
+				Seed:      f.seed,
+			},
+		}); err != nil {
+			return err
+		}
+	default:
+		if mdims, err = utils.ParseDims(f.dims); err != nil {
+			return err
+		}
+	}
+	_, dim := data.Dims()
+	initFn, err := som.ParseCbInitFunc(f.init)
+	if err != nil {
+		return err
+	}
+	metric, err := som.ParseMetric(f.metric)
+	if err != nil {
+		return err
+	}
+	// SOM configuration
+	mapCfg := &som.MapConfig{
+		Grid: &som.GridConfig{
+			Size:   mdims,
+			Type:   f.grid,
+			UShape: f.ushape,
+		},
+		Cb: &som.CbConfig{
+			Dim:      dim,
+			InitFunc: initFn,
+		},
+		Metric: metric,
+	}
+	// create new SOM
+	log.Printf("Creating new SOM. Dimensions: %v, Grid Type: %s, Unit shape: %s",
+		mapCfg.Grid.Size, mapCfg.Grid.Type, mapCfg.Grid.UShape)
+	m, err := som.NewMap(mapCfg, data)
+	if err != nil {
+		return err
+	}
+	// training configuration
+	radius := f.radius
+	if radius <= 0.0 {
+		radius = math.Max(som.MinRadius, m.Grid().Span()/2.0)
+	}
+	trainCfg := &som.TrainConfig{
+		Algorithm:   f.training,
+		Radius:      radius,
+		RDecay:      f.rdecay,
+		NeighbFn:    neighbFn,
+		LRate:       f.lrate,
+		LDecay:      f.ldecay,
+		Workers:     f.workers,
+		Schedule:    f.schedule,
+		Sampling:    f.sampling,
+		CheckFinite: f.checkFinite,
+		Weights:     weights,
+		Seed:        f.seed,
+		Empty:       f.empty,
+		MinNghb:     f.minNghb,
+		Subsample:   f.subsample,
+		Delay:       f.delay,
+		Metric:      metric,
+	}
+	if trainCfg.Carry, err = parseCols(f.carry); err != nil {
+		return err
+	}
+	if trainCfg.Fixed, err = parseCols(f.fixed); err != nil {
+		return err
+	}
+	if trainCfg.FeatureWeights, err = parseWeights(f.fweights); err != nil {
+		return err
+	}
+	if f.freeze > 0 {
+		trainCfg.Freeze = &som.FreezeConfig{Threshold: f.freeze, Patience: f.patience}
+	}
+	// suggest number of iterations if not provided
+	iters := f.iters
+	if iters == 0 {
+		rows, _ := data.Dims()
+		if iters, err = som.SuggestIterations(rows, utils.IntProduct(mdims), f.training); err != nil {
+			return err
+		}
+	}
+	// run SOM training
+	log.Printf("Starting SOM training. Method: %s, iterations: %d", trainCfg.Algorithm, iters)
+	t0 := time.Now()
+	if err := m.Train(trainCfg, data, iters); err != nil {
+		return err
+	}
+	d := time.Since(t0)
+	log.Printf("Training successfully completed. Duration: %v", d)
+	// if output is not empty save map model to a file
+	if j.output != "" {
+		log.Printf("Saving trained model to %s", j.output)
+		if err := saveModel(m, scaler, data, ds.Classes, f.smooth, j.output); err != nil {
+			return err
+		}
+	}
+	// if umatrix provided create U-matrix
+	if j.umatrix != "" {
+		log.Printf("Saving U-Matrix to %s", j.umatrix)
+		if err := saveUMatrix(m, umatrixFormat(j.umatrix), "U-Matrix", j.umatrix, data, ds.Classes, f.smooth); err != nil {
+			return err
+		}
+	}
+
+	// ======= SOM QUALITY measures =======
+	r := &report{
+		Input:      j.input,
+		Dims:       mdims,
+		UShape:     f.ushape,
+		Algorithm:  f.training,
+		Iterations: iters,
+		Duration:   d.String(),
+		Outliers:   outliers,
+		Schedule:   m.TrainHistory(),
+		Seed:       f.seed,
+		Carry:      trainCfg.Carry,
+		Fixed:      trainCfg.Fixed,
+		FWeights:   trainCfg.FeatureWeights,
+		Metric:     trainCfg.Metric.String(),
+		Subsample:  f.subsample,
+	}
+	// epochs of subsampled training record the number of their rows
+	if h := m.TrainHistory(); len(h) > 0 && h[0].Samples > 0 {
+		rows, _ := data.Dims()
+		r.Samples = h[0].Samples
+		log.Printf("Trained on %d of %d data rows per epoch", r.Samples, rows)
+	}
+	// empty units are only handled by batch training and updates are only delayed by sequential training
+	if f.training == "batch" {
+		r.Empty, r.MinNghb = f.empty, f.minNghb
+	} else {
+		r.Delay = f.delay
+	}
+	if r.QuantError, err = m.QuantError(data); err != nil {
+		return err
+	}
+	log.Printf("Quantization Error: %f\n", r.QuantError)
+	if r.TopoProduct, err = m.TopoProduct(); err != nil {
+		return err
+	}
+	log.Printf("Topographic Product: %f\n", r.TopoProduct)
+	if r.TopoError, err = m.TopoError(data); err != nil {
+		return err
+	}
+	log.Printf("Topographic Error: %f\n", r.TopoError)
+	// save training report in batch mode
+	if j.report != "" {
+		log.Printf("Saving training report to %s", j.report)
+		if err := saveReport(r, j.report); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveModel saves trained map m to a file in path.
+// If the path has .zip extension, the map is saved in a model bundle along with
+// the data scaler and unit classes, otherwise it is saved in som model format.
+// Unit classes are smoothed with minimum purity smooth unless it is negative.
+func saveModel(m *som.Map, scaler *dataset.Scaler, data *mat.Dense, classes map[int]int, smooth float64, path string) error {
+	if filepath.Ext(path) == ".zip" {
+		b := &model.Bundle{Map: m, Scaler: scaler}
+		if len(classes) > 0 {
+			stats, err := classStats(m, data, classes, smooth)
+			if err != nil {
+				return err
+			}
+			b.Classes = stats.Dominant()
+		}
+		return model.SaveFile(path, b)
+	}
+
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// save the model
+	if _, err := m.MarshalTo("som", file); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func saveReport(r interface{}, path string) error {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// parseCols parses comma separated data column indices; it returns nil if cols is empty
+func parseCols(cols string) ([]int, error) {
+	if cols == "" {
+		return nil, nil
+	}
+	idx, err := utils.ParseDims(cols)
+	if err != nil {
+		return nil, fmt.Errorf("invalid columns %q: %w", cols, err)
+	}
+	return idx, nil
+}
+
+// parseWeights parses comma separated feature weights; it returns nil if weights is empty
+func parseWeights(weights string) ([]float64, error) {
+	if weights == "" {
+		return nil, nil
+	}
+	fields := strings.Split(weights, ",")
+	w := make([]float64, len(fields))
+	for j, field := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feature weight: %s", field)
+		}
+		w[j] = v
+	}
+	return w, nil
+}
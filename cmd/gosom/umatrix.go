@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+	"github.com/milosgajdos/gosom/som"
+	"gonum.org/v1/gonum/mat"
+)
+
+func runUMatrix(args []string) error {
+	var modelPath, basePath, input, classes, class, mode, format, title, desc, labels, uShape, output string
+	var fragment bool
+	var fontSize float64
+	fs := flag.NewFlagSet("umatrix", flag.ExitOnError)
+	fs.StringVar(&modelPath, "model", "", "Path to trained SOM model or model bundle")
+	fs.StringVar(&basePath, "base", "", "Path to SOM model or model bundle with identical grid the shift map is computed against")
+	fs.StringVar(&input, "input", "", "Path to data set used to label SOM units with classes")
+	fs.StringVar(&classes, "classes", "", "Path to data set classification file")
+	fs.StringVar(&class, "class", "", "Restrict rendering to input data rows of a given class (default: all rows)")
+	fs.StringVar(&mode, "mode", "umatrix", "Rendered unit values: umatrix, hits (number of mapped input data rows), qerror (average quantization error of input data) or shift (codebook vector shift from base model)")
+	fs.StringVar(&format, "format", "", "U-matrix format: svg, png, html (default: inferred from output)")
+	fs.StringVar(&title, "title", "U-Matrix", "U-matrix title")
+	fs.StringVar(&desc, "desc", "", "U-matrix description embedded in standalone svg document")
+	fs.StringVar(&labels, "labels", "", "Path to CSV file with unit index and label on each line drawn on svg units")
+	fs.StringVar(&uShape, "ushape", "", "Unit shape of the rendered lattice: hexagon or rectangle, e.g. to compare both renderings of one model (default: unit shape of the model)")
+	fs.Float64Var(&fontSize, "fontsize", 12.0, "Font size of svg unit labels")
+	fs.BoolVar(&fragment, "fragment", false, "Write svg fragment which can be embedded in HTML instead of standalone document")
+	fs.StringVar(&output, "output", "", "Path to u-matrix output visualization")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if modelPath == "" {
+		return fmt.Errorf("invalid path to model: %s", modelPath)
+	}
+	if output == "" {
+		return fmt.Errorf("invalid path to output data: %s", output)
+	}
+	if classes != "" && input == "" {
+		return fmt.Errorf("classes require input data set")
+	}
+	if mode != "umatrix" && mode != "hits" && mode != "qerror" && mode != "shift" {
+		return fmt.Errorf("unsupported rendering mode: %s", mode)
+	}
+	if mode == "shift" && basePath == "" {
+		return fmt.Errorf("shift map requires base model")
+	}
+	if mode != "shift" && basePath != "" {
+		return fmt.Errorf("base model requires shift mode")
+	}
+	if (mode == "hits" || mode == "qerror") && input == "" {
+		return fmt.Errorf("%s map requires input data set", mode)
+	}
+	if class != "" && classes == "" {
+		return fmt.Errorf("class filter requires classes")
+	}
+	if err := checkStdio(modelPath, basePath, input, classes, labels); err != nil {
+		return err
+	}
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(output), ".")
+	}
+
+	log.Printf("Loading model %s", modelPath)
+	b, err := loadModel(modelPath)
+	if err != nil {
+		return err
+	}
+	var base *som.Map
+	if basePath != "" {
+		log.Printf("Loading base model %s", basePath)
+		bb, err := loadModel(basePath)
+		if err != nil {
+			return err
+		}
+		base = bb.Map
+	}
+	// the model is rendered in a different lattice of the same codebook
+	if uShape != "" {
+		if b.Map, err = b.Map.WithUShape(uShape); err != nil {
+			return err
+		}
+		if base != nil {
+			if base, err = base.WithUShape(uShape); err != nil {
+				return err
+			}
+		}
+	}
+	// use unit classes stored in model bundle unless data set is supplied
+	stats := som.NewClassStats()
+	for unit, class := range b.Classes {
+		stats.Add(unit, class)
+	}
+	var data *mat.Dense
+	if input != "" {
+		log.Printf("Loading data set %s", input)
+		ds, err := dataset.New(input, classes)
+		if err != nil {
+			return err
+		}
+		if data, err = b.Transform(ds.Data); err != nil {
+			return err
+		}
+		if stats, err = b.Map.ClassStats(data, ds.Classes); err != nil {
+			return err
+		}
+		// render only the rows of the requested class
+		if class != "" {
+			c, err := strconv.Atoi(class)
+			if err != nil {
+				return fmt.Errorf("invalid class: %s", class)
+			}
+			if data, err = som.ClassRows(data, ds.Classes, c); err != nil {
+				return err
+			}
+		}
+	}
+
+	file, err := createFile(output)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch mode {
+	case "qerror":
+		log.Printf("Saving quantization error map to %s", output)
+	case "hits":
+		log.Printf("Saving hit map to %s", output)
+	case "shift":
+		log.Printf("Saving shift map to %s", output)
+	default:
+		log.Printf("Saving U-Matrix to %s", output)
+	}
+	if format == "svg" {
+		c := &som.SVGConfig{Standalone: !fragment, Description: desc, FontSize: fontSize}
+		if labels != "" {
+			if c.Labels, err = loadLabels(labels); err != nil {
+				return err
+			}
+		}
+		switch {
+		case mode == "qerror":
+			return b.Map.QuantErrorMapSVG(file, data, stats, title, c)
+		case mode == "hits":
+			return b.Map.HitMapSVG(file, data, stats, title, c)
+		case mode == "shift":
+			return b.Map.ShiftMapSVG(file, base, stats, title, c)
+		case class != "":
+			return b.Map.UMatrixHitsSVG(file, data, stats, title, c)
+		}
+		return b.Map.UMatrixSVG(file, stats, title, c)
+	}
+	switch {
+	case mode == "qerror":
+		return b.Map.QuantErrorMap(file, data, stats, format, title)
+	case mode == "hits":
+		return b.Map.HitMap(file, data, stats, format, title)
+	case mode == "shift":
+		return b.Map.ShiftMap(file, base, stats, format, title)
+	case class != "":
+		return b.Map.UMatrixHits(file, data, stats, format, title)
+	}
+	return b.Map.UMatrixStats(file, stats, format, title)
+}
+
+// loadLabels loads unit labels from CSV file in path which contains unit index and label on each line
+func loadLabels(path string) (map[int]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = 2
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[int]string, len(records))
+	for _, rec := range records {
+		unit, err := strconv.Atoi(strings.TrimSpace(rec[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid unit index: %s", rec[0])
+		}
+		labels[unit] = rec[1]
+	}
+	return labels, nil
+}
+
+// umatrixFormat returns u-matrix format inferred from the extension of path.
+// Paths without extension, such as standard output, are saved in svg format.
+func umatrixFormat(path string) string {
+	if format := strings.TrimPrefix(filepath.Ext(path), "."); format != "" {
+		return format
+	}
+	return "svg"
+}
+
+// saveUMatrix saves u-matrix of m to a file in path.
+// The svg format is saved in a standalone SVG document.
+// Unit labels are smoothed with minimum purity smooth unless it is negative.
+func saveUMatrix(m *som.Map, format, title, path string, data *mat.Dense, classes map[int]int, smooth float64) error {
+	file, err := createFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stats := som.NewClassStats()
+	if len(classes) > 0 {
+		if stats, err = classStats(m, data, classes, smooth); err != nil {
+			return err
+		}
+	}
+	if format != "svg" {
+		return m.UMatrixStats(file, stats, format, title)
+	}
+	return m.UMatrixSVG(file, stats, title, &som.SVGConfig{Standalone: true})
+}
+
+// classStats computes class statistics of m from data and their classes.
+// The dominant classes are smoothed over the map grid with minimum purity smooth unless it is negative.
+func classStats(m *som.Map, data *mat.Dense, classes map[int]int, smooth float64) (*som.ClassStats, error) {
+	stats, err := m.ClassStats(data, classes)
+	if err != nil {
+		return nil, err
+	}
+	if smooth >= 0 {
+		if err := stats.Smooth(m.Grid(), smooth); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
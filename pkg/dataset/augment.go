@@ -0,0 +1,54 @@
+package dataset
+
+import (
+	"fmt"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Jitter returns a new data set which contains the data set rows followed by copies of them with added noise.
+// copies sets the number of noisy copies of each row. The noise added to each column of a copy is drawn
+// from normal distribution with zero mean and standard deviation set to the noise value of the column.
+// Noisy copies keep the classes of their original rows.
+// seed seeds the random noise. Jitter returns error if the data set has no data, copies is negative or
+// the number of noise values is different from the number of data set columns or some of them are negative.
+func (ds *DataSet) Jitter(copies int, noise []float64, seed int64) (*DataSet, error) {
+	if ds.Data == nil || ds.Data.IsEmpty() {
+		return nil, fmt.Errorf("invalid data supplied: %v", ds.Data)
+	}
+	if copies < 0 {
+		return nil, fmt.Errorf("invalid number of copies: %d", copies)
+	}
+	rows, cols := ds.Data.Dims()
+	if len(noise) != cols {
+		return nil, fmt.Errorf("invalid number of noise values: %d", len(noise))
+	}
+	for _, n := range noise {
+		if n < 0.0 {
+			return nil, fmt.Errorf("invalid noise value: %f", n)
+		}
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	data := mat.NewDense(rows*(copies+1), cols, nil)
+	classes := make(map[int]int)
+	for c := 0; c <= copies; c++ {
+		for i := 0; i < rows; i++ {
+			row := data.RawRowView(c*rows + i)
+			copy(row, ds.Data.RawRowView(i))
+			// the first copy is the original data set
+			if c > 0 {
+				for j := range row {
+					row[j] += rnd.NormFloat64() * noise[j]
+				}
+			}
+			if class, ok := ds.Classes[i]; ok {
+				classes[c*rows+i] = class
+			}
+		}
+	}
+	return &DataSet{
+		Data:    data,
+		Classes: classes,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package dataset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestJitter(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := &DataSet{
+		Data:    mat.NewDense(2, 2, []float64{1.0, 2.0, 3.0, 4.0}),
+		Classes: map[int]int{1: 5},
+	}
+	aug, err := ds.Jitter(100, []float64{0.1, 0.0}, 1)
+	assert.NoError(err)
+	rows, _ := aug.Data.Dims()
+	assert.Equal(202, rows)
+	// original rows come first
+	assert.True(mat.Equal(ds.Data, aug.Data.Slice(0, 2, 0, 2)))
+	var diff float64
+	for i := 2; i < rows; i++ {
+		orig := ds.Data.RawRowView(i % 2)
+		row := aug.Data.RawRowView(i)
+		// columns with zero noise are not modified
+		assert.Equal(orig[1], row[1])
+		diff += math.Abs(row[0] - orig[0])
+		// noisy copies keep classes
+		assert.Equal(ds.Classes[i%2], aug.Classes[i])
+	}
+	// mean absolute noise of normal distribution is stdev*sqrt(2/pi)
+	assert.InDelta(0.1*math.Sqrt(2/math.Pi), diff/200, 0.02)
+	assert.Len(aug.Classes, 101)
+	// the same seed yields the same data set
+	aug2, err := ds.Jitter(100, []float64{0.1, 0.0}, 1)
+	assert.NoError(err)
+	assert.True(mat.Equal(aug.Data, aug2.Data))
+	// invalid parameters
+	_, err = ds.Jitter(-1, []float64{0.1, 0.1}, 1)
+	assert.Error(err)
+	_, err = ds.Jitter(1, []float64{0.1}, 1)
+	assert.Error(err)
+	_, err = ds.Jitter(1, []float64{0.1, -0.1}, 1)
+	assert.Error(err)
+	_, err = (&DataSet{}).Jitter(1, nil, 1)
+	assert.Error(err)
+}
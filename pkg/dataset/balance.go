@@ -0,0 +1,93 @@
+package dataset
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Oversample returns a new data set in which randomly chosen rows of each class are duplicated until
+// every class has as many rows as the largest class. Rows without class are copied to the new data set
+// once. The original rows keep their order and are followed by the duplicates ordered by their class.
+// seed seeds the random choice of duplicated rows. It returns error if the data set has no classes.
+func (ds *DataSet) Oversample(seed int64) (*DataSet, error) {
+	classes, classRows, _, err := ds.classRows()
+	if err != nil {
+		return nil, err
+	}
+	max := 0
+	for _, rows := range classRows {
+		if len(rows) > max {
+			max = len(rows)
+		}
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	rows, _ := ds.Data.Dims()
+	keep := make([]int, rows, rows+len(classes)*max)
+	for i := range keep {
+		keep[i] = i
+	}
+	for _, class := range classes {
+		cRows := classRows[class]
+		for i := len(cRows); i < max; i++ {
+			keep = append(keep, cRows[rnd.Intn(len(cRows))])
+		}
+	}
+	return ds.subset(keep), nil
+}
+
+// Undersample returns a new data set which contains randomly chosen rows of each class so that every
+// class has as many rows as the smallest class. Rows without class are copied to the new data set.
+// The chosen rows keep their original order. seed seeds the random choice of rows.
+// It returns error if the data set has no classes.
+func (ds *DataSet) Undersample(seed int64) (*DataSet, error) {
+	classes, classRows, other, err := ds.classRows()
+	if err != nil {
+		return nil, err
+	}
+	min := -1
+	for _, rows := range classRows {
+		if min < 0 || len(rows) < min {
+			min = len(rows)
+		}
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	keep := make([]int, 0, len(classes)*min+len(other))
+	keep = append(keep, other...)
+	for _, class := range classes {
+		cRows := classRows[class]
+		for _, i := range rnd.Perm(len(cRows))[:min] {
+			keep = append(keep, cRows[i])
+		}
+	}
+	sort.Ints(keep)
+	return ds.subset(keep), nil
+}
+
+// classRows returns sorted data set classes, ascending rows of each class and rows without class.
+// It returns error if the data set has no data or classes.
+func (ds *DataSet) classRows() ([]int, map[int][]int, []int, error) {
+	if ds.Data == nil || ds.Data.IsEmpty() {
+		return nil, nil, nil, fmt.Errorf("invalid data supplied: %v", ds.Data)
+	}
+	rows, _ := ds.Data.Dims()
+	classRows := make(map[int][]int)
+	var other []int
+	for i := 0; i < rows; i++ {
+		class, ok := ds.Classes[i]
+		if !ok {
+			other = append(other, i)
+			continue
+		}
+		classRows[class] = append(classRows[class], i)
+	}
+	if len(classRows) == 0 {
+		return nil, nil, nil, fmt.Errorf("data set has no classes")
+	}
+	classes := make([]int, 0, len(classRows))
+	for class := range classRows {
+		classes = append(classes, class)
+	}
+	sort.Ints(classes)
+	return classes, classRows, other, nil
+}
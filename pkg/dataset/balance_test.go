@@ -0,0 +1,75 @@
+package dataset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func balanceDataSet() *DataSet {
+	return &DataSet{
+		Data: mat.NewDense(7, 1, []float64{0, 1, 2, 3, 4, 5, 6}),
+		// class 1 has 4 rows, class 2 has 2 rows and row 6 has no class
+		Classes: map[int]int{0: 1, 1: 2, 2: 1, 3: 1, 4: 2, 5: 1},
+	}
+}
+
+// classCounts returns the number of rows of each class
+func classCounts(ds *DataSet) map[int]int {
+	counts := make(map[int]int)
+	for _, class := range ds.Classes {
+		counts[class]++
+	}
+	return counts
+}
+
+func TestOversample(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := balanceDataSet()
+	bal, err := ds.Oversample(1)
+	assert.NoError(err)
+	rows, _ := bal.Data.Dims()
+	assert.Equal(9, rows)
+	assert.Equal(map[int]int{1: 4, 2: 4}, classCounts(bal))
+	// original rows keep their order
+	assert.Equal([]float64{0, 1, 2, 3, 4, 5, 6}, bal.Data.RawMatrix().Data[:7])
+	// duplicates are rows of class 2 and match their classes
+	for i := 7; i < rows; i++ {
+		assert.Equal(2, bal.Classes[i])
+		assert.Equal(2, ds.Classes[int(bal.Data.At(i, 0))])
+	}
+	// the same seed yields the same data set
+	bal2, err := ds.Oversample(1)
+	assert.NoError(err)
+	assert.True(mat.Equal(bal.Data, bal2.Data))
+	// no classes
+	_, err = (&DataSet{Data: mat.NewDense(1, 1, nil)}).Oversample(1)
+	assert.Error(err)
+}
+
+func TestUndersample(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := balanceDataSet()
+	bal, err := ds.Undersample(1)
+	assert.NoError(err)
+	rows, _ := bal.Data.Dims()
+	assert.Equal(5, rows)
+	assert.Equal(map[int]int{1: 2, 2: 2}, classCounts(bal))
+	// rows keep their original order and classes
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			assert.True(bal.Data.At(i-1, 0) < bal.Data.At(i, 0))
+		}
+		class, ok := ds.Classes[int(bal.Data.At(i, 0))]
+		assert.Equal(ok, bal.Classes[i] != 0)
+		assert.Equal(class, bal.Classes[i])
+	}
+	// unlabeled row is kept
+	assert.Equal(6.0, bal.Data.At(rows-1, 0))
+	// nil data
+	_, err = (&DataSet{}).Undersample(1)
+	assert.Error(err)
+}
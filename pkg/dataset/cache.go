@@ -0,0 +1,197 @@
+package dataset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// CacheExt is the extension of data set cache files
+const CacheExt = ".gsc"
+
+// cacheMagic identifies data set cache files
+var cacheMagic = [4]byte{'G', 'S', 'D', 'C'}
+
+// cacheVersion is the version of data set cache format
+const cacheVersion = 1
+
+// errStaleCache is returned when the cache does not match its source files
+var errStaleCache = errors.New("stale cache")
+
+// cacheKey identifies the versions of data set source files
+type cacheKey struct {
+	// DataMod is data file modification time in nanoseconds
+	DataMod int64
+	// DataSize is data file size
+	DataSize int64
+	// ClsMod is classification file modification time in nanoseconds
+	ClsMod int64
+	// ClsSize is classification file size
+	ClsSize int64
+}
+
+// newCacheKey returns cache key of given data and classification files
+func newCacheKey(dataPath, clsPath string) (cacheKey, error) {
+	var key cacheKey
+	fi, err := os.Stat(dataPath)
+	if err != nil {
+		return key, err
+	}
+	key.DataMod, key.DataSize = fi.ModTime().UnixNano(), fi.Size()
+	if clsPath != "" {
+		if fi, err = os.Stat(clsPath); err != nil {
+			return key, err
+		}
+		key.ClsMod, key.ClsSize = fi.ModTime().UnixNano(), fi.Size()
+	}
+	return key, nil
+}
+
+// NewCached returns data set loaded from files like New does, but it also keeps the parsed data set
+// in a compact binary cache file stored next to the data file with CacheExt extension appended to its name.
+// The following loads of the same files read the cache instead of parsing them. The cache is invalidated
+// and rebuilt when the modification time or size of the data or classification file changes.
+// Failure to write the cache, e.g. in read-only directories, is not an error.
+func NewCached(dataPath string, clsPath string) (*DataSet, error) {
+	key, err := newCacheKey(dataPath, clsPath)
+	if err != nil {
+		return nil, err
+	}
+	cachePath := dataPath + CacheExt
+	if ds, err := readCacheFile(cachePath, key); err == nil {
+		return ds, nil
+	}
+
+	ds, err := New(dataPath, clsPath)
+	if err != nil {
+		return nil, err
+	}
+	// the cache is written to a temporary file first so readers never see partial caches
+	tmpPath := fmt.Sprintf("%s.%d", cachePath, os.Getpid())
+	if err := writeCacheFile(tmpPath, key, ds); err != nil {
+		os.Remove(tmpPath)
+		return ds, nil
+	}
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		os.Remove(tmpPath)
+	}
+
+	return ds, nil
+}
+
+// readCacheFile reads data set from cache file in path if the cache matches key
+func readCacheFile(path string, key cacheKey) (*DataSet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readCache(bufio.NewReader(file), key)
+}
+
+// writeCacheFile writes data set to cache file in path
+func writeCacheFile(path string, key cacheKey, ds *DataSet) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	if err := writeCache(w, key, ds); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// writeCache encodes data set along with the cache key to w
+func writeCache(w io.Writer, key cacheKey, ds *DataSet) error {
+	if _, err := w.Write(cacheMagic[:]); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(cacheVersion)); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, key); err != nil {
+		return err
+	}
+	if _, err := ds.Data.MarshalBinaryTo(w); err != nil {
+		return err
+	}
+	// classes are sorted to make the cache deterministic
+	rows := make([]int, 0, len(ds.Classes))
+	for row := range ds.Classes {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+	if err := binary.Write(w, binary.LittleEndian, int64(len(rows))); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := binary.Write(w, binary.LittleEndian, [2]int64{int64(row), int64(ds.Classes[row])}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readCache decodes data set from r. It fails with error if the cache is corrupted
+// or if it was created from different versions of data set source files than the ones identified by key
+func readCache(r io.Reader, key cacheKey) (*DataSet, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, err
+	}
+	if magic != cacheMagic {
+		return nil, fmt.Errorf("invalid cache header")
+	}
+	var version uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != cacheVersion {
+		return nil, fmt.Errorf("unsupported cache version: %d", version)
+	}
+	var cached cacheKey
+	if err := binary.Read(r, binary.LittleEndian, &cached); err != nil {
+		return nil, err
+	}
+	if cached != key {
+		return nil, errStaleCache
+	}
+	data := new(mat.Dense)
+	if _, err := data.UnmarshalBinaryFrom(r); err != nil {
+		return nil, err
+	}
+	var n int64
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return nil, err
+	}
+	rows, _ := data.Dims()
+	if n < 0 || n > int64(rows) {
+		return nil, fmt.Errorf("invalid number of classes: %d", n)
+	}
+	classes := make(map[int]int, n)
+	for i := int64(0); i < n; i++ {
+		var rc [2]int64
+		if err := binary.Read(r, binary.LittleEndian, &rc); err != nil {
+			return nil, err
+		}
+		classes[int(rc[0])] = int(rc[1])
+	}
+
+	return &DataSet{
+		Data:    data,
+		Classes: classes,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package dataset
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNewCached(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "gosom_cache")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	dataPath := filepath.Join(dir, "data.csv")
+	clsPath := filepath.Join(dir, "data.cls")
+	assert.NoError(ioutil.WriteFile(dataPath, []byte("2.0,3.5\n4.5,5.5\n7.0,9.0"), 0666))
+	assert.NoError(ioutil.WriteFile(clsPath, []byte("% 2\n1\t1\n3\t2\n"), 0666))
+
+	// the first load creates the cache
+	ds, err := NewCached(dataPath, clsPath)
+	assert.NoError(err)
+	assert.Equal(map[int]int{0: 1, 2: 2}, ds.Classes)
+	key, err := newCacheKey(dataPath, clsPath)
+	assert.NoError(err)
+	cached, err := readCacheFile(dataPath+CacheExt, key)
+	assert.NoError(err)
+	assert.True(mat.Equal(ds.Data, cached.Data))
+	assert.Equal(ds.Classes, cached.Classes)
+	// the following loads read the cache
+	cds, err := NewCached(dataPath, clsPath)
+	assert.NoError(err)
+	assert.True(mat.Equal(ds.Data, cds.Data))
+	assert.Equal(ds.Classes, cds.Classes)
+	// modified data file invalidates the cache
+	assert.NoError(ioutil.WriteFile(dataPath, []byte("1.0,1.0\n2.0,2.0"), 0666))
+	mod := time.Now().Add(time.Minute)
+	assert.NoError(os.Chtimes(dataPath, mod, mod))
+	_, err = readCacheFile(dataPath+CacheExt, key)
+	assert.NoError(err)
+	newKey, err := newCacheKey(dataPath, clsPath)
+	assert.NoError(err)
+	_, err = readCacheFile(dataPath+CacheExt, newKey)
+	assert.Equal(errStaleCache, err)
+	cds, err = NewCached(dataPath, "")
+	assert.NoError(err)
+	rows, _ := cds.Data.Dims()
+	assert.Equal(2, rows)
+	assert.Len(cds.Classes, 0)
+	// corrupted cache is rebuilt
+	assert.NoError(ioutil.WriteFile(dataPath+CacheExt, []byte("corrupted"), 0666))
+	cds, err = NewCached(dataPath, "")
+	assert.NoError(err)
+	rows, _ = cds.Data.Dims()
+	assert.Equal(2, rows)
+	// nonexistent files
+	_, err = NewCached(filepath.Join(dir, "missing.csv"), "")
+	assert.Error(err)
+	_, err = NewCached(dataPath, filepath.Join(dir, "missing.cls"))
+	assert.Error(err)
+}
+
+func TestReadCacheInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	key := cacheKey{DataMod: 1, DataSize: 2}
+	ds := &DataSet{Data: mat.NewDense(1, 2, []float64{1.0, 2.0}), Classes: map[int]int{0: 1}}
+	var buf bytes.Buffer
+	assert.NoError(writeCache(&buf, key, ds))
+	valid := buf.Bytes()
+	// truncated caches
+	for _, n := range []int{0, 4, 8, len(valid) - 1} {
+		_, err := readCache(bytes.NewReader(valid[:n]), key)
+		assert.Error(err)
+	}
+	// invalid header
+	invalid := append([]byte("GSDX"), valid[4:]...)
+	_, err := readCache(bytes.NewReader(invalid), key)
+	assert.Error(err)
+	// unsupported version
+	invalid = append([]byte{}, valid...)
+	invalid[4] = 2
+	_, err = readCache(bytes.NewReader(invalid), key)
+	assert.Error(err)
+}
@@ -2,14 +2,20 @@ package dataset
 
 import (
 	"bufio"
+	"compress/gzip"
 	"encoding/csv"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/milosgajdos/gosom/pkg/matrix"
 	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/stat"
 )
@@ -31,14 +37,88 @@ const (
 // load data funcs
 var loadFuncs = map[string]func(io.Reader) (*mat.Dense, error){
 	".csv": LoadCSV,
+	".tsv": LoadTSV,
 	".lrn": LoadLRN,
 }
 
+// delimiters holds field delimiters recognized by format detection in the order of preference
+var delimiters = []rune{',', '\t', ';', '|'}
+
+// sniffSize is the maximum number of bytes inspected by format detection
+const sniffSize = 64 * 1024
+
 // load classifications funcs
 var loadClsFuncs = map[string]func(io.Reader) (map[int]int, error){
 	".cls": LoadCLS,
 }
 
+// Decompressor returns reader which decompresses data read from r
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// decompressors maps compressed file extensions to their decompressors
+var (
+	decompMu      sync.RWMutex
+	decompressors = map[string]Decompressor{
+		".gz": func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	}
+)
+
+// RegisterDecompressor registers decompressor of files with a given extension, e.g. ".zst".
+// Compressed files are decompressed when they are loaded and their format is inferred from
+// the extension which precedes the compression extension, e.g. data.csv.zst is loaded as csv.
+// Gzip compressed files with .gz extension are supported out of the box.
+func RegisterDecompressor(ext string, d Decompressor) {
+	decompMu.Lock()
+	defer decompMu.Unlock()
+	decompressors[ext] = d
+}
+
+// fileFormat returns the format extension of a file in path along with
+// its decompressor if the file is compressed, otherwise the decompressor is nil
+func fileFormat(path string) (string, Decompressor) {
+	ext := filepath.Ext(path)
+	decompMu.RLock()
+	d, ok := decompressors[ext]
+	decompMu.RUnlock()
+	if !ok {
+		return ext, nil
+	}
+	return filepath.Ext(strings.TrimSuffix(path, ext)), d
+}
+
+// openFile opens file in path in filesystem fsys and returns its reader which
+// decompresses the file contents using decompressor d if it is not nil
+func openFile(fsys fs.FS, path string, d Decompressor) (io.ReadCloser, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return file, nil
+	}
+	r, err := d(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &decompReader{ReadCloser: r, file: file}, nil
+}
+
+// decompReader closes both the decompressing reader and the underlying file
+type decompReader struct {
+	io.ReadCloser
+	file io.Closer
+}
+
+// Close closes decompressing reader and the underlying file
+func (r *decompReader) Close() error {
+	err := r.ReadCloser.Close()
+	if ferr := r.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
 // DataSet represents training data set
 type DataSet struct {
 	Data    *mat.Dense
@@ -46,30 +126,65 @@ type DataSet struct {
 }
 
 // New returns pointer to dataset or fails with error if either the file
-// in dataPath does not exist or if it is encoded in an unsupported format.
-// File format is inferred from the file extension. Currently only csv and lrn
-// data formats are supported.
+// in dataPath does not exist or if it can't be decoded.
+// File format is inferred from the file extension. Currently csv, tsv and lrn
+// data formats are supported. Files with other or no extensions are not rejected: their format
+// is sniffed from their contents like the auto format of NewFormat does, so they fail with error
+// only if their contents can't be decoded in the detected format. Compressed files, such as data.csv.gz,
+// are decompressed using the decompressor registered for their extension; see RegisterDecompressor.
 // If the dataset has classification information it can be provided as the second
 // parameter. If the file in clsPath doesn't exist New fails with error.
 func New(dataPath string, clsPath string) (*DataSet, error) {
-	// Check if the supplied file type is supported
-	fileType := filepath.Ext(dataPath)
-	loadData, ok := loadFuncs[fileType]
-	if !ok {
-		return nil, fmt.Errorf("unsupported file type: %s", fileType)
+	return NewFS(osFS{}, dataPath, clsPath)
+}
+
+// NewFormat returns pointer to dataset whose data file is encoded in a given format: csv, tsv, lrn or auto.
+// If the format is auto, the format is detected from the file contents: files whose first line which
+// is not a comment starts with % are loaded as lrn, other files are loaded as delimited text whose field
+// delimiter is the most frequent of comma, tab, semicolon and pipe on that line. If the format is empty,
+// NewFormat behaves like New. Data path - reads the data from standard input.
+// It fails with error if the format is not supported or the files can't be opened or decoded.
+func NewFormat(dataPath, clsPath, format string) (*DataSet, error) {
+	return newFS(osFS{}, dataPath, clsPath, format)
+}
+
+// NewFS returns pointer to dataset loaded from files in filesystem fsys, such as embed.FS,
+// zip archive or fstest.MapFS. Paths are interpreted by fsys; see New for the description
+// of supported formats. It fails with error if the files can't be opened or decoded.
+func NewFS(fsys fs.FS, dataPath string, clsPath string) (*DataSet, error) {
+	return newFS(fsys, dataPath, clsPath, "")
+}
+
+// newFS returns dataset loaded from files in filesystem fsys whose data file is encoded in format.
+// If the format is empty, it is inferred from the data file extension or detected from its contents.
+func newFS(fsys fs.FS, dataPath, clsPath, format string) (*DataSet, error) {
+	fileType, decomp := fileFormat(dataPath)
+	if format == "" {
+		format = fileType
+		if _, ok := loadFuncs[fileType]; !ok {
+			format = "auto"
+		}
 	}
-	// Check if the training data file exists
-	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
-		return nil, err
+	// Check if the supplied format is supported
+	format = "." + strings.TrimPrefix(format, ".")
+	loadData, ok := loadFuncs[format]
+	if !ok && format != ".auto" {
+		return nil, fmt.Errorf("unsupported data format: %s", strings.TrimPrefix(format, "."))
 	}
 	// Open training data file
-	file, err := os.Open(dataPath)
+	file, err := openFile(fsys, dataPath, decomp)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
+	var r io.Reader = file
+	if format == ".auto" {
+		if loadData, r, err = detectLoader(file); err != nil {
+			return nil, err
+		}
+	}
 	// Load file
-	data, err := loadData(file)
+	data, err := loadData(r)
 	if err != nil {
 		return nil, err
 	}
@@ -77,16 +192,12 @@ func New(dataPath string, clsPath string) (*DataSet, error) {
 	classes := make(map[int]int) // default empty classification information
 	if clsPath != "" {
 		// Check if the classification file type is supported
-		clsFileType := filepath.Ext(clsPath)
+		clsFileType, clsDecomp := fileFormat(clsPath)
 		loadCls, ok := loadClsFuncs[clsFileType]
 		if !ok {
 			return nil, fmt.Errorf("unsupported type of classification file: %s", clsFileType)
 		}
-		// Check if the classification file exists
-		if _, err := os.Stat(clsPath); os.IsNotExist(err) {
-			return nil, err
-		}
-		clsFile, err := os.Open(clsPath)
+		clsFile, err := openFile(fsys, clsPath, clsDecomp)
 		if err != nil {
 			return nil, err
 		}
@@ -104,23 +215,261 @@ func New(dataPath string, clsPath string) (*DataSet, error) {
 	}, nil
 }
 
+// Load loads data matrix encoded in a given format from reader r.
+// Supported formats are csv, tsv, lrn and auto which detects the format from the data like NewFormat;
+// the format can be given with a leading dot like file extension.
+// Compressed data is decompressed if the format has a compression suffix, e.g. csv.gz.
+// It fails with error if the format is not supported or the data could not be decoded.
+func Load(r io.Reader, format string) (*mat.Dense, error) {
+	ext, decomp := fileFormat("." + strings.TrimPrefix(format, "."))
+	loadData, ok := loadFuncs[ext]
+	if !ok && ext != ".auto" {
+		return nil, fmt.Errorf("unsupported data format: %s", format)
+	}
+	if decomp != nil {
+		dr, err := decomp(r)
+		if err != nil {
+			return nil, err
+		}
+		defer dr.Close()
+		r = dr
+	}
+	if ext == ".auto" {
+		var err error
+		if loadData, r, err = detectLoader(r); err != nil {
+			return nil, err
+		}
+	}
+	return loadData(r)
+}
+
+// detectLoader detects the format of data read from r and returns its loader
+// along with the reader which reads the data including the inspected bytes
+func detectLoader(r io.Reader) (func(io.Reader) (*mat.Dense, error), io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimSpace(line)
+		// skip empty lines and lrn comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "%") {
+			return LoadLRN, br, nil
+		}
+		return delimitedLoader(detectDelimiter(line)), br, nil
+	}
+	return LoadCSV, br, nil
+}
+
+// detectDelimiter returns the most frequent field delimiter found in line.
+// If line contains no delimiters, comma is returned.
+func detectDelimiter(line string) rune {
+	delim, count := delimiters[0], 0
+	for _, d := range delimiters {
+		if n := strings.Count(line, string(d)); n > count {
+			delim, count = d, n
+		}
+	}
+	return delim
+}
+
+// osFS is a filesystem which opens files in OS paths; path - opens standard input
+type osFS struct{}
+
+// Open opens file in a given OS path
+func (osFS) Open(name string) (fs.File, error) {
+	if name == "-" {
+		return stdinFile{os.Stdin}, nil
+	}
+	return os.Open(name)
+}
+
+// stdinFile is standard input which is not closed when the data is loaded
+type stdinFile struct {
+	*os.File
+}
+
+// Close does not close standard input
+func (stdinFile) Close() error {
+	return nil
+}
+
+// NewMulti loads data sets from files in dataPaths like New does and concatenates them into
+// a single data set using Concat. clsPaths are paths to classification files of the data sets;
+// they are either empty or there is one path per data set, empty path meaning no classification.
+// It fails with error if any of the data sets fails to load or if they can't be concatenated.
+func NewMulti(dataPaths []string, clsPaths []string) (*DataSet, error) {
+	if len(dataPaths) == 0 {
+		return nil, fmt.Errorf("no data sets supplied")
+	}
+	if len(clsPaths) > 0 && len(clsPaths) != len(dataPaths) {
+		return nil, fmt.Errorf("mismatched number of classification files: %d", len(clsPaths))
+	}
+	sets := make([]*DataSet, len(dataPaths))
+	for i, dataPath := range dataPaths {
+		clsPath := ""
+		if len(clsPaths) > 0 {
+			clsPath = clsPaths[i]
+		}
+		ds, err := New(dataPath, clsPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dataPath, err)
+		}
+		sets[i] = ds
+	}
+	return Concat(sets...)
+}
+
+// NewGlob loads data sets from all the files matching pattern and concatenates them in the lexical
+// order of their paths. The pattern syntax is the same as in filepath.Match. Classification file
+// of each data set is picked up if there is a file with the same name and cls extension next to it,
+// e.g. part-1.cls is picked up for part-1.csv or part-1.csv.gz.
+// It fails with error if the pattern matches no files or if the data sets can't be loaded or concatenated.
+func NewGlob(pattern string) (*DataSet, error) {
+	dataPaths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataPaths) == 0 {
+		return nil, fmt.Errorf("no data sets match pattern: %s", pattern)
+	}
+	clsPaths := make([]string, len(dataPaths))
+	for i, dataPath := range dataPaths {
+		ext, d := fileFormat(dataPath)
+		base := dataPath
+		if d != nil {
+			base = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		clsPath := strings.TrimSuffix(base, ext) + ".cls"
+		if _, err := os.Stat(clsPath); err == nil {
+			clsPaths[i] = clsPath
+		}
+	}
+	return NewMulti(dataPaths, clsPaths)
+}
+
+// Concat vertically concatenates data sets into a new data set. Classes of the concatenated
+// data sets are merged and their row indices are shifted to match the rows of the new data matrix.
+// It fails with error if no data sets are supplied or if they have different number of columns.
+func Concat(sets ...*DataSet) (*DataSet, error) {
+	if len(sets) == 0 {
+		return nil, fmt.Errorf("no data sets supplied")
+	}
+	rows := 0
+	_, cols := sets[0].Data.Dims()
+	for i, ds := range sets {
+		r, c := ds.Data.Dims()
+		if c != cols {
+			return nil, fmt.Errorf("inconsistent number of columns in data set %d: %d != %d", i, c, cols)
+		}
+		rows += r
+	}
+	data := mat.NewDense(rows, cols, nil)
+	classes := make(map[int]int)
+	offset := 0
+	for _, ds := range sets {
+		r, _ := ds.Data.Dims()
+		data.Slice(offset, offset+r, 0, cols).(*mat.Dense).Copy(ds.Data)
+		for row, class := range ds.Classes {
+			classes[offset+row] = class
+		}
+		offset += r
+	}
+	return &DataSet{
+		Data:    data,
+		Classes: classes,
+	}, nil
+}
+
 // Scale normalizes data in each column based on its mean and standard deviation and returns it.
 // It modifies the underlying daata. If this is not desirable use the standalone Scale function.
 func (ds *DataSet) Scale() *mat.Dense {
 	return scale(ds.Data, true)
 }
 
+// ColumnStats holds summary statistics of data set column
+type ColumnStats struct {
+	// Min is the minimum column value
+	Min float64 `json:"min"`
+	// Max is the maximum column value
+	Max float64 `json:"max"`
+	// Mean is the mean column value
+	Mean float64 `json:"mean"`
+	// Stdev is the standard deviation of column values
+	Stdev float64 `json:"stdev"`
+	// NaNs is the number of NaN column values
+	NaNs int `json:"nans"`
+}
+
+// Describe computes summary statistics of each data set column and returns them.
+// NaN values are counted and left out of the statistics; the statistics of columns
+// which contain only NaN values are set to NaN.
+// It returns error if the data set has no data.
+func (ds *DataSet) Describe() ([]ColumnStats, error) {
+	if ds.Data == nil || ds.Data.IsEmpty() {
+		return nil, fmt.Errorf("invalid data supplied: %v", ds.Data)
+	}
+	rows, cols := ds.Data.Dims()
+	stats := make([]ColumnStats, cols)
+	vals := make([]float64, 0, rows)
+	for j := 0; j < cols; j++ {
+		vals = vals[:0]
+		for i := 0; i < rows; i++ {
+			if x := ds.Data.At(i, j); !math.IsNaN(x) {
+				vals = append(vals, x)
+			}
+		}
+		stats[j].NaNs = rows - len(vals)
+		if len(vals) == 0 {
+			nan := math.NaN()
+			stats[j].Min, stats[j].Max, stats[j].Mean, stats[j].Stdev = nan, nan, nan, nan
+			continue
+		}
+		// no need to check for errors: col is a non-empty single column matrix
+		col := mat.NewDense(len(vals), 1, vals)
+		min, _ := matrix.ColsMin(1, col)
+		max, _ := matrix.ColsMax(1, col)
+		mean, _ := matrix.ColsMean(1, col)
+		stdev, _ := matrix.ColsStdev(1, col)
+		stats[j].Min, stats[j].Max, stats[j].Mean, stats[j].Stdev = min[0], max[0], mean[0], stdev[0]
+	}
+	return stats, nil
+}
+
 // LoadCSV loads data set from the path supplied as a parameter.
 // It returns data matrix that contains particular CSV fields in columns.
 // It returns error if the supplied data set contains corrrupted data or
 // if the data can not be converted to float numbers
 func LoadCSV(r io.Reader) (*mat.Dense, error) {
+	return loadDelimited(r, ',')
+}
+
+// LoadTSV loads data set of tab separated values from r.
+// It fails with error in the same cases as LoadCSV.
+func LoadTSV(r io.Reader) (*mat.Dense, error) {
+	return loadDelimited(r, '\t')
+}
+
+// delimitedLoader returns loader of delimited text with a given field delimiter
+func delimitedLoader(delim rune) func(io.Reader) (*mat.Dense, error) {
+	return func(r io.Reader) (*mat.Dense, error) {
+		return loadDelimited(r, delim)
+	}
+}
+
+// loadDelimited loads data matrix from delimited text read from r whose fields are separated by delim
+func loadDelimited(r io.Reader, delim rune) (*mat.Dense, error) {
 	// data matrix dimensions: rows x cols
 	var rows, cols int
 	// mxData contains ALL data read field by field
 	var mxData []float64
 	// create new CSV reader
 	csvReader := csv.NewReader(r)
+	csvReader.Comma = delim
 	// read all data record by record
 	for {
 		record, err := csvReader.Read()
@@ -291,15 +640,85 @@ func LoadCLS(reader io.Reader) (map[int]int, error) {
 	return classifications, nil
 }
 
+// WriteCSV writes data matrix to w in CSV format: one matrix row per CSV record.
+// It returns error if the data could not be written to w.
+func WriteCSV(w io.Writer, data mat.Matrix) error {
+	rows, cols := data.Dims()
+	csvWriter := csv.NewWriter(w)
+	record := make([]string, cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			record[j] = strconv.FormatFloat(data.At(i, j), 'g', -1, 64)
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// WriteLRN writes data matrix to w in .lrn format readable by LoadLRN.
+// The written data contains a key column with 1-based row indices followed by the data columns.
+// It returns error if the data could not be written to w.
+func WriteLRN(w io.Writer, data mat.Matrix) error {
+	const KeyCol, DataCol = 9, 1
+	rows, cols := data.Dims()
+	bw := bufio.NewWriter(w)
+	types := []string{strconv.Itoa(KeyCol)}
+	names := []string{"Key"}
+	for j := 0; j < cols; j++ {
+		types = append(types, strconv.Itoa(DataCol))
+		names = append(names, fmt.Sprintf("C%d", j+1))
+	}
+	fmt.Fprintf(bw, "%% %d\n%% %d\n", rows, cols+1)
+	fmt.Fprintf(bw, "%% %s\n%% %s\n", strings.Join(types, "\t"), strings.Join(names, "\t"))
+	vals := make([]string, cols+1)
+	for i := 0; i < rows; i++ {
+		vals[0] = strconv.Itoa(i + 1)
+		for j := 0; j < cols; j++ {
+			vals[j+1] = strconv.FormatFloat(data.At(i, j), 'g', -1, 64)
+		}
+		fmt.Fprintf(bw, "%s\n", strings.Join(vals, "\t"))
+	}
+	return bw.Flush()
+}
+
+// WriteCLS writes classification information to w in .cls format readable by LoadCLS.
+// classes maps 0-based data row indices to their classes; rows are written in ascending order.
+// It returns error if the data could not be written to w.
+func WriteCLS(w io.Writer, classes map[int]int) error {
+	rows := make([]int, 0, len(classes))
+	for row := range classes {
+		rows = append(rows, row)
+	}
+	sort.Ints(rows)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%% %d\n", len(rows))
+	for _, row := range rows {
+		// CLS indexes are 1-based, but we're using 0-based
+		fmt.Fprintf(bw, "%d\t%d\n", row+1, classes[row])
+	}
+	return bw.Flush()
+}
+
 // Scale centers the data set to zero mean values in each column and then normalizes them.
 // It does not modify the data stored in the matrix supplied as a parameter.
 func Scale(mx mat.Matrix) *mat.Dense {
 	return scale(mx, false)
 }
 
-// scale centers the supplied data set to zero mean in each column and then normalizes them.
-// You can specify whether you want to scale data in place or return new data set
-func scale(mx mat.Matrix, inPlace bool) *mat.Dense {
+// Scaler standardizes data columns using means and standard deviations fitted on some data set.
+// Scaler allows to apply the same scaling used on training data to any other data.
+type Scaler struct {
+	// Mean holds mean values of each column
+	Mean []float64 `json:"mean"`
+	// Stdev holds standard deviations of each column
+	Stdev []float64 `json:"stdev"`
+}
+
+// NewScaler fits a new scaler on the supplied data and returns it.
+func NewScaler(mx mat.Matrix) *Scaler {
 	rows, cols := mx.Dims()
 	// mean/stdev store each column mean/stdev values
 	col := make([]float64, rows)
@@ -311,19 +730,40 @@ func scale(mx mat.Matrix, inPlace bool) *mat.Dense {
 		mat.Col(col, i, mx)
 		mean[i], stdev[i] = stat.MeanStdDev(col, nil)
 	}
-	// initialize scale function
-	scale := func(i, j int, x float64) float64 {
-		return (x - mean[j]) / stdev[j]
+	return &Scaler{
+		Mean:  mean,
+		Stdev: stdev,
 	}
+}
+
+// Transform scales data in mx and returns it in a new matrix.
+// It fails with error if the number of mx columns does not match the number of fitted columns.
+func (s *Scaler) Transform(mx mat.Matrix) (*mat.Dense, error) {
+	if _, cols := mx.Dims(); cols != len(s.Mean) {
+		return nil, fmt.Errorf("column count mismatch: %d != %d", cols, len(s.Mean))
+	}
+	dataMx := new(mat.Dense)
+	dataMx.CloneFrom(mx)
+	dataMx.Apply(s.scale, dataMx)
+	return dataMx, nil
+}
+
+// scale scales matrix element x stored in column j
+func (s *Scaler) scale(i, j int, x float64) float64 {
+	return (x - s.Mean[j]) / s.Stdev[j]
+}
+
+// scale centers the supplied data set to zero mean in each column and then normalizes them.
+// You can specify whether you want to scale data in place or return new data set
+func scale(mx mat.Matrix, inPlace bool) *mat.Dense {
+	s := NewScaler(mx)
 	// if in place data should be modified
 	if inPlace {
 		mxDense := mx.(*mat.Dense)
-		mxDense.Apply(scale, mxDense)
+		mxDense.Apply(s.scale, mxDense)
 		return mxDense
 	}
-	// otherwise allocate new data matrix
-	dataMx := new(mat.Dense)
-	dataMx.CloneFrom(mx)
-	dataMx.Apply(scale, dataMx)
+	// otherwise allocate new data matrix; no need to check for error as the scaler was fitted on mx
+	dataMx, _ := s.Transform(mx)
 	return dataMx
 }
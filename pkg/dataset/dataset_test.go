@@ -1,13 +1,18 @@
 package dataset
 
 import (
+	"bytes"
+	"compress/gzip"
+	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"gonum.org/v1/gonum/mat"
@@ -68,8 +73,19 @@ func TestDataSet(t *testing.T) {
 	assert.True(mat.Equal(scaledMx, scaledDs))
 	assert.True(mat.Equal(scaledMx, ds.Data))
 
-	// unsupported file format
-	_, err = New("example", "")
+	// files with unknown extensions are loaded in the format detected from their contents
+	dir := t.TempDir()
+	datPath := filepath.Join(dir, "example.dat")
+	assert.NoError(ioutil.WriteFile(datPath, []byte("2.0;3.5\n4.5;5.5\n7.0;9.0"), 0666))
+	ds, err = New(datPath, "")
+	assert.NoError(err)
+	rows, cols = ds.Data.Dims()
+	assert.Equal(3, rows)
+	assert.Equal(2, cols)
+	// contents which can't be decoded in the detected format
+	txtPath := filepath.Join(dir, "example.txt")
+	assert.NoError(ioutil.WriteFile(txtPath, []byte("foo bar\nbaz"), 0666))
+	_, err = New(txtPath, "")
 	assert.Error(err)
 
 	// Nonexistent file
@@ -77,6 +93,128 @@ func TestDataSet(t *testing.T) {
 	assert.Error(err)
 }
 
+func TestNewFS(t *testing.T) {
+	assert := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"data/test.csv": {Data: []byte("2.0,3.5\n4.5,5.5\n7.0,9.0")},
+		"data/test.cls": {Data: []byte("% 3\n1\t1\n2\t2\n3\t1\n")},
+		"data/test.txt": {Data: []byte("foo")},
+	}
+	ds, err := NewFS(fsys, "data/test.csv", "data/test.cls")
+	assert.NoError(err)
+	rows, cols := ds.Data.Dims()
+	assert.Equal(3, rows)
+	assert.Equal(2, cols)
+	assert.Equal(map[int]int{0: 1, 1: 2, 2: 1}, ds.Classes)
+	// undecodable contents of unknown file format and unsupported classification file format
+	_, err = NewFS(fsys, "data/test.txt", "")
+	assert.Error(err)
+	_, err = NewFS(fsys, "data/test.csv", "data/test.txt")
+	assert.Error(err)
+	// nonexistent files
+	_, err = NewFS(fsys, "data/missing.csv", "")
+	assert.Error(err)
+	_, err = NewFS(fsys, "data/test.csv", "data/missing.cls")
+	assert.Error(err)
+}
+
+func TestCompressed(t *testing.T) {
+	assert := assert.New(t)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write([]byte("2.0,3.5\n4.5,5.5\n7.0,9.0"))
+	assert.NoError(err)
+	assert.NoError(zw.Close())
+	fsys := fstest.MapFS{
+		"test.csv.gz":  {Data: gz.Bytes()},
+		"test.lrn.gz":  {Data: []byte("corrupted")},
+		"test.csv.rev": {Data: []byte("0.9,0.7\n5.5,5.4\n5.3,0.2")},
+	}
+	ds, err := NewFS(fsys, "test.csv.gz", "")
+	assert.NoError(err)
+	rows, cols := ds.Data.Dims()
+	assert.Equal(3, rows)
+	assert.Equal(2, cols)
+	data, err := Load(bytes.NewReader(gz.Bytes()), "csv.gz")
+	assert.NoError(err)
+	assert.True(mat.Equal(ds.Data, data))
+	// corrupted compressed file
+	_, err = NewFS(fsys, "test.lrn.gz", "")
+	assert.Error(err)
+	// custom decompressor which reverses the data;
+	// unregistered extension is loaded in the format detected from the raw data
+	ds, err = NewFS(fsys, "test.csv.rev", "")
+	assert.NoError(err)
+	assert.True(mat.Equal(mat.NewDense(3, 2, []float64{0.9, 0.7, 5.5, 5.4, 5.3, 0.2}), ds.Data))
+	RegisterDecompressor(".rev", func(r io.Reader) (io.ReadCloser, error) {
+		b, err := ioutil.ReadAll(r)
+		for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+		return ioutil.NopCloser(bytes.NewReader(b)), err
+	})
+	ds, err = NewFS(fsys, "test.csv.rev", "")
+	assert.NoError(err)
+	assert.True(mat.Equal(mat.NewDense(3, 2, []float64{2.0, 3.5, 4.5, 5.5, 7.0, 9.0}), ds.Data))
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, format := range []string{"csv", ".csv"} {
+		data, err := Load(strings.NewReader("2.0,3.5\n4.5,5.5"), format)
+		assert.NoError(err)
+		assert.True(mat.Equal(mat.NewDense(2, 2, []float64{2.0, 3.5, 4.5, 5.5}), data))
+	}
+	data, err := Load(strings.NewReader("foo"), "txt")
+	assert.Nil(data)
+	assert.Error(err)
+}
+
+func TestDetectFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	lrn := "# comment\n% 2\n% 3\n% 9\t1\t1\n% Key\tC1\tC2\n1\t2.0\t3.5\n2\t4.5\t5.5\n"
+	exp := mat.NewDense(2, 2, []float64{2.0, 3.5, 4.5, 5.5})
+	testCases := []string{
+		"2.0,3.5\n4.5,5.5\n",
+		"2.0\t3.5\n4.5\t5.5\n",
+		"\n2.0;3.5\n4.5;5.5\n",
+		"2.0|3.5\n4.5|5.5",
+		lrn,
+	}
+	for _, data := range testCases {
+		mx, err := Load(strings.NewReader(data), "auto")
+		assert.NoError(err, data)
+		assert.True(mat.Equal(exp, mx), data)
+	}
+	// single column defaults to comma
+	assert.Equal(',', detectDelimiter("1.0"))
+
+	fsys := fstest.MapFS{
+		"data.txt":  {Data: []byte("2.0\t3.5\n4.5\t5.5\n")},
+		"data.esom": {Data: []byte(lrn)},
+		"data.tsv":  {Data: []byte("2.0\t3.5\n4.5\t5.5\n")},
+	}
+	for _, path := range []string{"data.txt", "data.esom", "data.tsv"} {
+		ds, err := NewFS(fsys, path, "")
+		assert.NoError(err, path)
+		assert.True(mat.Equal(exp, ds.Data), path)
+	}
+	// explicit format overrides file extension
+	ds, err := newFS(fsys, "data.esom", "", "auto")
+	assert.NoError(err)
+	assert.True(mat.Equal(exp, ds.Data))
+	_, err = newFS(fsys, "data.txt", "", "csv")
+	assert.Error(err)
+	_, err = newFS(fsys, "data.txt", "", "foo")
+	assert.Error(err)
+	_, err = NewFormat("missing.txt", "", "auto")
+	assert.Error(err)
+}
+
 func TestDataWithClasses(t *testing.T) {
 	assert := assert.New(t)
 
@@ -261,3 +399,136 @@ func TestScale(t *testing.T) {
 	scaledDs := Scale(ds.Data)
 	assert.True(mat.Equal(scaledDs, scaledMx))
 }
+
+func TestWriteLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(3, 2, []float64{1.5, -2.0, 3.25, 4.0, 0.0, 1e-7})
+	// CSV round trip
+	buf := new(bytes.Buffer)
+	err := WriteCSV(buf, data)
+	assert.NoError(err)
+	csvData, err := LoadCSV(buf)
+	assert.NoError(err)
+	assert.True(mat.Equal(data, csvData))
+	// LRN round trip
+	buf.Reset()
+	err = WriteLRN(buf, data)
+	assert.NoError(err)
+	lrnData, err := LoadLRN(buf)
+	assert.NoError(err)
+	assert.True(mat.Equal(data, lrnData))
+	// CLS round trip
+	classes := map[int]int{0: 1, 1: 0, 2: 1}
+	buf.Reset()
+	err = WriteCLS(buf, classes)
+	assert.NoError(err)
+	cls, err := LoadCLS(buf)
+	assert.NoError(err)
+	assert.Equal(classes, cls)
+}
+
+func TestScaler(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(3, 2, []float64{2.0, 3.5, 4.5, 5.5, 7.0, 9.0})
+	s := NewScaler(data)
+	assert.Len(s.Mean, 2)
+	assert.Len(s.Stdev, 2)
+	// scaler transforms data the same way as Scale
+	scaled, err := s.Transform(data)
+	assert.NoError(err)
+	assert.True(mat.EqualApprox(Scale(data), scaled, 1e-12))
+	// fitted data is not modified
+	assert.Equal(2.0, data.At(0, 0))
+	// column count mismatch
+	scaled, err = s.Transform(mat.NewDense(1, 3, nil))
+	assert.Nil(scaled)
+	assert.Error(err)
+}
+
+func TestConcat(t *testing.T) {
+	assert := assert.New(t)
+
+	a := &DataSet{Data: mat.NewDense(2, 2, []float64{1, 2, 3, 4}), Classes: map[int]int{1: 1}}
+	b := &DataSet{Data: mat.NewDense(1, 2, []float64{5, 6}), Classes: map[int]int{0: 2}}
+	ds, err := Concat(a, b)
+	assert.NoError(err)
+	assert.True(mat.Equal(mat.NewDense(3, 2, []float64{1, 2, 3, 4, 5, 6}), ds.Data))
+	assert.Equal(map[int]int{1: 1, 2: 2}, ds.Classes)
+	// inconsistent number of columns
+	_, err = Concat(a, &DataSet{Data: mat.NewDense(1, 3, nil)})
+	assert.Error(err)
+	// no data sets
+	_, err = Concat()
+	assert.Error(err)
+}
+
+func TestNewGlob(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "gosom_glob")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"part-1.csv": "1.0,2.0\n3.0,4.0",
+		"part-1.cls": "% 1\n2\t1\n",
+		"part-2.csv": "5.0,6.0",
+		"part-2.cls": "% 1\n1\t2\n",
+		"other.csv":  "1.0,2.0,3.0",
+	}
+	for name, content := range files {
+		assert.NoError(ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666))
+	}
+	ds, err := NewGlob(filepath.Join(dir, "part-*.csv"))
+	assert.NoError(err)
+	assert.True(mat.Equal(mat.NewDense(3, 2, []float64{1, 2, 3, 4, 5, 6}), ds.Data))
+	assert.Equal(map[int]int{1: 1, 2: 2}, ds.Classes)
+	// explicit paths without classification files
+	ds, err = NewMulti([]string{filepath.Join(dir, "part-2.csv"), filepath.Join(dir, "part-1.csv")}, nil)
+	assert.NoError(err)
+	assert.True(mat.Equal(mat.NewDense(3, 2, []float64{5, 6, 1, 2, 3, 4}), ds.Data))
+	assert.Len(ds.Classes, 0)
+	// inconsistent files
+	_, err = NewGlob(filepath.Join(dir, "*.csv"))
+	assert.Error(err)
+	// no matching files
+	_, err = NewGlob(filepath.Join(dir, "*.lrn"))
+	assert.Error(err)
+	// malformed pattern
+	_, err = NewGlob("[")
+	assert.Error(err)
+	// mismatched classification files
+	_, err = NewMulti([]string{filepath.Join(dir, "part-1.csv")}, []string{"", ""})
+	assert.Error(err)
+	_, err = NewMulti(nil, nil)
+	assert.Error(err)
+	// nonexistent file
+	_, err = NewMulti([]string{filepath.Join(dir, "missing.csv")}, nil)
+	assert.Error(err)
+}
+
+func TestDescribe(t *testing.T) {
+	assert := assert.New(t)
+
+	nan := math.NaN()
+	ds := &DataSet{Data: mat.NewDense(3, 3, []float64{
+		1.0, nan, nan,
+		2.0, 4.0, nan,
+		3.0, 6.0, nan,
+	})}
+	stats, err := ds.Describe()
+	assert.NoError(err)
+	assert.Len(stats, 3)
+	assert.Equal(ColumnStats{Min: 1.0, Max: 3.0, Mean: 2.0, Stdev: 1.0}, stats[0])
+	assert.Equal(4.0, stats[1].Min)
+	assert.Equal(6.0, stats[1].Max)
+	assert.Equal(5.0, stats[1].Mean)
+	assert.InDelta(math.Sqrt2, stats[1].Stdev, 1e-9)
+	assert.Equal(1, stats[1].NaNs)
+	assert.True(math.IsNaN(stats[2].Mean))
+	assert.Equal(3, stats[2].NaNs)
+	// empty data set
+	_, err = (&DataSet{}).Describe()
+	assert.Error(err)
+}
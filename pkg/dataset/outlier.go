@@ -0,0 +1,139 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// boundsFunc returns the range of column values vals which are not considered outliers
+type boundsFunc func(vals []float64, threshold float64) (lo, hi float64)
+
+// outlierMethods maps outlier detection methods to their bounds functions
+var outlierMethods = map[string]boundsFunc{
+	"zscore": zScoreBounds,
+	"iqr":    iqrBounds,
+}
+
+// Outlier is a data set row which contains values beyond the outlier threshold
+type Outlier struct {
+	// Row is the index of the data set row
+	Row int `json:"row"`
+	// Cols are the indices of columns whose values are outliers
+	Cols []int `json:"cols"`
+}
+
+// Outliers finds data set rows which contain values beyond the threshold of their column and returns them.
+// It supports the following detection methods:
+// - zscore: values whose distance from the column mean exceeds threshold column standard deviations
+// - iqr: values which lie more than threshold interquartile ranges below the first or above the third column quartile
+// NaN values are never considered outliers. Outliers are returned in ascending order of their rows.
+// It returns error if the data set has no data, unsupported method is requested or threshold is not positive.
+func (ds *DataSet) Outliers(method string, threshold float64) ([]Outlier, error) {
+	if ds.Data == nil || ds.Data.IsEmpty() {
+		return nil, fmt.Errorf("invalid data supplied: %v", ds.Data)
+	}
+	bounds, ok := outlierMethods[method]
+	if !ok {
+		return nil, fmt.Errorf("unsupported outlier detection method: %s", method)
+	}
+	if threshold <= 0.0 {
+		return nil, fmt.Errorf("invalid outlier threshold: %f", threshold)
+	}
+	rows, cols := ds.Data.Dims()
+	// outCols collects outlier columns of each row
+	outCols := make(map[int][]int)
+	vals := make([]float64, 0, rows)
+	for j := 0; j < cols; j++ {
+		vals = vals[:0]
+		for i := 0; i < rows; i++ {
+			if x := ds.Data.At(i, j); !math.IsNaN(x) {
+				vals = append(vals, x)
+			}
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		lo, hi := bounds(vals, threshold)
+		for i := 0; i < rows; i++ {
+			if x := ds.Data.At(i, j); x < lo || x > hi {
+				outCols[i] = append(outCols[i], j)
+			}
+		}
+	}
+	outliers := make([]Outlier, 0, len(outCols))
+	for row, cols := range outCols {
+		outliers = append(outliers, Outlier{Row: row, Cols: cols})
+	}
+	sort.Slice(outliers, func(i, j int) bool {
+		return outliers[i].Row < outliers[j].Row
+	})
+	return outliers, nil
+}
+
+// RemoveOutliers returns a new data set without the rows found by Outliers along with the removed outliers.
+// The classes of the remaining rows are reindexed to match the rows of the new data set.
+// It fails with the same errors as Outliers.
+func (ds *DataSet) RemoveOutliers(method string, threshold float64) (*DataSet, []Outlier, error) {
+	outliers, err := ds.Outliers(method, threshold)
+	if err != nil {
+		return nil, nil, err
+	}
+	drop := make(map[int]bool, len(outliers))
+	for _, o := range outliers {
+		drop[o.Row] = true
+	}
+	rows, _ := ds.Data.Dims()
+	keep := make([]int, 0, rows-len(drop))
+	for i := 0; i < rows; i++ {
+		if !drop[i] {
+			keep = append(keep, i)
+		}
+	}
+	if len(keep) == 0 {
+		return nil, nil, fmt.Errorf("all %d data set rows are outliers", rows)
+	}
+	return ds.subset(keep), outliers, nil
+}
+
+// subset returns a new data set which contains copies of data set rows in the given order.
+// Classes of the copied rows are reindexed to match the rows of the new data set.
+func (ds *DataSet) subset(rows []int) *DataSet {
+	_, cols := ds.Data.Dims()
+	data := mat.NewDense(len(rows), cols, nil)
+	classes := make(map[int]int)
+	for i, row := range rows {
+		data.SetRow(i, ds.Data.RawRowView(row))
+		if class, ok := ds.Classes[row]; ok {
+			classes[i] = class
+		}
+	}
+	return &DataSet{
+		Data:    data,
+		Classes: classes,
+	}
+}
+
+// zScoreBounds returns values which are at most threshold standard deviations away from the mean of vals
+func zScoreBounds(vals []float64, threshold float64) (float64, float64) {
+	mean, stdev := stat.MeanStdDev(vals, nil)
+	// a single value has no deviation
+	if math.IsNaN(stdev) {
+		stdev = 0.0
+	}
+	return mean - threshold*stdev, mean + threshold*stdev
+}
+
+// iqrBounds returns values which are at most threshold interquartile ranges away from the quartiles of vals
+func iqrBounds(vals []float64, threshold float64) (float64, float64) {
+	sorted := make([]float64, len(vals))
+	copy(sorted, vals)
+	sort.Float64s(sorted)
+	q1 := stat.Quantile(0.25, stat.Empirical, sorted, nil)
+	q3 := stat.Quantile(0.75, stat.Empirical, sorted, nil)
+	iqr := q3 - q1
+	return q1 - threshold*iqr, q3 + threshold*iqr
+}
@@ -0,0 +1,64 @@
+package dataset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestOutliers(t *testing.T) {
+	assert := assert.New(t)
+
+	nan := math.NaN()
+	ds := &DataSet{
+		Data: mat.NewDense(10, 2, []float64{
+			1.0, 1.0,
+			2.0, 1.0,
+			1.0, 2.0,
+			2.0, 2.0,
+			1.5, 1.5,
+			1.0, nan,
+			2.0, 1.0,
+			100.0, 1.0,
+			1.0, -100.0,
+			100.0, 100.0,
+		}),
+		Classes: map[int]int{0: 1, 6: 2, 8: 3, 9: 4},
+	}
+	testCases := []struct {
+		method    string
+		threshold float64
+		expected  []Outlier
+	}{
+		{"iqr", 1.5, []Outlier{{Row: 7, Cols: []int{0}}, {Row: 8, Cols: []int{1}}, {Row: 9, Cols: []int{0, 1}}}},
+		{"zscore", 1.5, []Outlier{{Row: 7, Cols: []int{0}}, {Row: 8, Cols: []int{1}}, {Row: 9, Cols: []int{0, 1}}}},
+		{"zscore", 3.0, []Outlier{}},
+	}
+	for _, tc := range testCases {
+		outliers, err := ds.Outliers(tc.method, tc.threshold)
+		assert.NoError(err)
+		assert.Equal(tc.expected, outliers, tc.method)
+	}
+	// remove outliers
+	clean, outliers, err := ds.RemoveOutliers("iqr", 1.5)
+	assert.NoError(err)
+	assert.Len(outliers, 3)
+	rows, _ := clean.Data.Dims()
+	assert.Equal(7, rows)
+	assert.Equal([]float64{2.0, 1.0}, clean.Data.RawRowView(6))
+	assert.Equal(map[int]int{0: 1, 6: 2}, clean.Classes)
+	// original data set is not modified
+	rows, _ = ds.Data.Dims()
+	assert.Equal(10, rows)
+	// unsupported method
+	_, err = ds.Outliers("foobar", 1.0)
+	assert.Error(err)
+	// invalid threshold
+	_, _, err = ds.RemoveOutliers("iqr", 0.0)
+	assert.Error(err)
+	// empty data set
+	_, err = (&DataSet{}).Outliers("iqr", 1.0)
+	assert.Error(err)
+}
@@ -0,0 +1,43 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// SplitWeights removes column col, such as a count of duplicate rows of a pre-aggregated data set,
+// from the data set and returns it as row weights along with a new data set without the column.
+// Classes of the rows are preserved. It returns error if col is out of range, the data set has a
+// single column or if any of the weights is negative or not finite.
+func (ds *DataSet) SplitWeights(col int) (*DataSet, []float64, error) {
+	rows, cols := ds.Data.Dims()
+	if col < 0 || col >= cols {
+		return nil, nil, fmt.Errorf("invalid weights column: %d", col)
+	}
+	if cols == 1 {
+		return nil, nil, fmt.Errorf("data set has no columns besides weights")
+	}
+	weights := mat.Col(nil, col, ds.Data)
+	for i, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return nil, nil, fmt.Errorf("invalid weight of row %d: %f", i, w)
+		}
+	}
+	data := mat.NewDense(rows, cols-1, nil)
+	for i := 0; i < rows; i++ {
+		row := ds.Data.RawRowView(i)
+		dst := data.RawRowView(i)
+		copy(dst, row[:col])
+		copy(dst[col:], row[col+1:])
+	}
+	classes := make(map[int]int, len(ds.Classes))
+	for row, class := range ds.Classes {
+		classes[row] = class
+	}
+	return &DataSet{
+		Data:    data,
+		Classes: classes,
+	}, weights, nil
+}
@@ -0,0 +1,45 @@
+package dataset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSplitWeights(t *testing.T) {
+	assert := assert.New(t)
+
+	ds := &DataSet{
+		Data: mat.NewDense(3, 3, []float64{
+			1.0, 2.0, 3.0,
+			4.0, 1.0, 6.0,
+			7.0, 0.0, 9.0}),
+		Classes: map[int]int{2: 1},
+	}
+	wds, weights, err := ds.SplitWeights(1)
+	assert.NoError(err)
+	assert.Equal([]float64{2.0, 1.0, 0.0}, weights)
+	assert.True(mat.Equal(mat.NewDense(3, 2, []float64{1.0, 3.0, 4.0, 6.0, 7.0, 9.0}), wds.Data))
+	assert.Equal(ds.Classes, wds.Classes)
+	// original data set is not modified
+	_, cols := ds.Data.Dims()
+	assert.Equal(3, cols)
+
+	// column out of range
+	for _, col := range []int{-1, 3} {
+		_, _, err = ds.SplitWeights(col)
+		assert.Error(err)
+	}
+	// invalid weights
+	ds.Data.Set(0, 1, -1.0)
+	_, _, err = ds.SplitWeights(1)
+	assert.Error(err)
+	ds.Data.Set(0, 1, math.Inf(1))
+	_, _, err = ds.SplitWeights(1)
+	assert.Error(err)
+	// data set with weights only
+	_, _, err = (&DataSet{Data: mat.NewDense(2, 1, []float64{1.0, 2.0})}).SplitWeights(0)
+	assert.Error(err)
+}
@@ -0,0 +1,292 @@
+// Package experiment runs reproducible SOM training experiments.
+// An experiment trains a map on a data set using a given configuration, evaluates it
+// and records everything needed to compare and reproduce its results in a manifest.
+package experiment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+	"github.com/milosgajdos/gosom/pkg/model"
+	"github.com/milosgajdos/gosom/pkg/utils"
+	"github.com/milosgajdos/gosom/som"
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	// ManifestFile is the name of experiment manifest file
+	ManifestFile = "manifest.json"
+	// modelFile is the name of the trained model bundle
+	modelFile = "model.zip"
+)
+
+// Config holds experiment configuration
+type Config struct {
+	// Name is experiment name
+	Name string `json:"name"`
+	// Input is path to data set
+	Input string `json:"input"`
+	// Classes is path to data set classification file
+	Classes string `json:"classes,omitempty"`
+	// Scale requests data feature scaling
+	Scale bool `json:"scale,omitempty"`
+	// Dims are SOM grid dimensions; they are estimated from data if empty
+	Dims []int `json:"dims,omitempty"`
+	// Grid is SOM grid type
+	Grid string `json:"grid"`
+	// UShape is SOM unit shape
+	UShape string `json:"ushape"`
+	// Algorithm is training algorithm
+	Algorithm string `json:"algorithm"`
+	// Radius is initial SOM unit radius; half of the largest grid dimension is used if 0
+	Radius float64 `json:"radius,omitempty"`
+	// RDecay is radius decay strategy
+	RDecay string `json:"rdecay"`
+	// Init is the name of codebook initialization function; see som.ParseCbInitFunc.
+	// If Init is empty, rand is used
+	Init string `json:"init,omitempty"`
+	// NeighbFn is the name of neighbourhood function
+	NeighbFn string `json:"neighb"`
+	// LRate is initial SOM learning rate
+	LRate float64 `json:"lrate"`
+	// LDecay is learning rate decay strategy
+	LDecay string `json:"ldecay"`
+	// Iters is the number of training iterations; it is suggested by som.SuggestIterations if 0
+	Iters int `json:"iters,omitempty"`
+	// Seed seeds the random number generators used by the experiment
+	Seed int64 `json:"seed"`
+}
+
+// Hash returns hex encoded SHA-256 hash of JSON encoded configuration
+func (c *Config) Hash() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Manifest records experiment run
+type Manifest struct {
+	// Config is experiment configuration
+	Config *Config `json:"config"`
+	// ConfigHash is the hash of experiment configuration
+	ConfigHash string `json:"config_hash"`
+	// DataFingerprint is SHA-256 fingerprint of the training data
+	DataFingerprint string `json:"data_fingerprint"`
+	// Seeds holds the seeds of random number generators
+	Seeds map[string]int64 `json:"seeds"`
+	// Version is gosom version which ran the experiment
+	Version string `json:"version"`
+	// Started is the time the experiment started
+	Started time.Time `json:"started"`
+	// Duration is the duration of map training
+	Duration string `json:"duration"`
+	// Dims are the dimensions of trained SOM grid
+	Dims []int `json:"dims"`
+	// Iters is the number of training iterations
+	Iters int `json:"iters"`
+	// Metrics holds map quality measures; measures which are not finite are omitted
+	Metrics map[string]float64 `json:"metrics"`
+	// Artifacts maps experiment artifacts to their paths relative to the experiment directory
+	Artifacts map[string]string `json:"artifacts"`
+}
+
+// Run runs the experiment configured by c and saves its artifacts and manifest to directory dir.
+// It returns the manifest or fails with error if the experiment could not be run or saved.
+func Run(c *Config, dir string) (*Manifest, error) {
+	if c == nil {
+		return nil, fmt.Errorf("invalid experiment configuration: %v", c)
+	}
+	hash, err := c.Hash()
+	if err != nil {
+		return nil, err
+	}
+	mf := &Manifest{
+		Config:     c,
+		ConfigHash: hash,
+		Seeds:      map[string]int64{"seed": c.Seed},
+		Version:    som.Version,
+		Started:    time.Now().UTC(),
+		Metrics:    make(map[string]float64),
+		Artifacts:  make(map[string]string),
+	}
+	// seed the global random number generator
+	rand.Seed(c.Seed)
+
+	ds, err := dataset.New(c.Input, c.Classes)
+	if err != nil {
+		return nil, err
+	}
+	data := ds.Data
+	var scaler *dataset.Scaler
+	if c.Scale {
+		scaler = dataset.NewScaler(ds.Data)
+		if data, err = scaler.Transform(ds.Data); err != nil {
+			return nil, err
+		}
+	}
+	mf.DataFingerprint = som.Fingerprint(data)
+
+	mf.Dims = c.Dims
+	if len(mf.Dims) == 0 {
+		if mf.Dims, err = som.GridSize(data, c.UShape); err != nil {
+			return nil, err
+		}
+	}
+	rows, dim := data.Dims()
+	initName := c.Init
+	if initName == "" {
+		initName = "rand"
+	}
+	initFn, err := som.ParseCbInitFunc(initName)
+	if err != nil {
+		return nil, err
+	}
+	m, err := som.NewMap(&som.MapConfig{
+		Grid: &som.GridConfig{
+			Size:   mf.Dims,
+			Type:   c.Grid,
+			UShape: c.UShape,
+		},
+		Cb: &som.CbConfig{
+			Dim:      dim,
+			InitFunc: initFn,
+		},
+	}, data)
+	if err != nil {
+		return nil, err
+	}
+
+	neighbFn, err := som.ParseNeighbFunc(c.NeighbFn)
+	if err != nil {
+		return nil, err
+	}
+	tc := &som.TrainConfig{
+		Algorithm: c.Algorithm,
+		Radius:    c.Radius,
+		RDecay:    c.RDecay,
+		NeighbFn:  neighbFn,
+		LRate:     c.LRate,
+		LDecay:    c.LDecay,
+		Seed:      c.Seed,
+	}
+	if tc.Radius == 0 {
+		tc.Radius = math.Max(som.MinRadius, float64(maxInt(mf.Dims))/2.0)
+	}
+	mf.Iters = c.Iters
+	if mf.Iters == 0 {
+		if mf.Iters, err = som.SuggestIterations(rows, utils.IntProduct(mf.Dims), c.Algorithm); err != nil {
+			return nil, err
+		}
+	}
+	t0 := time.Now()
+	if err := m.Train(tc, data, mf.Iters); err != nil {
+		return nil, err
+	}
+	mf.Duration = time.Since(t0).String()
+
+	if err := evaluate(mf, m, data, ds.Classes); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	b := &model.Bundle{Map: m, Scaler: scaler}
+	if len(ds.Classes) > 0 {
+		stats, err := m.ClassStats(data, ds.Classes)
+		if err != nil {
+			return nil, err
+		}
+		b.Classes = stats.Dominant()
+	}
+	if err := model.SaveFile(filepath.Join(dir, modelFile), b); err != nil {
+		return nil, err
+	}
+	mf.Artifacts["model"] = modelFile
+
+	return mf, saveManifest(mf, filepath.Join(dir, ManifestFile))
+}
+
+// evaluate computes quality measures of map m on data and records them in manifest
+func evaluate(mf *Manifest, m *som.Map, data *mat.Dense, classes map[int]int) error {
+	qe, err := m.QuantError(data)
+	if err != nil {
+		return err
+	}
+	mf.addMetric("quant_error", qe)
+	tp, err := m.TopoProduct()
+	if err != nil {
+		return err
+	}
+	mf.addMetric("topo_product", tp)
+	te, err := m.TopoError(data)
+	if err != nil {
+		return err
+	}
+	mf.addMetric("topo_error", te)
+	if len(classes) > 0 {
+		stats, err := m.ClassStats(data, classes)
+		if err != nil {
+			return err
+		}
+		mf.addMetric("purity", stats.Purity())
+	}
+	return nil
+}
+
+// addMetric records metric in manifest unless its value is not finite;
+// infinite topographic product signals duplicate codebook vectors
+func (mf *Manifest) addMetric(name string, val float64) {
+	if !math.IsInf(val, 0) && !math.IsNaN(val) {
+		mf.Metrics[name] = val
+	}
+}
+
+// LoadManifest loads experiment manifest from a file in path
+func LoadManifest(path string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	mf := &Manifest{}
+	if err := json.NewDecoder(file).Decode(mf); err != nil {
+		return nil, err
+	}
+	return mf, nil
+}
+
+// saveManifest saves experiment manifest to a file in path
+func saveManifest(mf *Manifest, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	return enc.Encode(mf)
+}
+
+func maxInt(vals []int) int {
+	max := 0
+	for _, v := range vals {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
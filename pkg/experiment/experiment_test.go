@@ -0,0 +1,94 @@
+package experiment
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const csvData = `5.1,3.5,1.4,0.1
+4.9,3.0,1.4,0.2
+4.7,3.2,1.3,0.3
+4.6,3.1,1.5,0.4
+5.0,3.6,1.4,0.5
+6.4,3.2,4.5,1.5
+6.9,3.1,4.9,1.5
+5.5,2.3,4.0,1.3
+`
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "experiment")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "data.csv")
+	assert.NoError(ioutil.WriteFile(input, []byte(csvData), 0666))
+
+	c := &Config{
+		Name:      "test",
+		Input:     input,
+		Dims:      []int{2, 3},
+		Grid:      "planar",
+		UShape:    "hexagon",
+		Algorithm: "batch",
+		RDecay:    "exp",
+		NeighbFn:  "gaussian",
+		LRate:     0.5,
+		LDecay:    "exp",
+		Iters:     10,
+		Seed:      42,
+	}
+	runDir := filepath.Join(dir, "run")
+	mf, err := Run(c, runDir)
+	assert.NoError(err)
+	hash, err := c.Hash()
+	assert.NoError(err)
+	assert.Equal(hash, mf.ConfigHash)
+	assert.Equal(int64(42), mf.Seeds["seed"])
+	assert.Equal(10, mf.Iters)
+	assert.Equal([]int{2, 3}, mf.Dims)
+	for _, metric := range []string{"quant_error", "topo_error"} {
+		assert.Contains(mf.Metrics, metric)
+	}
+	assert.FileExists(filepath.Join(runDir, mf.Artifacts["model"]))
+	// saved manifest matches the returned one
+	saved, err := LoadManifest(filepath.Join(runDir, ManifestFile))
+	assert.NoError(err)
+	assert.Equal(mf.ConfigHash, saved.ConfigHash)
+	assert.Equal(mf.DataFingerprint, saved.DataFingerprint)
+	assert.Equal(mf.Metrics, saved.Metrics)
+	// rerunning the saved config reproduces the results
+	rerun, err := Run(saved.Config, filepath.Join(dir, "rerun"))
+	assert.NoError(err)
+	assert.Equal(mf.ConfigHash, rerun.ConfigHash)
+	assert.Equal(mf.Metrics, rerun.Metrics)
+	// changing the config changes its hash
+	c.Seed = 1
+	other, err := c.Hash()
+	assert.NoError(err)
+	assert.NotEqual(hash, other)
+	// codebook initialization selected by name
+	c.Init = "sample"
+	_, err = Run(c, filepath.Join(dir, "sample"))
+	assert.NoError(err)
+	// invalid configurations
+	c.Init = "foo"
+	_, err = Run(c, runDir)
+	assert.Error(err)
+	c.Init = ""
+	c.NeighbFn = "foo"
+	_, err = Run(c, runDir)
+	assert.Error(err)
+	c.Input = filepath.Join(dir, "missing.csv")
+	_, err = Run(c, runDir)
+	assert.Error(err)
+	_, err = Run(nil, runDir)
+	assert.Error(err)
+	// missing manifest
+	_, err = LoadManifest(filepath.Join(dir, ManifestFile))
+	assert.Error(err)
+}
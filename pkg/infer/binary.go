@@ -0,0 +1,264 @@
+package infer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// binaryMagic starts binary encoded models
+const binaryMagic = "GSOM"
+
+// binaryVersion is the version of binary model encoding; version 1 encodings have no column weights
+const binaryVersion = 2
+
+// maxBinaryLength is the maximum length of slices and strings of binary encoded models
+const maxBinaryLength = 1 << 28
+
+// EncodeBinary encodes the model to w in a compact binary format which DecodeBinary reads back.
+// Unlike JSON encoding, the binary encoding needs no reflection, so the decoder stays small enough
+// for embedded and edge programs. All the numbers are little-endian: lengths and integers are varints
+// and codebook, coordinates and scaling values are IEEE 754 double precision numbers.
+// It fails with error if the model is not valid or if the write to w fails.
+func (m *Model) EncodeBinary(w io.Writer) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	bw := &binWriter{w: bufio.NewWriter(w)}
+	bw.bytes([]byte(binaryMagic))
+	bw.uvarint(binaryVersion)
+	bw.uvarint(uint64(len(m.Dims)))
+	for _, d := range m.Dims {
+		bw.varint(int64(d))
+	}
+	bw.str(m.UShape)
+	bw.rows(m.Coords)
+	if q := m.Quantized; q != nil {
+		bw.str(q.Kind)
+		bw.uvarint(uint64(q.Dim))
+		bw.floats(q.Offset)
+		bw.floats(q.Scale)
+		bw.uvarint(uint64(len(q.Data)))
+		bw.bytes(q.Data)
+	} else {
+		bw.str("")
+		bw.rows(m.Codebook)
+	}
+	bw.floats(m.Mean)
+	bw.floats(m.Stdev)
+	// classes are encoded in the order of their units, so equal models have equal encodings
+	units := make([]int, 0, len(m.Classes))
+	for unit := range m.Classes {
+		units = append(units, unit)
+	}
+	sort.Ints(units)
+	bw.uvarint(uint64(len(units)))
+	for _, unit := range units {
+		bw.varint(int64(unit))
+		bw.varint(int64(m.Classes[unit]))
+	}
+	bw.floats(m.Weights)
+	if bw.err != nil {
+		return bw.err
+	}
+	return bw.w.Flush()
+}
+
+// DecodeBinary decodes binary encoded model from r and returns it.
+// It fails with error if the model can not be decoded, was encoded by an unsupported version
+// of the encoding or is not valid. Models encoded by earlier versions of the encoding are still decoded.
+func DecodeBinary(r io.Reader) (*Model, error) {
+	br := &binReader{r: bufio.NewReader(r)}
+	if magic := br.bytes(len(binaryMagic)); br.err == nil && string(magic) != binaryMagic {
+		return nil, fmt.Errorf("invalid binary model")
+	}
+	version := br.uvarint()
+	if br.err == nil && (version < 1 || version > binaryVersion) {
+		return nil, fmt.Errorf("unsupported binary model version: %d", version)
+	}
+	m := new(Model)
+	for i, n := 0, br.length(); i < n && br.err == nil; i++ {
+		m.Dims = append(m.Dims, int(br.varint()))
+	}
+	m.UShape = br.str()
+	m.Coords = br.rows()
+	if kind := br.str(); kind != "" {
+		q := &Quantized{Kind: kind}
+		q.Dim = int(br.uvarint())
+		q.Offset = br.floats()
+		q.Scale = br.floats()
+		q.Data = br.bytes(br.length())
+		m.Quantized = q
+	} else {
+		m.Codebook = br.rows()
+	}
+	m.Mean = br.floats()
+	m.Stdev = br.floats()
+	if n := br.length(); n > 0 {
+		m.Classes = make(map[int]int)
+		for i := 0; i < n && br.err == nil; i++ {
+			unit := int(br.varint())
+			m.Classes[unit] = int(br.varint())
+		}
+	}
+	if version > 1 {
+		m.Weights = br.floats()
+	}
+	if br.err != nil {
+		return nil, br.err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// binWriter writes binary encoded values and keeps the first write error
+type binWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+// bytes writes raw bytes
+func (b *binWriter) bytes(p []byte) {
+	if b.err == nil {
+		_, b.err = b.w.Write(p)
+	}
+}
+
+// uvarint writes unsigned varint
+func (b *binWriter) uvarint(x uint64) {
+	b.bytes(b.buf[:binary.PutUvarint(b.buf[:], x)])
+}
+
+// varint writes signed varint
+func (b *binWriter) varint(x int64) {
+	b.bytes(b.buf[:binary.PutVarint(b.buf[:], x)])
+}
+
+// str writes length prefixed string
+func (b *binWriter) str(s string) {
+	b.uvarint(uint64(len(s)))
+	b.bytes([]byte(s))
+}
+
+// floats writes length prefixed float slice
+func (b *binWriter) floats(v []float64) {
+	b.uvarint(uint64(len(v)))
+	for _, x := range v {
+		binary.LittleEndian.PutUint64(b.buf[:8], math.Float64bits(x))
+		b.bytes(b.buf[:8])
+	}
+}
+
+// rows writes the number of rows followed by length prefixed float slices
+func (b *binWriter) rows(rows [][]float64) {
+	b.uvarint(uint64(len(rows)))
+	for _, row := range rows {
+		b.floats(row)
+	}
+}
+
+// binReader reads binary encoded values and keeps the first read error
+type binReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+// bytes reads n raw bytes
+func (b *binReader) bytes(n int) []byte {
+	if b.err != nil {
+		return nil
+	}
+	// corrupted lengths must not allocate much more memory than there is data,
+	// so the bytes are read in chunks of the reader buffer size
+	var p []byte
+	for len(p) < n && b.err == nil {
+		size := n - len(p)
+		if size > b.r.Size() {
+			size = b.r.Size()
+		}
+		chunk := make([]byte, size)
+		_, b.err = io.ReadFull(b.r, chunk)
+		p = append(p, chunk...)
+	}
+	b.unexpected()
+	return p
+}
+
+// uvarint reads unsigned varint
+func (b *binReader) uvarint() uint64 {
+	if b.err != nil {
+		return 0
+	}
+	var x uint64
+	x, b.err = binary.ReadUvarint(b.r)
+	b.unexpected()
+	return x
+}
+
+// varint reads signed varint
+func (b *binReader) varint() int64 {
+	if b.err != nil {
+		return 0
+	}
+	var x int64
+	x, b.err = binary.ReadVarint(b.r)
+	b.unexpected()
+	return x
+}
+
+// length reads the length of encoded slice or string
+func (b *binReader) length() int {
+	n := b.uvarint()
+	if n > maxBinaryLength && b.err == nil {
+		b.err = fmt.Errorf("invalid binary model length: %d", n)
+	}
+	return int(n)
+}
+
+// str reads length prefixed string
+func (b *binReader) str() string {
+	return string(b.bytes(b.length()))
+}
+
+// floats reads length prefixed float slice; it returns nil if the slice is empty
+func (b *binReader) floats() []float64 {
+	n := b.length()
+	if n == 0 || b.err != nil {
+		return nil
+	}
+	p := b.bytes(8 * n)
+	if b.err != nil {
+		return nil
+	}
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = math.Float64frombits(binary.LittleEndian.Uint64(p[8*i:]))
+	}
+	return v
+}
+
+// rows reads the number of rows followed by length prefixed float slices
+func (b *binReader) rows() [][]float64 {
+	n := b.length()
+	if n == 0 || b.err != nil {
+		return nil
+	}
+	var rows [][]float64
+	for i := 0; i < n && b.err == nil; i++ {
+		rows = append(rows, b.floats())
+	}
+	return rows
+}
+
+// unexpected turns the end of data in the middle of a value into an error
+func (b *binReader) unexpected() {
+	if b.err == io.EOF {
+		b.err = io.ErrUnexpectedEOF
+	}
+}
@@ -0,0 +1,58 @@
+package infer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecodeBinary(t *testing.T) {
+	assert := assert.New(t)
+
+	m := makeModel()
+	m.Mean, m.Stdev = []float64{1, 2}, []float64{0.5, 3}
+	q8, err := m.Quantize("int8")
+	assert.NoError(err)
+	q16, err := m.Quantize("float16")
+	assert.NoError(err)
+	w := makeModel()
+	w.Weights = []float64{0, 2}
+	for _, model := range []*Model{makeModel(), m, q8, q16, w} {
+		buf := new(bytes.Buffer)
+		assert.NoError(model.EncodeBinary(buf))
+		data := append([]byte(nil), buf.Bytes()...)
+		dm, err := DecodeBinary(buf)
+		assert.NoError(err)
+		assert.Equal(model, dm)
+		// equal models have equal encodings
+		buf.Reset()
+		assert.NoError(dm.EncodeBinary(buf))
+		assert.Equal(data, buf.Bytes())
+		// truncated data
+		for n := 0; n < len(data); n++ {
+			_, err := DecodeBinary(bytes.NewReader(data[:n]))
+			assert.Error(err, "%d bytes", n)
+		}
+	}
+
+	// version 1 encodings end with classes
+	buf := new(bytes.Buffer)
+	assert.NoError(m.EncodeBinary(buf))
+	data := buf.Bytes()
+	data[len(binaryMagic)] = 1
+	dm, err := DecodeBinary(bytes.NewReader(data[:len(data)-1]))
+	assert.NoError(err)
+	assert.Equal(m, dm)
+
+	// invalid models
+	assert.Error((&Model{}).EncodeBinary(new(bytes.Buffer)))
+	_, err = DecodeBinary(strings.NewReader("{}"))
+	assert.Error(err)
+	_, err = DecodeBinary(strings.NewReader(binaryMagic + "\x03"))
+	assert.Error(err)
+	// huge lengths of corrupted data
+	_, err = DecodeBinary(strings.NewReader(binaryMagic + "\x01\xff\xff\xff\xff\x0f"))
+	assert.Error(err)
+}
@@ -0,0 +1,209 @@
+// Package infer projects data onto trained self-organizing maps.
+// It only depends on the standard library and does no file IO, so the trained maps
+// can be compiled into small WebAssembly binaries and used in browsers.
+// Models are encoded in JSON or in a compact binary format which needs no reflection to decode.
+package infer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Model is a trained SOM used for inference
+type Model struct {
+	// Dims are map grid dimensions
+	Dims []int `json:"dims"`
+	// UShape is map unit shape
+	UShape string `json:"ushape"`
+	// Coords holds grid coordinates of map units
+	Coords [][]float64 `json:"coords"`
+	// Codebook holds codebook vectors of map units; it is empty if the codebook is quantized
+	Codebook [][]float64 `json:"codebook,omitempty"`
+	// Quantized holds quantized codebook vectors of map units; see Quantize
+	Quantized *Quantized `json:"quantized,omitempty"`
+	// Mean holds column means used to standardize data; it can be empty
+	Mean []float64 `json:"mean,omitempty"`
+	// Stdev holds column standard deviations used to standardize data; it can be empty
+	Stdev []float64 `json:"stdev,omitempty"`
+	// Weights holds column weights which scale squared differences in BMU search; it can be empty
+	Weights []float64 `json:"weights,omitempty"`
+	// Classes maps map units to their classes; it can be empty
+	Classes map[int]int `json:"classes,omitempty"`
+}
+
+// Decode decodes JSON encoded model from r and returns it.
+// It fails with error if the model can not be decoded or is not valid.
+func Decode(r io.Reader) (*Model, error) {
+	m := new(Model)
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Encode encodes the model to w in JSON format.
+// It fails with error if the write to w fails.
+func (m *Model) Encode(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m)
+}
+
+// Validate checks if the model is valid.
+// It returns error if the model has no units, if the number of unit coordinates differs from
+// the number of codebook vectors, if the codebook vectors, scaling parameters or column weights have different
+// dimensions, if any column weight is negative or non-finite or all of them are zero or if the model has both
+// plain and quantized codebook or its quantized codebook is invalid.
+func (m *Model) Validate() error {
+	if m.Quantized != nil {
+		if len(m.Codebook) > 0 {
+			return fmt.Errorf("model has both plain and quantized codebook")
+		}
+		if err := m.Quantized.validate(len(m.Coords)); err != nil {
+			return err
+		}
+	} else {
+		if len(m.Codebook) == 0 {
+			return fmt.Errorf("invalid codebook: %v", m.Codebook)
+		}
+		if len(m.Coords) != len(m.Codebook) {
+			return fmt.Errorf("unit count mismatch: %d != %d", len(m.Coords), len(m.Codebook))
+		}
+		dim := len(m.Codebook[0])
+		if dim == 0 {
+			return fmt.Errorf("invalid codebook dimension: %d", dim)
+		}
+		for i, vec := range m.Codebook {
+			if len(vec) != dim {
+				return fmt.Errorf("invalid codebook vector %d dimension: %d", i, len(vec))
+			}
+		}
+	}
+	dim := m.Dim()
+	if len(m.Mean) != len(m.Stdev) || (len(m.Mean) > 0 && len(m.Mean) != dim) {
+		return fmt.Errorf("invalid scaling dimensions: %d, %d", len(m.Mean), len(m.Stdev))
+	}
+	if len(m.Weights) > 0 {
+		if len(m.Weights) != dim {
+			return fmt.Errorf("invalid weights dimension: %d", len(m.Weights))
+		}
+		positive := false
+		for j, w := range m.Weights {
+			if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				return fmt.Errorf("invalid weight of column %d: %f", j, w)
+			}
+			positive = positive || w > 0
+		}
+		if !positive {
+			return fmt.Errorf("all column weights are zero")
+		}
+	}
+	return nil
+}
+
+// Units returns the number of map units
+func (m *Model) Units() int {
+	return len(m.Coords)
+}
+
+// Dim returns the dimension of codebook vectors
+func (m *Model) Dim() int {
+	if m.Quantized != nil {
+		return m.Quantized.Dim
+	}
+	return len(m.Codebook[0])
+}
+
+// Vector returns the codebook vector of i-th unit; quantized codebook vectors are dequantized.
+// It panics if i is out of range.
+func (m *Model) Vector(i int) []float64 {
+	if m.Quantized == nil {
+		return m.Codebook[i]
+	}
+	vec := make([]float64, m.Quantized.Dim)
+	for j := range vec {
+		vec[j] = m.Quantized.value(i, j)
+	}
+	return vec
+}
+
+// BMU returns the index of the best matching unit of vector v and its distance from v.
+// If the model has scaling parameters, v is standardized before the BMU is searched for.
+// Quantized codebook vectors are dequantized on the fly, so the codebook stays compressed in memory.
+// Missing values of v, i.e. NaNs, are skipped in the same way as by the som package distances and
+// squared differences of columns are scaled by the column weights, if the model has any.
+// It fails with error if the dimension of v is different from the model codebook dimension.
+func (m *Model) BMU(v []float64) (int, float64, error) {
+	if len(v) != m.Dim() {
+		return -1, 0.0, fmt.Errorf("incorrect vector dims: %d", len(v))
+	}
+	if len(m.Mean) > 0 {
+		scaled := make([]float64, len(v))
+		for i := range v {
+			scaled[i] = (v[i] - m.Mean[i]) / m.Stdev[i]
+		}
+		v = scaled
+	}
+	// units are abandoned as soon as their partial squared distance reaches the best one
+	bmu, dist := 0, math.MaxFloat64
+	if q := m.Quantized; q != nil {
+		for i := 0; i < m.Units(); i++ {
+			d := 0.0
+			for j := 0; j < len(v) && d < dist; j++ {
+				// missing values are skipped
+				if diff := v[j] - q.value(i, j); diff == diff {
+					d += m.weight(j) * diff * diff
+				}
+			}
+			if d < dist {
+				bmu, dist = i, d
+			}
+		}
+		return bmu, math.Sqrt(dist), nil
+	}
+	for i, vec := range m.Codebook {
+		d := 0.0
+		for j := 0; j < len(v) && d < dist; j++ {
+			if diff := v[j] - vec[j]; diff == diff {
+				d += m.weight(j) * diff * diff
+			}
+		}
+		if d < dist {
+			bmu, dist = i, d
+		}
+	}
+	return bmu, math.Sqrt(dist), nil
+}
+
+// weight returns the weight of j-th column; columns have weight 1 unless the model has column weights
+func (m *Model) weight(j int) float64 {
+	if len(m.Weights) == 0 {
+		return 1.0
+	}
+	return m.Weights[j]
+}
+
+// Project returns grid coordinates of the best matching unit of vector v.
+// It fails with the same errors as BMU.
+func (m *Model) Project(v []float64) ([]float64, error) {
+	bmu, _, err := m.BMU(v)
+	if err != nil {
+		return nil, err
+	}
+	return m.Coords[bmu], nil
+}
+
+// Classify returns the class of the best matching unit of vector v.
+// It returns false if the best matching unit has no class.
+// It fails with the same errors as BMU.
+func (m *Model) Classify(v []float64) (int, bool, error) {
+	bmu, _, err := m.BMU(v)
+	if err != nil {
+		return 0, false, err
+	}
+	class, ok := m.Classes[bmu]
+	return class, ok, nil
+}
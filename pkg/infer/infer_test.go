@@ -0,0 +1,117 @@
+package infer
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeModel() *Model {
+	return &Model{
+		Dims:     []int{2, 2},
+		UShape:   "rectangle",
+		Coords:   [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}},
+		Codebook: [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}},
+		Classes:  map[int]int{0: 1, 3: 2},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	m := makeModel()
+	assert.NoError(m.Validate())
+	// empty codebook
+	assert.Error((&Model{}).Validate())
+	// unit count mismatch
+	m.Coords = m.Coords[:3]
+	assert.Error(m.Validate())
+	// codebook vectors of different dimensions
+	m = makeModel()
+	m.Codebook[2] = []float64{1}
+	assert.Error(m.Validate())
+	// scaling dimensions mismatch
+	m = makeModel()
+	m.Mean, m.Stdev = []float64{0, 0}, []float64{1}
+	assert.Error(m.Validate())
+	// invalid column weights
+	for _, weights := range [][]float64{{1}, {1, -1}, {0, 0}, {1, math.Inf(1)}} {
+		m = makeModel()
+		m.Weights = weights
+		assert.Error(m.Validate())
+	}
+}
+
+func TestBMU(t *testing.T) {
+	assert := assert.New(t)
+
+	m := makeModel()
+	bmu, dist, err := m.BMU([]float64{0.9, 0.2})
+	assert.NoError(err)
+	assert.Equal(2, bmu)
+	assert.InDelta(0.2236, dist, 1e-4)
+	// vector is standardized before search
+	m.Mean, m.Stdev = []float64{1, 1}, []float64{2, 2}
+	bmu, _, err = m.BMU([]float64{3, 1})
+	assert.NoError(err)
+	assert.Equal(2, bmu)
+	// missing values are skipped
+	m = makeModel()
+	bmu, dist, err = m.BMU([]float64{math.NaN(), 0.8})
+	assert.NoError(err)
+	assert.Equal(1, bmu)
+	assert.InDelta(0.2, dist, 1e-9)
+	// incorrect dimension
+	_, _, err = m.BMU([]float64{1})
+	assert.Error(err)
+	// weighted columns
+	m.Weights = []float64{0, 4}
+	bmu, dist, err = m.BMU([]float64{0.9, 0.2})
+	assert.NoError(err)
+	assert.Equal(0, bmu)
+	assert.InDelta(0.4, dist, 1e-9)
+	q, err := m.Quantize("float16")
+	assert.NoError(err)
+	bmu, _, err = q.BMU([]float64{0.9, 0.2})
+	assert.NoError(err)
+	assert.Equal(0, bmu)
+}
+
+func TestProjectClassify(t *testing.T) {
+	assert := assert.New(t)
+
+	m := makeModel()
+	coords, err := m.Project([]float64{0.1, 0.8})
+	assert.NoError(err)
+	assert.Equal([]float64{0, 1}, coords)
+	class, ok, err := m.Classify([]float64{0.9, 0.9})
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(2, class)
+	// unit without class
+	_, ok, err = m.Classify([]float64{0.1, 0.8})
+	assert.NoError(err)
+	assert.False(ok)
+	_, err = m.Project([]float64{1, 2, 3})
+	assert.Error(err)
+}
+
+func TestEncodeDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	m := makeModel()
+	buf := new(bytes.Buffer)
+	assert.NoError(m.Encode(buf))
+	dm, err := Decode(buf)
+	assert.NoError(err)
+	assert.Equal(m, dm)
+	// invalid JSON
+	_, err = Decode(strings.NewReader("{"))
+	assert.Error(err)
+	// invalid model
+	_, err = Decode(strings.NewReader(`{"codebook": []}`))
+	assert.Error(err)
+}
@@ -0,0 +1,238 @@
+package infer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// quantKinds maps supported codebook quantization kinds to the number of bytes of quantized values
+var quantKinds = map[string]int{
+	"float16": 2,
+	"int8":    1,
+}
+
+// Quantized holds codebook vectors quantized to reduce the size and memory footprint of the model.
+// float16 quantization stores every value as IEEE 754 half precision number; int8 quantization maps
+// the values of each codebook column linearly onto 256 levels between the column minimum and maximum.
+type Quantized struct {
+	// Kind is quantization kind: float16 or int8
+	Kind string `json:"kind"`
+	// Dim is the dimension of codebook vectors
+	Dim int `json:"dim"`
+	// Offset holds the minimum of each codebook column; it is only used by int8 quantization
+	Offset []float64 `json:"offset,omitempty"`
+	// Scale holds the distance between quantization levels of each codebook column; it is only used
+	// by int8 quantization which dequantizes value q to Offset + Scale*(q+128)
+	Scale []float64 `json:"scale,omitempty"`
+	// Data holds quantized codebook vectors row by row: two little-endian bytes of each float16 value
+	// or a single byte of each int8 value
+	Data []byte `json:"data"`
+}
+
+// Quantize returns a copy of the model whose codebook is quantized to float16 or int8 values.
+// The quantized model keeps the codebook compressed in memory, so it is meant for memory-constrained
+// deployments; Compare reports how much the quantization changes the best matching units of data.
+// It fails with error if the quantization kind is unsupported, the model is already quantized
+// or the codebook holds values which can't be quantized.
+func (m *Model) Quantize(kind string) (*Model, error) {
+	size, ok := quantKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported quantization: %s", kind)
+	}
+	if m.Quantized != nil {
+		return nil, fmt.Errorf("model is already quantized: %s", m.Quantized.Kind)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	units, dim := m.Units(), m.Dim()
+	q := &Quantized{
+		Kind: kind,
+		Dim:  dim,
+		Data: make([]byte, units*dim*size),
+	}
+	switch kind {
+	case "float16":
+		for i, vec := range m.Codebook {
+			for j, v := range vec {
+				binary.LittleEndian.PutUint16(q.Data[2*(i*dim+j):], toFloat16(v))
+			}
+		}
+	case "int8":
+		q.Offset, q.Scale = make([]float64, dim), make([]float64, dim)
+		for j := 0; j < dim; j++ {
+			min, max := math.Inf(1), math.Inf(-1)
+			for _, vec := range m.Codebook {
+				min, max = math.Min(min, vec[j]), math.Max(max, vec[j])
+			}
+			if math.IsInf(min, 0) || math.IsInf(max, 0) || math.IsNaN(min) || math.IsNaN(max) {
+				return nil, fmt.Errorf("non-finite values in codebook column %d", j)
+			}
+			q.Offset[j], q.Scale[j] = min, (max-min)/255
+			for i, vec := range m.Codebook {
+				level := 0.0
+				if q.Scale[j] > 0 {
+					level = math.Round((vec[j] - min) / q.Scale[j])
+				}
+				q.Data[i*dim+j] = byte(int8(level - 128))
+			}
+		}
+	}
+	qm := *m
+	qm.Codebook = nil
+	qm.Quantized = q
+	return &qm, nil
+}
+
+// validate checks if the quantized codebook of given number of units is valid
+func (q *Quantized) validate(units int) error {
+	size, ok := quantKinds[q.Kind]
+	if !ok {
+		return fmt.Errorf("unsupported quantization: %s", q.Kind)
+	}
+	if units == 0 || q.Dim <= 0 {
+		return fmt.Errorf("invalid quantized codebook dimensions: %d, %d", units, q.Dim)
+	}
+	if len(q.Data) != units*q.Dim*size {
+		return fmt.Errorf("invalid quantized codebook size: %d", len(q.Data))
+	}
+	if q.Kind == "int8" && (len(q.Offset) != q.Dim || len(q.Scale) != q.Dim) {
+		return fmt.Errorf("invalid quantization parameters dimensions: %d, %d", len(q.Offset), len(q.Scale))
+	}
+	return nil
+}
+
+// value returns dequantized j-th value of i-th codebook vector
+func (q *Quantized) value(i, j int) float64 {
+	k := i*q.Dim + j
+	if q.Kind == "float16" {
+		return fromFloat16(binary.LittleEndian.Uint16(q.Data[2*k:]))
+	}
+	return q.Offset[j] + q.Scale[j]*(float64(int8(q.Data[k]))+128)
+}
+
+// toFloat16 returns the bits of IEEE 754 half precision number closest to v.
+// Values which are too large to be represented are converted to infinity.
+func toFloat16(v float64) uint16 {
+	b := math.Float32bits(float32(v))
+	sign := uint16(b>>16) & 0x8000
+	exp := int((b>>23)&0xff) - 127 + 15
+	mant := b & 0x7fffff
+	switch {
+	case (b>>23)&0xff == 0xff:
+		// infinity or NaN
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f:
+		return sign | 0x7c00
+	case exp <= 0:
+		// subnormal numbers lose the implicit leading bit
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		half := uint16(mant >> shift)
+		// round half to even
+		rem, mid := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > mid || (rem == mid && half&1 == 1) {
+			half++
+		}
+		return sign | half
+	}
+	half := sign | uint16(exp)<<10 | uint16(mant>>13)
+	// round half to even; the carry may correctly overflow into exponent
+	if rem := mant & 0x1fff; rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return half
+}
+
+// fromFloat16 returns the value of IEEE 754 half precision number with given bits
+func fromFloat16(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1.0
+	}
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(mant+1024, exp-25)
+}
+
+// Report compares the best matching units of data found by a model and its quantized copy
+type Report struct {
+	// Kind is quantization kind
+	Kind string `json:"kind"`
+	// Bytes is the size of the codebook values of the original model
+	Bytes int `json:"bytes"`
+	// QuantizedBytes is the size of the quantized codebook values and quantization parameters
+	QuantizedBytes int `json:"quantized_bytes"`
+	// MaxError is the largest absolute difference between original and dequantized codebook values
+	MaxError float64 `json:"max_error"`
+	// Rows is the number of compared data rows
+	Rows int `json:"rows"`
+	// Changed is the number of data rows whose best matching unit changed
+	Changed int `json:"changed"`
+	// Agreement is the fraction of data rows whose best matching unit did not change
+	Agreement float64 `json:"agreement"`
+	// GridShift is the mean grid distance between the original and quantized best matching units of the rows
+	GridShift float64 `json:"grid_shift"`
+}
+
+// Compare compares the best matching units of data rows found by model m and its quantized copy q.
+// It fails with error if q is not a quantized copy of m or if the rows have incorrect dimension.
+func Compare(m, q *Model, data [][]float64) (*Report, error) {
+	if m.Quantized != nil || q.Quantized == nil {
+		return nil, fmt.Errorf("invalid models: quantized model must be compared with the original one")
+	}
+	if m.Units() != q.Units() || m.Dim() != q.Dim() {
+		return nil, fmt.Errorf("model dimensions mismatch: %dx%d != %dx%d", m.Units(), m.Dim(), q.Units(), q.Dim())
+	}
+	r := &Report{
+		Kind:           q.Quantized.Kind,
+		Bytes:          8 * m.Units() * m.Dim(),
+		QuantizedBytes: len(q.Quantized.Data) + 8*(len(q.Quantized.Offset)+len(q.Quantized.Scale)),
+		Rows:           len(data),
+	}
+	for i := 0; i < m.Units(); i++ {
+		for j, v := range q.Vector(i) {
+			r.MaxError = math.Max(r.MaxError, math.Abs(v-m.Codebook[i][j]))
+		}
+	}
+	for _, row := range data {
+		a, _, err := m.BMU(row)
+		if err != nil {
+			return nil, err
+		}
+		b, _, err := q.BMU(row)
+		if err != nil {
+			return nil, err
+		}
+		if a == b {
+			continue
+		}
+		r.Changed++
+		d := 0.0
+		for k := range m.Coords[a] {
+			d += (m.Coords[a][k] - m.Coords[b][k]) * (m.Coords[a][k] - m.Coords[b][k])
+		}
+		r.GridShift += math.Sqrt(d)
+	}
+	if r.Rows > 0 {
+		r.Agreement = float64(r.Rows-r.Changed) / float64(r.Rows)
+		r.GridShift /= float64(r.Rows)
+	}
+	return r, nil
+}
@@ -0,0 +1,112 @@
+package infer
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat16(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		v     float64
+		bits  uint16
+		exact bool
+	}{
+		{0.0, 0x0000, true},
+		{1.0, 0x3c00, true},
+		{-2.0, 0xc000, true},
+		{0.5, 0x3800, true},
+		{65504.0, 0x7bff, true},
+		{1e6, 0x7c00, false},
+		{math.Inf(-1), 0xfc00, true},
+		// smallest subnormal number
+		{math.Ldexp(1, -24), 0x0001, true},
+		{math.Ldexp(1, -26), 0x0000, false},
+		// ties round to even
+		{1.0 + math.Ldexp(1, -11), 0x3c00, false},
+		{1.0 + 3*math.Ldexp(1, -11), 0x3c02, false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(tc.bits, toFloat16(tc.v), "%g", tc.v)
+		if tc.exact {
+			assert.Equal(tc.v, fromFloat16(tc.bits), "%g", tc.v)
+		}
+	}
+	assert.True(math.IsNaN(fromFloat16(toFloat16(math.NaN()))))
+	// half precision keeps 11 significant bits
+	for _, v := range []float64{0.1, -3.14159, 123.456, 1e-3} {
+		assert.InEpsilon(v, fromFloat16(toFloat16(v)), math.Ldexp(1, -11))
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	assert := assert.New(t)
+
+	m := makeModel()
+	m.Codebook = [][]float64{{0, 0.1}, {0, 1.3}, {1, 0.2}, {1, 1.9}}
+	data := [][]float64{{0.1, 0.1}, {0.2, 1.2}, {0.9, 0.3}, {0.8, 1.8}, {0.5, 1.0}}
+	for _, kind := range []string{"float16", "int8"} {
+		q, err := m.Quantize(kind)
+		assert.NoError(err)
+		assert.NoError(q.Validate())
+		assert.Nil(q.Codebook)
+		assert.Equal(m.Units(), q.Units())
+		assert.Equal(m.Dim(), q.Dim())
+		// the original model is left intact
+		assert.NotNil(m.Codebook)
+		assert.Nil(m.Quantized)
+		for i := 0; i < m.Units(); i++ {
+			for j, v := range q.Vector(i) {
+				assert.InDelta(m.Codebook[i][j], v, 1.8/255/2+1e-9, kind)
+			}
+		}
+		// quantized models survive encoding
+		var buf bytes.Buffer
+		assert.NoError(q.Encode(&buf))
+		decoded, err := Decode(&buf)
+		assert.NoError(err)
+		assert.Equal(q.Quantized, decoded.Quantized)
+		bmu, _, err := decoded.BMU([]float64{0.9, 0.2})
+		assert.NoError(err)
+		assert.Equal(2, bmu)
+
+		r, err := Compare(m, q, data)
+		assert.NoError(err)
+		assert.Equal(kind, r.Kind)
+		assert.Equal(64, r.Bytes)
+		assert.Less(r.QuantizedBytes, r.Bytes+32)
+		assert.Equal(len(data), r.Rows)
+		assert.Equal(0, r.Changed)
+		assert.Equal(1.0, r.Agreement)
+		assert.Equal(0.0, r.GridShift)
+		assert.Greater(r.MaxError, 0.0)
+		// quantized models can't be quantized again or compared the other way around
+		_, err = q.Quantize(kind)
+		assert.Error(err)
+		_, err = Compare(q, m, data)
+		assert.Error(err)
+		_, err = Compare(m, q, [][]float64{{1}})
+		assert.Error(err)
+	}
+	// constant columns are dequantized exactly
+	m.Codebook = [][]float64{{3, 0}, {3, 1}, {3, 2}, {3, 3}}
+	q, err := m.Quantize("int8")
+	assert.NoError(err)
+	for i := 0; i < q.Units(); i++ {
+		assert.Equal(3.0, q.Vector(i)[0])
+	}
+	// unsupported quantization
+	_, err = m.Quantize("int4")
+	assert.Error(err)
+	// invalid quantized codebook
+	q.Quantized.Data = q.Quantized.Data[1:]
+	assert.Error(q.Validate())
+	q, err = m.Quantize("float16")
+	assert.NoError(err)
+	q.Codebook = m.Codebook
+	assert.Error(q.Validate())
+}
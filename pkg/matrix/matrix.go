@@ -52,16 +52,24 @@ func RowsMin(rows int, m *mat.Dense) ([]float64, error) {
 
 // MakeRandom creates a new matrix with provided number of rows and columns
 // which is initialized to random numbers uniformly distributed in interval [min, max].
+// The random numbers are always drawn with seed 55, so MakeRandom returns the same matrix
+// for the same parameters; use MakeRandomSeed to draw different numbers.
 // MakeRandom fails if non-positive matrix dimensions are requested.
 func MakeRandom(rows, cols int, min, max float64) (*mat.Dense, error) {
+	return MakeRandomSeed(rows, cols, min, max, 55)
+}
+
+// MakeRandomSeed creates a new matrix with provided number of rows and columns which is initialized
+// to random numbers uniformly distributed in interval [min, max] drawn with a given seed.
+// MakeRandomSeed fails if non-positive matrix dimensions are requested.
+func MakeRandomSeed(rows, cols int, min, max float64, seed int64) (*mat.Dense, error) {
 	return withValidDims(rows, cols, func() (*mat.Dense, error) {
-		// set random seed
-		rand.Seed(55)
+		r := rand.New(rand.NewSource(seed))
 		// allocate data slice
 		randVals := make([]float64, rows*cols)
 		for i := range randVals {
 			// we need value between 0 and 1.0
-			randVals[i] = rand.Float64()*(max-min) + min
+			randVals[i] = r.Float64()*(max-min) + min
 		}
 		return mat.NewDense(rows, cols, randVals), nil
 	})
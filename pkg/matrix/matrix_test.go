@@ -150,6 +150,30 @@ func TestMakeRandom(t *testing.T) {
 	assert.Error(err)
 }
 
+func TestMakeRandomSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	rows, cols := 2, 3
+	min, max := 1.0, 2.0
+	// the same seed draws the same matrix
+	a, err := MakeRandomSeed(rows, cols, min, max, 7)
+	assert.NoError(err)
+	b, err := MakeRandomSeed(rows, cols, min, max, 7)
+	assert.NoError(err)
+	assert.True(mat.Equal(a, b))
+	// MakeRandom draws with seed 55
+	b, err = MakeRandomSeed(rows, cols, min, max, 55)
+	assert.NoError(err)
+	randMx, err := MakeRandom(rows, cols, min, max)
+	assert.NoError(err)
+	assert.True(mat.Equal(randMx, b))
+	assert.False(mat.Equal(a, b))
+	// Can't create new matrix
+	a, err = MakeRandomSeed(rows, 0, min, max, 7)
+	assert.Nil(a)
+	assert.Error(err)
+}
+
 func TestMakeConstant(t *testing.T) {
 	assert := assert.New(t)
 
@@ -0,0 +1,192 @@
+// Package model provides SOM model bundles which keep a trained map together
+// with the data scaler and class information needed to use it, along with
+// stores which persist the bundles in local filesystem or object storage.
+package model
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+	"github.com/milosgajdos/gosom/pkg/infer"
+	"github.com/milosgajdos/gosom/som"
+	"gonum.org/v1/gonum/mat"
+)
+
+// bundle archive entries
+const (
+	// modelFile stores SOM in som model format
+	modelFile = "model.som"
+	// scalerFile stores data scaler
+	scalerFile = "scaler.json"
+	// classesFile stores SOM unit classes
+	classesFile = "classes.json"
+	// colorsFile stores class colors
+	colorsFile = "colors.json"
+)
+
+// Bundle is a SOM model bundle stored in a single zip archive
+type Bundle struct {
+	// Map is trained SOM
+	Map *som.Map
+	// Scaler is the data scaler fitted on training data; it can be nil
+	Scaler *dataset.Scaler
+	// Classes maps SOM units to their classes; it can be nil
+	Classes map[int]int
+	// Colors maps classes to their RGB colors; it can be nil
+	Colors map[int][]int
+}
+
+// Transform scales data using the bundle scaler and returns it in a new matrix.
+// If the bundle has no scaler, data is returned unchanged.
+// It fails with error if the data could not be scaled.
+func (b *Bundle) Transform(data *mat.Dense) (*mat.Dense, error) {
+	if b.Scaler == nil {
+		return data, nil
+	}
+	return b.Scaler.Transform(data)
+}
+
+// Inference returns inference model of the bundle which can be used without gosom and gonum packages.
+// The model finds BMUs with the column weights of the map, so carried columns are left out of its BMU search.
+// It fails with error if the bundle has no map or if the map uses other than euclidean metric,
+// which inference models use to find BMUs, or a custom distance function.
+func (b *Bundle) Inference() (*infer.Model, error) {
+	if b.Map == nil {
+		return nil, fmt.Errorf("invalid map: %v", b.Map)
+	}
+	if metric := b.Map.Metric(); metric != som.Euclidean {
+		return nil, fmt.Errorf("unsupported inference metric: %s", metric)
+	}
+	if b.Map.DistanceFunc() != nil {
+		return nil, fmt.Errorf("custom distance function unsupported by inference models")
+	}
+	codebook, coords := b.Map.Codebook(), b.Map.Grid().Coords()
+	units, _ := codebook.Dims()
+	m := &infer.Model{
+		Dims:     b.Map.Grid().Size(),
+		UShape:   b.Map.Grid().UShape(),
+		Coords:   make([][]float64, units),
+		Codebook: make([][]float64, units),
+		Weights:  b.Map.ColumnWeights(),
+		Classes:  b.Classes,
+	}
+	for i := 0; i < units; i++ {
+		m.Coords[i] = mat.Row(nil, i, coords)
+		m.Codebook[i] = mat.Row(nil, i, codebook)
+	}
+	if b.Scaler != nil {
+		m.Mean, m.Stdev = b.Scaler.Mean, b.Scaler.Stdev
+	}
+	return m, nil
+}
+
+// Save writes bundle to w as a zip archive.
+// It fails with error if the bundle has no map or if the write to w fails.
+func (b *Bundle) Save(w io.Writer) error {
+	if b.Map == nil {
+		return fmt.Errorf("invalid map: %v", b.Map)
+	}
+	zw := zip.NewWriter(w)
+	mw, err := zw.Create(modelFile)
+	if err != nil {
+		return err
+	}
+	if _, err := b.Map.MarshalTo("som", mw); err != nil {
+		return err
+	}
+	// optional bundle entries
+	var names []string
+	entries := make(map[string]interface{})
+	if b.Scaler != nil {
+		names = append(names, scalerFile)
+		entries[scalerFile] = b.Scaler
+	}
+	if b.Classes != nil {
+		names = append(names, classesFile)
+		entries[classesFile] = b.Classes
+	}
+	if b.Colors != nil {
+		names = append(names, colorsFile)
+		entries[colorsFile] = b.Colors
+	}
+	for _, name := range names {
+		ew, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(ew).Encode(entries[name]); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// Load reads bundle zip archive from r and returns it.
+// It fails with error if the archive is corrupted or if it does not contain SOM model.
+func Load(r io.Reader) (*Bundle, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		switch f.Name {
+		case modelFile:
+			b.Map = new(som.Map)
+			_, err = b.Map.UnmarshalFrom("som", rc)
+		case scalerFile:
+			err = json.NewDecoder(rc).Decode(&b.Scaler)
+		case classesFile:
+			err = json.NewDecoder(rc).Decode(&b.Classes)
+		case colorsFile:
+			err = json.NewDecoder(rc).Decode(&b.Colors)
+		}
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	if b.Map == nil {
+		return nil, fmt.Errorf("missing bundle entry: %s", modelFile)
+	}
+
+	return b, nil
+}
+
+// SaveFile saves bundle b to a file in path
+func SaveFile(path string, b *Bundle) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return b.Save(file)
+}
+
+// LoadFile loads bundle from a file in path
+func LoadFile(path string) (*Bundle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Load(file)
+}
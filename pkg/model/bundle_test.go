@@ -0,0 +1,184 @@
+package model
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/milosgajdos/gosom/pkg/dataset"
+	"github.com/milosgajdos/gosom/som"
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+var data = mat.NewDense(5, 4, []float64{
+	5.1, 3.5, 1.4, 0.1,
+	4.9, 3.0, 1.4, 0.2,
+	4.7, 3.2, 1.3, 0.3,
+	4.6, 3.1, 1.5, 0.4,
+	5.0, 3.6, 1.4, 0.5})
+
+func makeMap(t *testing.T) *som.Map {
+	m, err := som.NewMap(&som.MapConfig{
+		Grid: &som.GridConfig{
+			Size:   []int{2, 3},
+			Type:   "planar",
+			UShape: "hexagon",
+		},
+		Cb: &som.CbConfig{
+			Dim:      4,
+			InitFunc: som.RandInit,
+		},
+	}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestBundle(t *testing.T) {
+	assert := assert.New(t)
+
+	// bundle without map can't be saved
+	buf := new(bytes.Buffer)
+	assert.Error((&Bundle{}).Save(buf))
+
+	b := &Bundle{
+		Map:     makeMap(t),
+		Scaler:  dataset.NewScaler(data),
+		Classes: map[int]int{0: 1, 3: 2},
+		Colors:  map[int][]int{1: {255, 0, 0}, 2: {0, 255, 0}},
+	}
+	err := b.Save(buf)
+	assert.NoError(err)
+	lb, err := Load(buf)
+	assert.NoError(err)
+	assert.True(mat.Equal(b.Map.Codebook(), lb.Map.Codebook()))
+	assert.Equal(b.Map.Grid().Size(), lb.Map.Grid().Size())
+	assert.Equal(b.Scaler, lb.Scaler)
+	assert.Equal(b.Classes, lb.Classes)
+	assert.Equal(b.Colors, lb.Colors)
+	// bundle scaler is applied to data
+	scaled, err := lb.Transform(data)
+	assert.NoError(err)
+	assert.True(mat.EqualApprox(dataset.Scale(data), scaled, 1e-12))
+	// optional entries can be omitted
+	buf.Reset()
+	err = (&Bundle{Map: b.Map}).Save(buf)
+	assert.NoError(err)
+	lb, err = Load(buf)
+	assert.NoError(err)
+	assert.Nil(lb.Scaler)
+	assert.Nil(lb.Classes)
+	assert.Nil(lb.Colors)
+	unscaled, err := lb.Transform(data)
+	assert.NoError(err)
+	assert.Equal(data, unscaled)
+}
+
+func TestBundleInference(t *testing.T) {
+	assert := assert.New(t)
+
+	// bundle without map has no inference model
+	_, err := (&Bundle{}).Inference()
+	assert.Error(err)
+
+	b := &Bundle{
+		Map:     makeMap(t),
+		Scaler:  dataset.NewScaler(data),
+		Classes: map[int]int{0: 1, 3: 2},
+	}
+	m, err := b.Inference()
+	assert.NoError(err)
+	assert.NoError(m.Validate())
+	assert.Equal(b.Map.Grid().Size(), m.Dims)
+	assert.Equal(b.Classes, m.Classes)
+	// inference model finds the same BMUs as the map
+	scaled, err := b.Transform(data)
+	assert.NoError(err)
+	bmus, err := b.Map.BMUs(scaled)
+	assert.NoError(err)
+	rows, _ := data.Dims()
+	for i := 0; i < rows; i++ {
+		bmu, _, err := m.BMU(mat.Row(nil, i, data))
+		assert.NoError(err)
+		assert.Equal(bmus[i], bmu)
+	}
+	// inference models only use euclidean metric
+	tc := &som.TrainConfig{
+		Algorithm: "seq",
+		Radius:    1.0,
+		RDecay:    "lin",
+		NeighbFn:  som.Gaussian,
+		LRate:     0.5,
+		LDecay:    "lin",
+		Metric:    som.Manhattan,
+	}
+	assert.NoError(b.Map.Train(tc, data, 10))
+	_, err = b.Inference()
+	assert.Error(err)
+	// carried columns are left out of inference BMU search
+	tc.Metric = som.Euclidean
+	tc.Carry = []int{0}
+	b.Map = makeMap(t)
+	assert.NoError(b.Map.Train(tc, data, 10))
+	m, err = b.Inference()
+	assert.NoError(err)
+	assert.Equal([]float64{0, 1, 1, 1}, m.Weights)
+	bmus, err = b.Map.BMUs(scaled)
+	assert.NoError(err)
+	for i := 0; i < rows; i++ {
+		row := mat.Row(nil, i, data)
+		// carried values don't change BMUs
+		row[0] = 100.0 * float64(i)
+		bmu, _, err := m.BMU(row)
+		assert.NoError(err)
+		assert.Equal(bmus[i], bmu)
+	}
+	// inference models find BMUs with the feature weights of the map
+	tc.Carry = nil
+	tc.FeatureWeights = []float64{4, 0.5, 1, 2}
+	b.Map = makeMap(t)
+	assert.NoError(b.Map.Train(tc, data, 10))
+	m, err = b.Inference()
+	assert.NoError(err)
+	assert.Equal(tc.FeatureWeights, m.Weights)
+	bmus, dists, err := b.Map.BMUDistances(scaled)
+	assert.NoError(err)
+	for i := 0; i < rows; i++ {
+		bmu, dist, err := m.BMU(mat.Row(nil, i, data))
+		assert.NoError(err)
+		assert.Equal(bmus[i], bmu)
+		assert.InDelta(dists[i], dist, 1e-9)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	// not a zip archive
+	b, err := Load(bytes.NewBufferString("foo"))
+	assert.Nil(b)
+	assert.Error(err)
+	// archive without model
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	assert.NoError(zw.Close())
+	b, err = Load(buf)
+	assert.Nil(b)
+	assert.Error(err)
+}
+
+func TestBundleFile(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(os.TempDir(), "gosom_bundle_test.zip")
+	defer os.Remove(path)
+	err := SaveFile(path, &Bundle{Map: makeMap(t)})
+	assert.NoError(err)
+	b, err := LoadFile(path)
+	assert.NoError(err)
+	assert.NotNil(b.Map)
+}
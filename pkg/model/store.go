@@ -0,0 +1,194 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when the requested bundle is not found in store
+var ErrNotFound = errors.New("bundle not found")
+
+// Store persists model bundles under string keys.
+// Keys are slash separated paths such as "models/hepta.zip".
+type Store interface {
+	// Save saves bundle b under key
+	Save(ctx context.Context, key string, b *Bundle) error
+	// Load loads bundle stored under key.
+	// It returns ErrNotFound if there is no bundle stored under key.
+	Load(ctx context.Context, key string) (*Bundle, error)
+}
+
+// validKey returns cleaned key or fails with error if the key is empty or escapes the store root
+func validKey(key string) (string, error) {
+	clean := path.Clean("/" + key)[1:]
+	if clean == "" || clean != strings.TrimPrefix(key, "/") {
+		return "", fmt.Errorf("invalid key: %q", key)
+	}
+	return clean, nil
+}
+
+// FileStore stores bundles in local filesystem directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a new store which keeps bundles in directory dir and returns it
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save saves bundle b to a file in store directory.
+// The bundle is written to a temporary file which is renamed once the bundle is written,
+// so concurrent readers never load partially written bundles.
+func (s *FileStore) Save(ctx context.Context, key string, b *Bundle) error {
+	key, err := validKey(key)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(p), "."+filepath.Base(p))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := b.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Load loads bundle from a file in store directory
+func (s *FileStore) Load(ctx context.Context, key string) (*Bundle, error) {
+	key, err := validKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	b, err := LoadFile(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return b, err
+}
+
+// ObjectStore stores bundles as objects in S3 or GCS compatible object storage.
+// Objects are uploaded with HTTP PUT and downloaded with HTTP GET requests sent to the URL
+// of the object which is the store base URL joined with the object key, e.g. the URL of a bucket
+// https://bucket.s3.amazonaws.com or https://storage.googleapis.com/bucket.
+// Authentication is delegated to Sign hook which can add the credentials, e.g. AWS Signature V4
+// or OAuth2 bearer token headers, to each request.
+type ObjectStore struct {
+	// base is the URL of the bucket
+	base *url.URL
+	// Client is HTTP client used to send requests; http.DefaultClient is used if nil
+	Client *http.Client
+	// Sign is an optional hook which authenticates requests before they are sent
+	Sign func(*http.Request) error
+}
+
+// NewObjectStore creates a new object store of bucket with a given URL and returns it.
+// It fails with error if the URL is invalid.
+func NewObjectStore(bucketURL string) (*ObjectStore, error) {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid bucket URL: %s", bucketURL)
+	}
+	return &ObjectStore{base: u}, nil
+}
+
+// Save uploads bundle b as an object with a given key
+func (s *ObjectStore) Save(ctx context.Context, key string, b *Bundle) error {
+	var buf bytes.Buffer
+	if err := b.Save(&buf); err != nil {
+		return err
+	}
+	req, err := s.request(ctx, http.MethodPut, key, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(buf.Len())
+	req.Header.Set("Content-Type", "application/zip")
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to save %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+// Load downloads bundle stored as an object with a given key
+func (s *ObjectStore) Load(ctx context.Context, key string) (*Bundle, error) {
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode/100 != 2:
+		return nil, fmt.Errorf("failed to load %s: %s", key, resp.Status)
+	}
+	return Load(resp.Body)
+}
+
+// request creates signed HTTP request of object with a given key
+func (s *ObjectStore) request(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	key, err := validKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u := *s.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if s.Sign != nil {
+		if err := s.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// do sends HTTP request using store client
+func (s *ObjectStore) do(req *http.Request) (*http.Response, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// testStore saves and loads bundle using store s
+func testStore(t *testing.T, s Store) {
+	assert := assert.New(t)
+
+	ctx := context.Background()
+	b := &Bundle{Map: makeMap(t), Classes: map[int]int{0: 1}}
+	assert.NoError(s.Save(ctx, "models/test.zip", b))
+	lb, err := s.Load(ctx, "models/test.zip")
+	assert.NoError(err)
+	assert.True(mat.Equal(b.Map.Codebook(), lb.Map.Codebook()))
+	assert.Equal(b.Classes, lb.Classes)
+	// missing bundle
+	lb, err = s.Load(ctx, "models/missing.zip")
+	assert.Nil(lb)
+	assert.Equal(ErrNotFound, err)
+	// invalid keys
+	for _, key := range []string{"", "../test.zip", "models/../../test.zip"} {
+		assert.Error(s.Save(ctx, key, b))
+		_, err = s.Load(ctx, key)
+		assert.Error(err)
+	}
+	// bundle without map can't be saved
+	assert.Error(s.Save(ctx, "models/empty.zip", &Bundle{}))
+	// cancelled context
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(s.Save(cctx, "models/test.zip", b))
+	_, err = s.Load(cctx, "models/test.zip")
+	assert.Error(err)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosom_store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testStore(t, NewFileStore(dir))
+}
+
+func TestObjectStore(t *testing.T) {
+	assert := assert.New(t)
+
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := ioutil.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer srv.Close()
+
+	s, err := NewObjectStore(srv.URL + "/bucket/")
+	assert.NoError(err)
+	s.Sign = func(r *http.Request) error {
+		r.Header.Set("Authorization", "Bearer token")
+		return nil
+	}
+	testStore(t, s)
+	assert.Contains(objects, "/bucket/models/test.zip")
+	// unauthorized requests fail
+	s.Sign = nil
+	ctx := context.Background()
+	assert.Error(s.Save(ctx, "models/test.zip", &Bundle{Map: makeMap(t)}))
+	_, err = s.Load(ctx, "models/test.zip")
+	assert.Error(err)
+	// invalid bucket URL
+	_, err = NewObjectStore("ftp://bucket")
+	assert.Error(err)
+}
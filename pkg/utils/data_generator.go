@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"math"
 	"math/rand"
 
 	"gonum.org/v1/gonum/mat"
@@ -43,6 +44,81 @@ func GenerateClusters(rows, cols, clusters int, max, min, maxOffset float64, ran
 	return data
 }
 
+// GenerateMoons generates two interleaving 2D half circles ("moons").
+// Sample i belongs to moon i % 2. Each sample coordinate is perturbed by a Gaussian noise
+// with standard deviation noise.
+// rows - how many data rows to generate
+// noise - standard deviation of the Gaussian noise
+// randSeed - random seed
+func GenerateMoons(rows int, noise float64, randSeed int64) *mat.Dense {
+	rnd := rand.New(rand.NewSource(randSeed))
+
+	data := mat.NewDense(rows, 2, nil)
+	for i := 0; i < rows; i++ {
+		t := rnd.Float64() * math.Pi
+		x, y := math.Cos(t), math.Sin(t)
+		// the second moon is flipped and shifted
+		if i%2 == 1 {
+			x, y = 1-x, 0.5-y
+		}
+		data.Set(i, 0, x+rnd.NormFloat64()*noise)
+		data.Set(i, 1, y+rnd.NormFloat64()*noise)
+	}
+
+	return data
+}
+
+// GenerateGaussianMixture generates data samples drawn from a mixture of Gaussian distributions.
+// The component means are picked randomly in a hypercube given by the max and min parameters.
+// Sample i is drawn from component i % components.
+// rows - how many data rows to generate
+// cols - dimension of data
+// components - how many mixture components
+// max,min - maximum and minimum coordinates of component means (applies to all dimensions)
+// stdev - standard deviation of each component in every dimension
+// randSeed - random seed
+func GenerateGaussianMixture(rows, cols, components int, max, min, stdev float64, randSeed int64) *mat.Dense {
+	rnd := rand.New(rand.NewSource(randSeed))
+
+	// randomly pick component means
+	means := make([][]float64, components)
+	for i := 0; i < components; i++ {
+		means[i] = make([]float64, cols)
+		for j := 0; j < cols; j++ {
+			means[i][j] = rnd.Float64()*(max-min) + min
+		}
+	}
+
+	data := mat.NewDense(rows, cols, nil)
+	for i := 0; i < rows; i++ {
+		mean := means[i%components]
+		for j := 0; j < cols; j++ {
+			data.Set(i, j, mean[j]+rnd.NormFloat64()*stdev)
+		}
+	}
+
+	return data
+}
+
+// GenerateSwissRoll generates 3D data samples lying on a "swiss roll" manifold.
+// Each sample coordinate is perturbed by a Gaussian noise with standard deviation noise.
+// rows - how many data rows to generate
+// noise - standard deviation of the Gaussian noise
+// randSeed - random seed
+func GenerateSwissRoll(rows int, noise float64, randSeed int64) *mat.Dense {
+	rnd := rand.New(rand.NewSource(randSeed))
+
+	data := mat.NewDense(rows, 3, nil)
+	for i := 0; i < rows; i++ {
+		t := 1.5 * math.Pi * (1 + 2*rnd.Float64())
+		data.Set(i, 0, t*math.Cos(t)+rnd.NormFloat64()*noise)
+		data.Set(i, 1, 21*rnd.Float64()+rnd.NormFloat64()*noise)
+		data.Set(i, 2, t*math.Sin(t)+rnd.NormFloat64()*noise)
+	}
+
+	return data
+}
+
 func randVector(max, min float64, cols int) []float64 {
 	v := make([]float64, cols)
 	for i := 0; i < cols; i++ {
@@ -69,3 +69,51 @@ func TestWithVar(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateMoons(t *testing.T) {
+	assert := assert.New(t)
+
+	data := GenerateMoons(100, 0.0, 1)
+	rows, cols := data.Dims()
+	assert.Equal(100, rows)
+	assert.Equal(2, cols)
+	// without noise the samples lie on unit circles
+	for i := 0; i < rows; i++ {
+		x, y := data.At(i, 0), data.At(i, 1)
+		if i%2 == 1 {
+			x, y = 1-x, 0.5-y
+		}
+		assert.InDelta(1.0, x*x+y*y, 1e-9)
+	}
+	// the same seed generates the same data
+	assert.Equal(data, GenerateMoons(100, 0.0, 1))
+}
+
+func TestGenerateGaussianMixture(t *testing.T) {
+	assert := assert.New(t)
+
+	data := GenerateGaussianMixture(50, 3, 2, 1.0, 0.0, 0.0, 1)
+	rows, cols := data.Dims()
+	assert.Equal(50, rows)
+	assert.Equal(3, cols)
+	// without variance all samples of the same component are equal
+	for i := 2; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			assert.Equal(data.At(i%2, j), data.At(i, j))
+		}
+	}
+}
+
+func TestGenerateSwissRoll(t *testing.T) {
+	assert := assert.New(t)
+
+	data := GenerateSwissRoll(100, 0.0, 1)
+	rows, cols := data.Dims()
+	assert.Equal(100, rows)
+	assert.Equal(3, cols)
+	for i := 0; i < rows; i++ {
+		r := math.Hypot(data.At(i, 0), data.At(i, 2))
+		assert.True(r >= 1.5*math.Pi-1e-9 && r <= 4.5*math.Pi+1e-9)
+		assert.True(data.At(i, 1) >= 0.0 && data.At(i, 1) <= 21.0)
+	}
+}
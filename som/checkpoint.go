@@ -0,0 +1,252 @@
+package som
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Checkpoint holds the state of sequential or batch training stopped by TrainContext when its context was done.
+// Together with the codebook of the map it captures the whole training state including the state of the
+// random number generator which draws data rows, so training resumed by Resume produces bit-identical map
+// to uninterrupted training. The updates of an unfinished block of delayed sequential training are held by
+// the checkpoint rather than applied to the codebook. Checkpoints can be encoded as JSON and saved along with the map model.
+type Checkpoint struct {
+	// Algorithm is the training algorithm
+	Algorithm string `json:"algorithm"`
+	// Iteration is the first training iteration which has not finished
+	Iteration int `json:"iteration"`
+	// Iters is the total number of training iterations
+	Iters int `json:"iters"`
+	// Seed is the seed of the random number generator which draws data rows
+	Seed int64 `json:"seed"`
+	// Draws is the number of values drawn from the random number generator
+	Draws uint64 `json:"draws"`
+	// Sampling holds cumulative row quantization errors estimated by qerror sampling in the current epoch
+	Sampling []float64 `json:"sampling,omitempty"`
+	// Subset holds indices of the data rows sampled in the current epoch of subsampled training
+	Subset []int `json:"subset,omitempty"`
+	// EpochCodebook holds codebook vectors at the start of the current epoch stored row by row;
+	// unit freezing measures the movement of codebook vectors against them. It is nil unless units are frozen.
+	EpochCodebook []float64 `json:"epoch_codebook,omitempty"`
+	// Still counts consecutive epochs in which units moved less than the freezing threshold
+	Still []int `json:"still,omitempty"`
+	// Frozen marks frozen units
+	Frozen []bool `json:"frozen,omitempty"`
+	// Delayed holds the accumulated updates of codebook vectors of the unfinished block of delayed training
+	// stored row by row. It is nil unless some updates are pending.
+	Delayed []float64 `json:"delayed,omitempty"`
+	// DelayedSamples is the number of samples whose updates are accumulated in Delayed
+	DelayedSamples int `json:"delayed_samples,omitempty"`
+	// History holds the training history recorded before the checkpoint
+	History []ScheduleStep `json:"history,omitempty"`
+	// DataFingerprint is the fingerprint of the training data
+	DataFingerprint string `json:"data_fingerprint"`
+}
+
+// countingSource is a source of random numbers which counts the values it draws,
+// so its state can be saved as its seed and the number of draws and restored by replaying them
+type countingSource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+// newCountingSource returns source seeded with seed which has already drawn given number of values
+func newCountingSource(seed int64, draws uint64) *countingSource {
+	s := &countingSource{
+		src:  rand.NewSource(seed).(rand.Source64),
+		seed: seed,
+	}
+	for s.draws < draws {
+		s.Uint64()
+	}
+	return s
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+// Uint64 returns a pseudo-random 64-bit integer
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+// Seed reseeds the source and resets the number of draws
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed, s.draws = seed, 0
+}
+
+// trainRun holds the state of a training run which is saved in checkpoints
+type trainRun struct {
+	// s is the training schedule
+	s *schedule
+	// next is the next training iteration
+	next int
+	// stop is the iteration at which the run stops
+	stop int
+	// src is the source of random numbers of sampler
+	src *countingSource
+	// smp draws data rows of sequential training
+	smp *sampler
+	// f freezes converged units
+	f *freezer
+	// du accumulates delayed updates of sequential training
+	du *delayedUpdates
+	// cm masks data columns
+	cm *colMask
+	// ms measures distances of data rows from codebook vectors
+	ms measure
+}
+
+// newTrainRun returns training run of map codebook on data following schedule s configured by c
+// which measures distances by ms. If cp is not nil, the run continues from the checkpoint.
+func (m *Map) newTrainRun(c *TrainConfig, ms measure, data *mat.Dense, s *schedule, cp *Checkpoint) *trainRun {
+	seed, draws := c.Seed, uint64(0)
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if cp != nil {
+		seed, draws = cp.Seed, cp.Draws
+	}
+	_, dim := data.Dims()
+	run := &trainRun{
+		s:    s,
+		stop: s.iters,
+		src:  newCountingSource(seed, draws),
+		f:    newFreezer(c.Freeze, m.codebook, s),
+		du:   newDelayedUpdates(c.Delay, m.codebook),
+		cm:   newColMask(c, dim),
+		ms:   ms,
+	}
+	run.smp = newSampler(c, run.ms, data, s, rand.New(run.src))
+	if cp == nil {
+		return run
+	}
+	run.next = cp.Iteration
+	s.steps = append(s.steps, cp.History...)
+	// staged training may continue on different data whose rows are sampled and row errors are estimated again
+	switch {
+	case run.smp.validSubset(cp.Subset):
+		run.smp.subset = append([]int(nil), cp.Subset...)
+	case run.smp.subsampled() && run.next%s.epoch != 0:
+		run.smp.draw()
+	}
+	switch rows, _ := data.Dims(); {
+	case len(cp.Sampling) == rows:
+		run.smp.cum = append([]float64(nil), cp.Sampling...)
+	case cp.Sampling != nil && run.next%s.epoch != 0:
+		run.smp.estimate(m.codebook)
+	}
+	if run.f != nil {
+		copy(run.f.prev.RawMatrix().Data, cp.EpochCodebook)
+		copy(run.f.still, cp.Still)
+		copy(run.f.frozen, cp.Frozen)
+		for _, frozen := range run.f.frozen {
+			if frozen {
+				run.f.count++
+			}
+		}
+	}
+	if run.du != nil && cp.DelayedSamples > 0 {
+		copy(run.du.deltas.RawMatrix().Data, cp.Delayed)
+		run.du.n = cp.DelayedSamples
+		// applying zero updates to the units which were not updated leaves them as they are
+		for i := range run.du.updated {
+			run.du.updated[i] = true
+		}
+	}
+	return run
+}
+
+// checkpoint returns checkpoint of the training run on data with given fingerprint
+func (run *trainRun) checkpoint(c *TrainConfig, fingerprint string) *Checkpoint {
+	cp := &Checkpoint{
+		Algorithm:       c.Algorithm,
+		Iteration:       run.next,
+		Iters:           run.s.iters,
+		Seed:            run.src.seed,
+		Draws:           run.src.draws,
+		History:         append([]ScheduleStep(nil), run.s.steps...),
+		DataFingerprint: fingerprint,
+	}
+	if run.smp.cum != nil {
+		cp.Sampling = append([]float64(nil), run.smp.cum...)
+	}
+	if run.smp.subset != nil {
+		cp.Subset = append([]int(nil), run.smp.subset...)
+	}
+	if run.f != nil {
+		cp.EpochCodebook = append([]float64(nil), run.f.prev.RawMatrix().Data...)
+		cp.Still = append([]int(nil), run.f.still...)
+		cp.Frozen = append([]bool(nil), run.f.frozen...)
+	}
+	if run.du != nil && run.du.n > 0 {
+		cp.Delayed = append([]float64(nil), run.du.deltas.RawMatrix().Data...)
+		cp.DelayedSamples = run.du.n
+	}
+	return cp
+}
+
+// Checkpoint returns the checkpoint of the last training stopped by TrainContext when its context was done.
+// It returns nil if the last training finished or failed for a different reason.
+func (m *Map) Checkpoint() *Checkpoint {
+	return m.checkpoint
+}
+
+// Resume resumes training stopped by TrainContext from checkpoint cp using the same training
+// configuration c and data. The map codebook must be the codebook left by the stopped training, e.g.
+// the map saved along with the checkpoint. The resumed training runs the remaining iterations like
+// uninterrupted training would and it can be stopped via ctx and resumed again the same way.
+// It returns error if the checkpoint does not match the configuration, data or map or if the training fails.
+func (m *Map) Resume(ctx context.Context, c *TrainConfig, data *mat.Dense, cp *Checkpoint) error {
+	if cp == nil {
+		return fmt.Errorf("invalid checkpoint: %v", cp)
+	}
+	return m.train(ctx, c, data, cp.Iters, cp, nil)
+}
+
+// validateCheckpoint validates checkpoint cp of training configured by c on data of map m
+func (m *Map) validateCheckpoint(c *TrainConfig, data *mat.Dense, cp *Checkpoint) error {
+	if cp.Iteration < 0 || cp.Iteration >= cp.Iters {
+		return fmt.Errorf("invalid checkpoint iteration: %d", cp.Iteration)
+	}
+	if fingerprint := Fingerprint(data); cp.DataFingerprint != fingerprint {
+		return fmt.Errorf("checkpoint data fingerprint mismatch: %s != %s", cp.DataFingerprint, fingerprint)
+	}
+	if rows, _ := data.Dims(); cp.Sampling != nil && len(cp.Sampling) != rows {
+		return fmt.Errorf("checkpoint sampling state mismatch: %d != %d", len(cp.Sampling), rows)
+	}
+	return m.validateCheckpointState(c, cp)
+}
+
+// validateCheckpointState validates that checkpoint cp holds the state of training configured by c of map m
+func (m *Map) validateCheckpointState(c *TrainConfig, cp *Checkpoint) error {
+	if cp.Algorithm != c.Algorithm {
+		return fmt.Errorf("checkpoint algorithm mismatch: %s != %s", cp.Algorithm, c.Algorithm)
+	}
+	if (c.Freeze != nil) != (cp.EpochCodebook != nil) {
+		return fmt.Errorf("checkpoint unit freezing state mismatch")
+	}
+	if c.Freeze != nil {
+		units, dim := m.codebook.Dims()
+		if len(cp.EpochCodebook) != units*dim || len(cp.Still) != units || len(cp.Frozen) != units {
+			return fmt.Errorf("checkpoint unit freezing state mismatch")
+		}
+	}
+	if cp.DelayedSamples != 0 || cp.Delayed != nil {
+		units, dim := m.codebook.Dims()
+		if cp.DelayedSamples < 0 || cp.DelayedSamples >= c.Delay || len(cp.Delayed) != units*dim {
+			return fmt.Errorf("checkpoint delayed updates mismatch")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package som
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestResume(t *testing.T) {
+	assert := assert.New(t)
+
+	configs := map[string]func(*TrainConfig){
+		"seq":             func(tc *TrainConfig) {},
+		"qerror":          func(tc *TrainConfig) { tc.Sampling = "qerror"; tc.Schedule = "epoch" },
+		"freeze":          func(tc *TrainConfig) { tc.Freeze = &FreezeConfig{Threshold: 0.05, Patience: 1} },
+		"batch":           func(tc *TrainConfig) { tc.Algorithm = "batch"; tc.Freeze = &FreezeConfig{Threshold: 0.05, Patience: 1} },
+		"subsample":       func(tc *TrainConfig) { tc.Subsample = 0.6; tc.Sampling = "qerror" },
+		"batch subsample": func(tc *TrainConfig) { tc.Algorithm = "batch"; tc.Subsample = 0.6 },
+		"delay":           func(tc *TrainConfig) { tc.Delay = 7 },
+	}
+	for name, configure := range configs {
+		tc := makeDefaultTrainConfig()
+		tc.Seed = 7
+		configure(tc)
+		iters := 40
+		// uninterrupted training
+		exp, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		assert.NoError(exp.Train(tc, dataMx, iters), name)
+		assert.Nil(exp.Checkpoint())
+
+		// training stopped twice by its context and resumed from JSON encoded checkpoints
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		stopped := *tc
+		stopped.NeighbFn = func(d, r float64) float64 {
+			if calls++; calls == 50 {
+				cancel()
+			}
+			return tc.NeighbFn(d, r)
+		}
+		assert.Equal(context.Canceled, m.TrainContext(ctx, &stopped, dataMx, iters), name)
+		cp := m.Checkpoint()
+		assert.NotNil(cp, name)
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		calls = 0
+		for cp != nil {
+			b, err := json.Marshal(cp)
+			assert.NoError(err)
+			var decoded Checkpoint
+			assert.NoError(json.Unmarshal(b, &decoded))
+			err = m.Resume(ctx, &stopped, dataMx, &decoded)
+			cp = m.Checkpoint()
+			if cp != nil {
+				assert.Equal(context.Canceled, err, name)
+				assert.Greater(cp.Iteration, decoded.Iteration, name)
+				ctx = context.Background()
+				continue
+			}
+			assert.NoError(err, name)
+		}
+		assert.True(mat.Equal(exp.Codebook(), m.Codebook()), name)
+		assert.Equal(exp.TrainHistory(), m.TrainHistory(), name)
+	}
+
+	// checkpoint must match the training
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tc := makeDefaultTrainConfig()
+	assert.Equal(context.Canceled, m.TrainContext(ctx, tc, dataMx, 10))
+	cp := m.Checkpoint()
+	assert.NotNil(cp)
+	assert.Equal(0, cp.Iteration)
+	assert.Error(m.Resume(context.Background(), tc, dataMx, nil))
+	other := mat.DenseCopyOf(dataMx)
+	other.Set(0, 0, 10.0)
+	assert.Error(m.Resume(context.Background(), tc, other, cp))
+	batch := makeDefaultTrainConfig()
+	batch.Algorithm = "batch"
+	assert.Error(m.Resume(context.Background(), batch, dataMx, cp))
+	frozen := makeDefaultTrainConfig()
+	frozen.Freeze = &FreezeConfig{Threshold: 0.05, Patience: 1}
+	assert.Error(m.Resume(context.Background(), frozen, dataMx, cp))
+	// delayed updates must fit the map and the delay
+	delayed := *cp
+	delayed.DelayedSamples, delayed.Delayed = 2, make([]float64, 3)
+	delay := makeDefaultTrainConfig()
+	delay.Delay = 4
+	assert.Error(m.Resume(context.Background(), delay, dataMx, &delayed))
+	delayed.Delayed = make([]float64, 24)
+	assert.Error(m.Resume(context.Background(), tc, dataMx, &delayed))
+	assert.NoError(m.Resume(context.Background(), tc, dataMx, cp))
+	assert.Nil(m.Checkpoint())
+}
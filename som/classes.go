@@ -0,0 +1,186 @@
+package som
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ClassStats holds per-unit class histograms of labeled samples.
+// The histograms can be maintained incrementally as the labeled samples arrive.
+type ClassStats struct {
+	// hists maps SOM unit to counts of its samples classes
+	hists map[int]map[int]int
+	// smooth configures smoothing of dominant classes; it is nil if the classes are not smoothed
+	smooth *classSmoothing
+}
+
+// NewClassStats creates new empty class statistics and returns it
+func NewClassStats() *ClassStats {
+	return &ClassStats{
+		hists: make(map[int]map[int]int),
+	}
+}
+
+// Add records a sample of a given class mapped to SOM unit
+func (s *ClassStats) Add(unit, class int) {
+	hist, ok := s.hists[unit]
+	if !ok {
+		hist = make(map[int]int)
+		s.hists[unit] = hist
+	}
+	hist[class]++
+}
+
+// Update finds the BMU of the sample in map m and records the sample class in its histogram.
+// It returns the BMU index or fails with error if the BMU could not be found.
+func (s *ClassStats) Update(m *Map, sample []float64, class int) (int, error) {
+	bmu, err := m.measure().closest(sample, m.codebook)
+	if err != nil {
+		return -1, err
+	}
+	s.Add(bmu, class)
+
+	return bmu, nil
+}
+
+// Hist returns class histogram of SOM unit: class -> number of samples.
+// It returns empty map if no samples have been mapped to the unit.
+func (s *ClassStats) Hist(unit int) map[int]int {
+	hist := make(map[int]int)
+	for class, count := range s.hists[unit] {
+		hist[class] = count
+	}
+	return hist
+}
+
+// Units returns sorted indices of SOM units which have at least one sample mapped to them
+func (s *ClassStats) Units() []int {
+	units := make([]int, 0, len(s.hists))
+	for unit := range s.hists {
+		units = append(units, unit)
+	}
+	sort.Ints(units)
+	return units
+}
+
+// Dominant returns a map of the most frequent class of each SOM unit.
+// If there are several most frequent classes in the unit, the smallest one is returned.
+// Units which don't have any samples mapped to them are omitted unless the classes are smoothed by Smooth.
+func (s *ClassStats) Dominant() map[int]int {
+	if s.smooth != nil {
+		return s.smooth.classes(s)
+	}
+	dominant := make(map[int]int)
+	for unit, hist := range s.hists {
+		dominant[unit], _ = dominantClass(hist)
+	}
+	return dominant
+}
+
+// UnitPurity returns the fraction of samples mapped to SOM unit which belong to its dominant class.
+// It returns 0 if no samples have been mapped to the unit.
+func (s *ClassStats) UnitPurity(unit int) float64 {
+	hist := s.hists[unit]
+	total := histTotal(hist)
+	if total == 0 {
+		return 0.0
+	}
+	_, count := dominantClass(hist)
+	return float64(count) / float64(total)
+}
+
+// UnitEntropy returns the Shannon entropy in bits of the class distribution of samples mapped to SOM unit.
+// Units whose samples all belong to the same class have zero entropy.
+// It returns 0 if no samples have been mapped to the unit.
+func (s *ClassStats) UnitEntropy(unit int) float64 {
+	hist := s.hists[unit]
+	total := histTotal(hist)
+	entropy := 0.0
+	for _, count := range hist {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Purity returns the map purity: the fraction of all the recorded samples which belong
+// to the dominant class of the SOM unit they are mapped to. This is the average unit purity
+// weighted by the number of samples mapped to units. It returns 0 if no samples have been recorded.
+func (s *ClassStats) Purity() float64 {
+	dominant, total := 0, 0
+	for _, hist := range s.hists {
+		_, count := dominantClass(hist)
+		dominant += count
+		total += histTotal(hist)
+	}
+	if total == 0 {
+		return 0.0
+	}
+	return float64(dominant) / float64(total)
+}
+
+// dominantClass returns the most frequent class in hist along with its count.
+// If there are several most frequent classes, the smallest one is returned.
+func dominantClass(hist map[int]int) (int, int) {
+	best, bestCount := 0, 0
+	for class, count := range hist {
+		if count > bestCount || (count == bestCount && class < best) {
+			best, bestCount = class, count
+		}
+	}
+	return best, bestCount
+}
+
+// histTotal returns the total number of samples in class histogram
+func histTotal(hist map[int]int) int {
+	total := 0
+	for _, count := range hist {
+		total += count
+	}
+	return total
+}
+
+// ClassRows returns a matrix of the data rows which belong to a given class.
+// classes maps data row index to its class; rows which have no class are skipped.
+// It fails with error if the data is nil or if no rows belong to the class.
+func ClassRows(data *mat.Dense, classes map[int]int, class int) (*mat.Dense, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	rows, cols := data.Dims()
+	var classData []float64
+	for row := 0; row < rows; row++ {
+		if c, ok := classes[row]; ok && c == class {
+			classData = append(classData, data.RawRowView(row)...)
+		}
+	}
+	if len(classData) == 0 {
+		return nil, fmt.Errorf("no data rows of class: %d", class)
+	}
+	return mat.NewDense(len(classData)/cols, cols, classData), nil
+}
+
+// ClassStats computes class statistics of the map from data samples and their classes.
+// classes maps data row index to its class; rows which have no class are skipped.
+// It fails with error if the data is nil or the BMUs could not be computed.
+func (m *Map) ClassStats(data *mat.Dense, classes map[int]int) (*ClassStats, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	s := NewClassStats()
+	rows, _ := data.Dims()
+	for row := 0; row < rows; row++ {
+		class, ok := classes[row]
+		if !ok {
+			continue
+		}
+		if _, err := s.Update(m, data.RawRowView(row), class); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
@@ -0,0 +1,96 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestClassStats(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewClassStats()
+	assert.Len(s.Units(), 0)
+	assert.Len(s.Dominant(), 0)
+	s.Add(0, 1)
+	s.Add(0, 2)
+	s.Add(0, 2)
+	s.Add(3, 1)
+	s.Add(3, 0)
+	assert.Equal([]int{0, 3}, s.Units())
+	assert.Equal(map[int]int{1: 1, 2: 2}, s.Hist(0))
+	assert.Equal(map[int]int{}, s.Hist(1))
+	// ties are resolved by picking the smallest class
+	assert.Equal(map[int]int{0: 2, 3: 0}, s.Dominant())
+}
+
+func TestClassStatsPurity(t *testing.T) {
+	assert := assert.New(t)
+
+	s := NewClassStats()
+	assert.Equal(0.0, s.Purity())
+	assert.Equal(0.0, s.UnitPurity(0))
+	assert.Equal(0.0, s.UnitEntropy(0))
+	// pure unit
+	s.Add(0, 1)
+	s.Add(0, 1)
+	s.Add(0, 1)
+	// evenly mixed unit
+	s.Add(1, 0)
+	s.Add(1, 2)
+	assert.Equal(1.0, s.UnitPurity(0))
+	assert.Equal(0.0, s.UnitEntropy(0))
+	assert.Equal(0.5, s.UnitPurity(1))
+	assert.InDelta(1.0, s.UnitEntropy(1), 1e-9)
+	// 4 out of 5 samples belong to the dominant class of their unit
+	assert.InDelta(0.8, s.Purity(), 1e-9)
+	// four evenly mixed classes
+	for class := 0; class < 4; class++ {
+		s.Add(2, class)
+	}
+	assert.Equal(0.25, s.UnitPurity(2))
+	assert.InDelta(2.0, s.UnitEntropy(2), 1e-9)
+}
+
+func TestClassStatsUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	s := NewClassStats()
+	// incorrect sample dimension
+	bmu, err := s.Update(m, []float64{1.0}, 1)
+	assert.Equal(-1, bmu)
+	assert.Error(err)
+	// incremental stats match the stats computed from the whole data set
+	classes := map[int]int{0: 0, 1: 0, 2: 1, 4: 1}
+	for row, class := range classes {
+		_, err = s.Update(m, dataMx.RawRowView(row), class)
+		assert.NoError(err)
+	}
+	ms, err := m.ClassStats(dataMx, classes)
+	assert.NoError(err)
+	assert.Equal(s.Dominant(), ms.Dominant())
+	// nil data
+	ms, err = m.ClassStats(nil, classes)
+	assert.Nil(ms)
+	assert.Error(err)
+}
+
+func TestClassRows(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(3, 2, []float64{
+		1.0, 2.0,
+		3.0, 4.0,
+		5.0, 6.0,
+	})
+	rows, err := ClassRows(data, map[int]int{0: 1, 2: 1, 1: 2}, 1)
+	assert.NoError(err)
+	assert.True(mat.Equal(mat.NewDense(2, 2, []float64{1.0, 2.0, 5.0, 6.0}), rows))
+	_, err = ClassRows(data, map[int]int{0: 1}, 3)
+	assert.Error(err)
+	_, err = ClassRows(nil, map[int]int{0: 1}, 1)
+	assert.Error(err)
+}
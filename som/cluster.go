@@ -0,0 +1,350 @@
+package som
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/milosgajdos/gosom/pkg/matrix"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	// maxKMeansIters is the maximum number of k-means iterations
+	maxKMeansIters = 100
+	// gapRefs is the number of reference data sets used to compute gap statistic
+	gapRefs = 20
+)
+
+// kSelections maps supported methods of selecting the number of clusters
+// elbow picks the k at the elbow of within-cluster dispersion curve,
+// gap picks the k using the gap statistic of Tibshirani, Walther and Hastie
+var kSelections = map[string]bool{
+	"elbow": true,
+	"gap":   true,
+}
+
+// KMeans clusters the rows of matrix mx into k clusters using k-means algorithm
+// and returns the cluster index of each row. Cluster centres are seeded deterministically:
+// the first one is the row closest to the mean of all rows, each of the following ones is the row
+// farthest from the already chosen centres. Rows are clustered using Euclidean distance.
+// It returns error if mx is nil or if k is not a positive integer not higher than the number of mx rows.
+func KMeans(mx *mat.Dense, k int) ([]int, error) {
+	if mx == nil {
+		return nil, fmt.Errorf("invalid matrix supplied: %v", mx)
+	}
+	rows, cols := mx.Dims()
+	if k <= 0 || k > rows {
+		return nil, fmt.Errorf("invalid number of clusters: %d", k)
+	}
+
+	centres := kMeansSeeds(mx, k)
+	clusters := make([]int, rows)
+	for i := range clusters {
+		clusters[i] = -1
+	}
+	counts := make([]int, k)
+	for iter := 0; iter < maxKMeansIters; iter++ {
+		// assign rows to their closest centres
+		changed := false
+		for row := 0; row < rows; row++ {
+			// no need to check for errors: centres have the same dimension as rows
+			c, _ := ClosestVec(Euclidean, mx.RawRowView(row), centres)
+			if c != clusters[row] {
+				clusters[row] = c
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		// move centres to the means of their rows; empty clusters keep their centres
+		for c := range counts {
+			counts[c] = 0
+		}
+		sums := mat.NewDense(k, cols, nil)
+		for row, c := range clusters {
+			counts[c]++
+			floats.Add(sums.RawRowView(c), mx.RawRowView(row))
+		}
+		for c, count := range counts {
+			if count > 0 {
+				floats.ScaleTo(centres.RawRowView(c), 1/float64(count), sums.RawRowView(c))
+			}
+		}
+	}
+
+	return clusters, nil
+}
+
+// kMeansSeeds returns k initial cluster centres picked from mx rows
+func kMeansSeeds(mx *mat.Dense, k int) *mat.Dense {
+	rows, cols := mx.Dims()
+	mean := make([]float64, cols)
+	for row := 0; row < rows; row++ {
+		floats.Add(mean, mx.RawRowView(row))
+	}
+	floats.Scale(1/float64(rows), mean)
+
+	centres := mat.NewDense(k, cols, nil)
+	// no need to check for errors: mean has the same dimension as rows
+	first, _ := ClosestVec(Euclidean, mean, mx)
+	centres.SetRow(0, mx.RawRowView(first))
+	// minDist holds the distance of each row to its closest centre
+	minDist := make([]float64, rows)
+	for row := range minDist {
+		minDist[row] = math.MaxFloat64
+	}
+	for c := 1; c < k; c++ {
+		farthest, farthestDist := 0, -1.0
+		for row := 0; row < rows; row++ {
+			d := euclideanVec(mx.RawRowView(row), centres.RawRowView(c-1))
+			if d < minDist[row] {
+				minDist[row] = d
+			}
+			if minDist[row] > farthestDist {
+				farthest, farthestDist = row, minDist[row]
+			}
+		}
+		centres.SetRow(c, mx.RawRowView(farthest))
+	}
+
+	return centres
+}
+
+// Clusters clusters the map codebook vectors into k clusters using KMeans
+// and returns the cluster index of each map unit.
+// It returns error if k is not a positive integer not higher than the number of map units.
+func (m *Map) Clusters(k int) ([]int, error) {
+	return KMeans(m.codebook, k)
+}
+
+// Silhouette computes silhouette of each data sample clustered into given clusters.
+// clusters contains the cluster index of each data row. Silhouette of a sample is (b-a)/max(a,b)
+// where a is the mean distance of the sample to the other samples of its cluster and b is the smallest
+// mean distance of the sample to the samples of any other cluster. Silhouettes range from -1 to 1,
+// the higher the better the sample fits its cluster; samples which are alone in their cluster have zero
+// silhouette. The average of the returned silhouettes is a common measure of the clustering quality.
+// Distances are computed row by row so the distance matrix of data is never materialized.
+// It returns error if data is nil or if the number of clusters is different from the number of data rows.
+func Silhouette(data *mat.Dense, clusters []int) ([]float64, error) {
+	return silhouette(measure{metric: Euclidean}, data, clusters)
+}
+
+// silhouette computes silhouette of each data sample using a given measure
+func silhouette(ms measure, data *mat.Dense, clusters []int) ([]float64, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	rows, _ := data.Dims()
+	if len(clusters) != rows {
+		return nil, fmt.Errorf("invalid number of clusters: %d", len(clusters))
+	}
+	// number of samples in each cluster
+	sizes := make(map[int]int)
+	for _, c := range clusters {
+		sizes[c]++
+	}
+
+	sil := make([]float64, rows)
+	dist := make([]float64, rows)
+	sums := make(map[int]float64, len(sizes))
+	for row := 0; row < rows; row++ {
+		own := clusters[row]
+		if sizes[own] == 1 {
+			continue
+		}
+		// no need to check for errors: row is always a valid row
+		ms.row(row, data, dist)
+		for c := range sums {
+			delete(sums, c)
+		}
+		for i, d := range dist {
+			sums[clusters[i]] += d
+		}
+		// the sample distance to itself is zero
+		a := sums[own] / float64(sizes[own]-1)
+		b := math.Inf(1)
+		for c, sum := range sums {
+			if c != own {
+				b = math.Min(b, sum/float64(sizes[c]))
+			}
+		}
+		// there is only one cluster
+		if math.IsInf(b, 1) {
+			continue
+		}
+		if norm := math.Max(a, b); norm > 0 {
+			sil[row] = (b - a) / norm
+		}
+	}
+
+	return sil, nil
+}
+
+// Silhouette computes silhouette of each data sample clustered into the cluster of its BMU.
+// clusters contains the cluster index of each map unit such as the one returned by Clusters.
+// See the package Silhouette function for the description of sample silhouette.
+// It returns error if data is nil, if the number of clusters is different from the number of map units
+// or if the BMUs of data samples could not be computed.
+func (m *Map) Silhouette(data *mat.Dense, clusters []int) ([]float64, error) {
+	if units, _ := m.codebook.Dims(); len(clusters) != units {
+		return nil, fmt.Errorf("invalid number of clusters: %d", len(clusters))
+	}
+	bmus, err := bmus(m.measure(), data, m.codebook)
+	if err != nil {
+		return nil, err
+	}
+	sampleClusters := make([]int, len(bmus))
+	for i, bmu := range bmus {
+		sampleClusters[i] = clusters[bmu]
+	}
+
+	return silhouette(m.measure(), data, sampleClusters)
+}
+
+// KScore holds clustering scores of a given number of clusters
+type KScore struct {
+	// K is the number of clusters
+	K int
+	// Dispersion is the within-cluster sum of squared distances to cluster means
+	Dispersion float64
+	// Gap is the gap statistic; it is only computed by the gap method
+	Gap float64
+	// GapErr is the standard error of the gap statistic; it is only computed by the gap method
+	GapErr float64
+}
+
+// SuggestK clusters the rows of matrix mx using KMeans for each number of clusters from kMin to kMax
+// and recommends the number of clusters using a given method. The following methods are supported:
+// elbow - picks k at the elbow of the within-cluster dispersion curve, i.e. the point of the curve
+// which is the farthest from the line joining its ends
+// gap   - picks the smallest k whose gap statistic is not lower than the gap statistic of k+1 less its
+// standard error; gap statistic compares dispersion with dispersions of uniform reference data sets
+// It returns the recommended number of clusters along with the scores of all the evaluated clusterings.
+// It returns error if mx is nil, if the k range is invalid or if the method is not supported.
+func SuggestK(mx *mat.Dense, kMin, kMax int, method string) (int, []KScore, error) {
+	if mx == nil {
+		return 0, nil, fmt.Errorf("invalid matrix supplied: %v", mx)
+	}
+	rows, _ := mx.Dims()
+	if kMin <= 0 || kMax < kMin || kMax > rows {
+		return 0, nil, fmt.Errorf("invalid range of clusters: %d-%d", kMin, kMax)
+	}
+	if !kSelections[method] {
+		return 0, nil, fmt.Errorf("unsupported cluster selection method: %s", method)
+	}
+
+	scores := make([]KScore, 0, kMax-kMin+1)
+	for k := kMin; k <= kMax; k++ {
+		// no need to check for errors: k has been validated
+		clusters, _ := KMeans(mx, k)
+		scores = append(scores, KScore{K: k, Dispersion: dispersion(mx, clusters, k)})
+	}
+
+	switch method {
+	case "gap":
+		gapScores(mx, scores)
+		return gapK(scores), scores, nil
+	default:
+		return elbowK(scores), scores, nil
+	}
+}
+
+// SuggestClusters recommends the number of clusters of the map codebook vectors.
+// See SuggestK for the description of parameters.
+func (m *Map) SuggestClusters(kMin, kMax int, method string) (int, []KScore, error) {
+	return SuggestK(m.codebook, kMin, kMax, method)
+}
+
+// dispersion returns the sum of squared distances of mx rows to the means of their k clusters
+func dispersion(mx *mat.Dense, clusters []int, k int) float64 {
+	_, cols := mx.Dims()
+	means := mat.NewDense(k, cols, nil)
+	counts := make([]float64, k)
+	for row, c := range clusters {
+		counts[c]++
+		floats.Add(means.RawRowView(c), mx.RawRowView(row))
+	}
+	for c, count := range counts {
+		if count > 0 {
+			floats.Scale(1/count, means.RawRowView(c))
+		}
+	}
+	w := 0.0
+	for row, c := range clusters {
+		d := euclideanVec(mx.RawRowView(row), means.RawRowView(c))
+		w += d * d
+	}
+	return w
+}
+
+// gapScores computes gap statistic of the scores using reference data sets
+// sampled uniformly from the bounding box of mx rows
+func gapScores(mx *mat.Dense, scores []KScore) {
+	rows, cols := mx.Dims()
+	// no need to check for errors: mx is not nil
+	min, _ := matrix.ColsMin(cols, mx)
+	max, _ := matrix.ColsMax(cols, mx)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	// logW holds the log dispersions of the reference data sets for each k
+	logW := make([][]float64, len(scores))
+	ref := mat.NewDense(rows, cols, nil)
+	for b := 0; b < gapRefs; b++ {
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				ref.Set(i, j, min[j]+r.Float64()*(max[j]-min[j]))
+			}
+		}
+		for i, s := range scores {
+			clusters, _ := KMeans(ref, s.K)
+			logW[i] = append(logW[i], math.Log(dispersion(ref, clusters, s.K)))
+		}
+	}
+	for i := range scores {
+		mean := floats.Sum(logW[i]) / gapRefs
+		sd := 0.0
+		for _, l := range logW[i] {
+			sd += (l - mean) * (l - mean)
+		}
+		sd = math.Sqrt(sd / gapRefs)
+		scores[i].Gap = mean - math.Log(scores[i].Dispersion)
+		scores[i].GapErr = sd * math.Sqrt(1+1/float64(gapRefs))
+	}
+}
+
+// gapK returns the smallest k whose gap is not lower than the gap of k+1 less its standard error.
+// It returns the highest k if there is no such k.
+func gapK(scores []KScore) int {
+	for i := 0; i < len(scores)-1; i++ {
+		if scores[i].Gap >= scores[i+1].Gap-scores[i+1].GapErr {
+			return scores[i].K
+		}
+	}
+	return scores[len(scores)-1].K
+}
+
+// elbowK returns k at the elbow of dispersion curve: the point with the largest distance
+// from the line joining the curve ends in the space normalized to unit square.
+// It returns the smallest k if the curve has fewer than 3 points or it is flat.
+func elbowK(scores []KScore) int {
+	n := len(scores)
+	first, last := scores[0], scores[n-1]
+	dk := float64(last.K - first.K)
+	dw := first.Dispersion - last.Dispersion
+	if n < 3 || dw <= 0 {
+		return first.K
+	}
+	best, bestDist := first.K, 0.0
+	for _, s := range scores {
+		x := float64(s.K-first.K) / dk
+		y := (first.Dispersion - s.Dispersion) / dw
+		// the normalized curve rises from (0,0) to (1,1): distance from the diagonal
+		if d := y - x; d > bestDist {
+			best, bestDist = s.K, d
+		}
+	}
+	return best
+}
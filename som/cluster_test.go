@@ -0,0 +1,159 @@
+package som
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// two well separated groups of points
+var clusterMx = mat.NewDense(6, 2, []float64{
+	0.0, 0.0,
+	0.0, 1.0,
+	1.0, 0.0,
+	10.0, 10.0,
+	10.0, 11.0,
+	11.0, 10.0,
+})
+
+func TestKMeans(t *testing.T) {
+	assert := assert.New(t)
+
+	clusters, err := KMeans(clusterMx, 2)
+	assert.NoError(err)
+	assert.Len(clusters, 6)
+	for i := 1; i < 3; i++ {
+		assert.Equal(clusters[0], clusters[i])
+		assert.Equal(clusters[3], clusters[3+i])
+	}
+	assert.NotEqual(clusters[0], clusters[3])
+	// every row is its own cluster
+	clusters, err = KMeans(clusterMx, 6)
+	assert.NoError(err)
+	seen := make(map[int]bool)
+	for _, c := range clusters {
+		seen[c] = true
+	}
+	assert.Len(seen, 6)
+	// invalid number of clusters
+	for _, k := range []int{0, -1, 7} {
+		clusters, err = KMeans(clusterMx, k)
+		assert.Nil(clusters)
+		assert.Error(err)
+	}
+	// nil matrix
+	clusters, err = KMeans(nil, 2)
+	assert.Nil(clusters)
+	assert.Error(err)
+}
+
+func TestSilhouette(t *testing.T) {
+	assert := assert.New(t)
+
+	// well separated clusters have silhouettes close to 1
+	sil, err := Silhouette(clusterMx, []int{0, 0, 0, 1, 1, 1})
+	assert.NoError(err)
+	assert.Len(sil, 6)
+	for _, s := range sil {
+		assert.True(s > 0.9)
+	}
+	// mixed clusters have lower average silhouette
+	mixed, err := Silhouette(clusterMx, []int{0, 1, 0, 1, 0, 1})
+	assert.NoError(err)
+	assert.True(floats.Sum(mixed) < floats.Sum(sil))
+	// samples alone in their cluster and a single cluster have zero silhouette
+	sil, err = Silhouette(clusterMx, []int{0, 0, 0, 0, 0, 1})
+	assert.NoError(err)
+	assert.Equal(0.0, sil[5])
+	sil, err = Silhouette(clusterMx, make([]int, 6))
+	assert.NoError(err)
+	assert.Equal(make([]float64, 6), sil)
+	// known silhouette of a 1D data set
+	line := mat.NewDense(3, 1, []float64{0.0, 1.0, 4.0})
+	sil, err = Silhouette(line, []int{0, 0, 1})
+	assert.NoError(err)
+	// a=1, b=4 and a=1, b=3
+	assert.InDeltaSlice([]float64{0.75, 2.0 / 3.0, 0.0}, sil, 1e-9)
+	// mismatched clusters
+	sil, err = Silhouette(clusterMx, []int{0, 1})
+	assert.Nil(sil)
+	assert.Error(err)
+	// nil data
+	sil, err = Silhouette(nil, nil)
+	assert.Nil(sil)
+	assert.Error(err)
+}
+
+func TestMapSilhouette(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	units, _ := m.codebook.Dims()
+	clusters, err := m.Clusters(2)
+	assert.NoError(err)
+	assert.Len(clusters, units)
+	rows, _ := dataMx.Dims()
+	sil, err := m.Silhouette(dataMx, clusters)
+	assert.NoError(err)
+	assert.Len(sil, rows)
+	for _, s := range sil {
+		assert.False(math.IsNaN(s))
+		assert.True(s >= -1.0 && s <= 1.0)
+	}
+	// mismatched number of clusters
+	sil, err = m.Silhouette(dataMx, []int{0})
+	assert.Nil(sil)
+	assert.Error(err)
+	// nil data
+	sil, err = m.Silhouette(nil, clusters)
+	assert.Nil(sil)
+	assert.Error(err)
+}
+
+func TestSuggestK(t *testing.T) {
+	assert := assert.New(t)
+
+	// three well separated groups of points
+	data := make([]float64, 0, 150)
+	for _, centre := range [][2]float64{{0, 0}, {20, 0}, {0, 20}} {
+		for i := 0; i < 25; i++ {
+			data = append(data, centre[0]+float64(i%5)*0.5, centre[1]+float64(i/5)*0.5)
+		}
+	}
+	mx := mat.NewDense(75, 2, data)
+	for _, method := range []string{"elbow", "gap"} {
+		k, scores, err := SuggestK(mx, 1, 6, method)
+		assert.NoError(err)
+		assert.Equal(3, k, method)
+		assert.Len(scores, 6)
+		for i, s := range scores {
+			assert.Equal(i+1, s.K)
+			// dispersion never increases with k on separated data
+			if i > 0 {
+				assert.True(s.Dispersion <= scores[i-1].Dispersion)
+			}
+		}
+	}
+	// single k
+	k, scores, err := SuggestK(mx, 2, 2, "elbow")
+	assert.NoError(err)
+	assert.Equal(2, k)
+	assert.Len(scores, 1)
+	// invalid k ranges
+	for _, r := range [][2]int{{0, 3}, {3, 2}, {1, 76}} {
+		k, scores, err = SuggestK(mx, r[0], r[1], "elbow")
+		assert.Equal(0, k)
+		assert.Nil(scores)
+		assert.Error(err)
+	}
+	// unsupported method
+	_, _, err = SuggestK(mx, 1, 3, "foo")
+	assert.Error(err)
+	// nil matrix
+	_, _, err = SuggestK(nil, 1, 3, "gap")
+	assert.Error(err)
+}
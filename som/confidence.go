@@ -0,0 +1,83 @@
+package som
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// confidenceFuncs maps supported BMU confidence measures to functions which compute
+// the confidence from distances of a data vector to all map units and its BMU index
+var confidenceFuncs = map[string]func(dists []float64, bmu int) float64{
+	"ratio":   ratioConfidence,
+	"softmax": softmaxConfidence,
+}
+
+// Confidence returns the confidence of BMU assignment of each data row using a given measure.
+// The following measures are supported:
+// ratio   - one minus the ratio of the first to the second BMU distance
+// softmax - softmax probability of the BMU with unit activations given by negative unit distances
+// Both measures are between 0 and 1: the lower the confidence, the closer the row is to some other unit
+// than its BMU; ratio is 0 when the row is equally distant from the two closest units and softmax
+// probabilities fall towards the reciprocal of the number of units when all units are equally distant.
+// It returns error if unsupported measure is requested, if data is nil or if the data dimension
+// differs from the codebook dimension.
+func (m *Map) Confidence(data *mat.Dense, measure string) ([]float64, error) {
+	confFn, ok := confidenceFuncs[measure]
+	if !ok {
+		return nil, fmt.Errorf("unsupported confidence measure: %s", measure)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	rows, _ := data.Dims()
+	units, _ := m.codebook.Dims()
+	dists := make([]float64, units)
+	conf := make([]float64, rows)
+	for i := 0; i < rows; i++ {
+		bmu := 0
+		for j := 0; j < units; j++ {
+			d, err := m.measure().distance(data.RawRowView(i), m.codebook.RawRowView(j))
+			if err != nil {
+				return nil, err
+			}
+			dists[j] = d
+			if d < dists[bmu] {
+				bmu = j
+			}
+		}
+		conf[i] = confFn(dists, bmu)
+	}
+
+	return conf, nil
+}
+
+// ratioConfidence returns one minus the ratio of the BMU distance to the second BMU distance.
+// Maps with a single unit have no competing unit, so their assignments are fully confident.
+func ratioConfidence(dists []float64, bmu int) float64 {
+	second := math.Inf(1)
+	for j, d := range dists {
+		if j != bmu && d < second {
+			second = d
+		}
+	}
+	switch {
+	case math.IsInf(second, 1):
+		return 1.0
+	case second == 0:
+		// both BMUs match the vector exactly
+		return 0.0
+	}
+	return 1.0 - dists[bmu]/second
+}
+
+// softmaxConfidence returns the softmax probability of the BMU with unit activations given by negative distances.
+// Activations are shifted by the BMU distance which keeps the exponentials in numeric range.
+func softmaxConfidence(dists []float64, bmu int) float64 {
+	var sum float64
+	for _, d := range dists {
+		sum += math.Exp(dists[bmu] - d)
+	}
+	return 1.0 / sum
+}
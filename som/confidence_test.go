@@ -0,0 +1,50 @@
+package som
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestConfidence(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(&MapConfig{
+		Grid: &GridConfig{Size: []int{1, 2}, Type: "planar", UShape: "rectangle"},
+		Cb:   &CbConfig{Dim: 1, InitFunc: RandInit},
+	}, mat.NewDense(1, 1, []float64{0.0}))
+	assert.NoError(err)
+	m.codebook = mat.NewDense(2, 1, []float64{0.0, 4.0})
+	// exact match, clear assignment and ambiguous assignment
+	data := mat.NewDense(3, 1, []float64{0.0, 1.0, 2.0})
+
+	conf, err := m.Confidence(data, "ratio")
+	assert.NoError(err)
+	assert.InDeltaSlice([]float64{1.0, 1.0 - 1.0/3.0, 0.0}, conf, 1e-12)
+
+	conf, err = m.Confidence(data, "softmax")
+	assert.NoError(err)
+	sigmoid := func(x float64) float64 { return 1.0 / (1.0 + math.Exp(-x)) }
+	assert.InDeltaSlice([]float64{sigmoid(4.0), sigmoid(2.0), 0.5}, conf, 1e-12)
+
+	// unsupported measure
+	_, err = m.Confidence(data, "foo")
+	assert.Error(err)
+	// invalid data
+	_, err = m.Confidence(nil, "ratio")
+	assert.Error(err)
+	_, err = m.Confidence(mat.NewDense(1, 2, nil), "ratio")
+	assert.Error(err)
+}
+
+func TestRatioConfidence(t *testing.T) {
+	assert := assert.New(t)
+
+	// single unit map
+	assert.Equal(1.0, ratioConfidence([]float64{3.0}, 0))
+	// two units matching the vector exactly
+	assert.Equal(0.0, ratioConfidence([]float64{0.0, 1.0, 0.0}, 0))
+	assert.Equal(0.5, ratioConfidence([]float64{4.0, 1.0, 2.0}, 1))
+}
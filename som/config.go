@@ -2,6 +2,7 @@ package som
 
 import (
 	"fmt"
+	"math"
 
 	"gonum.org/v1/gonum/mat"
 )
@@ -13,8 +14,13 @@ var uShapes = map[string]bool{
 }
 
 // gridTypes maps supported grid types
+// toroid grids wrap around both grid dimensions, cylinder grids wrap around grid columns
+// and sphere grids place units on geodesic sphere
 var coordsInitFns = map[string]coordsInitFunc{
-	"planar": GridCoords,
+	"planar":   GridCoords,
+	"toroid":   GridCoords,
+	"cylinder": GridCoords,
+	"sphere":   SphereCoords,
 }
 
 // decays maps supported decay strategies
@@ -30,6 +36,13 @@ var trainingAlgs = map[string]bool{
 	"batch": true,
 }
 
+// schedules maps supported radius and learning rate schedule units
+var schedules = map[string]bool{
+	"":      true,
+	"iter":  true,
+	"epoch": true,
+}
+
 // coordsInitFunc defines SOM grid coordinates initialization function
 type coordsInitFunc func(string, []int) (*mat.Dense, error)
 
@@ -41,9 +54,10 @@ type CbInitFunc func(*mat.Dense, []int) (*mat.Dense, error)
 
 // GridConfig holds SOM grid configuration
 type GridConfig struct {
-	// Size specifies SOM grid dimensions
+	// Size specifies SOM grid dimensions: [rows, cols] or [rows, cols, layers] of 3D grids; see GridCoords
 	Size []int
-	// Type specifies the type of SOM grid: planar
+	// Type specifies the type of SOM grid: planar, toroid, cylinder or sphere.
+	// Sphere grid size must be the size of geodesic sphere grid; see SphereSize.
 	Type string
 	// UShape specifies SOM unit shape: hexagon, rectangle
 	UShape string
@@ -63,6 +77,63 @@ type MapConfig struct {
 	Grid *GridConfig
 	// Codebook holds SOM codebook configuration
 	Cb *CbConfig
+	// Prealloc requests the unit distance matrix and batch training
+	// buffers to be allocated when the map is created
+	Prealloc bool
+	// Metric is the distance metric the map uses to find BMUs and evaluate data from its creation.
+	// Training keeps it unless the training configuration sets a metric other than Euclidean,
+	// so the map is trained, evaluated and visualized with the same metric.
+	Metric Metric
+	// DistanceFn is an optional custom distance function the map uses in place of its metric
+	// to find BMUs and evaluate data. It is also used by training unless the training configuration
+	// sets its own distance function.
+	DistanceFn DistanceFunc
+}
+
+// DefaultSizeScale is the default scale of the number of map units heuristic
+const DefaultSizeScale = 5.0
+
+// SizeConfig holds configuration of map size estimation
+type SizeConfig struct {
+	// Scale scales the number of map units: Scale*sqrt(data samples).
+	// If Scale is 0, DefaultSizeScale is used
+	Scale float64
+	// MaxUnits caps the number of map units estimated by the Scale heuristic, which keeps maps
+	// of big data sets small enough to train. If MaxUnits is 0, the number of units is not capped
+	MaxUnits int
+	// Units requests an explicit number of map units and overrides Scale and MaxUnits
+	Units int
+	// Ratio forces the ratio of the first to the second grid dimension.
+	// If Ratio is 0, the ratio is estimated from data eigenvalues
+	Ratio float64
+	// Type is the type of grid the size is estimated for: planar, toroid or cylinder.
+	// If Type is empty, planar grid is assumed
+	Type string
+}
+
+// DefaultResamples is the default number of bootstrap resamples of sampled quality estimates
+const DefaultResamples = 1000
+
+// EstimateConfig holds configuration of quality measures estimated on a sample of data
+type EstimateConfig struct {
+	// Samples is the number of data rows the measure is evaluated on; the rows are sampled
+	// without replacement. If Samples is 0 or not smaller than the number of data rows, all rows are used
+	Samples int
+	// Resamples is the number of bootstrap resamples of the evaluated rows.
+	// If Resamples is 0, DefaultResamples is used
+	Resamples int
+	// Level is the confidence level of the estimate interval in (0, 1). If Level is 0, 0.95 is used
+	Level float64
+	// Seed seeds the random sampling; if it is 0, the current time is used
+	Seed int64
+}
+
+// sizeTypes maps grid types supported by map size estimation
+var sizeTypes = map[string]bool{
+	"":         true,
+	"planar":   true,
+	"toroid":   true,
+	"cylinder": true,
 }
 
 // TrainConfig holds SOM training configuration
@@ -79,37 +150,152 @@ type TrainConfig struct {
 	LRate float64
 	// LDecay specifies learning rate decay strategy: lin, exp
 	LDecay string
+	// Workers specifies the number of batch training workers; if 0, the number of CPUs is used
+	Workers int
+	// PhaseHook is an optional hook which receives time spent in training phases
+	PhaseHook PhaseHook
+	// Metric is the distance metric used to find BMUs: Euclidean, Manhattan, Chebyshev, Mahalanobis or Tanimoto.
+	// The trained map keeps using the metric to evaluate and project data. Euclidean, the zero value,
+	// leaves the metric of the map configuration in place.
+	Metric Metric
+	// Covariance is an optional covariance matrix of data features used by Mahalanobis metric.
+	// If it is nil, the covariance is estimated from the training data; streamed and online training
+	// have no data to estimate it from, so they use the covariance the map was last trained with.
+	// The covariance is recorded in the training metadata, so loaded maps keep using it.
+	Covariance *mat.SymDense
+	// DistanceFn is an optional custom distance function used in place of Metric. Like the metric,
+	// the trained map keeps using it; it is recorded as "custom" metric in the training metadata.
+	// It can't be combined with carried columns or feature weights.
+	DistanceFn DistanceFunc
+	// Schedule specifies how often the radius and learning rate decay: iter or epoch.
+	// Sequential training updates them with every iteration by default; epoch keeps them constant
+	// during each pass over the data. Batch training iterations are epochs, so both behave the same.
+	Schedule string
+	// Sampling specifies how sequential training draws data rows: uniform or qerror
+	Sampling string
+	// CheckFinite enables checks of non-finite data and codebook values which stop training with NonFiniteError
+	CheckFinite bool
+	// Freeze configures progressive freezing of converged units; it is optional
+	Freeze *FreezeConfig
+	// Eval configures periodic evaluation of stream training on a window of recent samples.
+	// It is optional and only used by stream training.
+	Eval *StreamEval
+	// Weights holds optional weights of training data rows, such as counts of pre-aggregated
+	// duplicate rows. Batch training scales the contribution of each row by its weight.
+	// Weights are only supported by batch training; if empty, all rows have weight 1.
+	Weights []float64
+	// Seed seeds drawing of data rows by sequential training and subsampling, so training runs with the same seed,
+	// data and initial codebook produce the same map. If Seed is 0, current time is used
+	Seed int64
+	// Subsample is the fraction of data rows each epoch trains on; if 0 or 1, all the rows are trained on
+	Subsample float64
+	// Delay is the number of samples whose sequential training updates are applied at once; if 0 or 1, none are delayed
+	Delay int
+	// Empty specifies how batch training updates units outside the radius of any BMU: keep, decay or reassign
+	Empty string
+	// MinNghb is the minimum neighbourhood function sum of batch training updates; if 0, there is no minimum
+	MinNghb float64
+	// Carry holds optional indices of data columns which are left out of BMU search but are still updated,
+	// so carry-along attributes such as IDs or auxiliary measurements are summarized by the units
+	// without influencing the map topology.
+	Carry []int
+	// Fixed holds optional indices of data columns which take part in BMU search but are never updated,
+	// so their codebook values stay the same as they were initialized.
+	Fixed []int
+	// FeatureWeights holds optional non-negative weights of data columns which scale their differences
+	// in BMU search, squared ones in euclidean metric and coordinate products in tanimoto metric, so some features
+	// count more than others even after scaling.
+	// If empty, all the columns have weight 1; carried columns have weight 0 regardless of their weights.
+	FeatureWeights []float64
 }
 
 // validateGridConfig validates SOM grid configuration
 // It returns error if any of the config parameters are invalid
 func validateGridConfig(c *GridConfig) error {
-	// SOM must have 2 dimensions
-	// TODO: figure out 3D maps
-	if len(c.Size) != 2 {
-		return fmt.Errorf("unsupported number of SOM grid dimensions supplied: %d", len(c.Size))
+	if err := validateGridSize(c.Size); err != nil {
+		return err
+	}
+	// check if the supplied grid type is supported
+	if _, ok := coordsInitFns[c.Type]; !ok {
+		return fmt.Errorf("unsupported SOM grid type: %s", c.Type)
+	}
+	// sphere grid size is given by its subdivision level
+	if c.Type == "sphere" {
+		if _, err := sphereLevel(c.Size); err != nil {
+			return err
+		}
+	}
+	// hexagon rows only line up across toroid border if their number is even
+	if c.Type == "toroid" && c.UShape == "hexagon" && !isChain(c.Size) && c.Size[0]%2 == 1 {
+		return fmt.Errorf("hexagon toroid requires even number of rows: %v", c.Size)
+	}
+	// check if the supplied unit shape type is supported
+	if _, ok := uShapes[c.UShape]; !ok {
+		return fmt.Errorf("unsupported SOM unit shape: %s", c.UShape)
+	}
+
+	return nil
+}
+
+// validateGridSize validates SOM grid size
+// It returns error if the grid size is invalid
+func validateGridSize(size []int) error {
+	// SOM must have 2 dimensions or 3 dimensions of layered grids
+	if len(size) != 2 && len(size) != 3 {
+		return fmt.Errorf("unsupported number of SOM grid dimensions supplied: %d", len(size))
 	}
 	// check if the supplied dimensions are negative integers or if they are single node
 	product := 1
-	for _, dim := range c.Size {
+	for _, dim := range size {
 		if dim <= 0 {
-			return fmt.Errorf("incorrect SOM grid dimensions supplied: %v", c.Size)
+			return fmt.Errorf("incorrect SOM grid dimensions supplied: %v", size)
 		}
 		product *= dim
 	}
 	// 1D dimensions supplied: [1,1,1...]
 	if product == 1 {
-		return fmt.Errorf("incorrect SOM grid dimensions supplied: %v", c.Size)
+		return fmt.Errorf("incorrect SOM grid dimensions supplied: %v", size)
 	}
-	// check if the supplied grid type is supported
-	if _, ok := coordsInitFns[c.Type]; !ok {
-		return fmt.Errorf("unsupported SOM grid type: %s", c.Type)
+
+	return nil
+}
+
+// validateSizeConfig validates map size estimation configuration
+// It returns error if any of the config parameters are invalid
+func validateSizeConfig(c *SizeConfig) error {
+	if c.Scale < 0 {
+		return fmt.Errorf("invalid map size scale: %f", c.Scale)
 	}
-	// check if the supplied unit shape type is supported
-	if _, ok := uShapes[c.UShape]; !ok {
-		return fmt.Errorf("unsupported SOM unit shape: %s", c.UShape)
+	if c.Units < 0 {
+		return fmt.Errorf("invalid number of map units: %d", c.Units)
+	}
+	if c.MaxUnits < 0 {
+		return fmt.Errorf("invalid maximum number of map units: %d", c.MaxUnits)
+	}
+	if c.Ratio < 0 {
+		return fmt.Errorf("invalid map dimensions ratio: %f", c.Ratio)
+	}
+	if _, ok := sizeTypes[c.Type]; !ok {
+		return fmt.Errorf("unsupported SOM grid type: %s", c.Type)
 	}
+	return nil
+}
 
+// validateEstimateConfig validates sampled quality estimate configuration
+// It returns error if any of the config parameters are invalid
+func validateEstimateConfig(c *EstimateConfig) error {
+	if c == nil {
+		return fmt.Errorf("invalid estimate config: %v", c)
+	}
+	if c.Samples < 0 {
+		return fmt.Errorf("invalid number of samples: %d", c.Samples)
+	}
+	if c.Resamples < 0 {
+		return fmt.Errorf("invalid number of resamples: %d", c.Resamples)
+	}
+	if c.Level < 0 || c.Level >= 1 {
+		return fmt.Errorf("invalid confidence level: %f", c.Level)
+	}
 	return nil
 }
 
@@ -154,5 +340,61 @@ func validateTrainConfig(c *TrainConfig) error {
 	if _, ok := decays[c.LDecay]; !ok {
 		return fmt.Errorf("unsupported Learning rate decay strategy: %s", c.LDecay)
 	}
+	// number of batch workers can't be negative
+	if c.Workers < 0 {
+		return fmt.Errorf("invalid number of workers: %d", c.Workers)
+	}
+	// check the distance metric
+	if _, ok := metricNames[c.Metric]; !ok {
+		return fmt.Errorf("unsupported metric: %s", c.Metric)
+	}
+	// check the schedule unit
+	if !schedules[c.Schedule] {
+		return fmt.Errorf("unsupported schedule: %s", c.Schedule)
+	}
+	// check the sampling strategy
+	if !samplings[c.Sampling] {
+		return fmt.Errorf("unsupported sampling: %s", c.Sampling)
+	}
+	if c.Sampling == "qerror" && c.Algorithm != "seq" {
+		return fmt.Errorf("sampling %s unsupported by training algorithm: %s", c.Sampling, c.Algorithm)
+	}
+	// check unit freezing configuration
+	if err := validateFreezeConfig(c.Freeze); err != nil {
+		return err
+	}
+	// row weights are only used by batch training and can't be negative
+	if len(c.Weights) > 0 && c.Algorithm != "batch" {
+		return fmt.Errorf("row weights unsupported by training algorithm: %s", c.Algorithm)
+	}
+	for i, w := range c.Weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("invalid weight of row %d: %f", i, w)
+		}
+	}
+	// subsampled fraction of data rows must be between 0 and 1
+	if c.Subsample < 0 || c.Subsample > 1 || math.IsNaN(c.Subsample) {
+		return fmt.Errorf("invalid subsample fraction: %f", c.Subsample)
+	}
+	// delayed updates are only accumulated by sequential training
+	if c.Delay < 0 {
+		return fmt.Errorf("invalid update delay: %d", c.Delay)
+	}
+	if c.Delay > 1 && c.Algorithm != "seq" {
+		return fmt.Errorf("update delay unsupported by training algorithm: %s", c.Algorithm)
+	}
+	// check empty units update and the minimum neighbourhood sum of batch training
+	if !emptyUpdates[c.Empty] {
+		return fmt.Errorf("unsupported empty units update: %s", c.Empty)
+	}
+	if c.MinNghb < 0 || math.IsNaN(c.MinNghb) || math.IsInf(c.MinNghb, 0) {
+		return fmt.Errorf("invalid minimum neighbourhood sum: %f", c.MinNghb)
+	}
+	if c.Empty != "" && c.Empty != "keep" && c.Algorithm != "batch" {
+		return fmt.Errorf("empty units update %s unsupported by training algorithm: %s", c.Empty, c.Algorithm)
+	}
+	if c.MinNghb > 0 && c.Algorithm != "batch" {
+		return fmt.Errorf("minimum neighbourhood sum unsupported by training algorithm: %s", c.Algorithm)
+	}
 	return nil
 }
@@ -2,6 +2,7 @@ package som
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -313,3 +314,87 @@ func TestValidateLDecay(t *testing.T) {
 	}
 	tr.LDecay = origLDecay
 }
+
+func TestValidateWorkers(t *testing.T) {
+	assert := assert.New(t)
+
+	tr := makeDefaultTrainConfig()
+	errString := "invalid number of workers: %d"
+	testCases := []struct {
+		workers int
+		expErr  bool
+	}{
+		{0, false},
+		{4, false},
+		{-1, true},
+	}
+
+	for _, tc := range testCases {
+		tr.Workers = tc.workers
+		err := validateTrainConfig(tr)
+		if tc.expErr {
+			assert.EqualError(err, fmt.Sprintf(errString, tr.Workers))
+		} else {
+			assert.NoError(err)
+		}
+	}
+}
+
+func TestValidateWeights(t *testing.T) {
+	assert := assert.New(t)
+
+	tr := makeDefaultTrainConfig()
+	testCases := []struct {
+		algorithm string
+		weights   []float64
+		expErr    bool
+	}{
+		{"seq", nil, false},
+		{"batch", []float64{1, 0, 2.5}, false},
+		{"seq", []float64{1, 2}, true},
+		{"batch", []float64{1, -1}, true},
+		{"batch", []float64{1, math.NaN()}, true},
+	}
+
+	for _, tc := range testCases {
+		tr.Algorithm, tr.Weights = tc.algorithm, tc.weights
+		err := validateTrainConfig(tr)
+		if tc.expErr {
+			assert.Error(err)
+		} else {
+			assert.NoError(err)
+		}
+	}
+}
+
+func TestValidateEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	tr := makeDefaultTrainConfig()
+	testCases := []struct {
+		algorithm string
+		empty     string
+		minNghb   float64
+		expErr    bool
+	}{
+		{"seq", "", 0, false},
+		{"seq", "keep", 0, false},
+		{"batch", "decay", 0.5, false},
+		{"batch", "reassign", 0, false},
+		{"batch", "foo", 0, true},
+		{"batch", "", -1, true},
+		{"batch", "", math.Inf(1), true},
+		{"seq", "decay", 0, true},
+		{"seq", "", 1, true},
+	}
+
+	for _, tc := range testCases {
+		tr.Algorithm, tr.Empty, tr.MinNghb = tc.algorithm, tc.empty, tc.minNghb
+		err := validateTrainConfig(tr)
+		if tc.expErr {
+			assert.Error(err)
+		} else {
+			assert.NoError(err)
+		}
+	}
+}
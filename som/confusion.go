@@ -0,0 +1,149 @@
+package som
+
+import (
+	"fmt"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ConfusionMatrix holds the counts of true and predicted classes of labeled samples
+type ConfusionMatrix struct {
+	// Classes contains sorted classes of the matrix rows and columns
+	Classes []int
+	// Counts stores the number of samples of class Classes[i] predicted as class Classes[j] in Counts[i][j]
+	Counts [][]int
+	// Unassigned stores the number of samples of class Classes[i] whose BMU has no class
+	Unassigned []int
+}
+
+// NewConfusionMatrix creates confusion matrix from true and predicted classes of samples.
+// Samples which have no predicted class are counted as unassigned. Samples which have
+// no true class are skipped. Both maps are indexed by sample row.
+func NewConfusionMatrix(truth, predicted map[int]int) *ConfusionMatrix {
+	seen := make(map[int]bool)
+	for row, class := range truth {
+		seen[class] = true
+		if pred, ok := predicted[row]; ok {
+			seen[pred] = true
+		}
+	}
+	classes := make([]int, 0, len(seen))
+	for class := range seen {
+		classes = append(classes, class)
+	}
+	sort.Ints(classes)
+	index := make(map[int]int, len(classes))
+	for i, class := range classes {
+		index[class] = i
+	}
+
+	c := &ConfusionMatrix{
+		Classes:    classes,
+		Counts:     make([][]int, len(classes)),
+		Unassigned: make([]int, len(classes)),
+	}
+	for i := range c.Counts {
+		c.Counts[i] = make([]int, len(classes))
+	}
+	for row, class := range truth {
+		pred, ok := predicted[row]
+		if !ok {
+			c.Unassigned[index[class]]++
+			continue
+		}
+		c.Counts[index[class]][index[pred]]++
+	}
+
+	return c
+}
+
+// Total returns the number of samples in the confusion matrix including the unassigned ones
+func (c *ConfusionMatrix) Total() int {
+	total := 0
+	for i := range c.Classes {
+		total += c.rowTotal(i)
+	}
+	return total
+}
+
+// Accuracy returns the fraction of samples whose predicted class matches their true class.
+// Unassigned samples are counted as misclassified. It returns 0 if the matrix is empty.
+func (c *ConfusionMatrix) Accuracy() float64 {
+	total := c.Total()
+	if total == 0 {
+		return 0.0
+	}
+	correct := 0
+	for i := range c.Classes {
+		correct += c.Counts[i][i]
+	}
+	return float64(correct) / float64(total)
+}
+
+// F1 returns F1 score of a given class, i.e. the harmonic mean of its precision and recall.
+// It returns 0 if the class is not in the matrix or if it has neither been predicted nor correctly recalled.
+func (c *ConfusionMatrix) F1(class int) float64 {
+	i := sort.SearchInts(c.Classes, class)
+	if i == len(c.Classes) || c.Classes[i] != class {
+		return 0.0
+	}
+	predicted := 0
+	for j := range c.Classes {
+		predicted += c.Counts[j][i]
+	}
+	// F1 is 2*TP / (2*TP + FP + FN)
+	if denom := predicted + c.rowTotal(i); denom > 0 {
+		return 2 * float64(c.Counts[i][i]) / float64(denom)
+	}
+	return 0.0
+}
+
+// MacroF1 returns the unweighted average of F1 scores of all the matrix classes.
+// It returns 0 if the matrix is empty.
+func (c *ConfusionMatrix) MacroF1() float64 {
+	if len(c.Classes) == 0 {
+		return 0.0
+	}
+	f1 := 0.0
+	for _, class := range c.Classes {
+		f1 += c.F1(class)
+	}
+	return f1 / float64(len(c.Classes))
+}
+
+// rowTotal returns the number of samples of i-th class
+func (c *ConfusionMatrix) rowTotal(i int) int {
+	total := c.Unassigned[i]
+	for _, count := range c.Counts[i] {
+		total += count
+	}
+	return total
+}
+
+// Confusion classifies labeled data samples with the class of their BMU and returns the confusion
+// matrix of the classification. unitClasses maps SOM units to their classes such as the majority
+// classes of training samples returned by ClassStats Dominant. classes maps data row index to its class;
+// rows which have no class are skipped. Samples whose BMU has no class are counted as unassigned.
+// It fails with error if the data is nil or the BMUs could not be computed.
+func (m *Map) Confusion(data *mat.Dense, classes, unitClasses map[int]int) (*ConfusionMatrix, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	predicted := make(map[int]int)
+	rows, _ := data.Dims()
+	for row := 0; row < rows; row++ {
+		if _, ok := classes[row]; !ok {
+			continue
+		}
+		bmu, err := m.measure().closest(data.RawRowView(row), m.codebook)
+		if err != nil {
+			return nil, err
+		}
+		if class, ok := unitClasses[bmu]; ok {
+			predicted[row] = class
+		}
+	}
+
+	return NewConfusionMatrix(classes, predicted), nil
+}
@@ -0,0 +1,60 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfusionMatrix(t *testing.T) {
+	assert := assert.New(t)
+
+	// empty matrix
+	c := NewConfusionMatrix(nil, nil)
+	assert.Len(c.Classes, 0)
+	assert.Equal(0, c.Total())
+	assert.Equal(0.0, c.Accuracy())
+	assert.Equal(0.0, c.MacroF1())
+
+	truth := map[int]int{0: 0, 1: 0, 2: 0, 3: 1, 4: 1, 5: 2}
+	// sample 2 is misclassified, sample 5 has no predicted class,
+	// sample 6 has no true class
+	predicted := map[int]int{0: 0, 1: 0, 2: 1, 3: 1, 4: 1, 6: 2}
+	c = NewConfusionMatrix(truth, predicted)
+	assert.Equal([]int{0, 1, 2}, c.Classes)
+	assert.Equal([][]int{{2, 1, 0}, {0, 2, 0}, {0, 0, 0}}, c.Counts)
+	assert.Equal([]int{0, 0, 1}, c.Unassigned)
+	assert.Equal(6, c.Total())
+	assert.InDelta(4.0/6.0, c.Accuracy(), 1e-9)
+	// precision 1, recall 2/3
+	assert.InDelta(0.8, c.F1(0), 1e-9)
+	// precision 2/3, recall 1
+	assert.InDelta(0.8, c.F1(1), 1e-9)
+	assert.Equal(0.0, c.F1(2))
+	// unknown class
+	assert.Equal(0.0, c.F1(10))
+	assert.InDelta(1.6/3.0, c.MacroF1(), 1e-9)
+}
+
+func TestMapConfusion(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	classes := map[int]int{0: 0, 1: 0, 2: 1, 4: 1}
+	stats, err := m.ClassStats(dataMx, classes)
+	assert.NoError(err)
+	// classifying training data with majority classes matches map purity
+	c, err := m.Confusion(dataMx, classes, stats.Dominant())
+	assert.NoError(err)
+	assert.Equal(len(classes), c.Total())
+	assert.InDelta(stats.Purity(), c.Accuracy(), 1e-9)
+	// no unit classes
+	c, err = m.Confusion(dataMx, classes, nil)
+	assert.NoError(err)
+	assert.Equal(0.0, c.Accuracy())
+	// nil data
+	c, err = m.Confusion(nil, classes, nil)
+	assert.Nil(c)
+	assert.Error(err)
+}
@@ -0,0 +1,73 @@
+package som
+
+import "gonum.org/v1/gonum/mat"
+
+// delayedUpdates accumulates sequential training updates of codebook vectors which are applied at once
+type delayedUpdates struct {
+	// delay is the number of samples whose updates are accumulated
+	delay int
+	// n is the number of samples accumulated since the updates were last applied
+	n int
+	// deltas holds the accumulated updates of codebook vectors: units x data features
+	deltas *mat.Dense
+	// updated marks the units which have some accumulated updates
+	updated []bool
+}
+
+// newDelayedUpdates returns updates of codebook accumulated over delay samples.
+// The samples of each block find their BMUs in the same codebook, so the updates of the block don't depend
+// on the order of its samples and the codebook of very large maps is written once per block instead of once
+// per sample. The last block of a training is applied even if it is shorter, while the unfinished block of
+// a training stopped early or of a training stage is saved in its checkpoint and completed when it continues.
+// It returns nil if the updates are not delayed, i.e. delay is lower than 2.
+func newDelayedUpdates(delay int, codebook *mat.Dense) *delayedUpdates {
+	if delay < 2 {
+		return nil
+	}
+	units, dim := codebook.Dims()
+	return &delayedUpdates{
+		delay:   delay,
+		deltas:  mat.NewDense(units, dim, nil),
+		updated: make([]bool, units),
+	}
+}
+
+// target returns the vector sequential update of codebook vector cbVec of unit is written to:
+// cbVec itself if updates are not delayed or the accumulated update of the unit otherwise.
+func (du *delayedUpdates) target(unit int, cbVec []float64) []float64 {
+	if du == nil {
+		return cbVec
+	}
+	du.updated[unit] = true
+	return du.deltas.RawRowView(unit)
+}
+
+// step counts a sample whose updates have been accumulated and applies the updates to codebook
+// once there are delay samples.
+func (du *delayedUpdates) step(codebook *mat.Dense) {
+	if du == nil {
+		return
+	}
+	if du.n++; du.n == du.delay {
+		du.flush(codebook)
+	}
+}
+
+// flush applies the accumulated updates to codebook and resets them
+func (du *delayedUpdates) flush(codebook *mat.Dense) {
+	if du == nil || du.n == 0 {
+		return
+	}
+	for unit, ok := range du.updated {
+		if !ok {
+			continue
+		}
+		cbVec, delta := codebook.RawRowView(unit), du.deltas.RawRowView(unit)
+		for i := range cbVec {
+			cbVec[i] += delta[i]
+			delta[i] = 0.0
+		}
+		du.updated[unit] = false
+	}
+	du.n = 0
+}
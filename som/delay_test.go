@@ -0,0 +1,82 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestDelayedUpdates(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(newDelayedUpdates(0, dataMx))
+	assert.Nil(newDelayedUpdates(1, dataMx))
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	unitDist, err := m.UnitDist()
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	init := mat.DenseCopyOf(m.codebook)
+	// every sample of the block is applied to the initial codebook
+	exp := mat.DenseCopyOf(init)
+	rows, _ := dataMx.Dims()
+	for i := 0; i < rows; i++ {
+		m.codebook.Copy(init)
+		m.seqStep(tc, m.measure(), unitDist, dataMx.RawRowView(i), 0.5, 2.0, nil, nil, nil, newPhaseTimer(nil))
+		var delta mat.Dense
+		delta.Sub(m.codebook, init)
+		exp.Add(exp, &delta)
+	}
+	m.codebook.Copy(init)
+	du := newDelayedUpdates(rows, m.codebook)
+	for i := 0; i < rows; i++ {
+		// the codebook is only updated once the block is complete
+		assert.True(mat.Equal(init, m.codebook), "sample %d", i)
+		m.seqStep(tc, m.measure(), unitDist, dataMx.RawRowView(i), 0.5, 2.0, nil, nil, du, newPhaseTimer(nil))
+	}
+	assert.True(mat.EqualApprox(exp, m.codebook, 1e-12))
+	// incomplete blocks are applied by flush
+	m.codebook.Copy(init)
+	m.seqStep(tc, m.measure(), unitDist, dataMx.RawRowView(0), 0.5, 2.0, nil, nil, du, newPhaseTimer(nil))
+	assert.True(mat.Equal(init, m.codebook))
+	du.flush(m.codebook)
+	assert.False(mat.Equal(init, m.codebook))
+	assert.Equal(0, du.n)
+}
+
+func TestTrainDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	// delay of a single sample updates the codebook right away
+	tc := makeDefaultTrainConfig()
+	tc.Seed = 3
+	m1, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	m2, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	m2.codebook.Copy(m1.codebook)
+	assert.NoError(m1.Train(tc, dataMx, 20))
+	tc.Delay = 1
+	assert.NoError(m2.Train(tc, dataMx, 20))
+	assert.True(mat.Equal(m1.codebook, m2.codebook))
+
+	// delayed updates are recorded in the metadata and streamed training delays them, too
+	tc.Delay = 4
+	m3, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	assert.NoError(m3.Train(tc, dataMx, 22))
+	assert.Equal(4, m3.Metadata().Train.Delay)
+	assert.NoError(m3.TrainStream(tc, feed(dataMx, 10), 5, 10))
+
+	// invalid delays
+	tc.Delay = -1
+	assert.Error(m3.Train(tc, dataMx, 10))
+	tc.Delay = 2
+	tc.Algorithm = "batch"
+	assert.Error(m3.Train(tc, dataMx, 10))
+	tc.Algorithm = "seq"
+	_, err = NewOnlineTrainer(m3, tc, 10, 5)
+	assert.Error(err)
+}
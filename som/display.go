@@ -3,17 +3,18 @@ package som
 import (
 	"encoding/xml"
 	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
 	"io"
 	"math"
+	"unicode/utf8"
 
 	"gonum.org/v1/gonum/mat"
 )
 
-type rowWithDist struct {
-	Row  int
-	Dist float64
-}
-
 type h1 struct {
 	XMLName xml.Name `xml:"h1"`
 	Title   string   `xml:",innerxml"`
@@ -25,6 +26,20 @@ type polygon struct {
 	Style   string   `xml:"style,attr"`
 }
 
+type polyline struct {
+	XMLName xml.Name `xml:"polyline"`
+	Points  []byte   `xml:"points,attr"`
+	Style   string   `xml:"style,attr"`
+}
+
+type circle struct {
+	XMLName xml.Name `xml:"circle"`
+	Cx      float64  `xml:"cx,attr"`
+	Cy      float64  `xml:"cy,attr"`
+	R       float64  `xml:"r,attr"`
+	Style   string   `xml:"style,attr"`
+}
+
 type svgElement struct {
 	XMLName  xml.Name `xml:"svg"`
 	Width    float64  `xml:"width,attr"`
@@ -32,6 +47,17 @@ type svgElement struct {
 	Polygons []interface{}
 }
 
+type svgDocument struct {
+	XMLName xml.Name `xml:"svg"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Width   float64  `xml:"width,attr"`
+	Height  float64  `xml:"height,attr"`
+	ViewBox string   `xml:"viewBox,attr"`
+	Title   string   `xml:"title,omitempty"`
+	Desc    string   `xml:"desc,omitempty"`
+	Elems   []interface{}
+}
+
 type textElement struct {
 	XMLName xml.Name `xml:"text"`
 	X       float64  `xml:"x,attr"`
@@ -39,112 +65,269 @@ type textElement struct {
 	Text    string   `xml:",innerxml"`
 }
 
+type labelElement struct {
+	XMLName    xml.Name `xml:"text"`
+	X          float64  `xml:"x,attr"`
+	Y          float64  `xml:"y,attr"`
+	FontSize   float64  `xml:"font-size,attr"`
+	TextAnchor string   `xml:"text-anchor,attr"`
+	Text       string   `xml:",chardata"`
+}
+
+// svgNamespace is the SVG XML namespace
+const svgNamespace = "http://www.w3.org/2000/svg"
+
+// SVGConfig configures SVG representation of U-Matrix
+type SVGConfig struct {
+	// Standalone requests a standalone SVG document with XML declaration, namespace and viewBox
+	// instead of the h1 title followed by the svg element which can be embedded in HTML documents
+	Standalone bool
+	// Description is embedded in the desc element of the standalone document
+	Description string
+	// Labels maps unit indices to text labels which are drawn instead of unit class numbers.
+	// Labels are placed so that they do not overlap: if a label does not fit the unit centre,
+	// it is moved to the upper or lower part of the unit and its font is shrunk down to half
+	// of FontSize. Labels which do not fit anywhere are left out.
+	Labels map[int]string
+	// FontSize is the font size of labels; it defaults to 12
+	FontSize float64
+}
+
 var colors = [][]int{{255, 0, 0}, {0, 255, 0}, {0, 0, 255}, {255, 255, 0}, {255, 0, 255}, {0, 255, 255}}
 
 // UMatrixSVG creates an SVG representation of the U-Matrix of the given codebook.
 // It accepts the following parameters:
 // codebook - the codebook we're displaying the U-Matrix for
-// dims     - the dimensions of the map grid
+// dims     - the dimensions of the map grid; layers of 3D grids are drawn side by side
 // uShape   - the shape of the map grid
 // title    - the title of the output SVG
 // writer   - the io.Writter to write the output SVG to.
 // classes  - if the classes are known (i.e. these are test data) they can be displayed providing the information in this map.
 // The map is: codebook vector row -> class number. When classes are not known (i.e. running with real data), just provide an empty map
 func UMatrixSVG(codebook *mat.Dense, dims []int, uShape, title string, writer io.Writer, classes map[int]int) error {
+	u, err := latticeMap(codebook, dims, uShape)
+	if err != nil {
+		return err
+	}
+
+	return u.svg(title, writer, classes)
+}
+
+// UMatrixSVGWith creates an SVG representation of the U-Matrix of the given codebook configured by c.
+// If c is nil, it creates the same SVG representation as UMatrixSVG.
+// See UMatrixSVG for the description of the remaining parameters.
+func UMatrixSVGWith(codebook *mat.Dense, dims []int, uShape, title string, writer io.Writer, classes map[int]int, c *SVGConfig) error {
+	u, err := latticeMap(codebook, dims, uShape)
+	if err != nil {
+		return err
+	}
+
+	return u.svgWith(title, writer, classes, c)
+}
+
+// umatrixMap holds the map whose U-Matrix is displayed
+type umatrixMap struct {
+	// ms is used to compute codebook distances
+	ms measure
+	// codebook holds map codebook vectors
+	codebook *mat.Dense
+	// coords holds map grid coordinates
+	coords *mat.Dense
+	// dims are map grid dimensions
+	dims []int
+	// uShape is the unit shape used to draw the map
+	uShape string
+	// adj decides which units are averaged in U-Matrix
+	adj Adjacency
+	// values holds unit values displayed instead of U-Matrix, such as unit quantization errors
+	values []float64
+	// hidden marks units which are not drawn; all units are drawn if it is nil
+	hidden []bool
+	// path holds units connected by a line drawn over the map, such as BMUs of a feature sweep
+	path []int
+}
+
+// newUMatrixMap returns a new umatrixMap
+func newUMatrixMap(ms measure, codebook, coords *mat.Dense, dims []int, uShape string, adj Adjacency) *umatrixMap {
+	return &umatrixMap{
+		ms:       ms,
+		codebook: codebook,
+		coords:   coords,
+		dims:     dims,
+		uShape:   displayShape(uShape, dims),
+		adj:      adj,
+	}
+}
+
+// latticeMap returns U-Matrix display of codebook of planar grid of given dims and unit shape.
+// Layers of 3D grids are displayed side by side.
+func latticeMap(codebook *mat.Dense, dims []int, uShape string) (*umatrixMap, error) {
+	coords, err := GridCoords(uShape, dims)
+	if err != nil {
+		return nil, err
+	}
+	adj := latticeAdjacency(coords, dims, uShape)
+	if len(dims) == 3 {
+		coords, dims = sliceCoords(coords, dims)
+	}
+
+	return newUMatrixMap(measure{metric: Euclidean}, codebook, coords, dims, uShape, adj), nil
+}
+
+// latticeAdjacency returns adjacency of the units of planar grid with given coords, dims and unit shape
+func latticeAdjacency(coords *mat.Dense, dims []int, uShape string) Adjacency {
+	radius := neighbourRadius(uShape, dims)
+	return func(a, b int) bool {
+		return a != b && euclideanVec(coords.RawRowView(a), coords.RawRowView(b)) < radius
+	}
+}
+
+// unitValues returns displayed unit values along with their min and max values.
+// Unless the map has its own values, U-Matrix values are returned.
+func (u *umatrixMap) unitValues() ([]float64, float64, float64, error) {
+	if u.values == nil {
+		return uMatrix(u.ms, u.codebook, u.coords, u.adj)
+	}
+	if rows, _ := u.codebook.Dims(); len(u.values) != rows {
+		return nil, 0, 0, fmt.Errorf("unit values and codebook dimension mismatch")
+	}
+	min, max := math.MaxFloat64, -math.MaxFloat64
+	for _, v := range u.values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	// equal values are drawn in the lightest shade
+	if max == min {
+		max = min + 1
+	}
+	return u.values, min, max, nil
+}
+
+// isHidden returns true if the unit is not drawn
+func (u *umatrixMap) isHidden(unit int) bool {
+	return u.hidden != nil && u.hidden[unit]
+}
+
+// write writes the U-Matrix with units labeled by classes to w in a given format: svg, html or png
+func (u *umatrixMap) write(w io.Writer, classes map[int]int, format, title string) error {
+	switch format {
+	case "svg":
+		return u.svg(title, w, classes)
+	case "html":
+		return u.html(title, w, classes)
+	case "png":
+		img, err := u.image(classes)
+		if err != nil {
+			return err
+		}
+		return png.Encode(w, img)
+	}
+
+	return fmt.Errorf("unsupported format %s", format)
+}
+
+// svg creates an SVG representation of the U-Matrix
+func (u *umatrixMap) svg(title string, writer io.Writer, classes map[int]int) error {
+	return u.svgWith(title, writer, classes, nil)
+}
+
+// svgWith creates an SVG representation of the U-Matrix configured by c
+func (u *umatrixMap) svgWith(title string, writer io.Writer, classes map[int]int, c *SVGConfig) error {
+	if c == nil {
+		c = &SVGConfig{}
+	}
+	if c.Standalone {
+		return u.svgDocument(title, writer, classes, c)
+	}
+
 	xmlEncoder := xml.NewEncoder(writer)
 	// array to hold the xml elements
 	elems := []interface{}{h1{Title: title}}
 
-	rows, _ := codebook.Dims()
-	distMat, err := DistanceMx(Euclidean, codebook)
+	svgElem, err := u.svgElement(classes, c)
 	if err != nil {
 		return err
 	}
-	coords, err := GridCoords(uShape, dims)
-	if err != nil {
+
+	elems = append(elems, svgElem)
+
+	if err := xmlEncoder.Encode(elems); err != nil {
 		return err
 	}
-	coordsDistMat, err := DistanceMx(Euclidean, coords)
+	xmlEncoder.Flush()
+
+	return nil
+}
+
+// svgDocument creates a standalone SVG document with the U-Matrix configured by c
+func (u *umatrixMap) svgDocument(title string, writer io.Writer, classes map[int]int, c *SVGConfig) error {
+	svgElem, err := u.svgElement(classes, c)
 	if err != nil {
 		return err
 	}
-
-	umatrix := make([]float64, rows)
-	maxDistance := -math.MaxFloat64
-	minDistance := math.MaxFloat64
+	// view box covers all unit polygons including their strokes
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	rows, _ := u.codebook.Dims()
 	for row := 0; row < rows; row++ {
-		avgDistance := 0.0
-		// this is a rough approximation of the notion of neighbor grid coords
-		allRowsInRadius := allRowsInRadius(row, math.Sqrt2*1.01, coordsDistMat)
-		for _, rwd := range allRowsInRadius {
-			if rwd.Dist > 0.0 {
-				avgDistance += distMat.At(row, rwd.Row)
-			}
-		}
-		avgDistance /= float64(len(allRowsInRadius) - 1)
-		umatrix[row] = avgDistance
-		if avgDistance > maxDistance {
-			maxDistance = avgDistance
-		}
-		if avgDistance < maxDistance {
-			minDistance = avgDistance
-		}
+		x0, y0, x1, y1 := polygonBounds(unitPolygon(u.uShape, scale(u.coords.At(row, 0)), scale(u.coords.At(row, 1))))
+		minX, minY = math.Min(minX, x0), math.Min(minY, y0)
+		maxX, maxY = math.Max(maxX, x1), math.Max(maxY, y1)
 	}
+	minX, minY, maxX, maxY = minX-1, minY-1, maxX+1, maxY+1
 
-	// function to scale the coord grid to something visible
-	const MUL = 50.0
-	const OFF = 10.0
-	scale := func(x float64) float64 { return MUL*x + OFF }
+	doc := svgDocument{
+		Xmlns:   svgNamespace,
+		Width:   maxX - minX,
+		Height:  maxY - minY,
+		ViewBox: fmt.Sprintf("%f %f %f %f", minX, minY, maxX-minX, maxY-minY),
+		Title:   title,
+		Desc:    c.Description,
+		Elems:   svgElem.Polygons,
+	}
 
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	xmlEncoder := xml.NewEncoder(writer)
+	if err := xmlEncoder.Encode(doc); err != nil {
+		return err
+	}
+
+	return xmlEncoder.Flush()
+}
+
+// svgElement creates the svg element which contains U-Matrix unit polygons and their labels or class numbers
+func (u *umatrixMap) svgElement(classes map[int]int, c *SVGConfig) (svgElement, error) {
+	codebook, coords, dims, uShape := u.codebook, u.coords, u.dims, u.uShape
+
+	umatrix, minDistance, maxDistance, err := u.unitValues()
+	if err != nil {
+		return svgElement{}, err
+	}
+
+	rows, _ := codebook.Dims()
 	svgElem := svgElement{
-		Width:    float64(dims[1])*MUL + 2*OFF,
-		Height:   float64(dims[0])*MUL + 2*OFF,
+		Width:    float64(dims[1])*unitSize + 2*gridOffset,
+		Height:   float64(dims[0])*unitSize + 2*gridOffset,
 		Polygons: make([]interface{}, rows*2),
 	}
+	fontSize := c.FontSize
+	if fontSize <= 0 {
+		fontSize = defaultFontSize
+	}
+	placer := &labelPlacer{fontSize: fontSize}
 	for row := 0; row < rows; row++ {
+		if u.isHidden(row) {
+			continue
+		}
 		coord := coords.RowView(row)
-		var colorMask []int
 		classID, classFound := classes[row]
-		// if no class information, just use shades of gray
-		if !classFound || classID == -1 {
-			colorMask = []int{255, 255, 255}
-		} else {
-			colorMask = colors[classes[row]%len(colors)]
-		}
-		colorMul := 1.0 - (umatrix[row]-minDistance)/(maxDistance-minDistance)
-		r := int(colorMul * float64(colorMask[0]))
-		g := int(colorMul * float64(colorMask[1]))
-		b := int(colorMul * float64(colorMask[2]))
-		polygonCoords := ""
+		r, g, b := unitColor(umatrix[row], minDistance, maxDistance, classID, classFound)
 		x := scale(coord.At(0, 0))
 		y := scale(coord.At(1, 0))
-		// hexagon has a different yOffset
-		switch uShape {
-		case "hexagon":
-			{
-				xOffset := 0.5 * MUL
-				yBigOffset := math.Tan(math.Pi/6.0) * MUL
-				ySmallOffset := yBigOffset / 2.0
-				// draw a hexagon around the current coord
-				polygonCoords += fmt.Sprintf("%f,%f ", x+xOffset, y+ySmallOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x, y+yBigOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x-xOffset, y+ySmallOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x-xOffset, y-ySmallOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x, y-yBigOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x+xOffset, y-ySmallOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x+xOffset, y+ySmallOffset)
-			}
-		default:
-			{
-				xOffset := 0.5 * MUL
-				yOffset := 0.5 * MUL
-				// draw a box around the current coord
-				polygonCoords += fmt.Sprintf("%f,%f ", x+xOffset, y+yOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x+xOffset, y-yOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x-xOffset, y-yOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x-xOffset, y+yOffset)
-				polygonCoords += fmt.Sprintf("%f,%f ", x+xOffset, y+yOffset)
-			}
+		polygonCoords := ""
+		for _, p := range unitPolygon(uShape, x, y) {
+			polygonCoords += fmt.Sprintf("%f,%f ", p[0], p[1])
 		}
 
 		svgElem.Polygons[row*2] = polygon{
@@ -152,32 +335,319 @@ func UMatrixSVG(codebook *mat.Dense, dims []int, uShape, title string, writer io
 			Style:  fmt.Sprintf("fill:rgb(%d,%d,%d);stroke:black;stroke-width:1", r, g, b),
 		}
 
+		// print unit label if it fits the map
+		if label, ok := c.Labels[row]; ok {
+			if lx, ly, size, ok := placer.place(label, x, y); ok {
+				svgElem.Polygons[row*2+1] = labelElement{
+					X:          lx,
+					Y:          ly,
+					FontSize:   size,
+					TextAnchor: "middle",
+					Text:       label,
+				}
+			}
+			continue
+		}
+
 		// print class number
 		if classFound {
 			svgElem.Polygons[row*2+1] = textElement{
-				X:    x - 0.25*MUL,
-				Y:    y + 0.25*MUL,
+				X:    x - 0.25*unitSize,
+				Y:    y + 0.25*unitSize,
 				Text: fmt.Sprintf("%d", classes[row]),
 			}
 		}
 	}
+	svgElem.Polygons = append(svgElem.Polygons, u.pathElements()...)
 
-	elems = append(elems, svgElem)
+	return svgElem, nil
+}
 
-	if err := xmlEncoder.Encode(elems); err != nil {
+// pathElements returns svg elements which draw the path over the map: a line connecting
+// the path unit centres with a hollow circle marking its start and a filled circle marking its end
+func (u *umatrixMap) pathElements() []interface{} {
+	if len(u.path) == 0 {
+		return nil
+	}
+	points := ""
+	for _, unit := range u.path {
+		points += fmt.Sprintf("%f,%f ", scale(u.coords.At(unit, 0)), scale(u.coords.At(unit, 1)))
+	}
+	first, last := u.path[0], u.path[len(u.path)-1]
+	return []interface{}{
+		polyline{
+			Points: []byte(points),
+			Style:  "fill:none;stroke:black;stroke-width:3",
+		},
+		circle{
+			Cx:    scale(u.coords.At(first, 0)),
+			Cy:    scale(u.coords.At(first, 1)),
+			R:     0.15 * unitSize,
+			Style: "fill:white;stroke:black;stroke-width:3",
+		},
+		circle{
+			Cx:    scale(u.coords.At(last, 0)),
+			Cy:    scale(u.coords.At(last, 1)),
+			R:     0.15 * unitSize,
+			Style: "fill:black;stroke:black;stroke-width:3",
+		},
+	}
+}
+
+// UMatrixHTML creates a standalone HTML document which contains the SVG representation
+// of the U-Matrix of the given codebook. See UMatrixSVG for the description of parameters.
+func UMatrixHTML(codebook *mat.Dense, dims []int, uShape, title string, writer io.Writer, classes map[int]int) error {
+	u, err := latticeMap(codebook, dims, uShape)
+	if err != nil {
 		return err
 	}
-	xmlEncoder.Flush()
 
-	return nil
+	return u.html(title, writer, classes)
+}
+
+// html creates a standalone HTML document with the U-Matrix
+func (u *umatrixMap) html(title string, writer io.Writer, classes map[int]int) error {
+	if _, err := fmt.Fprintf(writer, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n",
+		html.EscapeString(title)); err != nil {
+		return err
+	}
+	if err := u.svg(html.EscapeString(title), writer, classes); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(writer, "\n</body>\n</html>\n")
+	return err
+}
+
+// UMatrixImage creates a raster image of the U-Matrix of the given codebook.
+// The image has the same layout and colors as the SVG representation created by UMatrixSVG
+// except for the class numbers which are not printed. See UMatrixSVG for the description of parameters.
+func UMatrixImage(codebook *mat.Dense, dims []int, uShape string, classes map[int]int) (image.Image, error) {
+	u, err := latticeMap(codebook, dims, uShape)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.image(classes)
+}
+
+// image creates a raster image of the U-Matrix
+func (u *umatrixMap) image(classes map[int]int) (image.Image, error) {
+	codebook, coords, dims, uShape := u.codebook, u.coords, u.dims, u.uShape
+	umatrix, minDistance, maxDistance, err := u.unitValues()
+	if err != nil {
+		return nil, err
+	}
+
+	width := int(float64(dims[1])*unitSize + 2*gridOffset)
+	height := int(float64(dims[0])*unitSize + 2*gridOffset)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	rows, _ := codebook.Dims()
+	for row := 0; row < rows; row++ {
+		if u.isHidden(row) {
+			continue
+		}
+		classID, classFound := classes[row]
+		r, g, b := unitColor(umatrix[row], minDistance, maxDistance, classID, classFound)
+		fill := color.RGBA{uint8(r), uint8(g), uint8(b), 255}
+		poly := unitPolygon(uShape, scale(coords.At(row, 0)), scale(coords.At(row, 1)))
+		// fill all pixels whose centre lies inside the unit polygon
+		minX, minY, maxX, maxY := polygonBounds(poly)
+		for py := int(math.Max(0, math.Floor(minY))); py < height && float64(py) <= maxY; py++ {
+			for px := int(math.Max(0, math.Floor(minX))); px < width && float64(px) <= maxX; px++ {
+				if inPolygon(float64(px)+0.5, float64(py)+0.5, poly) {
+					img.SetRGBA(px, py, fill)
+				}
+			}
+		}
+	}
+
+	return img, nil
+}
+
+const (
+	// unitSize is the size of unit polygon
+	unitSize = 50.0
+	// gridOffset is the offset of the grid from image borders
+	gridOffset = 10.0
+)
+
+const (
+	// defaultFontSize is the default font size of unit labels
+	defaultFontSize = 12.0
+	// charWidth is the estimated width of label characters relative to the font size
+	charWidth = 0.6
+)
+
+// labelBox is the bounding box of a label
+type labelBox struct {
+	x0, y0, x1, y1 float64
+}
+
+// overlaps returns true if the label boxes b and o overlap
+func (b labelBox) overlaps(o labelBox) bool {
+	return b.x0 < o.x1 && o.x0 < b.x1 && b.y0 < o.y1 && o.y0 < b.y1
+}
+
+// labelPlacer places unit labels so that they do not overlap each other
+type labelPlacer struct {
+	// fontSize is the preferred label font size
+	fontSize float64
+	// placed holds bounding boxes of the placed labels
+	placed []labelBox
+}
+
+// place finds a position of text label of unit centred in x, y which does not overlap any placed label.
+// It tries the unit centre, upper and lower part of the unit with font sizes shrinking down to half of
+// the preferred font size. It returns the position of the label baseline centre and the label font size.
+// If the label does not fit anywhere, place returns false.
+func (p *labelPlacer) place(text string, x, y float64) (float64, float64, float64, bool) {
+	chars := float64(utf8.RuneCountInString(text))
+	for size := p.fontSize; size >= p.fontSize/2; size *= 0.8 {
+		width := chars * charWidth * size
+		for _, dy := range []float64{0.0, -0.25 * unitSize, 0.25 * unitSize} {
+			// baseline is shifted so the label is vertically centred
+			ly := y + dy + size/2
+			box := labelBox{x0: x - width/2, y0: ly - size, x1: x + width/2, y1: ly}
+			if !p.collides(box) {
+				p.placed = append(p.placed, box)
+				return x, ly, size, true
+			}
+		}
+	}
+	return 0.0, 0.0, 0.0, false
+}
+
+// collides returns true if box overlaps any placed label
+func (p *labelPlacer) collides(box labelBox) bool {
+	for _, b := range p.placed {
+		if box.overlaps(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// scale scales the coord grid to something visible
+func scale(x float64) float64 { return unitSize*x + gridOffset }
+
+// uMatrix computes u-matrix values of the codebook of grid with given coords along with their min and max values.
+// U-matrix value of a unit is the average codebook distance to its lattice neighbours as reported by adj.
+// Codebook vector distances are computed using the given measure.
+func uMatrix(ms measure, codebook, coords *mat.Dense, adj Adjacency) ([]float64, float64, float64, error) {
+	rows, _ := codebook.Dims()
+	if coordsRows, _ := coords.Dims(); coordsRows != rows {
+		return nil, 0, 0, fmt.Errorf("Grid and codebook dimension mismatch")
+	}
+	// distances are computed row by row
+	dist := make([]float64, rows)
+
+	umatrix := make([]float64, rows)
+	maxDistance := -math.MaxFloat64
+	minDistance := math.MaxFloat64
+	for row := 0; row < rows; row++ {
+		// no need to check for errors here: row is always a valid row
+		ms.row(row, codebook, dist)
+		avgDistance, neighbs := 0.0, 0
+		for i := 0; i < rows; i++ {
+			if adj(row, i) {
+				avgDistance += dist[i]
+				neighbs++
+			}
+		}
+		// units without any neighbours have zero U-matrix value
+		if neighbs > 0 {
+			avgDistance /= float64(neighbs)
+		}
+		umatrix[row] = avgDistance
+		if avgDistance > maxDistance {
+			maxDistance = avgDistance
+		}
+		if avgDistance < maxDistance {
+			minDistance = avgDistance
+		}
+	}
+
+	return umatrix, minDistance, maxDistance, nil
+}
+
+// unitColor returns the color of the unit with u-matrix value val
+func unitColor(val, min, max float64, classID int, classFound bool) (int, int, int) {
+	var colorMask []int
+	// if no class information, just use shades of gray
+	if !classFound || classID == -1 {
+		colorMask = []int{255, 255, 255}
+	} else {
+		colorMask = colors[classID%len(colors)]
+	}
+	colorMul := 1.0 - (val-min)/(max-min)
+	r := int(colorMul * float64(colorMask[0]))
+	g := int(colorMul * float64(colorMask[1]))
+	b := int(colorMul * float64(colorMask[2]))
+	return r, g, b
+}
+
+// displayShape returns the unit shape used to draw a grid of given dimensions.
+// 1D chains are drawn as a strip of rectangles regardless of their unit shape.
+func displayShape(uShape string, dims []int) string {
+	if isChain(dims) {
+		return "rectangle"
+	}
+	return uShape
+}
+
+// unitPolygon returns closed polygon outline of the unit of a given shape centred at x, y
+func unitPolygon(uShape string, x, y float64) [][2]float64 {
+	// hexagon has a different yOffset
+	switch uShape {
+	case "hexagon":
+		xOffset := 0.5 * unitSize
+		yBigOffset := math.Tan(math.Pi/6.0) * unitSize
+		ySmallOffset := yBigOffset / 2.0
+		// draw a hexagon around the current coord
+		return [][2]float64{
+			{x + xOffset, y + ySmallOffset},
+			{x, y + yBigOffset},
+			{x - xOffset, y + ySmallOffset},
+			{x - xOffset, y - ySmallOffset},
+			{x, y - yBigOffset},
+			{x + xOffset, y - ySmallOffset},
+			{x + xOffset, y + ySmallOffset},
+		}
+	default:
+		xOffset := 0.5 * unitSize
+		yOffset := 0.5 * unitSize
+		// draw a box around the current coord
+		return [][2]float64{
+			{x + xOffset, y + yOffset},
+			{x + xOffset, y - yOffset},
+			{x - xOffset, y - yOffset},
+			{x - xOffset, y + yOffset},
+			{x + xOffset, y + yOffset},
+		}
+	}
+}
+
+// polygonBounds returns the bounding box of polygon
+func polygonBounds(poly [][2]float64) (float64, float64, float64, float64) {
+	minX, minY := math.MaxFloat64, math.MaxFloat64
+	maxX, maxY := -math.MaxFloat64, -math.MaxFloat64
+	for _, p := range poly {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	return minX, minY, maxX, maxY
 }
 
-func allRowsInRadius(selectedRow int, radius float64, distMatrix *mat.Dense) []rowWithDist {
-	rowsInRadius := []rowWithDist{}
-	for i, dist := range distMatrix.RawRowView(selectedRow) {
-		if dist < radius {
-			rowsInRadius = append(rowsInRadius, rowWithDist{Row: i, Dist: dist})
+// inPolygon checks if the point x, y lies inside closed polygon using ray casting
+func inPolygon(x, y float64, poly [][2]float64) bool {
+	in := false
+	for i, j := 0, len(poly)-1; i < len(poly); j, i = i, i+1 {
+		a, b := poly[i], poly[j]
+		if (a[1] > y) != (b[1] > y) && x < (b[0]-a[0])*(y-a[1])/(b[1]-a[1])+a[0] {
+			in = !in
 		}
 	}
-	return rowsInRadius
+	return in
 }
@@ -2,6 +2,10 @@ package som
 
 import (
 	"bytes"
+	"encoding/xml"
+	"image/color"
+	"image/png"
+	"io"
 	"strings"
 	"testing"
 
@@ -61,3 +65,306 @@ func TestUMatrixSVGWithClusters(t *testing.T) {
 	// make sure there is at least one text element
 	assert.True(strings.Contains(svg, "<text "))
 }
+
+func TestUMatrixHTML(t *testing.T) {
+	assert := assert.New(t)
+
+	mUnits := mat.NewDense(2, 2, []float64{
+		0.0, 0.0,
+		1.0, 1.0,
+	})
+	writer := bytes.NewBufferString("")
+	err := UMatrixHTML(mUnits, []int{2, 1}, "rectangle", "<Done>", writer, make(map[int]int))
+	assert.NoError(err)
+	out := writer.String()
+	assert.True(strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.True(strings.Contains(out, "<title>&lt;Done&gt;</title>"))
+	assert.True(strings.Contains(out, "<svg "))
+	assert.True(strings.HasSuffix(out, "</html>\n"))
+}
+
+func TestUMatrixImage(t *testing.T) {
+	assert := assert.New(t)
+
+	mUnits := mat.NewDense(4, 2, []float64{
+		0.0, 0.0,
+		0.0, 0.1,
+		1.0, 1.0,
+		1.0, 1.1,
+	})
+	for _, uShape := range []string{"rectangle", "hexagon"} {
+		img, err := UMatrixImage(mUnits, []int{2, 2}, uShape, make(map[int]int))
+		assert.NoError(err)
+		assert.Equal(120, img.Bounds().Dx())
+		assert.Equal(120, img.Bounds().Dy())
+	}
+	// unit centres are filled with unit colors
+	img, err := UMatrixImage(mUnits, []int{2, 2}, "rectangle", make(map[int]int))
+	assert.NoError(err)
+	coords, err := GridCoords("rectangle", []int{2, 2})
+	assert.NoError(err)
+	adj := latticeAdjacency(coords, []int{2, 2}, "rectangle")
+	umatrix, min, max, err := uMatrix(measure{metric: Euclidean}, mUnits, coords, adj)
+	assert.NoError(err)
+	for unit, centre := range [][2]int{{10, 10}, {10, 60}, {60, 10}, {60, 60}} {
+		r, g, b := unitColor(umatrix[unit], min, max, 0, false)
+		assert.Equal(color.RGBA{uint8(r), uint8(g), uint8(b), 255}, img.At(centre[0], centre[1]))
+	}
+	// 1D chains are drawn as a strip
+	img, err = UMatrixImage(mUnits, []int{1, 4}, "hexagon", make(map[int]int))
+	assert.NoError(err)
+	assert.Equal(220, img.Bounds().Dx())
+	assert.Equal(70, img.Bounds().Dy())
+	coords, err = GridCoords("hexagon", []int{1, 4})
+	assert.NoError(err)
+	adj = latticeAdjacency(coords, []int{1, 4}, "hexagon")
+	umatrix, min, max, err = uMatrix(measure{metric: Euclidean}, mUnits, coords, adj)
+	assert.NoError(err)
+	for unit := 0; unit < 4; unit++ {
+		r, g, b := unitColor(umatrix[unit], min, max, 0, false)
+		assert.Equal(color.RGBA{uint8(r), uint8(g), uint8(b), 255}, img.At(10+unit*50, 10))
+	}
+	// unsupported unit shape
+	img, err = UMatrixImage(mUnits, []int{2, 2}, "foo", make(map[int]int))
+	assert.Nil(img)
+	assert.Error(err)
+}
+
+func TestUMatrixNeighbours(t *testing.T) {
+	assert := assert.New(t)
+
+	// codebook vectors store unit indices
+	cbook := mat.NewDense(9, 1, []float64{0, 1, 2, 3, 4, 5, 6, 7, 8})
+	testCases := []struct {
+		uShape   string
+		expected float64
+	}{
+		// all the other units are neighbours of the centre rectangle unit
+		{"rectangle", 20.0 / 8.0},
+		// centre hexagon unit has 6 neighbours: 1, 3, 5, 6, 7, 8
+		{"hexagon", 14.0 / 6.0},
+	}
+
+	for _, tc := range testCases {
+		coords, err := GridCoords(tc.uShape, []int{3, 3})
+		assert.NoError(err)
+		adj := latticeAdjacency(coords, []int{3, 3}, tc.uShape)
+		umatrix, _, _, err := uMatrix(measure{metric: Euclidean}, cbook, coords, adj)
+		assert.NoError(err)
+		assert.InDelta(tc.expected, umatrix[4], 1e-9)
+	}
+}
+
+func TestUMatrixSVGWith(t *testing.T) {
+	assert := assert.New(t)
+
+	mUnits := mat.NewDense(4, 2, []float64{
+		0.0, 0.0,
+		0.0, 0.1,
+		1.0, 1.0,
+		1.0, 1.1,
+	})
+	// nil config creates svg fragment
+	fragment := bytes.NewBufferString("")
+	err := UMatrixSVG(mUnits, []int{2, 2}, "hexagon", "Done", fragment, make(map[int]int))
+	assert.NoError(err)
+	writer := bytes.NewBufferString("")
+	err = UMatrixSVGWith(mUnits, []int{2, 2}, "hexagon", "Done", writer, make(map[int]int), nil)
+	assert.NoError(err)
+	assert.Equal(fragment.String(), writer.String())
+	// standalone document
+	writer.Reset()
+	c := &SVGConfig{Standalone: true, Description: "a < b"}
+	err = UMatrixSVGWith(mUnits, []int{2, 2}, "hexagon", "<Done>", writer, map[int]int{0: 1}, c)
+	assert.NoError(err)
+	out := writer.String()
+	assert.True(strings.HasPrefix(out, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<svg xmlns="http://www.w3.org/2000/svg" width="127" height="103.03629710818451"`))
+	// view box covers the hexagons which stick out of the grid offset
+	assert.Contains(out, `viewBox="-16.000000 -19.867513 127.000000 103.036297"`)
+	assert.Contains(out, "<title>&lt;Done&gt;</title><desc>a &lt; b</desc>")
+	assert.Contains(out, "<text ")
+	assert.NotContains(out, "<h1>")
+	assert.True(strings.HasSuffix(out, "</svg>"))
+	// the document is well formed
+	dec := xml.NewDecoder(strings.NewReader(out))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(err)
+		if err != nil {
+			break
+		}
+	}
+}
+
+func TestUMatrixSVGLabels(t *testing.T) {
+	assert := assert.New(t)
+
+	mUnits := mat.NewDense(4, 2, []float64{
+		0.0, 0.0,
+		0.0, 0.1,
+		1.0, 1.0,
+		1.0, 1.1,
+	})
+	c := &SVGConfig{
+		Labels: map[int]string{
+			0: "a&b",
+			1: "a very long product name",
+			2: "another long product name",
+		},
+		FontSize: 10,
+	}
+	writer := bytes.NewBufferString("")
+	err := UMatrixSVGWith(mUnits, []int{2, 2}, "rectangle", "Done", writer, map[int]int{0: 7, 3: 8}, c)
+	assert.NoError(err)
+	out := writer.String()
+	// labels are escaped and replace class numbers
+	assert.Contains(out, `<text x="10" y="15" font-size="10" text-anchor="middle">a&amp;b</text>`)
+	assert.NotContains(out, ">7</text>")
+	// units without labels keep class numbers
+	assert.Contains(out, ">8</text>")
+	// long label which overlaps the label of unit 0 is moved to the upper part of its unit
+	assert.Contains(out, `<text x="10" y="65" font-size="10" text-anchor="middle">a very long product name</text>`)
+	assert.Contains(out, `<text x="60" y="2.5" font-size="10" text-anchor="middle">another long product name</text>`)
+}
+
+func TestLabelPlacer(t *testing.T) {
+	assert := assert.New(t)
+
+	p := &labelPlacer{fontSize: 10}
+	x, y, size, ok := p.place("abc", 0.0, 0.0)
+	assert.True(ok)
+	assert.Equal([]float64{0.0, 5.0, 10.0}, []float64{x, y, size})
+	// the same position is taken so the label moves to the upper part of the unit
+	x, y, size, ok = p.place("abc", 0.0, 0.0)
+	assert.True(ok)
+	assert.Equal([]float64{0.0, -7.5, 10.0}, []float64{x, y, size})
+	x, y, size, ok = p.place("abc", 0.0, 0.0)
+	assert.True(ok)
+	assert.Equal([]float64{0.0, 17.5, 10.0}, []float64{x, y, size})
+	// neighbour unit label is moved below the placed labels
+	_, y, size, ok = p.place("abc", 0.0, 11.0)
+	assert.True(ok)
+	assert.Equal([]float64{28.5, 10.0}, []float64{y, size})
+	// label which does not fit anywhere is left out
+	_, _, _, ok = p.place("abc", 0.0, 0.0)
+	assert.False(ok)
+	// distant labels do not collide
+	_, y, _, ok = p.place("abc", 100.0, 0.0)
+	assert.True(ok)
+	assert.Equal(5.0, y)
+	// smaller font fits between the placed labels
+	p = &labelPlacer{
+		fontSize: 10,
+		placed:   []labelBox{{-100, -5, 100, -4.5}, {-100, -8, 100, -7.6}, {-100, 7.6, 100, 8}},
+	}
+	_, y, size, ok = p.place("abc", 0.0, 0.0)
+	assert.True(ok)
+	assert.Equal([]float64{4.0, 8.0}, []float64{y, size})
+}
+
+func TestQuantErrorMap(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(&MapConfig{
+		Grid: &GridConfig{Size: []int{2, 2}, Type: "planar", UShape: "rectangle"},
+		Cb:   &CbConfig{Dim: 2, InitFunc: RandInit},
+	}, mat.NewDense(1, 2, []float64{0.0, 0.0}))
+	assert.NoError(err)
+	m.codebook = mat.NewDense(4, 2, []float64{
+		0.0, 0.0,
+		0.0, 1.0,
+		1.0, 0.0,
+		1.0, 1.0,
+	})
+	// the second unit represents its data worst, the last unit has no data
+	data := mat.NewDense(4, 2, []float64{
+		0.0, 0.1,
+		0.0, 0.5,
+		0.1, 1.0,
+		1.0, 0.2,
+	})
+	qErrs, err := m.UnitQuantErrors(data)
+	assert.NoError(err)
+	assert.InDeltaSlice([]float64{0.3, 0.1, 0.2, 0.0}, qErrs, 1e-12)
+
+	img := new(bytes.Buffer)
+	err = m.QuantErrorMap(img, data, NewClassStats(), "png", "QE")
+	assert.NoError(err)
+	decoded, err := png.Decode(img)
+	assert.NoError(err)
+	for unit, centre := range [][2]int{{10, 10}, {10, 60}, {60, 10}, {60, 60}} {
+		r, g, b := unitColor(qErrs[unit], 0.0, 0.3, 0, false)
+		assert.Equal(color.RGBA{uint8(r), uint8(g), uint8(b), 255}, decoded.At(centre[0], centre[1]))
+	}
+	for _, format := range []string{"svg", "html"} {
+		buf := new(bytes.Buffer)
+		assert.NoError(m.QuantErrorMap(buf, data, NewClassStats(), format, "QE"))
+		assert.Contains(buf.String(), "QE")
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(m.QuantErrorMapSVG(buf, data, NewClassStats(), "QE", &SVGConfig{Standalone: true}))
+	assert.Contains(buf.String(), "<title>QE</title>")
+	// unsupported format
+	assert.Error(m.QuantErrorMap(buf, data, NewClassStats(), "foo", "QE"))
+	// invalid data
+	assert.Error(m.QuantErrorMap(buf, nil, NewClassStats(), "svg", "QE"))
+}
+
+func TestHitMap(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(&MapConfig{
+		Grid: &GridConfig{Size: []int{2, 2}, Type: "planar", UShape: "rectangle"},
+		Cb:   &CbConfig{Dim: 2, InitFunc: RandInit},
+	}, mat.NewDense(1, 2, []float64{0.0, 0.0}))
+	assert.NoError(err)
+	m.codebook = mat.NewDense(4, 2, []float64{
+		0.0, 0.0,
+		0.0, 1.0,
+		1.0, 0.0,
+		1.0, 1.0,
+	})
+	// the first unit has two hits of class 1, the third unit has a hit of class 2
+	data := mat.NewDense(3, 2, []float64{
+		0.0, 0.1,
+		0.1, 0.0,
+		1.0, 0.1,
+	})
+	classes := map[int]int{0: 1, 1: 1, 2: 2}
+
+	img := new(bytes.Buffer)
+	assert.NoError(m.HitMap(img, data, NewClassStats(), "png", "Hits"))
+	decoded, err := png.Decode(img)
+	assert.NoError(err)
+	for unit, centre := range [][2]int{{10, 10}, {10, 60}, {60, 10}, {60, 60}} {
+		r, g, b := unitColor([]float64{2, 0, 1, 0}[unit], 0.0, 2.0, 0, false)
+		assert.Equal(color.RGBA{uint8(r), uint8(g), uint8(b), 255}, decoded.At(centre[0], centre[1]))
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(m.HitMapSVG(buf, data, NewClassStats(), "Hits", &SVGConfig{Standalone: true}))
+	assert.Contains(buf.String(), "<title>Hits</title>")
+	assert.Error(m.HitMap(buf, data, NewClassStats(), "foo", "Hits"))
+	assert.Error(m.HitMap(buf, nil, NewClassStats(), "svg", "Hits"))
+
+	// u-matrix of class 2 only draws the third unit in full u-matrix colors
+	classData, err := ClassRows(data, classes, 2)
+	assert.NoError(err)
+	img.Reset()
+	assert.NoError(m.UMatrixHits(img, classData, NewClassStats(), "png", "Class 2"))
+	decoded, err = png.Decode(img)
+	assert.NoError(err)
+	umatrix, min, max, err := m.umatrixMap().unitValues()
+	assert.NoError(err)
+	r, g, b := unitColor(umatrix[2], min, max, 0, false)
+	assert.Equal(color.RGBA{uint8(r), uint8(g), uint8(b), 255}, decoded.At(60, 10))
+	for _, centre := range [][2]int{{10, 10}, {10, 60}, {60, 60}} {
+		assert.Equal(color.RGBA{255, 255, 255, 255}, decoded.At(centre[0], centre[1]))
+	}
+	buf.Reset()
+	assert.NoError(m.UMatrixHitsSVG(buf, classData, NewClassStats(), "Class 2", nil))
+	assert.Equal(1, strings.Count(buf.String(), "<polygon"))
+	assert.Error(m.UMatrixHits(buf, nil, NewClassStats(), "svg", "Class 2"))
+}
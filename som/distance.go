@@ -1,7 +1,6 @@
 package som
 
 import (
-	"container/heap"
 	"fmt"
 	"math"
 
@@ -14,24 +13,69 @@ type Metric int
 const (
 	// Euclidean metric
 	Euclidean Metric = iota
+	// Manhattan metric sums absolute coordinate differences (L1 distance)
+	Manhattan
+	// Chebyshev metric is the largest absolute coordinate difference (L∞ distance)
+	Chebyshev
+	// Mahalanobis metric is euclidean distance of vectors decorrelated and scaled by the inverse of
+	// covariance matrix of data features, so correlated features don't dominate BMU selection.
+	// Maps estimate the covariance from their training data unless TrainConfig.Covariance is set;
+	// the functions which get no covariance, such as Distance or ClosestVec, compute euclidean distance.
+	Mahalanobis
+	// Tanimoto metric is one minus the ratio of the dot product of vectors to the sum of their squared
+	// norms less the dot product. On binary vectors, such as fingerprints or one-hot encodings, it is
+	// Jaccard distance: the fraction of features set in either vector which are not set in both.
+	Tanimoto
 )
 
+// metricNames maps metrics to their names
+var metricNames = map[Metric]string{
+	Euclidean:   "euclidean",
+	Manhattan:   "manhattan",
+	Chebyshev:   "chebyshev",
+	Mahalanobis: "mahalanobis",
+	Tanimoto:    "tanimoto",
+}
+
+// String returns metric name
+func (m Metric) String() string {
+	if name, ok := metricNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Metric(%d)", int(m))
+}
+
+// ParseMetric returns metric of a given name.
+// It returns error if the metric name is unknown.
+func ParseMetric(name string) (Metric, error) {
+	for m, n := range metricNames {
+		if n == name {
+			return m, nil
+		}
+	}
+	return Euclidean, fmt.Errorf("unsupported metric: %s", name)
+}
+
 // Distance calculates given metric distance between vectors a and b and returns it.
+// Missing values, i.e. NaNs, are skipped: the distance only counts the dimensions present in both vectors.
 // If unsupported metric is requested it returns default distance which is Euclidean distance.
 // It returns error if the supplied vectors are either nil or have different dimensions
 func Distance(m Metric, a, b []float64) (float64, error) {
-	if a == nil || b == nil {
-		return 0.0, fmt.Errorf("invalid vectors supplied. a: %v, b: %v", a, b)
-	}
-	if len(a) != len(b) {
-		return 0.0, fmt.Errorf("incorrect vector dims. a: %d, b: %d", len(a), len(b))
-	}
+	return measure{metric: m}.distance(a, b)
+}
 
+// metricFunc returns the function which computes distances of a given metric.
+// Unknown metrics default to euclidean distance.
+func metricFunc(m Metric) func(a, b []float64) float64 {
 	switch m {
-	case Euclidean:
-		return euclideanVec(a, b), nil
+	case Manhattan:
+		return manhattanVec
+	case Chebyshev:
+		return chebyshevVec
+	case Tanimoto:
+		return tanimotoVec
 	default:
-		return euclideanVec(a, b), nil
+		return euclideanVec
 	}
 }
 
@@ -45,18 +89,30 @@ func DistanceMx(m Metric, mat *mat.Dense) (*mat.Dense, error) {
 		return nil, fmt.Errorf("invalid matrix supplied: %v", mat)
 	}
 
-	switch m {
-	case Euclidean:
-		return euclideanMx(mat), nil
-	default:
-		return euclideanMx(mat), nil
-	}
+	return distanceMx(mat, metricFunc(m)), nil
+}
+
+// DistanceRow calculates given metric distances between the vector stored in the i-th row of matrix mat
+// and all mat rows. It stores the distances in dst and returns it. If dst is nil, a new slice is allocated.
+// DistanceRow computes the i-th row of the distance matrix returned by DistanceMx without materializing
+// the whole matrix, so the distance matrices of big maps and data sets can be processed row by row.
+// If an unknown metric is supplied Euclidean distance is computed.
+// It returns error if the supplied matrix is nil, if i is out of range of mat rows or
+// if the length of dst is different from the number of mat rows.
+func DistanceRow(m Metric, i int, mat *mat.Dense, dst []float64) ([]float64, error) {
+	return measure{metric: m}.row(i, mat, dst)
 }
 
 // ClosestVec finds the index of the closest vector to v in the list of vectors
 // stored as rows in matrix m using the supplied distance metric.
 // If unsupported metric is requested, ClosestVec falls over to euclidean metric.
 // If several vectors of the same distance are found, it returns the index of the first one from the top.
+// Missing values of v, i.e. NaNs, are skipped in the same way as by Distance.
+// The search uses partial distance elimination: a vector is abandoned as soon as the running sum of its
+// squared coordinate differences, or the running sum or maximum of its absolute coordinate differences
+// in Manhattan and Chebyshev metrics, reaches the distance of the closest vector found so far, which
+// speeds up the search on high-dimensional data without changing its result. Tanimoto distances
+// don't grow with the coordinates summed, so they are computed in full.
 // ClosestVec returns error if either v or m are nil or if the v dimension is different from
 // the number of m columns. When the ClosestVec fails with error returned index is set to -1.
 func ClosestVec(m Metric, v []float64, mat *mat.Dense) (int, error) {
@@ -68,69 +124,147 @@ func ClosestVec(m Metric, v []float64, mat *mat.Dense) (int, error) {
 		return -1, fmt.Errorf("invalid matrix: %v", mat)
 	}
 
+	rows, cols := mat.Dims()
+	if rows > 0 && cols != len(v) {
+		return -1, fmt.Errorf("incorrect vector dims. a: %d, b: %d", len(v), cols)
+	}
+
+	switch m {
+	case Manhattan:
+		return closestBound(v, mat, manhattanBound), nil
+	case Chebyshev:
+		return closestBound(v, mat, chebyshevBound), nil
+	case Tanimoto:
+		return closestBound(v, mat, tanimotoBound), nil
+	default:
+		return closestEuclidean(v, mat), nil
+	}
+}
+
+// pruneBlock is the number of coordinates summed between the partial distance checks of closestEuclidean
+const pruneBlock = 8
+
+// closestEuclidean returns the index of the closest row of mat to v in euclidean distance.
+// The distances of candidate rows are compared in the same way as euclideanVec distances, so the
+// elimination of rows whose partial squared distance reaches the best one does not change the result.
+func closestEuclidean(v []float64, mat *mat.Dense) int {
+	// only vectors with missing values take the slower path
+	masked := hasMissing(v)
 	rows, _ := mat.Dims()
 	closest := 0
-	dist := math.MaxFloat64
+	dist, sqDist := math.MaxFloat64, math.Inf(1)
 	for i := 0; i < rows; i++ {
-		d, err := Distance(m, v, mat.RawRowView(i))
-		if err != nil {
-			return -1, err
+		var d float64
+		if masked {
+			d = sqEuclideanMasked(v, mat.RawRowView(i), sqDist)
+		} else {
+			d = sqEuclideanBound(v, mat.RawRowView(i), sqDist)
 		}
-		if d < dist {
-			dist = d
-			closest = i
+		// the running sum never decreases, so eliminated rows are not closer than the closest one
+		if d >= sqDist {
+			continue
+		}
+		if rd := math.Sqrt(d); rd < dist {
+			closest, dist, sqDist = i, rd, d
 		}
 	}
 
-	return closest, nil
+	return closest
 }
 
-// ClosestNVec finds the N closest vectors to v in the list of vectors stored in m rows
-// using the supplied distance metric. It returns a slice which contains indices to the m
-// rows. The length of the slice is the same as number of requested closest vectors - n.
-// ClosestNVec fails in the same way as ClosestVec. If n is higher than the number of
-// rows in m, or if it is not a positive integer, it fails with error too.
-func ClosestNVec(m Metric, n int, v []float64, mat *mat.Dense) ([]int, error) {
-	if len(v) == 0 {
-		return nil, fmt.Errorf("invalid vector: %v", v)
+// sqEuclideanBound computes squared euclidean distance between vectors a and b in the same order as
+// euclideanVec does. The running sum is checked against bound once per block of pruneBlock coordinates
+// and the partial sum is returned as soon as it reaches the bound.
+func sqEuclideanBound(a, b []float64, bound float64) float64 {
+	d := 0.0
+	if len(a) <= pruneBlock {
+		for i, x := range a {
+			d += (x - b[i]) * (x - b[i])
+		}
+		return d
+	}
+	for j := 0; j < len(a) && d < bound; j += pruneBlock {
+		block := a[j:]
+		if len(block) > pruneBlock {
+			block = block[:pruneBlock]
+		}
+		rest := b[j:]
+		rest = rest[:len(block)]
+		for i, x := range block {
+			d += (x - rest[i]) * (x - rest[i])
+		}
 	}
 
-	if mat == nil {
-		return nil, fmt.Errorf("invalid matrix: %v", mat)
+	return d
+}
+
+// sqEuclideanMasked computes squared euclidean distance between vectors a and b in the same order as
+// euclideanVec does, skipping missing values. The partial sum is returned as soon as it reaches bound.
+func sqEuclideanMasked(a, b []float64, bound float64) float64 {
+	d := 0.0
+	for i := 0; i < len(a) && d < bound; i++ {
+		if diff := a[i] - b[i]; diff == diff {
+			d += diff * diff
+		}
 	}
 
+	return d
+}
+
+// closestBound returns the index of the closest row of mat to v in the distance computed by dist.
+// dist returns the partial distance as soon as it reaches bound; the partial distances never decrease,
+// so the eliminated rows are not closer than the closest one.
+func closestBound(v []float64, mat *mat.Dense, dist func(a, b []float64, bound float64) float64) int {
 	rows, _ := mat.Dims()
-	if n <= 0 || n > rows {
-		return nil, fmt.Errorf("invalid number of closest vectors requested: %d", n)
+	closest, best := 0, math.Inf(1)
+	for i := 0; i < rows; i++ {
+		if d := dist(v, mat.RawRowView(i), best); d < best {
+			closest, best = i, d
+		}
 	}
 
-	closest := make([]int, n)
+	return closest
+}
 
-	switch {
-	case n == 1:
-		idx, err := ClosestVec(m, v, mat)
-		if err != nil {
-			return nil, err
-		}
-		closest[0] = idx
-	default:
-		h, _ := newFloat64Heap(n)
-		rows, _ := mat.Dims()
-		for i := 0; i < rows; i++ {
-			d, err := Distance(m, v, mat.RawRowView(i))
-			if err != nil {
-				return nil, err
-			}
-			f := &float64Item{val: d, index: i}
-			heap.Push(h, f)
+// manhattanBound computes manhattan distance between vectors a and b in the same order as manhattanVec
+// does. The partial sum is returned as soon as it reaches bound.
+func manhattanBound(a, b []float64, bound float64) float64 {
+	d := 0.0
+	for i := 0; i < len(a) && d < bound; i++ {
+		if diff := a[i] - b[i]; diff == diff {
+			d += math.Abs(diff)
 		}
+	}
 
-		for j := 0; j < n; j++ {
-			closest[j] = (heap.Pop(h).(*float64Item)).index
+	return d
+}
+
+// chebyshevBound computes chebyshev distance between vectors a and b.
+// The partial maximum is returned as soon as it reaches bound.
+func chebyshevBound(a, b []float64, bound float64) float64 {
+	d := 0.0
+	for i := 0; i < len(a) && d < bound; i++ {
+		if diff := math.Abs(a[i] - b[i]); diff > d {
+			d = diff
 		}
 	}
 
-	return closest, nil
+	return d
+}
+
+// tanimotoBound computes tanimoto distance between vectors a and b. Partial tanimoto distances may
+// both grow and shrink, so the bound is ignored and the full distance is returned.
+func tanimotoBound(a, b []float64, bound float64) float64 {
+	return tanimotoVec(a, b)
+}
+
+// ClosestNVec finds the N closest vectors to v in the list of vectors stored in m rows
+// using the supplied distance metric. It returns a slice which contains indices to the m
+// rows. The length of the slice is the same as number of requested closest vectors - n.
+// ClosestNVec fails in the same way as ClosestVec. If n is higher than the number of
+// rows in m, or if it is not a positive integer, it fails with error too.
+func ClosestNVec(m Metric, n int, v []float64, mat *mat.Dense) ([]int, error) {
+	return measure{metric: m}.closestN(n, v, mat)
 }
 
 // BMUs returns a slice which contains indices of the Best Match Unit (BMU) codebook vectors for each
@@ -138,6 +272,30 @@ func ClosestNVec(m Metric, n int, v []float64, mat *mat.Dense) ([]int, error) {
 // a particular data sample. If some data row has more than one BMU the index of the first one found is used.
 // It returns error if either the data or codebook are nil or if their dimensions are mismatched.
 func BMUs(data, codebook *mat.Dense) ([]int, error) {
+	return bmus(measure{metric: Euclidean}, data, codebook)
+}
+
+// validateFeatureWeights returns error if there isn't a weight for each of dim features, if any weight
+// is negative or non-finite or if all weights are zero
+func validateFeatureWeights(weights []float64, dim int) error {
+	if len(weights) != dim {
+		return fmt.Errorf("feature weights count mismatch: %d != %d", len(weights), dim)
+	}
+	positive := false
+	for j, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("invalid weight of feature %d: %f", j, w)
+		}
+		positive = positive || w > 0
+	}
+	if !positive {
+		return fmt.Errorf("all feature weights are zero")
+	}
+	return nil
+}
+
+// bmus returns indices of BMU codebook vectors for each vector stored in data rows using a given measure
+func bmus(ms measure, data, codebook *mat.Dense) ([]int, error) {
 	if data == nil {
 		return nil, fmt.Errorf("invalid data supplied: %v", data)
 	}
@@ -149,7 +307,7 @@ func BMUs(data, codebook *mat.Dense) ([]int, error) {
 	rows, _ := data.Dims()
 	bmus := make([]int, rows)
 	for i := 0; i < rows; i++ {
-		idx, err := ClosestVec(Euclidean, data.RawRowView(i), codebook)
+		idx, err := ms.closest(data.RawRowView(i), codebook)
 		if err != nil {
 			return nil, err
 		}
@@ -160,29 +318,147 @@ func BMUs(data, codebook *mat.Dense) ([]int, error) {
 }
 
 // euclideanVec computes euclidean distance between vectors a and b.
+// The dimensions in which either vector has a missing value are skipped.
 func euclideanVec(a, b []float64) float64 {
 	d := 0.0
 	for i := 0; i < len(a); i++ {
-		d += (a[i] - b[i]) * (a[i] - b[i])
+		// NaN is the only value which differs from itself
+		if diff := a[i] - b[i]; diff == diff {
+			d += diff * diff
+		}
+	}
+
+	return math.Sqrt(d)
+}
+
+// manhattanVec computes manhattan distance between vectors a and b.
+// The dimensions in which either vector has a missing value are skipped.
+func manhattanVec(a, b []float64) float64 {
+	d := 0.0
+	for i := 0; i < len(a); i++ {
+		if diff := a[i] - b[i]; diff == diff {
+			d += math.Abs(diff)
+		}
+	}
+
+	return d
+}
+
+// chebyshevVec computes chebyshev distance between vectors a and b.
+// The dimensions in which either vector has a missing value are skipped.
+func chebyshevVec(a, b []float64) float64 {
+	d := 0.0
+	for i := 0; i < len(a); i++ {
+		// NaN differences fail the comparison
+		if diff := math.Abs(a[i] - b[i]); diff > d {
+			d = diff
+		}
+	}
+
+	return d
+}
+
+// tanimotoVec computes tanimoto distance between vectors a and b.
+// The distance of two zero vectors is zero.
+// The dimensions in which either vector has a missing value are skipped.
+func tanimotoVec(a, b []float64) float64 {
+	return tanimoto(a, b, nil)
+}
+
+// tanimoto computes tanimoto distance between vectors a and b whose coordinate products are scaled by
+// weights unless weights are nil. The dimensions in which either vector has a missing value are skipped.
+func tanimoto(a, b, weights []float64) float64 {
+	ab, aa, bb := 0.0, 0.0, 0.0
+	for i := 0; i < len(a); i++ {
+		x, y := a[i], b[i]
+		if diff := x - y; diff != diff {
+			continue
+		}
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		ab += w * x * y
+		aa += w * x * x
+		bb += w * y * y
+	}
+	den := aa + bb - ab
+	if den == 0 {
+		return 0.0
+	}
+
+	return 1 - ab/den
+}
+
+// closestWeighted returns the index of the closest row of mat to v in a given metric whose coordinate
+// differences are scaled by weights: euclidean distance scales the squared differences, tanimoto distance
+// the coordinate products and the other metrics scale the absolute differences. Rows are abandoned as soon as their partial weighted distance
+// reaches the closest one; the ties and missing values are handled in the same way as by ClosestVec.
+func closestWeighted(m Metric, v []float64, mat *mat.Dense, weights []float64) int {
+	rows, _ := mat.Dims()
+	closest, best := 0, math.Inf(1)
+	for i := 0; i < rows; i++ {
+		if d := weightedBound(m, v, mat.RawRowView(i), weights, best); d < best {
+			closest, best = i, d
+		}
+	}
+
+	return closest
+}
+
+// weightedDistance computes the weighted distance of closestWeighted between vectors a and b
+func weightedDistance(m Metric, a, b, weights []float64) float64 {
+	d := weightedBound(m, a, b, weights, math.Inf(1))
+	switch m {
+	case Manhattan, Chebyshev, Tanimoto:
+		return d
 	}
 
 	return math.Sqrt(d)
 }
 
-// euclideanMx computes a matrix of euclidean distances between each row in m
-func euclideanMx(m *mat.Dense) *mat.Dense {
+// weightedBound computes the weighted distance of closestWeighted between vectors a and b; euclidean
+// distance is left squared. The partial distance is returned as soon as it reaches bound.
+func weightedBound(m Metric, a, b, weights []float64, bound float64) float64 {
+	d := 0.0
+	switch m {
+	case Manhattan:
+		for j := 0; j < len(a) && d < bound; j++ {
+			if diff := a[j] - b[j]; diff == diff {
+				d += weights[j] * math.Abs(diff)
+			}
+		}
+	case Chebyshev:
+		for j := 0; j < len(a) && d < bound; j++ {
+			if diff := weights[j] * math.Abs(a[j]-b[j]); diff > d {
+				d = diff
+			}
+		}
+	case Tanimoto:
+		d = tanimoto(a, b, weights)
+	default:
+		for j := 0; j < len(a) && d < bound; j++ {
+			if diff := a[j] - b[j]; diff == diff {
+				d += weights[j] * diff * diff
+			}
+		}
+	}
+
+	return d
+}
+
+// distanceMx computes a matrix of distances between each row in m computed by dist
+func distanceMx(m *mat.Dense, dist func(a, b []float64) float64) *mat.Dense {
 	rows, _ := m.Dims()
 	out := mat.NewDense(rows, rows, nil)
 
 	for row := 0; row < rows-1; row++ {
-		dist := 0.0
 		a := m.RawRowView(row)
 		for i := row + 1; i < rows; i++ {
 			if i != row {
-				b := m.RawRowView(i)
-				dist = euclideanVec(a, b)
-				out.Set(row, i, dist)
-				out.Set(i, row, dist)
+				d := dist(a, m.RawRowView(i))
+				out.Set(row, i, d)
+				out.Set(i, row, d)
 			}
 		}
 	}
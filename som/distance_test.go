@@ -2,6 +2,8 @@ package som
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
 	"sort"
 	"testing"
 
@@ -22,6 +24,19 @@ func TestDistance(t *testing.T) {
 		{metric, []float64{0.0, 0.0}, []float64{0.0, 1.0}, 1.0},
 		{metric, []float64{0.0, 0.0}, []float64{0.0, 0.0}, 0.0},
 		{metric, []float64{3.0, 1.0}, []float64{1.0, 3.0}, 2.828},
+		// missing values are skipped
+		{metric, []float64{math.NaN(), 1.0}, []float64{5.0, 3.0}, 2.0},
+		{metric, []float64{4.0, 1.0}, []float64{1.0, math.NaN()}, 3.0},
+		{Manhattan, []float64{3.0, 1.0}, []float64{1.0, 4.0}, 5.0},
+		{Manhattan, []float64{math.NaN(), 1.0}, []float64{5.0, 3.0}, 2.0},
+		{Chebyshev, []float64{3.0, 1.0}, []float64{1.0, 4.0}, 3.0},
+		{Chebyshev, []float64{4.0, math.NaN()}, []float64{1.0, 9.0}, 3.0},
+		// binary vectors share 2 of 4 set features
+		{Tanimoto, []float64{1.0, 1.0, 0.0, 1.0}, []float64{1.0, 0.0, 1.0, 1.0}, 0.5},
+		{Tanimoto, []float64{1.0, 0.0}, []float64{1.0, 0.0}, 0.0},
+		{Tanimoto, []float64{1.0, 0.0}, []float64{0.0, 1.0}, 1.0},
+		{Tanimoto, []float64{0.0, 0.0}, []float64{0.0, 0.0}, 0.0},
+		{Tanimoto, []float64{1.0, math.NaN(), 1.0}, []float64{1.0, 1.0, 0.0}, 0.5},
 	}
 
 	for _, tc := range testCases {
@@ -48,6 +63,57 @@ func TestDistance(t *testing.T) {
 	assert.Equal(0.0, d)
 }
 
+func TestMetric(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("euclidean", Euclidean.String())
+	assert.Equal("Metric(1000)", Metric(1000).String())
+	for _, metric := range []Metric{Euclidean, Manhattan, Chebyshev, Tanimoto} {
+		m, err := ParseMetric(metric.String())
+		assert.NoError(err)
+		assert.Equal(metric, m)
+	}
+	_, err := ParseMetric("foobar")
+	assert.Error(err)
+}
+
+func TestDistanceRow(t *testing.T) {
+	assert := assert.New(t)
+
+	m := mat.NewDense(3, 2, []float64{
+		0.0, 0.0,
+		3.0, 4.0,
+		1.0, 0.0,
+	})
+	// rows match the rows of distance matrix
+	dst := make([]float64, 3)
+	for _, metric := range []Metric{Euclidean, Manhattan, Chebyshev, Tanimoto} {
+		distMx, err := DistanceMx(metric, m)
+		assert.NoError(err)
+		for i := 0; i < 3; i++ {
+			row, err := DistanceRow(metric, i, m, dst)
+			assert.NoError(err)
+			assert.InDeltaSlice(distMx.RawRowView(i), row, 1e-9)
+		}
+	}
+	// nil dst is allocated
+	row, err := DistanceRow(Euclidean, 1, m, nil)
+	assert.NoError(err)
+	assert.InDeltaSlice([]float64{5.0, 0.0, math.Sqrt(20)}, row, 1e-9)
+	// nil matrix
+	row, err = DistanceRow(Euclidean, 0, nil, nil)
+	assert.Nil(row)
+	assert.Error(err)
+	// row out of range
+	row, err = DistanceRow(Euclidean, 3, m, nil)
+	assert.Nil(row)
+	assert.Error(err)
+	// incorrect dst length
+	row, err = DistanceRow(Euclidean, 0, m, make([]float64, 2))
+	assert.Nil(row)
+	assert.Error(err)
+}
+
 func TestDistanceMx(t *testing.T) {
 	assert := assert.New(t)
 
@@ -106,6 +172,16 @@ func TestDistanceMx(t *testing.T) {
 	assert.NoError(err)
 	assert.True(mat.EqualApprox(negativeOutExpected, negativeOut, 0.01))
 
+	manhattanOut, err := DistanceMx(Manhattan, mat.NewDense(2, 2, []float64{0.0, 0.0, 3.0, -4.0}))
+
+	assert.NoError(err)
+	assert.Equal([]float64{0.0, 7.0, 7.0, 0.0}, manhattanOut.RawMatrix().Data)
+
+	chebyshevOut, err := DistanceMx(Chebyshev, mat.NewDense(2, 2, []float64{0.0, 0.0, 3.0, -4.0}))
+
+	assert.NoError(err)
+	assert.Equal([]float64{0.0, 4.0, 4.0, 0.0}, chebyshevOut.RawMatrix().Data)
+
 	nilMatrix, err := DistanceMx(metric, nil)
 
 	assert.Error(err)
@@ -235,3 +311,86 @@ func TestBMUs(t *testing.T) {
 	assert.NotNil(bmus)
 	assert.Equal(rows, len(bmus))
 }
+
+func TestWeightedBMUs(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(2, 2, []float64{1, 3.5, math.NaN(), 1})
+	codebook := mat.NewDense(2, 2, []float64{0, 0, 4, 4})
+	idx, err := bmus(measure{metric: Euclidean, weights: []float64{1, 1}}, data, codebook)
+	assert.NoError(err)
+	assert.Equal([]int{1, 0}, idx)
+	// the first feature outweighs the second one
+	idx, err = bmus(measure{metric: Euclidean, weights: []float64{10, 1}}, data, codebook)
+	assert.NoError(err)
+	assert.Equal([]int{0, 0}, idx)
+	// zero weight leaves the feature out
+	idx, err = bmus(measure{metric: Manhattan, weights: []float64{0, 1}}, data, codebook)
+	assert.NoError(err)
+	assert.Equal([]int{1, 0}, idx)
+	// invalid input
+	_, err = bmus(measure{metric: Euclidean, weights: []float64{1, 1}}, nil, codebook)
+	assert.Error(err)
+	_, err = bmus(measure{metric: Euclidean, weights: []float64{1, 1}}, data, nil)
+	assert.Error(err)
+	_, err = bmus(measure{metric: Euclidean, weights: []float64{1, 1, 1}}, data, mat.NewDense(2, 3, nil))
+	assert.Error(err)
+}
+
+func TestClosestVecPruning(t *testing.T) {
+	assert := assert.New(t)
+
+	rnd := rand.New(rand.NewSource(1))
+	for _, dim := range []int{1, 3, 64} {
+		codebook := mat.NewDense(50, dim, nil)
+		for i := 0; i < 50; i++ {
+			for j := 0; j < dim; j++ {
+				codebook.Set(i, j, rnd.NormFloat64())
+			}
+		}
+		// duplicate rows tie with the first one
+		codebook.SetRow(30, codebook.RawRowView(10))
+		for n := 0; n < 100; n++ {
+			v := make([]float64, dim)
+			for j := range v {
+				v[j] = rnd.NormFloat64()
+			}
+			if n%10 == 0 {
+				copy(v, codebook.RawRowView(30))
+			}
+			// missing values are skipped by both searches
+			if n%10 == 5 {
+				v[dim/2] = math.NaN()
+			}
+			for _, metric := range []Metric{Euclidean, Manhattan, Chebyshev, Tanimoto} {
+				// exhaustive search picks the first closest row
+				exp, dist := 0, math.MaxFloat64
+				for i := 0; i < 50; i++ {
+					if d, _ := Distance(metric, v, codebook.RawRowView(i)); d < dist {
+						exp, dist = i, d
+					}
+				}
+				closest, err := ClosestVec(metric, v, codebook)
+				assert.NoError(err)
+				assert.Equal(exp, closest, "dim %d, metric %s", dim, metric)
+			}
+		}
+	}
+	// rows with non-finite distances are never closest
+	codebook := mat.NewDense(3, 2, []float64{math.NaN(), 0, math.Inf(1), 0, 5, 5})
+	closest, err := ClosestVec(Euclidean, []float64{0, 0}, codebook)
+	assert.NoError(err)
+	assert.Equal(2, closest)
+	// missing dimensions are skipped
+	codebook = mat.NewDense(2, 2, []float64{0, 5, 9, 1.5})
+	closest, err = ClosestVec(Euclidean, []float64{math.NaN(), 1}, codebook)
+	assert.NoError(err)
+	assert.Equal(1, closest)
+	// metrics disagree on the closest row
+	codebook = mat.NewDense(2, 2, []float64{2, 2, 3, 0})
+	for metric, exp := range map[Metric]int{Euclidean: 0, Manhattan: 1, Chebyshev: 0} {
+		closest, err = ClosestVec(metric, []float64{0.1, 0.1}, codebook)
+		assert.NoError(err)
+		assert.Equal(exp, closest, "metric %s", metric)
+	}
+}
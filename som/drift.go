@@ -0,0 +1,181 @@
+package som
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DriftConfig holds concept drift detection configuration
+type DriftConfig struct {
+	// Window is the number of most recent samples rolling statistics are computed from
+	Window int
+	// QERatio is the maximum allowed ratio of rolling to baseline quantization error
+	QERatio float64
+	// HitShift is the maximum allowed total variation distance between baseline
+	// and rolling BMU hit distributions; it must be in (0.0, 1.0]
+	HitShift float64
+	// OnDrift is called whenever a drift is detected; it can be nil
+	OnDrift func(*DriftEvent)
+}
+
+// DriftEvent describes detected concept drift
+type DriftEvent struct {
+	// Samples is the number of samples observed when the drift was detected
+	Samples int
+	// QuantError is the rolling quantization error
+	QuantError float64
+	// BaseQuantError is the baseline quantization error
+	BaseQuantError float64
+	// HitShift is the total variation distance between baseline and rolling BMU hit distributions
+	HitShift float64
+}
+
+// DriftMonitor monitors streamed samples for concept drift.
+// It tracks rolling quantization error and BMU hit distribution of the last Window samples
+// and compares them to the baseline statistics computed from reference data.
+type DriftMonitor struct {
+	m *Map
+	c *DriftConfig
+	// baseline statistics
+	baseQE   float64
+	baseHits []float64
+	// rolling window of BMUs and their distances
+	bmus  []int
+	dists []float64
+	// rolling statistics
+	hits    []float64
+	qeSum   float64
+	samples int
+}
+
+// NewDriftMonitor creates new drift monitor for map m and returns it.
+// The baseline statistics are computed from the reference data which is usually the training data.
+// It fails with error if the supplied configuration is invalid, data has no rows or if the baseline could not be computed.
+func NewDriftMonitor(m *Map, data *mat.Dense, c *DriftConfig) (*DriftMonitor, error) {
+	if m == nil {
+		return nil, fmt.Errorf("invalid map supplied: %v", m)
+	}
+	if err := validateDriftConfig(c); err != nil {
+		return nil, err
+	}
+	// baseline hit fractions are undefined without reference data
+	if data != nil {
+		if rows, _ := data.Dims(); rows == 0 {
+			return nil, fmt.Errorf("empty data supplied")
+		}
+	}
+	bmus, err := m.BMUs(data)
+	if err != nil {
+		return nil, err
+	}
+	baseQE, err := m.QuantError(data)
+	if err != nil {
+		return nil, err
+	}
+	units, _ := m.codebook.Dims()
+	baseHits := make([]float64, units)
+	for _, bmu := range bmus {
+		baseHits[bmu]++
+	}
+	for i := range baseHits {
+		baseHits[i] /= float64(len(bmus))
+	}
+
+	return &DriftMonitor{
+		m:        m,
+		c:        c,
+		baseQE:   baseQE,
+		baseHits: baseHits,
+		bmus:     make([]int, c.Window),
+		dists:    make([]float64, c.Window),
+		hits:     make([]float64, units),
+	}, nil
+}
+
+// Observe adds sample to the rolling window and checks the rolling statistics for drift.
+// Drift is only checked once the window has been filled. When drift is detected Observe
+// calls OnDrift callback and returns the drift event, otherwise it returns nil.
+// It fails with error if the sample dimension does not match the map codebook dimension.
+func (d *DriftMonitor) Observe(sample []float64) (*DriftEvent, error) {
+	bmu, err := d.m.measure().closest(sample, d.m.codebook)
+	if err != nil {
+		return nil, err
+	}
+	dist, err := d.m.measure().distance(sample, d.m.codebook.RawRowView(bmu))
+	if err != nil {
+		return nil, err
+	}
+	// evict the oldest sample from the window
+	idx := d.samples % d.c.Window
+	if d.samples >= d.c.Window {
+		d.hits[d.bmus[idx]]--
+		d.qeSum -= d.dists[idx]
+	}
+	d.bmus[idx], d.dists[idx] = bmu, dist
+	d.hits[bmu]++
+	d.qeSum += dist
+	d.samples++
+	// wait until the window is full
+	if d.samples < d.c.Window {
+		return nil, nil
+	}
+	event := &DriftEvent{
+		Samples:        d.samples,
+		QuantError:     d.qeSum / float64(d.c.Window),
+		BaseQuantError: d.baseQE,
+		HitShift:       d.hitShift(),
+	}
+	if event.QuantError <= d.c.QERatio*d.baseQE && event.HitShift <= d.c.HitShift {
+		return nil, nil
+	}
+	if d.c.OnDrift != nil {
+		d.c.OnDrift(event)
+	}
+
+	return event, nil
+}
+
+// Reset clears the rolling window and recomputes baseline statistics from data.
+// It is usually called after the map has been retrained on the drifted data.
+// It fails with error if the baseline statistics could not be computed.
+func (d *DriftMonitor) Reset(data *mat.Dense) error {
+	nd, err := NewDriftMonitor(d.m, data, d.c)
+	if err != nil {
+		return err
+	}
+	*d = *nd
+
+	return nil
+}
+
+// hitShift returns total variation distance between baseline and rolling BMU hit distributions
+func (d *DriftMonitor) hitShift() float64 {
+	shift := 0.0
+	for i := range d.hits {
+		shift += math.Abs(d.hits[i]/float64(d.c.Window) - d.baseHits[i])
+	}
+	return shift / 2.0
+}
+
+// validateDriftConfig validates drift detection configuration
+// It returns error if any of the config parameters are invalid
+func validateDriftConfig(c *DriftConfig) error {
+	if c == nil {
+		return fmt.Errorf("invalid drift config: %v", c)
+	}
+	// rolling window must contain at least one sample
+	if c.Window <= 0 {
+		return fmt.Errorf("invalid drift window: %d", c.Window)
+	}
+	// quantization error ratio must be positive number
+	if c.QERatio <= 0.0 {
+		return fmt.Errorf("invalid quantization error ratio: %f", c.QERatio)
+	}
+	// hit distribution shift is a distance between probability distributions
+	if c.HitShift <= 0.0 || c.HitShift > 1.0 {
+		return fmt.Errorf("invalid hit distribution shift: %f", c.HitShift)
+	}
+	return nil
+}
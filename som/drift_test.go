@@ -0,0 +1,88 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestNewDriftMonitor(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	c := &DriftConfig{Window: 2, QERatio: 2.0, HitShift: 0.5}
+	// nil map
+	d, err := NewDriftMonitor(nil, dataMx, c)
+	assert.Nil(d)
+	assert.Error(err)
+	// nil data
+	d, err = NewDriftMonitor(m, nil, c)
+	assert.Nil(d)
+	assert.Error(err)
+	// empty data
+	d, err = NewDriftMonitor(m, &mat.Dense{}, c)
+	assert.Nil(d)
+	assert.Error(err)
+	// invalid configs
+	testCases := []*DriftConfig{
+		nil,
+		{Window: 0, QERatio: 2.0, HitShift: 0.5},
+		{Window: 2, QERatio: 0.0, HitShift: 0.5},
+		{Window: 2, QERatio: 2.0, HitShift: 1.5},
+	}
+	for _, tc := range testCases {
+		d, err = NewDriftMonitor(m, dataMx, tc)
+		assert.Nil(d)
+		assert.Error(err)
+	}
+	// valid config
+	d, err = NewDriftMonitor(m, dataMx, c)
+	assert.NotNil(d)
+	assert.NoError(err)
+}
+
+func TestDriftMonitorObserve(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	var events []*DriftEvent
+	c := &DriftConfig{
+		Window:   2,
+		QERatio:  2.0,
+		HitShift: 1.0,
+		OnDrift:  func(e *DriftEvent) { events = append(events, e) },
+	}
+	d, err := NewDriftMonitor(m, dataMx, c)
+	assert.NoError(err)
+	// incorrect sample dimension
+	e, err := d.Observe([]float64{1.0})
+	assert.Nil(e)
+	assert.Error(err)
+	// samples from reference data do not drift
+	for i := 0; i < 2; i++ {
+		e, err = d.Observe(dataMx.RawRowView(i))
+		assert.Nil(e)
+		assert.NoError(err)
+	}
+	assert.Len(events, 0)
+	// distant samples cause drift once they fill the window
+	far := []float64{100.0, 100.0, 100.0, 100.0}
+	e, err = d.Observe(far)
+	assert.NoError(err)
+	assert.NotNil(e)
+	e, err = d.Observe(far)
+	assert.NoError(err)
+	assert.NotNil(e)
+	assert.Equal(4, e.Samples)
+	assert.True(e.QuantError > e.BaseQuantError)
+	assert.Len(events, 2)
+	// reset clears the window
+	err = d.Reset(dataMx)
+	assert.NoError(err)
+	e, err = d.Observe(far)
+	assert.Nil(e)
+	assert.NoError(err)
+}
@@ -0,0 +1,103 @@
+package som
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// emptyUpdates maps supported batch training updates of empty units
+var emptyUpdates = map[string]bool{
+	"":         true,
+	"keep":     true,
+	"decay":    true,
+	"reassign": true,
+}
+
+// updateEmpty updates codebook vectors of empty units which were not within radius of any BMU of data rows
+// in batch training configured by bc. The vectors of the other units must have been updated already.
+// Empty units keep their stale codebook vectors by default; decay and reassign updates move them
+// every iteration by decayEmpty and reassignEmpty respectively.
+func (m *Map) updateEmpty(bc *batchConfig, data *mat.Dense, empty []int) {
+	if len(empty) == 0 {
+		return
+	}
+	switch bc.tc.Empty {
+	case "decay":
+		m.decayEmpty(empty, bc.cm)
+	case "reassign":
+		m.reassignEmpty(bc, data, empty)
+	}
+}
+
+// decayEmpty moves codebook vectors of empty units halfway towards the mean of their adjacent non-empty units.
+// Units without any non-empty adjacent units keep their codebook vectors and fixed columns of column mask cm
+// keep their values.
+func (m *Map) decayEmpty(empty []int, cm *colMask) {
+	units, dim := m.codebook.Dims()
+	isEmpty := make([]bool, units)
+	for _, u := range empty {
+		isEmpty[u] = true
+	}
+	// empty units are only moved towards the non-empty ones, so the moves don't depend on their order
+	mean := make([]float64, dim)
+	for _, u := range empty {
+		for l := range mean {
+			mean[l] = 0.0
+		}
+		n := 0
+		for v := 0; v < units; v++ {
+			if isEmpty[v] || !m.grid.Adjacent(u, v) {
+				continue
+			}
+			for l, x := range m.codebook.RawRowView(v) {
+				mean[l] += x
+			}
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		vec := m.codebook.RawRowView(u)
+		for l := range vec {
+			if !cm.isFixed(l) {
+				vec[l] = 0.5 * (vec[l] + mean[l]/float64(n))
+			}
+		}
+	}
+}
+
+// reassignEmpty moves codebook vectors of empty units to the data rows which are the farthest from their BMUs
+// in the metric of batch training configured by bc. Rows with zero weight are never picked.
+// If there are fewer rows than empty units, the remaining units keep their codebook vectors.
+// The elements of missing row values and fixed columns keep their codebook values too.
+func (m *Map) reassignEmpty(bc *batchConfig, data *mat.Dense, empty []int) {
+	rows, _ := data.Dims()
+	idx := make([]int, 0, rows)
+	errs := make([]float64, rows)
+	for i := 0; i < rows; i++ {
+		if bc.weights != nil && bc.weights[i] == 0 {
+			continue
+		}
+		row := data.RawRowView(i)
+		bmu := bc.cm.closest(bc.ms, row, m.codebook)
+		// no need to check for errors:
+		// row and codebook are not nil and have the same dimension
+		errs[i], _ = bc.ms.distance(row, m.codebook.RawRowView(bmu))
+		idx = append(idx, i)
+	}
+	// rows with the same error are picked in their order
+	sort.SliceStable(idx, func(a, b int) bool { return errs[idx[a]] > errs[idx[b]] })
+	for i, u := range empty {
+		if i == len(idx) {
+			break
+		}
+		vec := m.codebook.RawRowView(u)
+		for l, x := range data.RawRowView(idx[i]) {
+			if !math.IsNaN(x) && !bc.cm.isFixed(l) {
+				vec[l] = x
+			}
+		}
+	}
+}
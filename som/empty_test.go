@@ -0,0 +1,77 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestBatchEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	// all units of 2x2 rectangle grid are adjacent to each other
+	codebook := []float64{
+		0.0, 0.0,
+		0.0, 1.0,
+		1.0, 0.0,
+		1.0, 1.0,
+	}
+	// all rows are mapped to unit 0 and the radius doesn't reach the other units
+	data := mat.NewDense(3, 2, []float64{
+		0.1, 0.1,
+		0.4, 0.2,
+		0.1, 0.0,
+	})
+	train := func(empty string, minNghb float64) (*Map, error) {
+		m, err := NewMap(&MapConfig{
+			Grid: &GridConfig{Size: []int{2, 2}, Type: "planar", UShape: "rectangle"},
+			Cb:   &CbConfig{Dim: 2, InitFunc: RandInit},
+		}, data)
+		if err != nil {
+			return nil, err
+		}
+		m.codebook = mat.NewDense(4, 2, append([]float64(nil), codebook...))
+		tc := &TrainConfig{
+			Algorithm: "batch",
+			Radius:    MinRadius,
+			RDecay:    "lin",
+			NeighbFn:  Bubble,
+			LDecay:    "lin",
+			Workers:   2,
+			Empty:     empty,
+			MinNghb:   minNghb,
+		}
+		return m, m.Train(tc, data, 2)
+	}
+
+	testCases := []struct {
+		empty   string
+		minNghb float64
+		exp     []float64
+		empties []int
+		sparse  int
+	}{
+		{"", 0, []float64{0.2, 0.1, 0.0, 1.0, 1.0, 0.0, 1.0, 1.0}, []int{3, 3}, 0},
+		{"keep", 0, []float64{0.2, 0.1, 0.0, 1.0, 1.0, 0.0, 1.0, 1.0}, []int{3, 3}, 0},
+		// empty units adjacent to unit 0 move halfway towards it every iteration
+		{"decay", 0, []float64{0.2, 0.1, 0.15, 0.325, 0.4, 0.075, 0.4, 0.325}, []int{3, 3}, 0},
+		// empty units move to the rows farthest from their BMUs which then map to their own units
+		{"reassign", 0, []float64{0.1, 0.1, 0.4, 0.2, 0.1, 0.0, 0.1, 0.1}, []int{3, 1}, 0},
+		// the codebook vector makes up the missing neighbourhood sum
+		{"", 4, []float64{0.1875, 0.09375, 0.0, 1.0, 1.0, 0.0, 1.0, 1.0}, []int{3, 3}, 1},
+	}
+	for _, tc := range testCases {
+		m, err := train(tc.empty, tc.minNghb)
+		assert.NoError(err)
+		assert.InDeltaSlice(tc.exp, m.codebook.RawMatrix().Data, 1e-9, tc.empty)
+		history := m.TrainHistory()
+		assert.Len(history, 2)
+		for i, step := range history {
+			assert.Equal(tc.empties[i], step.Empty, tc.empty)
+			assert.Equal(tc.sparse, step.Sparse)
+		}
+		assert.Equal(tc.empty, m.Metadata().Train.Empty)
+		assert.Equal(tc.minNghb, m.Metadata().Train.MinNghb)
+	}
+}
@@ -0,0 +1,115 @@
+package som
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Estimate is a quality measure estimated on a sample of data rows
+type Estimate struct {
+	// Value is the measure evaluated on the sampled rows
+	Value float64
+	// Lower is the lower bound of the bootstrap confidence interval
+	Lower float64
+	// Upper is the upper bound of the bootstrap confidence interval
+	Upper float64
+	// Samples is the number of sampled rows
+	Samples int
+}
+
+// SampledQuantError estimates the quantization error of data like QuantError does on a random sample
+// of data rows configured by c, which keeps the evaluation of maps trained on huge data sets tractable.
+// The confidence interval is given by the percentiles of the errors of bootstrap resamples of the sampled rows.
+// It fails with error if data is nil, the configuration is invalid or if the error could not be computed.
+func (m *Map) SampledQuantError(data *mat.Dense, c *EstimateConfig) (*Estimate, error) {
+	ms := m.measure()
+	return sampledEstimate(data, c, func(row []float64) (float64, error) {
+		bmu, err := ms.closest(row, m.codebook)
+		if err != nil {
+			return 0, err
+		}
+		return ms.distance(row, m.codebook.RawRowView(bmu))
+	})
+}
+
+// SampledTopoError estimates the topographic error of data like TopoError does on a random sample
+// of data rows configured by c. See SampledQuantError for the description of the confidence interval.
+// It fails with error if data is nil, the configuration is invalid or if the error could not be computed.
+func (m *Map) SampledTopoError(data *mat.Dense, c *EstimateConfig) (*Estimate, error) {
+	ms := m.measure()
+	return sampledEstimate(data, c, func(row []float64) (float64, error) {
+		closest, err := ms.closestN(2, row, m.codebook)
+		if err != nil {
+			return 0, err
+		}
+		if m.grid.Adjacent(closest[0], closest[1]) {
+			return 0, nil
+		}
+		return 1, nil
+	})
+}
+
+// sampledEstimate averages rowErr over data rows sampled as configured by c
+// and estimates the confidence interval of the average by bootstrap
+func sampledEstimate(data *mat.Dense, c *EstimateConfig, rowErr func(row []float64) (float64, error)) (*Estimate, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	if err := validateEstimateConfig(c); err != nil {
+		return nil, err
+	}
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(seed))
+
+	rows, _ := data.Dims()
+	samples := rows
+	if c.Samples > 0 && c.Samples < rows {
+		samples = c.Samples
+	}
+	if samples == 0 {
+		return nil, fmt.Errorf("no data rows to sample")
+	}
+	subset := r.Perm(rows)[:samples]
+	errs := make([]float64, samples)
+	for i, row := range subset {
+		e, err := rowErr(data.RawRowView(row))
+		if err != nil {
+			return nil, err
+		}
+		errs[i] = e
+	}
+
+	resamples := c.Resamples
+	if resamples == 0 {
+		resamples = DefaultResamples
+	}
+	level := c.Level
+	if level == 0 {
+		level = 0.95
+	}
+	means := make([]float64, resamples)
+	for i := range means {
+		sum := 0.0
+		for j := 0; j < samples; j++ {
+			sum += errs[r.Intn(samples)]
+		}
+		means[i] = sum / float64(samples)
+	}
+	sort.Float64s(means)
+	alpha := (1 - level) / 2
+
+	return &Estimate{
+		Value:   stat.Mean(errs, nil),
+		Lower:   stat.Quantile(alpha, stat.Empirical, means, nil),
+		Upper:   stat.Quantile(1-alpha, stat.Empirical, means, nil),
+		Samples: samples,
+	}, nil
+}
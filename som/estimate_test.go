@@ -0,0 +1,64 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampledEstimate(t *testing.T) {
+	assert := assert.New(t)
+
+	data := correlatedData(200)
+	mc := &MapConfig{
+		Grid: &GridConfig{Size: []int{4, 4}, Type: "planar", UShape: "hexagon"},
+		Cb:   &CbConfig{Dim: 3, InitFunc: RandInit},
+	}
+	m, err := NewMap(mc, data)
+	assert.NoError(err)
+	assert.NoError(m.Train(makeDefaultTrainConfig(), data, 200))
+
+	// sampling all rows evaluates the exact measures
+	qe, err := m.QuantError(data)
+	assert.NoError(err)
+	est, err := m.SampledQuantError(data, &EstimateConfig{Seed: 1})
+	assert.NoError(err)
+	assert.Equal(200, est.Samples)
+	assert.InDelta(qe, est.Value, 1e-9)
+	assert.True(est.Lower <= est.Value && est.Value <= est.Upper)
+	te, err := m.TopoError(data)
+	assert.NoError(err)
+	est, err = m.SampledTopoError(data, &EstimateConfig{Samples: 1000, Seed: 1})
+	assert.NoError(err)
+	assert.Equal(200, est.Samples)
+	assert.InDelta(te, est.Value, 1e-9)
+	assert.True(est.Lower <= est.Value && est.Value <= est.Upper)
+
+	// samples are reproducible and smaller samples have wider intervals
+	c := &EstimateConfig{Samples: 50, Resamples: 500, Level: 0.9, Seed: 7}
+	est, err = m.SampledQuantError(data, c)
+	assert.NoError(err)
+	assert.Equal(50, est.Samples)
+	again, err := m.SampledQuantError(data, c)
+	assert.NoError(err)
+	assert.Equal(est, again)
+	c.Samples = 10
+	small, err := m.SampledQuantError(data, c)
+	assert.NoError(err)
+	assert.True(small.Upper-small.Lower > est.Upper-est.Lower)
+	// wider confidence level widens the interval
+	c.Level = 0.99
+	wide, err := m.SampledQuantError(data, c)
+	assert.NoError(err)
+	assert.True(wide.Upper-wide.Lower >= small.Upper-small.Lower)
+
+	// invalid data and configurations
+	_, err = m.SampledQuantError(nil, &EstimateConfig{})
+	assert.Error(err)
+	_, err = m.SampledTopoError(data, nil)
+	assert.Error(err)
+	for _, c := range []*EstimateConfig{{Samples: -1}, {Resamples: -1}, {Level: -0.5}, {Level: 1}} {
+		_, err := m.SampledQuantError(data, c)
+		assert.Error(err)
+	}
+}
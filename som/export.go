@@ -0,0 +1,80 @@
+package som
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// csvCodebook encodes the map codebook in CSV format with a header row. Each following row holds
+// the unit index, its x and y grid coordinates and the unit codebook vector in columns w0..wn.
+func (m *Map) csvCodebook() ([]byte, error) {
+	_, dim := m.codebook.Dims()
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	record := make([]string, 3+dim)
+	record[0], record[1], record[2] = "unit", "x", "y"
+	for j := 0; j < dim; j++ {
+		record[3+j] = fmt.Sprintf("w%d", j)
+	}
+	if err := w.Write(record); err != nil {
+		return nil, err
+	}
+	coords := make([]float64, 2)
+	for i := 0; i < m.grid.Units(); i++ {
+		record[0] = strconv.Itoa(i)
+		for j, v := range m.grid.UnitCoords(i, coords) {
+			record[1+j] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		for j, v := range m.codebook.RawRowView(i) {
+			record[3+j] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// jsonUnit is a map unit encoded in JSON codebook
+type jsonUnit struct {
+	// Unit is unit index
+	Unit int `json:"unit"`
+	// Coords holds unit grid coordinates
+	Coords []float64 `json:"coords"`
+	// Vector is unit codebook vector
+	Vector []float64 `json:"vector"`
+}
+
+// jsonCodebook is the map codebook encoded in JSON format along with the map grid parameters
+type jsonCodebook struct {
+	// Size holds grid dimensions
+	Size []int `json:"size"`
+	// Type is grid type
+	Type string `json:"type"`
+	// UShape is unit shape
+	UShape string `json:"ushape"`
+	// Units holds map units
+	Units []jsonUnit `json:"units"`
+}
+
+// jsonCodebook encodes the map codebook in JSON format
+func (m *Map) jsonCodebook() ([]byte, error) {
+	cb := jsonCodebook{
+		Size:   m.grid.Size(),
+		Type:   m.grid.Type(),
+		UShape: m.grid.UShape(),
+		Units:  make([]jsonUnit, m.grid.Units()),
+	}
+	for i := range cb.Units {
+		cb.Units[i] = jsonUnit{
+			Unit:   i,
+			Coords: m.grid.UnitCoords(i, nil),
+			Vector: append([]float64(nil), m.codebook.RawRowView(i)...),
+		}
+	}
+	return json.Marshal(cb)
+}
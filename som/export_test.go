@@ -0,0 +1,59 @@
+package som
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestMarshalTable(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(&MapConfig{
+		Grid: &GridConfig{Size: []int{2, 2}, Type: "planar", UShape: "rectangle"},
+		Cb:   &CbConfig{Dim: 2, InitFunc: RandInit},
+	}, mat.NewDense(1, 2, []float64{0.0, 0.0}))
+	assert.NoError(err)
+	for u := 0; u < 4; u++ {
+		m.codebook.SetRow(u, []float64{float64(u), 0.5})
+	}
+
+	// csv
+	var buf bytes.Buffer
+	n, err := m.MarshalTo("csv", &buf)
+	assert.NoError(err)
+	assert.Equal(buf.Len(), n)
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(err)
+	assert.Equal([][]string{
+		{"unit", "x", "y", "w0", "w1"},
+		{"0", "0", "0", "0", "0.5"},
+		{"1", "0", "1", "1", "0.5"},
+		{"2", "1", "0", "2", "0.5"},
+		{"3", "1", "1", "3", "0.5"},
+	}, records)
+	// csv can't be unmarshaled
+	_, err = m.UnmarshalFrom("csv", bytes.NewBufferString(""))
+	assert.Error(err)
+
+	// json
+	buf.Reset()
+	n, err = m.MarshalTo("json", &buf)
+	assert.NoError(err)
+	assert.Equal(buf.Len(), n)
+	cb := new(jsonCodebook)
+	assert.NoError(json.Unmarshal(buf.Bytes(), cb))
+	assert.Equal([]int{2, 2}, cb.Size)
+	assert.Equal("planar", cb.Type)
+	assert.Equal("rectangle", cb.UShape)
+	assert.Len(cb.Units, 4)
+	for i, u := range cb.Units {
+		assert.Equal(i, u.Unit)
+		assert.Equal(m.grid.UnitCoords(i, nil), u.Coords)
+		assert.Equal([]float64{float64(i), 0.5}, u.Vector)
+	}
+}
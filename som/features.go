@@ -0,0 +1,122 @@
+package som
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// featureGeometry is a polygon feature geometry
+type featureGeometry struct {
+	// Type is geometry type: Polygon
+	Type string `json:"type"`
+	// Coordinates holds polygon rings; the first ring is the polygon outline
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+// featureProps holds map unit feature properties
+type featureProps struct {
+	// Unit is unit index
+	Unit int `json:"unit"`
+	// UMatrix is unit u-matrix value
+	UMatrix float64 `json:"umatrix"`
+	// Hits is the number of data rows mapped to the unit; it is omitted if no data is supplied
+	Hits *int `json:"hits,omitempty"`
+	// Class is the dominant class of the unit; it is omitted if the unit has no class
+	Class *int `json:"class,omitempty"`
+}
+
+// feature is map unit encoded as a polygon feature
+type feature struct {
+	// Type is feature type: Feature
+	Type string `json:"type"`
+	// Geometry is unit polygon
+	Geometry featureGeometry `json:"geometry"`
+	// Properties holds unit attributes
+	Properties featureProps `json:"properties"`
+}
+
+// featureCollection is a layer of map unit features
+type featureCollection struct {
+	// Type is collection type: FeatureCollection
+	Type string `json:"type"`
+	// Features holds map unit features
+	Features []feature `json:"features"`
+}
+
+// MarshalFeatures encodes map units as a GeoJSON-like FeatureCollection of polygons and writes it to w.
+// Each unit is a Polygon feature whose outline is the hexagon or rectangle drawn in u-matrix, in grid
+// coordinates as returned by Grid Coords with unit spacing of 1; outlines are counterclockwise closed rings.
+// Units of sphere grids are hexagons placed in the equirectangular projection of the sphere drawn by u-matrix.
+// Feature properties hold the unit index, its u-matrix value and optionally the number of data rows
+// mapped to the unit if data is not nil and the dominant class of the unit found in stats if stats is not nil.
+// It returns the number of bytes written to w or fails with error if u-matrix or hits could not be
+// computed or if the write to w fails.
+func (m *Map) MarshalFeatures(w io.Writer, data *mat.Dense, stats *ClassStats) (int, error) {
+	coords, dims, uShape := m.grid.display()
+	umatrix, _, _, err := uMatrix(m.measure(), m.codebook, coords, m.grid.Adjacent)
+	if err != nil {
+		return 0, err
+	}
+	var hits []int
+	if data != nil {
+		if hits, err = m.Hits(data); err != nil {
+			return 0, err
+		}
+	}
+	classes := make(map[int]int)
+	if stats != nil {
+		classes = stats.Dominant()
+	}
+
+	uShape = displayShape(uShape, dims)
+	rows, _ := m.codebook.Dims()
+	fc := featureCollection{
+		Type:     "FeatureCollection",
+		Features: make([]feature, rows),
+	}
+	for i := 0; i < rows; i++ {
+		props := featureProps{Unit: i, UMatrix: umatrix[i]}
+		if hits != nil {
+			props.Hits = &hits[i]
+		}
+		if class, ok := classes[i]; ok {
+			props.Class = &class
+		}
+		fc.Features[i] = feature{
+			Type: "Feature",
+			Geometry: featureGeometry{
+				Type:        "Polygon",
+				Coordinates: [][][2]float64{unitRing(uShape, coords.At(i, 0), coords.At(i, 1))},
+			},
+			Properties: props,
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(fc); err != nil {
+		return 0, err
+	}
+	return w.Write(buf.Bytes())
+}
+
+// unitRing returns counterclockwise closed outline of the unit of a given shape centred at grid coordinates x, y
+func unitRing(uShape string, x, y float64) [][2]float64 {
+	poly := unitPolygon(uShape, x*unitSize, y*unitSize)
+	area := 0.0
+	for i := range poly {
+		poly[i][0], poly[i][1] = poly[i][0]/unitSize, poly[i][1]/unitSize
+		if i > 0 {
+			area += poly[i-1][0]*poly[i][1] - poly[i][0]*poly[i-1][1]
+		}
+	}
+	// reverse clockwise outlines
+	if area < 0 {
+		for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+			poly[i], poly[j] = poly[j], poly[i]
+		}
+	}
+	return poly
+}
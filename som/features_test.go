@@ -0,0 +1,73 @@
+package som
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalFeatures(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	rows, _ := dataMx.Dims()
+	stats, err := m.ClassStats(dataMx, map[int]int{0: 1, 1: 1, 2: 2, 3: 2, 4: 2})
+	assert.NoError(err)
+
+	buf := new(bytes.Buffer)
+	n, err := m.MarshalFeatures(buf, dataMx, stats)
+	assert.NoError(err)
+	assert.Equal(buf.Len(), n)
+	fc := new(featureCollection)
+	assert.NoError(json.Unmarshal(buf.Bytes(), fc))
+	assert.Equal("FeatureCollection", fc.Type)
+	units := m.Grid().Units()
+	assert.Len(fc.Features, units)
+	umatrix, _, _, err := uMatrix(m.measure(), m.codebook, m.grid.coordinates(), m.grid.Adjacent)
+	assert.NoError(err)
+	dominant := stats.Dominant()
+	hits := 0
+	for i, f := range fc.Features {
+		assert.Equal("Feature", f.Type)
+		assert.Equal("Polygon", f.Geometry.Type)
+		assert.Equal(i, f.Properties.Unit)
+		assert.InDelta(umatrix[i], f.Properties.UMatrix, 1e-9)
+		// hexagon outlines are closed rings
+		ring := f.Geometry.Coordinates[0]
+		assert.Len(ring, 7)
+		assert.Equal(ring[0], ring[len(ring)-1])
+		hits += *f.Properties.Hits
+		if class, ok := dominant[i]; ok {
+			assert.Equal(class, *f.Properties.Class)
+		} else {
+			assert.Nil(f.Properties.Class)
+		}
+	}
+	assert.Equal(rows, hits)
+
+	// hits and classes are omitted without data and class statistics
+	buf.Reset()
+	_, err = m.MarshalFeatures(buf, nil, nil)
+	assert.NoError(err)
+	assert.NotContains(buf.String(), "hits")
+	assert.NotContains(buf.String(), "class")
+}
+
+func TestUnitRing(t *testing.T) {
+	assert := assert.New(t)
+
+	// rectangle outlines are unit squares turned counterclockwise
+	ring := unitRing("rectangle", 1, 2)
+	assert.Equal([][2]float64{{1.5, 2.5}, {0.5, 2.5}, {0.5, 1.5}, {1.5, 1.5}, {1.5, 2.5}}, ring)
+	for _, uShape := range []string{"rectangle", "hexagon"} {
+		ring := unitRing(uShape, 0, 0)
+		area := 0.0
+		for i := 1; i < len(ring); i++ {
+			area += ring[i-1][0]*ring[i][1] - ring[i][0]*ring[i-1][1]
+		}
+		assert.True(area > 0, uShape)
+	}
+}
@@ -0,0 +1,108 @@
+package som
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// NonFiniteError is returned by training with CheckFinite enabled when an infinite value or a row whose
+// values are all missing is found in training data or when a NaN or infinite value is found in a codebook
+// vector updated by a training iteration. Missing data values, i.e. NaNs, are skipped by training.
+// It identifies the iteration and unit instead of letting training produce corrupted map; the codebook
+// is checked after every training iteration, so the checks slow training down.
+type NonFiniteError struct {
+	// Iteration is the training iteration which produced the value; it is -1 for data values
+	Iteration int
+	// Unit is the index of the unit whose codebook vector holds the value; it is -1 for data values
+	Unit int
+	// Row is the index of the data row which holds the value; it is -1 for codebook values.
+	// Stream training rows are indexed in the order in which the samples are received.
+	Row int
+	// Radius is the neighbourhood radius of the iteration
+	Radius float64
+	// LRate is the learning rate of the iteration
+	LRate float64
+	// Hint suggests the configuration which likely produced the value
+	Hint string
+}
+
+// Error implements error interface
+func (e *NonFiniteError) Error() string {
+	if e.Row >= 0 {
+		return fmt.Sprintf("non-finite value in data row %d: %s", e.Row, e.Hint)
+	}
+	return fmt.Sprintf("non-finite codebook vector of unit %d in iteration %d (radius: %g, learning rate: %g): %s",
+		e.Unit, e.Iteration, e.Radius, e.LRate, e.Hint)
+}
+
+// isFinite returns true if none of the values is NaN or infinite
+func isFinite(vals []float64) bool {
+	for _, v := range vals {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkFiniteRow returns NonFiniteError if i-th data row holds infinite value or if all its values are missing
+func checkFiniteRow(i int, row []float64) error {
+	present, inf := false, false
+	for _, v := range row {
+		present = present || !math.IsNaN(v)
+		inf = inf || math.IsInf(v, 0)
+	}
+	if present && !inf {
+		return nil
+	}
+	return &NonFiniteError{
+		Iteration: -1,
+		Unit:      -1,
+		Row:       i,
+		Hint:      "remove infinite values and rows without any values before training",
+	}
+}
+
+// checkFiniteData returns NonFiniteError if any data row holds infinite value or only missing values
+func checkFiniteData(data *mat.Dense) error {
+	rows, _ := data.Dims()
+	for i := 0; i < rows; i++ {
+		if err := checkFiniteRow(i, data.RawRowView(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFiniteCodebook returns NonFiniteError if any codebook vector holds NaN or infinite value
+// after i-th training iteration with given learning rate and radius
+func checkFiniteCodebook(c *TrainConfig, codebook *mat.Dense, i int, lRate, radius float64) error {
+	units, _ := codebook.Dims()
+	for unit := 0; unit < units; unit++ {
+		if isFinite(codebook.RawRowView(unit)) {
+			continue
+		}
+		return &NonFiniteError{
+			Iteration: i,
+			Unit:      unit,
+			Row:       -1,
+			Radius:    radius,
+			LRate:     lRate,
+			Hint:      nonFiniteHint(c, lRate, radius),
+		}
+	}
+	return nil
+}
+
+// nonFiniteHint suggests the training configuration which likely produced non-finite codebook values
+func nonFiniteHint(c *TrainConfig, lRate, radius float64) string {
+	switch {
+	case math.IsNaN(radius) || math.IsInf(radius, 0) || radius <= 0.0:
+		return "radius is not a positive number; set positive Radius and train for more than one iteration"
+	case c.Algorithm == "seq" && (math.IsNaN(lRate) || lRate > 1.0):
+		return "learning rate diverges codebook vectors; set LRate in (0.0, 1.0]"
+	}
+	return "neighbourhood function returned non-finite value; check NeighbFn or scale the data"
+}
@@ -0,0 +1,91 @@
+package som
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestTrainCheckFinite(t *testing.T) {
+	assert := assert.New(t)
+
+	// infinite value in data
+	data := mat.DenseCopyOf(dataMx)
+	data.Set(3, 1, math.Inf(1))
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	c := *tSom
+	c.CheckFinite = true
+	err = m.Train(&c, data, 10)
+	var nfe *NonFiniteError
+	assert.True(errors.As(err, &nfe))
+	assert.Equal(3, nfe.Row)
+	assert.Equal(-1, nfe.Unit)
+	assert.Contains(err.Error(), "data row 3")
+	// missing values are skipped unless the whole row is missing
+	data = mat.DenseCopyOf(dataMx)
+	data.Set(3, 1, math.NaN())
+	assert.NoError(m.Train(&c, data, 10))
+	_, cols := data.Dims()
+	for j := 0; j < cols; j++ {
+		data.Set(2, j, math.NaN())
+	}
+	err = m.Train(&c, data, 10)
+	assert.True(errors.As(err, &nfe))
+	assert.Equal(2, nfe.Row)
+	// huge learning rate makes codebook vectors diverge
+	c.LRate = 1e300
+	err = m.Train(&c, dataMx, 100)
+	assert.True(errors.As(err, &nfe))
+	assert.True(nfe.Iteration >= 0)
+	assert.True(nfe.Unit >= 0)
+	assert.Equal(-1, nfe.Row)
+	assert.Contains(nfe.Hint, "LRate")
+	assert.Contains(err.Error(), "iteration")
+	// the same training silently produces corrupted map without the checks
+	m, err = NewMap(mSom, dataMx)
+	assert.NoError(err)
+	c.CheckFinite = false
+	assert.NoError(m.Train(&c, dataMx, 100))
+	assert.False(isFinite(m.codebook.RawMatrix().Data))
+	// valid training passes the checks
+	m, err = NewMap(mSom, dataMx)
+	assert.NoError(err)
+	c = *tSom
+	c.CheckFinite = true
+	assert.NoError(m.Train(&c, dataMx, 10))
+}
+
+func TestTrainStreamCheckFinite(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, alg := range []string{"seq", "batch"} {
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		c := *tSom
+		c.Algorithm = alg
+		c.CheckFinite = true
+		samples := make(chan []float64, 3)
+		samples <- dataMx.RawRowView(0)
+		samples <- []float64{1.0, math.Inf(1), 1.0, 1.0}
+		samples <- dataMx.RawRowView(1)
+		close(samples)
+		err = m.TrainStream(&c, samples, 1, 3)
+		var nfe *NonFiniteError
+		assert.True(errors.As(err, &nfe))
+		assert.Equal(1, nfe.Row)
+	}
+}
+
+func TestNonFiniteHint(t *testing.T) {
+	assert := assert.New(t)
+
+	c := *tSom
+	assert.Contains(nonFiniteHint(&c, 0.5, math.NaN()), "Radius")
+	assert.Contains(nonFiniteHint(&c, 0.5, 0.0), "Radius")
+	assert.Contains(nonFiniteHint(&c, 2.0, 1.0), "LRate")
+	assert.Contains(nonFiniteHint(&c, 0.5, 1.0), "NeighbFn")
+}
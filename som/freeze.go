@@ -0,0 +1,102 @@
+package som
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// FreezeConfig configures progressive locking of converged map units.
+// Codebook vectors of units which keep moving less than Threshold per epoch for Patience
+// consecutive epochs are frozen: they are no longer updated for the rest of the training,
+// which focuses late training on the regions of the map which are still moving.
+// Sequential training epoch is a pass over the data set, batch training epoch is a single iteration.
+// Frozen units remain BMU candidates, so the data mapped to them keeps shaping their neighbours.
+type FreezeConfig struct {
+	// Threshold is the euclidean distance a codebook vector must move in an epoch to stay unfrozen
+	Threshold float64
+	// Patience is the number of consecutive epochs a unit must stay below Threshold to be frozen
+	Patience int
+}
+
+// validateFreezeConfig validates unit freezing configuration; nil configuration is valid.
+// It returns error if the threshold is negative or the patience is not positive.
+func validateFreezeConfig(c *FreezeConfig) error {
+	if c == nil {
+		return nil
+	}
+	if c.Threshold < 0 || math.IsNaN(c.Threshold) {
+		return fmt.Errorf("invalid freeze threshold: %f", c.Threshold)
+	}
+	if c.Patience <= 0 {
+		return fmt.Errorf("invalid freeze patience: %d", c.Patience)
+	}
+	return nil
+}
+
+// freezer tracks codebook movement and freezes converged units.
+// Its methods are safe to call on nil freezer which never freezes any units.
+type freezer struct {
+	// c is freezing configuration
+	c *FreezeConfig
+	// epoch is the number of iterations in an epoch
+	epoch int
+	// prev holds codebook at the start of the current epoch
+	prev *mat.Dense
+	// still counts consecutive epochs in which units stayed below threshold
+	still []int
+	// frozen marks frozen units
+	frozen []bool
+	// count is the number of frozen units
+	count int
+}
+
+// newFreezer returns freezer of the codebook configured by c whose schedule s defines the epochs.
+// It returns nil if c is nil.
+func newFreezer(c *FreezeConfig, codebook *mat.Dense, s *schedule) *freezer {
+	if c == nil {
+		return nil
+	}
+	units, _ := codebook.Dims()
+	return &freezer{
+		c:      c,
+		epoch:  s.epoch,
+		prev:   mat.DenseCopyOf(codebook),
+		still:  make([]int, units),
+		frozen: make([]bool, units),
+	}
+}
+
+// isFrozen returns true if the unit is frozen
+func (f *freezer) isFrozen(unit int) bool {
+	return f != nil && f.frozen[unit]
+}
+
+// step checks codebook movement if the i-th finished training iteration completes an epoch.
+// Units which stayed below threshold for patience epochs are frozen and the number of
+// frozen units is recorded in the last step of schedule s.
+func (f *freezer) step(i int, codebook *mat.Dense, s *schedule) {
+	if f == nil || (i+1)%f.epoch != 0 {
+		return
+	}
+	for u := range f.frozen {
+		if f.frozen[u] {
+			continue
+		}
+		vec, prev := codebook.RawRowView(u), f.prev.RawRowView(u)
+		if euclideanVec(vec, prev) < f.c.Threshold {
+			f.still[u]++
+		} else {
+			f.still[u] = 0
+		}
+		if f.still[u] >= f.c.Patience {
+			f.frozen[u] = true
+			f.count++
+		}
+		copy(prev, vec)
+	}
+	if len(s.steps) > 0 {
+		s.steps[len(s.steps)-1].Frozen = f.count
+	}
+}
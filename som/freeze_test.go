@@ -0,0 +1,78 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestValidateFreezeConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(validateFreezeConfig(nil))
+	assert.NoError(validateFreezeConfig(&FreezeConfig{Threshold: 0.1, Patience: 2}))
+	assert.Error(validateFreezeConfig(&FreezeConfig{Threshold: -0.1, Patience: 2}))
+	assert.Error(validateFreezeConfig(&FreezeConfig{Threshold: 0.1, Patience: 0}))
+}
+
+func TestFreezer(t *testing.T) {
+	assert := assert.New(t)
+
+	// nil freezer never freezes any units
+	var f *freezer
+	assert.False(f.isFrozen(0))
+
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	s := newSchedule(tc, 10, 1)
+	codebook := mat.NewDense(2, 2, []float64{0, 0, 1, 1})
+	f = newFreezer(&FreezeConfig{Threshold: 0.5, Patience: 2}, codebook, s)
+	for i := 0; i < 3; i++ {
+		s.at(i)
+		// the first unit keeps moving, the second one barely moves
+		codebook.Set(0, 0, codebook.At(0, 0)+1.0)
+		codebook.Set(1, 0, codebook.At(1, 0)+0.1)
+		f.step(i, codebook, s)
+	}
+	assert.False(f.isFrozen(0))
+	assert.True(f.isFrozen(1))
+	assert.Equal(0, s.steps[0].Frozen)
+	assert.Equal(1, s.steps[1].Frozen)
+}
+
+func TestTrainFreeze(t *testing.T) {
+	assert := assert.New(t)
+
+	rows, _ := dataMx.Dims()
+	for _, alg := range []string{"seq", "batch"} {
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		tc := makeDefaultTrainConfig()
+		tc.Algorithm = alg
+		// huge threshold freezes all units after the first epoch
+		tc.Freeze = &FreezeConfig{Threshold: 1e6, Patience: 1}
+		assert.NoError(m.Train(tc, dataMx, 3*rows))
+		units, _ := m.Codebook().Dims()
+		for _, step := range m.TrainHistory() {
+			assert.Equal(units, step.Frozen)
+		}
+	}
+
+	// units frozen after the first batch iteration are not updated by the remaining ones
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	frozen, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	frozen.codebook.Copy(m.codebook)
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	tc.Freeze = &FreezeConfig{Threshold: 1e6, Patience: 1}
+	assert.NoError(m.Train(tc, dataMx, 2))
+	assert.NoError(frozen.Train(tc, dataMx, 5))
+	assert.True(mat.Equal(m.Codebook(), frozen.Codebook()))
+
+	// invalid freeze config
+	tc.Freeze = &FreezeConfig{Threshold: 1, Patience: -1}
+	assert.Error(m.Train(tc, dataMx, 10))
+}
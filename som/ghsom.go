@@ -0,0 +1,220 @@
+package som
+
+import (
+	"fmt"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// GHSOMConfig holds Growing Hierarchical SOM configuration
+type GHSOMConfig struct {
+	// Map configures maps at all levels of the hierarchy
+	Map *MapConfig
+	// Train configures training of maps at all levels of the hierarchy
+	Train *TrainConfig
+	// Iters is the number of training iterations of each map
+	Iters int
+	// Tau is the hierarchical expansion threshold: a unit spawns a child map trained on the data rows
+	// mapped to it if their average quantization error exceeds Tau times the mean distance of all
+	// the data rows from their mean. The lower the Tau, the deeper the hierarchy.
+	Tau float64
+	// MaxDepth limits the depth of the hierarchy; the root map has depth 0
+	MaxDepth int
+	// MinRows is the minimum number of data rows mapped to a unit which spawns a child map
+	MinRows int
+}
+
+// HMap is a map in Growing Hierarchical SOM.
+// Units whose data is represented poorly by the map have child maps trained on the data rows mapped to them.
+type HMap struct {
+	*Map
+	// depth is the depth of the map in the hierarchy
+	depth int
+	// children maps units to their child maps
+	children map[int]*HMap
+}
+
+// TrainGHSOM trains Growing Hierarchical SOM on data using configuration c and returns its root map.
+// The root map is trained on all the data rows. Then each unit whose average quantization error exceeds
+// the expansion threshold spawns a child map which is trained on the data rows mapped to the unit in
+// the same way, until MaxDepth is reached or no unit exceeds the threshold.
+// It fails with error if data is nil, the configuration is invalid or if any of the maps fails to train.
+func TrainGHSOM(c *GHSOMConfig, data *mat.Dense) (*HMap, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	if err := validateGHSOMConfig(c); err != nil {
+		return nil, err
+	}
+
+	return trainHMap(c, data, 0, c.Tau*dataSpread(data))
+}
+
+// validateGHSOMConfig validates Growing Hierarchical SOM configuration
+func validateGHSOMConfig(c *GHSOMConfig) error {
+	if c == nil || c.Map == nil || c.Train == nil {
+		return fmt.Errorf("invalid GHSOM configuration: %v", c)
+	}
+	if c.Iters <= 0 {
+		return fmt.Errorf("invalid number of iterations: %d", c.Iters)
+	}
+	if c.Tau <= 0.0 {
+		return fmt.Errorf("invalid expansion threshold: %f", c.Tau)
+	}
+	if c.MaxDepth < 0 {
+		return fmt.Errorf("invalid maximum depth: %d", c.MaxDepth)
+	}
+	if c.MinRows <= 0 {
+		return fmt.Errorf("invalid minimum number of rows: %d", c.MinRows)
+	}
+	return nil
+}
+
+// trainHMap trains map at a given depth on data and expands the units whose
+// average quantization error exceeds threshold into child maps
+func trainHMap(c *GHSOMConfig, data *mat.Dense, depth int, threshold float64) (*HMap, error) {
+	m, err := NewMap(c.Map, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Train(c.Train, data, c.Iters); err != nil {
+		return nil, err
+	}
+	h := &HMap{
+		Map:      m,
+		depth:    depth,
+		children: make(map[int]*HMap),
+	}
+	if depth >= c.MaxDepth {
+		return h, nil
+	}
+
+	bmus, err := m.BMUs(data)
+	if err != nil {
+		return nil, err
+	}
+	qErrs, err := m.UnitQuantErrors(data)
+	if err != nil {
+		return nil, err
+	}
+	// collect data rows mapped to each unit
+	unitRows := make(map[int][]int)
+	for row, bmu := range bmus {
+		unitRows[bmu] = append(unitRows[bmu], row)
+	}
+	_, cols := data.Dims()
+	for unit, rows := range unitRows {
+		if len(rows) < c.MinRows || qErrs[unit] <= threshold {
+			continue
+		}
+		unitData := mat.NewDense(len(rows), cols, nil)
+		for i, row := range rows {
+			unitData.SetRow(i, data.RawRowView(row))
+		}
+		child, err := trainHMap(c, unitData, depth+1, threshold)
+		if err != nil {
+			return nil, err
+		}
+		h.children[unit] = child
+	}
+
+	return h, nil
+}
+
+// Depth returns the depth of the map in the hierarchy; the root map has depth 0
+func (h *HMap) Depth() int {
+	return h.depth
+}
+
+// Child returns the child map of a given unit or nil if the unit has no child map
+func (h *HMap) Child(unit int) *HMap {
+	return h.children[unit]
+}
+
+// Expanded returns sorted indices of the units which have child maps
+func (h *HMap) Expanded() []int {
+	units := make([]int, 0, len(h.children))
+	for unit := range h.children {
+		units = append(units, unit)
+	}
+	sort.Ints(units)
+	return units
+}
+
+// Walk calls fn for each map in the hierarchy in depth-first order starting with h.
+// The path holds the units which lead from h to the visited map; it is empty for h.
+func (h *HMap) Walk(fn func(path []int, m *HMap)) {
+	h.walk(nil, fn)
+}
+
+// walk visits h and its descendants reached by path
+func (h *HMap) walk(path []int, fn func([]int, *HMap)) {
+	fn(path, h)
+	for _, unit := range h.Expanded() {
+		h.children[unit].walk(append(path[:len(path):len(path)], unit), fn)
+	}
+}
+
+// BMUPath returns the best matching units of sample at every level of the hierarchy starting with h:
+// the first unit is the BMU on h, the next one the BMU on its child map and so on until a map
+// whose BMU has no child map is reached.
+// It returns error if the sample dimension differs from codebook dimension.
+func (h *HMap) BMUPath(sample []float64) ([]int, error) {
+	var path []int
+	for m := h; m != nil; {
+		bmu, err := m.measure().closest(sample, m.codebook)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, bmu)
+		m = m.children[bmu]
+	}
+	return path, nil
+}
+
+// BMUPaths returns the BMU path of each data row; see BMUPath.
+// It returns error if data is nil or its dimension differs from codebook dimension.
+func (h *HMap) BMUPaths(data *mat.Dense) ([][]int, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	rows, _ := data.Dims()
+	paths := make([][]int, rows)
+	for i := range paths {
+		path, err := h.BMUPath(data.RawRowView(i))
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = path
+	}
+	return paths, nil
+}
+
+// QuantError returns quantization error of data on the hierarchy: the distance of each data row
+// from the codebook vector of its BMU on the deepest map of its BMU path, averaged over all the rows.
+// It returns error if data is nil or its dimension differs from codebook dimension.
+func (h *HMap) QuantError(data *mat.Dense) (float64, error) {
+	if data == nil {
+		return -1.0, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	rows, _ := data.Dims()
+	qe := 0.0
+	for i := 0; i < rows; i++ {
+		sample := data.RawRowView(i)
+		path, err := h.BMUPath(sample)
+		if err != nil {
+			return -1.0, err
+		}
+		m := h
+		for _, unit := range path[:len(path)-1] {
+			m = m.children[unit]
+		}
+		d, err := m.measure().distance(sample, m.codebook.RawRowView(path[len(path)-1]))
+		if err != nil {
+			return -1.0, err
+		}
+		qe += d
+	}
+	return qe / float64(rows), nil
+}
@@ -0,0 +1,114 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// ghsomData returns data with two groups of two tight clusters
+func ghsomData() *mat.Dense {
+	centres := [][]float64{{0, 0}, {0, 1}, {10, 0}, {10, 1}}
+	offsets := [][]float64{{0, 0}, {0.01, 0}, {0, 0.01}, {0.01, 0.01}}
+	data := mat.NewDense(len(centres)*len(offsets), 2, nil)
+	for i, c := range centres {
+		for j, o := range offsets {
+			data.SetRow(i*len(offsets)+j, []float64{c[0] + o[0], c[1] + o[1]})
+		}
+	}
+	return data
+}
+
+// ghsomConfig returns GHSOM configuration used in tests
+func ghsomConfig() *GHSOMConfig {
+	return &GHSOMConfig{
+		Map: &MapConfig{
+			Grid: &GridConfig{Size: []int{1, 2}, Type: "planar", UShape: "rectangle"},
+			Cb:   &CbConfig{Dim: 2, InitFunc: RandInit},
+		},
+		Train: &TrainConfig{
+			Algorithm: "batch",
+			Radius:    1.0,
+			RDecay:    "lin",
+			NeighbFn:  Bubble,
+			LRate:     0.5,
+			LDecay:    "lin",
+		},
+		Iters:    10,
+		Tau:      0.05,
+		MaxDepth: 2,
+		MinRows:  2,
+	}
+}
+
+func TestTrainGHSOM(t *testing.T) {
+	assert := assert.New(t)
+
+	data := ghsomData()
+	// invalid input
+	_, err := TrainGHSOM(ghsomConfig(), nil)
+	assert.Error(err)
+	_, err = TrainGHSOM(nil, data)
+	assert.Error(err)
+	for _, fn := range []func(*GHSOMConfig){
+		func(c *GHSOMConfig) { c.Map = nil },
+		func(c *GHSOMConfig) { c.Iters = 0 },
+		func(c *GHSOMConfig) { c.Tau = 0.0 },
+		func(c *GHSOMConfig) { c.MaxDepth = -1 },
+		func(c *GHSOMConfig) { c.MinRows = 0 },
+	} {
+		c := ghsomConfig()
+		fn(c)
+		_, err = TrainGHSOM(c, data)
+		assert.Error(err)
+	}
+
+	h, err := TrainGHSOM(ghsomConfig(), data)
+	assert.NoError(err)
+	assert.Equal(0, h.Depth())
+	// both groups of clusters are expanded into child maps which separate the clusters
+	assert.Equal([]int{0, 1}, h.Expanded())
+	for _, unit := range h.Expanded() {
+		child := h.Child(unit)
+		assert.Equal(1, child.Depth())
+		assert.Len(child.Expanded(), 0)
+	}
+	assert.Nil(h.Child(2))
+	var depths []int
+	h.Walk(func(path []int, m *HMap) {
+		assert.Len(path, m.Depth())
+		depths = append(depths, m.Depth())
+	})
+	assert.Equal([]int{0, 1, 1}, depths)
+
+	paths, err := h.BMUPaths(data)
+	assert.NoError(err)
+	assert.Len(paths, 16)
+	for i, path := range paths {
+		assert.Len(path, 2)
+		// rows of the same cluster share their path, rows of different clusters don't
+		assert.Equal(paths[i/4*4], path)
+		if i%4 == 0 && i > 0 {
+			assert.NotEqual(paths[i-4], path)
+		}
+	}
+	qe, err := h.QuantError(data)
+	assert.NoError(err)
+	rootQE, err := h.Map.QuantError(data)
+	assert.NoError(err)
+	assert.True(qe < 0.05)
+	assert.True(qe < rootQE)
+	_, err = h.BMUPath([]float64{1.0})
+	assert.Error(err)
+	_, err = h.BMUPaths(nil)
+	assert.Error(err)
+	_, err = h.QuantError(nil)
+	assert.Error(err)
+	// depth limit
+	c := ghsomConfig()
+	c.MaxDepth = 0
+	h, err = TrainGHSOM(c, data)
+	assert.NoError(err)
+	assert.Len(h.Expanded(), 0)
+}
@@ -0,0 +1,343 @@
+package som
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// GNGConfig holds Growing Neural Gas configuration.
+// Fritzke's original paper uses Lambda 100, WinnerRate 0.2, NeighbRate 0.006, MaxAge 50,
+// Alpha 0.5 and Decay 0.995 which work well for data sets scaled to the unit range.
+type GNGConfig struct {
+	// MaxUnits is the maximum number of units; the network stops growing once it is reached
+	MaxUnits int
+	// Lambda is the number of training iterations between unit insertions
+	Lambda int
+	// WinnerRate is the learning rate of the best matching unit
+	WinnerRate float64
+	// NeighbRate is the learning rate of the topological neighbours of the best matching unit
+	NeighbRate float64
+	// MaxAge is the maximum age of edges; older edges are removed along with units left without edges
+	MaxAge int
+	// Alpha scales the errors of the units between which a new unit is inserted; it must be in (0.0, 1.0]
+	Alpha float64
+	// Decay scales the errors of all units after every iteration; it must be in (0.0, 1.0]
+	Decay float64
+	// Metric is the distance metric used to find best matching units
+	Metric Metric
+	// Seed seeds unit initialization and data sampling. If Seed is 0, current time is used
+	Seed int64
+}
+
+// gngEdge connects units a and b, where a < b
+type gngEdge struct {
+	a, b int
+}
+
+// newGNGEdge returns edge between units a and b
+func newGNGEdge(a, b int) gngEdge {
+	if a > b {
+		a, b = b, a
+	}
+	return gngEdge{a: a, b: b}
+}
+
+// other returns the unit at the other end of the edge from unit u if u is one of the edge units
+func (e gngEdge) other(u int) (int, bool) {
+	switch u {
+	case e.a:
+		return e.b, true
+	case e.b:
+		return e.a, true
+	}
+	return -1, false
+}
+
+// GNG is Growing Neural Gas network.
+// Unlike SOM, GNG has no fixed grid: it starts with two units and learns both the unit
+// weights and the topology of data by inserting units and edges between units as it trains.
+type GNG struct {
+	c *GNGConfig
+	r *rand.Rand
+	// units holds unit weights
+	units [][]float64
+	// errs holds accumulated unit errors
+	errs []float64
+	// edges maps edges between units to their age
+	edges map[gngEdge]int
+	// iters is the number of finished training iterations
+	iters int
+}
+
+// NewGNG creates new Growing Neural Gas network for data and returns it.
+// The network is initialized with two units whose weights are randomly picked data rows.
+// It returns error if the configuration is invalid or if data has less than two rows.
+func NewGNG(c *GNGConfig, data *mat.Dense) (*GNG, error) {
+	if err := validateGNGConfig(c); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	rows, _ := data.Dims()
+	if rows < 2 {
+		return nil, fmt.Errorf("insufficient number of data rows: %d", rows)
+	}
+
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(seed))
+	g := &GNG{
+		c:     c,
+		r:     r,
+		edges: make(map[gngEdge]int),
+	}
+	for _, row := range r.Perm(rows)[:2] {
+		g.units = append(g.units, append([]float64(nil), data.RawRowView(row)...))
+		g.errs = append(g.errs, 0.0)
+	}
+
+	return g, nil
+}
+
+// validateGNGConfig validates Growing Neural Gas configuration
+// It returns error if any of the config parameters are invalid
+func validateGNGConfig(c *GNGConfig) error {
+	if c == nil {
+		return fmt.Errorf("invalid GNG config: %v", c)
+	}
+	if c.MaxUnits < 2 {
+		return fmt.Errorf("invalid maximum number of units: %d", c.MaxUnits)
+	}
+	if c.Lambda <= 0 {
+		return fmt.Errorf("invalid unit insertion period: %d", c.Lambda)
+	}
+	if c.WinnerRate <= 0.0 || c.WinnerRate > 1.0 {
+		return fmt.Errorf("invalid winner learning rate: %f", c.WinnerRate)
+	}
+	if c.NeighbRate < 0.0 || c.NeighbRate > 1.0 {
+		return fmt.Errorf("invalid neighbour learning rate: %f", c.NeighbRate)
+	}
+	if c.MaxAge <= 0 {
+		return fmt.Errorf("invalid maximum edge age: %d", c.MaxAge)
+	}
+	if c.Alpha <= 0.0 || c.Alpha > 1.0 {
+		return fmt.Errorf("invalid insertion error scale: %f", c.Alpha)
+	}
+	if c.Decay <= 0.0 || c.Decay > 1.0 {
+		return fmt.Errorf("invalid error decay: %f", c.Decay)
+	}
+	// growing networks have no training data to estimate covariance of mahalanobis metric from
+	if _, ok := metricNames[c.Metric]; !ok || c.Metric == Mahalanobis {
+		return fmt.Errorf("unsupported metric: %s", c.Metric)
+	}
+	return nil
+}
+
+// Train runs iters iterations of Growing Neural Gas training on randomly drawn data rows.
+// Training can be resumed by calling Train again; unit insertions follow the total number of iterations.
+// It returns error if data is nil, its dimension differs from unit dimension or iters is not positive.
+func (g *GNG) Train(data *mat.Dense, iters int) error {
+	if data == nil {
+		return fmt.Errorf("invalid data supplied: %v", data)
+	}
+	if iters <= 0 {
+		return fmt.Errorf("invalid number of iterations: %d", iters)
+	}
+	rows, cols := data.Dims()
+	if cols != len(g.units[0]) {
+		return fmt.Errorf("invalid data dimension: %d", cols)
+	}
+	for i := 0; i < iters; i++ {
+		if err := g.step(data.RawRowView(g.r.Intn(rows))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// step runs a single training iteration on sample
+func (g *GNG) step(sample []float64) error {
+	s1, s2, dist, err := g.closest2(sample)
+	if err != nil {
+		return err
+	}
+	g.errs[s1] += dist * dist
+	// move the winner and its neighbours towards the sample and age the winner edges
+	moveUnit(g.units[s1], sample, g.c.WinnerRate)
+	for e := range g.edges {
+		if n, ok := e.other(s1); ok {
+			moveUnit(g.units[n], sample, g.c.NeighbRate)
+			g.edges[e]++
+		}
+	}
+	// connect the two closest units or refresh their edge
+	g.edges[newGNGEdge(s1, s2)] = 0
+	g.prune()
+
+	g.iters++
+	if g.iters%g.c.Lambda == 0 && len(g.units) < g.c.MaxUnits {
+		g.insert()
+	}
+	for i := range g.errs {
+		g.errs[i] *= g.c.Decay
+	}
+
+	return nil
+}
+
+// moveUnit moves unit weights towards sample by rate
+func moveUnit(unit, sample []float64, rate float64) {
+	for i := range unit {
+		unit[i] += rate * (sample[i] - unit[i])
+	}
+}
+
+// closest2 returns the two units closest to sample and the distance of the closest one
+func (g *GNG) closest2(sample []float64) (int, int, float64, error) {
+	s1, s2 := -1, -1
+	var d1, d2 float64
+	for i, unit := range g.units {
+		d, err := Distance(g.c.Metric, sample, unit)
+		if err != nil {
+			return -1, -1, 0.0, err
+		}
+		switch {
+		case s1 < 0 || d < d1:
+			s2, d2 = s1, d1
+			s1, d1 = i, d
+		case s2 < 0 || d < d2:
+			s2, d2 = i, d
+		}
+	}
+	return s1, s2, d1, nil
+}
+
+// prune removes edges older than the maximum edge age and units left without edges
+func (g *GNG) prune() {
+	degree := make([]int, len(g.units))
+	for e, age := range g.edges {
+		if age > g.c.MaxAge {
+			delete(g.edges, e)
+			continue
+		}
+		degree[e.a]++
+		degree[e.b]++
+	}
+	// removing units from the back leaves the indices of the units yet to be checked intact
+	for u := len(g.units) - 1; u >= 0; u-- {
+		if degree[u] == 0 && len(g.units) > 2 {
+			g.remove(u)
+		}
+	}
+}
+
+// remove removes unit u by replacing it with the last unit
+func (g *GNG) remove(u int) {
+	last := len(g.units) - 1
+	g.units[u], g.errs[u] = g.units[last], g.errs[last]
+	g.units, g.errs = g.units[:last], g.errs[:last]
+	if u == last {
+		return
+	}
+	for e, age := range g.edges {
+		if n, ok := e.other(last); ok {
+			delete(g.edges, e)
+			g.edges[newGNGEdge(u, n)] = age
+		}
+	}
+}
+
+// insert inserts a new unit halfway between the unit with the largest error and its neighbour with the largest error
+func (g *GNG) insert() {
+	q := 0
+	for i, err := range g.errs {
+		if err > g.errs[q] {
+			q = i
+		}
+	}
+	f := -1
+	for e := range g.edges {
+		if n, ok := e.other(q); ok {
+			if f < 0 || g.errs[n] > g.errs[f] || (g.errs[n] == g.errs[f] && n < f) {
+				f = n
+			}
+		}
+	}
+	if f < 0 {
+		return
+	}
+	unit := make([]float64, len(g.units[q]))
+	for i := range unit {
+		unit[i] = (g.units[q][i] + g.units[f][i]) / 2.0
+	}
+	n := len(g.units)
+	g.units = append(g.units, unit)
+	delete(g.edges, newGNGEdge(q, f))
+	g.edges[newGNGEdge(q, n)] = 0
+	g.edges[newGNGEdge(n, f)] = 0
+	g.errs[q] *= g.c.Alpha
+	g.errs[f] *= g.c.Alpha
+	g.errs = append(g.errs, g.errs[q])
+}
+
+// Units returns the number of network units
+func (g *GNG) Units() int {
+	return len(g.units)
+}
+
+// Codebook returns a copy of network unit weights stored in matrix rows
+func (g *GNG) Codebook() *mat.Dense {
+	codebook := mat.NewDense(len(g.units), len(g.units[0]), nil)
+	for i, unit := range g.units {
+		codebook.SetRow(i, unit)
+	}
+	return codebook
+}
+
+// Edges returns pairs of connected units sorted by unit indices
+func (g *GNG) Edges() [][2]int {
+	edges := make([][2]int, 0, len(g.edges))
+	for e := range g.edges {
+		edges = append(edges, [2]int{e.a, e.b})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
+
+// Adjacent returns true if units a and b are connected by an edge
+func (g *GNG) Adjacent(a, b int) bool {
+	_, ok := g.edges[newGNGEdge(a, b)]
+	return ok
+}
+
+// BMUs returns the index of the best matching unit of each data row
+// It returns error if data is nil or its dimension differs from unit dimension.
+func (g *GNG) BMUs(data *mat.Dense) ([]int, error) {
+	return bmus(measure{metric: g.c.Metric}, data, g.Codebook())
+}
+
+// QuantError returns quantization error of data
+// It returns error if data is nil or its dimension differs from unit dimension.
+func (g *GNG) QuantError(data *mat.Dense) (float64, error) {
+	return quantError(measure{metric: g.c.Metric}, data, g.Codebook())
+}
+
+// TopoError returns topographic error of data: the fraction of data rows whose two
+// best matching units are not connected by an edge.
+// It returns error if data is nil or its dimension differs from unit dimension.
+func (g *GNG) TopoError(data *mat.Dense) (float64, error) {
+	return topoError(measure{metric: g.c.Metric}, data, g.Codebook(), g.Adjacent)
+}
@@ -0,0 +1,138 @@
+package som
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// gngConfig returns GNG configuration used in tests
+func gngConfig() *GNGConfig {
+	return &GNGConfig{
+		MaxUnits:   20,
+		Lambda:     50,
+		WinnerRate: 0.2,
+		NeighbRate: 0.006,
+		MaxAge:     50,
+		Alpha:      0.5,
+		Decay:      0.995,
+		Metric:     Euclidean,
+		Seed:       42,
+	}
+}
+
+// ringData returns data sampled from unit circle
+func ringData(rows int) *mat.Dense {
+	data := mat.NewDense(rows, 2, nil)
+	for i := 0; i < rows; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(rows)
+		data.SetRow(i, []float64{math.Cos(angle), math.Sin(angle)})
+	}
+	return data
+}
+
+func TestNewGNG(t *testing.T) {
+	assert := assert.New(t)
+
+	data := ringData(10)
+	g, err := NewGNG(gngConfig(), data)
+	assert.NoError(err)
+	assert.Equal(2, g.Units())
+	assert.Len(g.Edges(), 0)
+	// initial units are data rows
+	bmus, err := g.BMUs(data)
+	assert.NoError(err)
+	assert.Len(bmus, 10)
+	// insufficient data
+	_, err = NewGNG(gngConfig(), data.Slice(0, 1, 0, 2).(*mat.Dense))
+	assert.Error(err)
+	_, err = NewGNG(gngConfig(), nil)
+	assert.Error(err)
+	// invalid configs
+	_, err = NewGNG(nil, data)
+	assert.Error(err)
+	for _, fn := range []func(*GNGConfig){
+		func(c *GNGConfig) { c.MaxUnits = 1 },
+		func(c *GNGConfig) { c.Lambda = 0 },
+		func(c *GNGConfig) { c.WinnerRate = 0.0 },
+		func(c *GNGConfig) { c.NeighbRate = -0.1 },
+		func(c *GNGConfig) { c.MaxAge = 0 },
+		func(c *GNGConfig) { c.Alpha = 1.5 },
+		func(c *GNGConfig) { c.Decay = 0.0 },
+		func(c *GNGConfig) { c.Metric = Metric(-1) },
+	} {
+		c := gngConfig()
+		fn(c)
+		_, err = NewGNG(c, data)
+		assert.Error(err)
+	}
+}
+
+func TestGNGTrain(t *testing.T) {
+	assert := assert.New(t)
+
+	data := ringData(200)
+	g, err := NewGNG(gngConfig(), data)
+	assert.NoError(err)
+	// invalid input
+	assert.Error(g.Train(nil, 10))
+	assert.Error(g.Train(data, 0))
+	assert.Error(g.Train(mat.NewDense(2, 3, nil), 10))
+
+	assert.NoError(g.Train(data, 5000))
+	// the network grows up to the maximum number of units
+	assert.Equal(20, g.Units())
+	codebook := g.Codebook()
+	rows, cols := codebook.Dims()
+	assert.Equal(20, rows)
+	assert.Equal(2, cols)
+	// units settle on the ring
+	for i := 0; i < rows; i++ {
+		assert.InDelta(1.0, mat.Norm(codebook.RowView(i), 2), 0.1)
+	}
+	// ring topology: units are connected to two neighbours
+	edges := g.Edges()
+	degree := make([]int, rows)
+	for _, e := range edges {
+		assert.True(e[0] < e[1])
+		assert.True(g.Adjacent(e[0], e[1]))
+		assert.True(g.Adjacent(e[1], e[0]))
+		degree[e[0]]++
+		degree[e[1]]++
+	}
+	for _, d := range degree {
+		assert.True(d > 0)
+	}
+	qe, err := g.QuantError(data)
+	assert.NoError(err)
+	assert.True(qe < 0.1)
+	te, err := g.TopoError(data)
+	assert.NoError(err)
+	assert.True(te < 0.1)
+	// training with the same seed is deterministic
+	g2, err := NewGNG(gngConfig(), data)
+	assert.NoError(err)
+	assert.NoError(g2.Train(data, 5000))
+	assert.True(mat.Equal(codebook, g2.Codebook()))
+}
+
+func TestGNGPrune(t *testing.T) {
+	assert := assert.New(t)
+
+	g, err := NewGNG(gngConfig(), ringData(4))
+	assert.NoError(err)
+	g.units = [][]float64{{0, 0}, {1, 0}, {2, 0}, {3, 0}}
+	g.errs = []float64{0, 1, 2, 3}
+	g.edges = map[gngEdge]int{
+		newGNGEdge(0, 3): 0,
+		newGNGEdge(2, 1): 100,
+	}
+	// expired edge leaves units 1 and 2 isolated
+	g.prune()
+	assert.Equal(2, g.Units())
+	assert.Equal([][2]int{{0, 1}}, g.Edges())
+	assert.Equal([]float64{3, 0}, g.units[1])
+	assert.Equal([]float64{0, 3}, g.errs)
+}
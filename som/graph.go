@@ -0,0 +1,177 @@
+package som
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// graphNode is a grid unit encoded as a graph node
+type graphNode struct {
+	// ID is unit index
+	ID int `json:"id"`
+	// X is unit x coordinate
+	X float64 `json:"x"`
+	// Y is unit y coordinate
+	Y float64 `json:"y"`
+}
+
+// graphEdge connects adjacent grid units
+type graphEdge struct {
+	// Source is index of the first unit
+	Source int `json:"source"`
+	// Target is index of the second unit
+	Target int `json:"target"`
+	// Dist is grid distance between the units
+	Dist float64 `json:"dist"`
+}
+
+// graph is grid lattice encoded as an undirected graph
+type graph struct {
+	// Size holds grid dimensions
+	Size []int `json:"size"`
+	// Type is grid type
+	Type string `json:"type"`
+	// UShape is unit shape
+	UShape string `json:"ushape"`
+	// Nodes holds grid units
+	Nodes []graphNode `json:"nodes"`
+	// Edges holds pairs of adjacent units
+	Edges []graphEdge `json:"edges"`
+}
+
+// graph returns the grid lattice as a graph
+func (g *Grid) graph() *graph {
+	coords, _, _ := g.display()
+	units := g.Units()
+	gr := &graph{
+		Size:   g.size,
+		Type:   g.gtype,
+		UShape: g.ushape,
+		Nodes:  make([]graphNode, units),
+	}
+	for i := 0; i < units; i++ {
+		gr.Nodes[i] = graphNode{ID: i, X: coords.At(i, 0), Y: coords.At(i, 1)}
+		for j := i + 1; j < units; j++ {
+			if g.Adjacent(i, j) {
+				gr.Edges = append(gr.Edges, graphEdge{Source: i, Target: j, Dist: g.Dist(i, j)})
+			}
+		}
+	}
+	return gr
+}
+
+// graphML elements
+type (
+	graphMLKey struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+	graphMLData struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	graphMLNode struct {
+		ID   string        `xml:"id,attr"`
+		Data []graphMLData `xml:"data"`
+	}
+	graphMLEdge struct {
+		Source string        `xml:"source,attr"`
+		Target string        `xml:"target,attr"`
+		Data   []graphMLData `xml:"data"`
+	}
+	graphMLGraph struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Data        []graphMLData `xml:"data"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	}
+	graphML struct {
+		XMLName xml.Name     `xml:"graphml"`
+		Xmlns   string       `xml:"xmlns,attr"`
+		Keys    []graphMLKey `xml:"key"`
+		Graph   graphMLGraph `xml:"graph"`
+	}
+)
+
+// graphMLNamespace is the GraphML XML namespace
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// graphML returns the graph encoded in GraphML document
+func (gr *graph) graphML() graphML {
+	doc := graphML{
+		Xmlns: graphMLNamespace,
+		Keys: []graphMLKey{
+			// size holds comma separated grid dimensions
+			{ID: "size", For: "graph", Name: "size", Type: "string"},
+			{ID: "type", For: "graph", Name: "type", Type: "string"},
+			{ID: "ushape", For: "graph", Name: "ushape", Type: "string"},
+			{ID: "x", For: "node", Name: "x", Type: "double"},
+			{ID: "y", For: "node", Name: "y", Type: "double"},
+			{ID: "dist", For: "edge", Name: "dist", Type: "double"},
+		},
+		Graph: graphMLGraph{
+			ID:          "G",
+			EdgeDefault: "undirected",
+			Data: []graphMLData{
+				{Key: "size", Value: strings.Trim(strings.Join(strings.Fields(fmt.Sprint(gr.Size)), ","), "[]")},
+				{Key: "type", Value: gr.Type},
+				{Key: "ushape", Value: gr.UShape},
+			},
+			Nodes: make([]graphMLNode, len(gr.Nodes)),
+			Edges: make([]graphMLEdge, len(gr.Edges)),
+		},
+	}
+	for i, n := range gr.Nodes {
+		doc.Graph.Nodes[i] = graphMLNode{
+			ID: fmt.Sprintf("n%d", n.ID),
+			Data: []graphMLData{
+				{Key: "x", Value: fmt.Sprint(n.X)},
+				{Key: "y", Value: fmt.Sprint(n.Y)},
+			},
+		}
+	}
+	for i, e := range gr.Edges {
+		doc.Graph.Edges[i] = graphMLEdge{
+			Source: fmt.Sprintf("n%d", e.Source),
+			Target: fmt.Sprintf("n%d", e.Target),
+			Data:   []graphMLData{{Key: "dist", Value: fmt.Sprint(e.Dist)}},
+		}
+	}
+	return doc
+}
+
+// MarshalGraph encodes the grid lattice as an undirected graph in a given format and writes it to w.
+// Graph nodes are grid units with their coordinates, edges connect the units which are adjacent as
+// reported by Adjacent and hold their grid distance, so they respect the unit shape and the borders
+// of toroid and cylinder grids. Nodes of sphere grids hold the equirectangular projection of their coordinates. The following formats are supported:
+// json    - JSON document with grid size, type and unit shape and lists of nodes and edges
+// graphml - GraphML document with the same grid attributes, nodes and edges; node n<i> is the i-th unit
+// It returns the number of bytes written to w or fails with error if unsupported format is requested
+// or if the write to w fails.
+func (g *Grid) MarshalGraph(format string, w io.Writer) (int, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		if err := json.NewEncoder(&buf).Encode(g.graph()); err != nil {
+			return 0, err
+		}
+	case "graphml":
+		buf.WriteString(xml.Header)
+		enc := xml.NewEncoder(&buf)
+		enc.Indent("", "  ")
+		if err := enc.Encode(g.graph().graphML()); err != nil {
+			return 0, err
+		}
+		buf.WriteByte('\n')
+	default:
+		return 0, fmt.Errorf("unsupported format: %s", format)
+	}
+	return w.Write(buf.Bytes())
+}
@@ -0,0 +1,66 @@
+package som
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalGraph(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		c     *GridConfig
+		edges int
+	}{
+		// 2 rows of 3 units: 7 straight and 4 diagonal edges
+		{&GridConfig{Size: []int{2, 3}, Type: "planar", UShape: "rectangle"}, 11},
+		// 2 rows of 3 hexagons: 4 edges within and 5 edges between the rows
+		{&GridConfig{Size: []int{2, 3}, Type: "planar", UShape: "hexagon"}, 9},
+		// chain of 4 units wraps around cylinder
+		{&GridConfig{Size: []int{1, 4}, Type: "cylinder", UShape: "hexagon"}, 4},
+	}
+
+	for _, tc := range testCases {
+		g, err := NewGrid(tc.c)
+		assert.NoError(err)
+		buf := new(bytes.Buffer)
+		n, err := g.MarshalGraph("json", buf)
+		assert.NoError(err)
+		assert.Equal(buf.Len(), n)
+		gr := new(graph)
+		assert.NoError(json.Unmarshal(buf.Bytes(), gr))
+		assert.Equal(tc.c.Size, gr.Size)
+		assert.Equal(tc.c.Type, gr.Type)
+		assert.Len(gr.Nodes, g.Units())
+		assert.Len(gr.Edges, tc.edges, tc.c.UShape)
+		for _, e := range gr.Edges {
+			assert.True(g.Adjacent(e.Source, e.Target))
+			assert.True(e.Source < e.Target)
+		}
+	}
+
+	g, err := NewGrid(&GridConfig{Size: []int{2, 2}, Type: "planar", UShape: "hexagon"})
+	assert.NoError(err)
+	buf := new(bytes.Buffer)
+	n, err := g.MarshalGraph("graphml", buf)
+	assert.NoError(err)
+	assert.Equal(buf.Len(), n)
+	assert.True(strings.HasPrefix(buf.String(), xml.Header))
+	doc := new(graphML)
+	assert.NoError(xml.Unmarshal(buf.Bytes(), doc))
+	assert.Equal("undirected", doc.Graph.EdgeDefault)
+	assert.Equal(graphMLData{Key: "size", Value: "2,2"}, doc.Graph.Data[0])
+	assert.Len(doc.Graph.Nodes, 4)
+	assert.Equal("n1", doc.Graph.Nodes[1].ID)
+	assert.Len(doc.Graph.Edges, 5)
+
+	// unsupported format
+	n, err = g.MarshalGraph("foo", buf)
+	assert.Equal(0, n)
+	assert.Error(err)
+}
@@ -3,7 +3,10 @@ package som
 import (
 	"fmt"
 	"math"
+	"math/rand"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/milosgajdos/gosom/pkg/matrix"
 	"github.com/milosgajdos/gosom/pkg/utils"
@@ -18,6 +21,10 @@ type Grid struct {
 	size []int
 	// ushape holds grid unit shape
 	ushape string
+	// gtype holds grid type
+	gtype string
+	// once guards lazy generation of coords
+	once sync.Once
 	// coords holds grid point coordinates
 	coords *mat.Dense
 }
@@ -30,19 +37,94 @@ func NewGrid(c *GridConfig) (*Grid, error) {
 		return nil, err
 	}
 
-	// grid coordinates matrix
-	coords, err := GridCoords(c.UShape, c.Size)
-	if err != nil {
-		return nil, err
-	}
-
+	// grid coordinates are generated on first use
 	return &Grid{
 		size:   c.Size,
 		ushape: c.UShape,
-		coords: coords,
+		gtype:  c.Type,
 	}, nil
 }
 
+// coordinates returns grid coordinates matrix which is generated on the first call
+func (g *Grid) coordinates() *mat.Dense {
+	g.once.Do(func() {
+		// grid configuration has been validated so no need to check for errors
+		g.coords, _ = coordsInitFns[g.gtype](g.ushape, g.size)
+	})
+	return g.coords
+}
+
+// isChain returns true if the grid dimensions describe a 1D chain of units
+func isChain(dims []int) bool {
+	return len(dims) == 2 && (dims[0] == 1 || dims[1] == 1)
+}
+
+// hexagonal returns true if the grid units are laid out in a hexagonal lattice
+func (g *Grid) hexagonal() bool {
+	return g.ushape == "hexagon" && !isChain(g.size)
+}
+
+// layered returns true if the grid is a 3D grid made of layers
+func (g *Grid) layered() bool {
+	return len(g.size) == 3
+}
+
+// spherical returns true if the grid units are placed on a sphere
+func (g *Grid) spherical() bool {
+	return g.gtype == "sphere"
+}
+
+// display returns 2D coordinates, dimensions and unit shape used to draw the grid.
+// Sphere grids are drawn in equirectangular projection as hexagons on a canvas which covers the projection.
+// 3D grids are drawn sliced: their layers are drawn side by side from left to right, one column apart.
+func (g *Grid) display() (*mat.Dense, []int, string) {
+	if g.layered() {
+		slices, dims := sliceCoords(g.coordinates(), g.size)
+		return slices, dims, g.ushape
+	}
+	if !g.spherical() {
+		return g.coordinates(), g.size, g.ushape
+	}
+	proj := sphereProjection(g.coordinates())
+	width, height := math.Ceil(2*math.Pi*g.radius())+1, math.Ceil(math.Pi*g.radius())+1
+	return proj, []int{int(height), int(width)}, "hexagon"
+}
+
+// sliceCoords returns 2D coordinates and dimensions of the layers of 3D grid of given dims and coords
+// drawn side by side from left to right, one column apart
+func sliceCoords(coords *mat.Dense, dims []int) (*mat.Dense, []int) {
+	rows, cols, layers := dims[0], dims[1], dims[2]
+	units, _ := coords.Dims()
+	slices := mat.NewDense(units, 2, nil)
+	for i := 0; i < units; i++ {
+		unit := coords.RawRowView(i)
+		slices.Set(i, 0, unit[0]+unit[2]*float64(cols+1))
+		slices.Set(i, 1, unit[1])
+	}
+	return slices, []int{rows, layers*(cols+1) - 1}
+}
+
+// radius returns the radius of sphere grid
+func (g *Grid) radius() float64 {
+	return mat.Norm(g.coordinates().RowView(0), 2)
+}
+
+// Span returns the largest extent of the grid which bounds the distances between grid units.
+// Span of planar, toroid and cylinder grids is their largest dimension; sphere grids span
+// half of their circumference which is the distance between antipodal units.
+func (g *Grid) Span() float64 {
+	if g.spherical() {
+		return math.Pi * g.radius()
+	}
+	span := 0
+	for _, dim := range g.size {
+		if dim > span {
+			span = dim
+		}
+	}
+	return float64(span)
+}
+
 // Size returns a slice that contains Grid dimensions
 func (g *Grid) Size() []int {
 	return g.size
@@ -53,9 +135,155 @@ func (g *Grid) UShape() string {
 	return g.ushape
 }
 
-// Coords returns a matrix that contains grid coordinates
+// Type returns grid type
+func (g *Grid) Type() string {
+	return g.gtype
+}
+
+// periods returns the periods of grid coordinates along x and y axes.
+// Zero period means the grid does not wrap around the particular axis.
+// Layers of 3D grids wrap around the same axes; 3D grids never wrap around z axis.
+func (g *Grid) periods() (float64, float64) {
+	rows, cols := float64(g.size[0]), float64(g.size[1])
+	// hexagon unit rows are sqrt(0.75) apart
+	if g.hexagonal() {
+		rows *= math.Sqrt(0.75)
+	}
+	switch g.gtype {
+	case "toroid":
+		return cols, rows
+	case "cylinder":
+		return cols, 0.0
+	}
+	return 0.0, 0.0
+}
+
+// UnitDist returns a matrix which contains distances between grid units.
+// Distances on toroid and cylinder grids wrap around the grid borders:
+// the distance between two units is the length of the shortest path between them.
+// Distances on sphere grids are measured along the sphere surface.
+func (g *Grid) UnitDist() *mat.Dense {
+	coords := g.coordinates()
+	if g.spherical() {
+		units := g.Units()
+		dist := mat.NewDense(units, units, nil)
+		for i := 0; i < units-1; i++ {
+			for j := i + 1; j < units; j++ {
+				d := arcDist(coords.RawRowView(i), coords.RawRowView(j))
+				dist.Set(i, j, d)
+				dist.Set(j, i, d)
+			}
+		}
+		return dist
+	}
+	px, py := g.periods()
+	if px == 0 && py == 0 {
+		// no need to check for error: coords are never nil
+		dist, _ := DistanceMx(Euclidean, coords)
+		return dist
+	}
+	units := g.Units()
+	dist := mat.NewDense(units, units, nil)
+	for i := 0; i < units-1; i++ {
+		a := coords.RawRowView(i)
+		for j := i + 1; j < units; j++ {
+			d := wrappedDist(a, coords.RawRowView(j), px, py)
+			dist.Set(i, j, d)
+			dist.Set(j, i, d)
+		}
+	}
+	return dist
+}
+
+// Dist returns the distance between grid units with indices a and b.
+// Distances on toroid and cylinder grids wrap around the grid borders,
+// distances on sphere grids are measured along the sphere surface.
+func (g *Grid) Dist(a, b int) float64 {
+	coords := g.coordinates()
+	if g.spherical() {
+		return arcDist(coords.RawRowView(a), coords.RawRowView(b))
+	}
+	px, py := g.periods()
+	return wrappedDist(coords.RawRowView(a), coords.RawRowView(b), px, py)
+}
+
+// distRow stores the distances of grid unit with index i to all grid units in dst.
+// The distances are the same as the ones in the i-th row of UnitDist.
+func (g *Grid) distRow(i int, dst []float64) {
+	for j := range dst {
+		dst[j] = g.Dist(i, j)
+	}
+}
+
+// Adjacent returns true if grid units with indices a and b are neighbours on the grid lattice.
+// Units of hexagon grids have six neighbours at distance 1, units of rectangle grids have eight
+// neighbours including the diagonal ones and units of 1D chains have two neighbours.
+// Units of 3D grids are also adjacent to the units right above and below them in the neighbouring
+// layers and rectangle units to the diagonal neighbours of those, so they have up to 26 neighbours.
+// Neighbours of units on toroid and cylinder grids wrap around the grid borders.
+// Units of sphere grids have six neighbours except for the twelve icosahedron vertices which have five.
+func (g *Grid) Adjacent(a, b int) bool {
+	if a == b {
+		return false
+	}
+	if g.spherical() {
+		return g.Dist(a, b) < sphereNeighbourRadius
+	}
+	return g.Dist(a, b) < neighbourRadius(g.ushape, g.size)
+}
+
+// neighbourRadius returns the distance within which the units of the grid of given unit shape and dims
+// are lattice neighbours. Rectangle units have 8 neighbours including the diagonal ones, hexagon units
+// and units of 1D chains have the neighbours at unit distance. Rectangle units of 3D grids have
+// 26 neighbours including the diagonal ones in the neighbouring layers.
+func neighbourRadius(uShape string, dims []int) float64 {
+	// radius slightly exceeds the neighbour distance to account for rounding errors
+	switch {
+	case uShape == "hexagon" || isChain(dims):
+		return 1.01
+	case len(dims) == 3:
+		return 1.01 * math.Sqrt(3)
+	}
+	return 1.01 * math.Sqrt2
+}
+
+// wrappedDist returns euclidean distance between 2D or 3D points a and b in space which wraps
+// around x and y axes with periods px and py. Zero period means the axis does not wrap.
+// The z axis of 3D points never wraps.
+func wrappedDist(a, b []float64, px, py float64) float64 {
+	dx, dy := math.Abs(a[0]-b[0]), math.Abs(a[1]-b[1])
+	if px > 0 {
+		dx = math.Min(dx, px-dx)
+	}
+	if py > 0 {
+		dy = math.Min(dy, py-dy)
+	}
+	if len(a) > 2 {
+		return math.Sqrt(dx*dx + dy*dy + (a[2]-b[2])*(a[2]-b[2]))
+	}
+	return math.Hypot(dx, dy)
+}
+
+// Units returns the number of grid units
+func (g *Grid) Units() int {
+	return utils.IntProduct(g.size)
+}
+
+// Coords returns a matrix that contains grid coordinates.
+// The matrix is generated on the first call and cached for the subsequent calls.
 func (g *Grid) Coords() mat.Matrix {
-	return g.coords
+	return g.coordinates()
+}
+
+// UnitCoords stores the coordinates of i-th grid unit in dst and returns it.
+// If dst is nil, a new slice is allocated. It panics if i is out of grid range.
+func (g *Grid) UnitCoords(i int, dst []float64) []float64 {
+	coords := g.coordinates()
+	_, cols := coords.Dims()
+	if dst == nil {
+		dst = make([]float64, cols)
+	}
+	return mat.Row(dst, i, coords)
 }
 
 // GridSize tries to estimate the best dimensions of map from data matrix and given unit shape.
@@ -63,58 +291,169 @@ func (g *Grid) Coords() mat.Matrix {
 // calculated from the ratio of two highest input eigenvalues.
 // It returns error if the map dimensions could not be calculated.
 func GridSize(data *mat.Dense, uShape string) ([]int, error) {
+	return GridSizeWith(data, uShape, &SizeConfig{})
+}
+
+// GridSizeWith estimates dimensions of map from data matrix and given unit shape like GridSize does
+// using the provided size configuration. The configuration lets you tune the scale of the number of
+// map units heuristic, cap or request an explicit number of map units and force the ratio of grid dimensions.
+// It returns error if the configuration is invalid or if the map dimensions could not be calculated.
+func GridSizeWith(data *mat.Dense, uShape string, c *SizeConfig) ([]int, error) {
+	est, err := EstimateGridSize(data, uShape, c)
+	if err != nil {
+		return nil, err
+	}
+	return est.Dims, nil
+}
+
+// SizeEstimate holds map dimensions estimated from data along with the data spectrum they were derived from
+type SizeEstimate struct {
+	// Dims are the estimated map dimensions
+	Dims []int
+	// Units is the number of map units the dimensions were derived from
+	Units int
+	// Ratio is the ratio of the first to the second grid dimension
+	Ratio float64
+	// Eigenvalues are the variances of data principal components in descending order.
+	// They are nil if the ratio was not estimated from data.
+	Eigenvalues []float64
+	// Explained holds the ratios of data variance explained by each principal component
+	Explained []float64
+}
+
+// EstimateGridSize estimates dimensions of map from data matrix and given unit shape like GridSizeWith does
+// and returns them along with the eigenvalue spectrum of data used to pick the ratio of grid dimensions.
+// Toroid grids have no borders which would stop the map from stretching along the data, so their
+// estimated dimensions ratio is the square root of the planar one; hexagon toroids get an even number
+// of rows so that the row offsets line up across the wrapped border.
+// It returns error if the configuration is invalid or if the map dimensions could not be calculated.
+func EstimateGridSize(data *mat.Dense, uShape string, c *SizeConfig) (*SizeEstimate, error) {
 	// data matrix can't be nil
 	if data == nil {
 		return nil, fmt.Errorf("invalid data matrix: %v", data)
 	}
+	if err := validateSizeConfig(c); err != nil {
+		return nil, err
+	}
 	dataLen, dataDim := data.Dims()
-	// this is a simple heuristic - you can pick the scale > 5
-	mUnits := math.Ceil(5 * math.Sqrt(float64(dataLen)))
+	mUnits := sizeUnits(dataLen, c)
+	est := &SizeEstimate{Units: int(mUnits), Ratio: 1.0}
 	// if the data is 1D - we return [1 x mUnits] map dimensions
-	if dataDim == 1 && dataLen > 1 {
-		return []int{1, int(mUnits)}, nil
-	}
-	// Not enough data to calculate eigenvectors
-	// We will use heuristic: number of mUnits = square area of SOM
-	if dataLen < 2 {
-		gDim := math.Sqrt(mUnits)
-		return []int{int(gDim), int(gDim)}, nil
-	}
-	// We have more than 2 samples and at least 2D data
-	// Calculate eigenvalue ie. SVD singular values
-	// Vars() returned here are actually their square values -
-	// this does not matter as we are using them to compute their ratios
-	var pc stat.PC
-	ok := pc.PrincipalComponents(data, nil)
-	if !ok {
-		return nil, fmt.Errorf("Could not determine Principal Components")
+	if dataDim == 1 && dataLen > 1 && c.Ratio == 0 {
+		est.Dims = []int{1, int(mUnits)}
+		est.Ratio = 1.0 / mUnits
+		return est, nil
 	}
-	eigVals := pc.VarsTo(nil)
 	// by default we use 1:1 ratio of the map
 	ratio := 1.0
-	// pick first two components: we only support 2D data maps
-	// length check here is redundant, but let's make sure just in case
-	if len(eigVals) >= 2 {
-		if eigVals[0] != 0 && eigVals[1]*mUnits >= eigVals[0] {
-			ratio = math.Sqrt(eigVals[0] / eigVals[1])
+	switch {
+	case c.Ratio > 0:
+		ratio = c.Ratio
+	case dataLen < 2:
+		// Not enough data to calculate eigenvectors
+		// We will use heuristic: number of mUnits = square area of SOM
+		gDim := int(math.Sqrt(mUnits))
+		est.Dims = []int{gDim, gDim}
+		return est, nil
+	default:
+		// We have more than 2 samples and at least 2D data
+		// Calculate eigenvalue ie. SVD singular values
+		// Vars() returned here are actually their square values -
+		// this does not matter as we are using them to compute their ratios
+		var pc stat.PC
+		ok := pc.PrincipalComponents(data, nil)
+		if !ok {
+			return nil, fmt.Errorf("Could not determine Principal Components")
+		}
+		eigVals := pc.VarsTo(nil)
+		est.Eigenvalues = eigVals
+		est.Explained = make([]float64, len(eigVals))
+		if total := floats.Sum(eigVals); total > 0 {
+			floats.ScaleTo(est.Explained, 1/total, eigVals)
+		}
+		// pick first two components: we only support 2D data maps
+		// length check here is redundant, but let's make sure just in case
+		if len(eigVals) >= 2 {
+			if eigVals[0] != 0 && eigVals[1]*mUnits >= eigVals[0] {
+				ratio = math.Sqrt(eigVals[0] / eigVals[1])
+			}
+		}
+		// toroids prefer balanced dimensions
+		if c.Type == "toroid" {
+			ratio = math.Sqrt(ratio)
 		}
 	}
+	est.Ratio = ratio
 	// For hexagon unit shape, the ratio is modified a bit to take it into account
 	// Remember when using hexagon we don't get rectangle so the area != dimA * dimB
 	tmpDim := math.Sqrt(mUnits / ratio)
 	if strings.EqualFold(uShape, "hexagon") {
 		tmpDim = math.Sqrt(mUnits / ratio * math.Sqrt(0.75))
 	}
-	yDim := int(floats.Min([]float64{mUnits, tmpDim}))
+	yDim := int(math.Max(1.0, floats.Min([]float64{mUnits, tmpDim})))
 	xDim := int(mUnits / float64(yDim))
-	// Return map dimensions
-	return []int{xDim, yDim}, nil
+	// hexagon row offsets only line up across toroid border with even number of rows
+	if c.Type == "toroid" && strings.EqualFold(uShape, "hexagon") && xDim%2 == 1 {
+		xDim++
+	}
+	est.Dims = []int{xDim, yDim}
+
+	return est, nil
+}
+
+// sizeUnits returns the number of map units for a data set with dataLen samples
+func sizeUnits(dataLen int, c *SizeConfig) float64 {
+	// explicit number of units overrides the heuristic
+	if c.Units > 0 {
+		return float64(c.Units)
+	}
+	// this is a simple heuristic - you can pick the scale > 5
+	scale := c.Scale
+	if scale == 0 {
+		scale = DefaultSizeScale
+	}
+	units := math.Ceil(scale * math.Sqrt(float64(dataLen)))
+	if c.MaxUnits > 0 {
+		units = math.Min(units, float64(c.MaxUnits))
+	}
+	return units
+}
+
+// SphereLevel estimates the subdivision level of a geodesic sphere grid for a given data matrix.
+// Geodesic grid of level L is created by L-times subdividing the faces of an icosahedron and
+// has SphereUnits(L) units. SphereLevel returns the lowest level whose number of units is at least
+// the number of map units estimated using the provided size configuration, but not above MaxUnits
+// unless even the icosahedron has more units; Ratio and Type are ignored.
+// It returns error if the data matrix is nil or the configuration is invalid.
+func SphereLevel(data *mat.Dense, c *SizeConfig) (int, error) {
+	// data matrix can't be nil
+	if data == nil {
+		return -1, fmt.Errorf("invalid data matrix: %v", data)
+	}
+	if err := validateSizeConfig(c); err != nil {
+		return -1, err
+	}
+	dataLen, _ := data.Dims()
+	mUnits := sizeUnits(dataLen, c)
+	level := 0
+	for float64(SphereUnits(level)) < mUnits {
+		level++
+	}
+	if c.MaxUnits > 0 && level > 0 && SphereUnits(level) > c.MaxUnits {
+		level--
+	}
+	return level, nil
+}
+
+// SphereUnits returns the number of units of geodesic sphere grid of a given subdivision level
+func SphereUnits(level int) int {
+	return 10*(1<<(2*uint(level))) + 2
 }
 
 // RandInit returns a matrix initialized to uniformly distributed random values
 // in each column in range between [max, min] where max and min are maximum and minmum values
 // in particular matrix column. The returned matrix has product(dims) number of rows and
-// as many columns as the matrix passed in as a parameter.
+// as many columns as the matrix passed in as a parameter. Missing data values are left out of the column ranges.
 // It fails with error if the new matrix could not be initialized or if data is nil.
 func RandInit(data *mat.Dense, dims []int) (*mat.Dense, error) {
 	// if nil matrix is passed in, return error
@@ -144,6 +483,13 @@ func RandInit(data *mat.Dense, dims []int) (*mat.Dense, error) {
 		return nil, err
 	}
 
+	// NaNs of missing values would propagate to the column ranges
+	if dataMissing(data) {
+		for i := 0; i < cols; i++ {
+			min[i], max[i] = colRange(data, i)
+		}
+	}
+
 	mUnits := utils.IntProduct(dims)
 	// initialize matrix to rand values between 0.0 and 1.0
 	codebook, err := matrix.MakeRandom(mUnits, cols, 0.0, 1.0)
@@ -164,11 +510,17 @@ func RandInit(data *mat.Dense, dims []int) (*mat.Dense, error) {
 
 // LinInit returns a matrix initialized to values lying in a linear space
 // spanned by principal components of data stored in the data matrix passed in as parameter.
+// Missing data values are replaced by the means of their columns before the principal components are computed.
 // It fails with error if the new matrix could not be initialized or if data is nil.
 func LinInit(data *mat.Dense, dims []int) (*mat.Dense, error) {
 	if err := validateLinInit(data, dims); err != nil {
 		return nil, err
 	}
+	if dataMissing(data) {
+		filled := mat.DenseCopyOf(data)
+		fillMissing(filled, data)
+		data = filled
+	}
 	// Adjust map dimensions size to account for 1D cases
 	mapDim := len(dims)
 	for _, dim := range dims {
@@ -229,6 +581,93 @@ func LinInit(data *mat.Dense, dims []int) (*mat.Dense, error) {
 	return codebook, nil
 }
 
+// SampleInit returns a matrix whose rows are randomly picked rows of data.
+// Each data row is picked at most once unless there are fewer data rows than map units.
+// The returned matrix has product(dims) number of rows and as many columns as data.
+// Missing values of the picked rows are replaced by the means of their data columns.
+// It fails with error if data is nil or if dims are not positive.
+func SampleInit(data *mat.Dense, dims []int) (*mat.Dense, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid input matrix: %v", data)
+	}
+	if dims == nil {
+		return nil, fmt.Errorf("invalid dimensions: %v", dims)
+	}
+	for _, dim := range dims {
+		if dim <= 0 {
+			return nil, fmt.Errorf("Non-Positive dimensions supplied: %v", dims)
+		}
+	}
+	rows, cols := data.Dims()
+	mUnits := utils.IntProduct(dims)
+	codebook := mat.NewDense(mUnits, cols, nil)
+	perm := rand.Perm(rows)
+	for i := 0; i < mUnits; i++ {
+		row := rand.Intn(rows)
+		if i < rows {
+			row = perm[i]
+		}
+		codebook.SetRow(i, data.RawRowView(row))
+	}
+	fillMissing(codebook, data)
+
+	return codebook, nil
+}
+
+// cbInitFns maps names of codebook initialization functions to their implementations
+var cbInitFns = map[string]CbInitFunc{
+	"rand":   RandInit,
+	"lin":    LinInit,
+	"sample": SampleInit,
+}
+
+// cbInitMu guards cbInitFns
+var cbInitMu sync.RWMutex
+
+// RegisterCbInitFunc registers codebook initialization function fn under a given name,
+// so it can be selected by name in configuration files and on command line via ParseCbInitFunc.
+// It returns error if name is empty, fn is nil or if a function is already registered under the name.
+func RegisterCbInitFunc(name string, fn CbInitFunc) error {
+	if name == "" {
+		return fmt.Errorf("invalid codebook init function name: %q", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("invalid InitFunc: %v", fn)
+	}
+	cbInitMu.Lock()
+	defer cbInitMu.Unlock()
+	if _, ok := cbInitFns[name]; ok {
+		return fmt.Errorf("codebook init function already registered: %s", name)
+	}
+	cbInitFns[name] = fn
+	return nil
+}
+
+// ParseCbInitFunc returns codebook initialization function registered under a given name.
+// Builtin functions are rand (RandInit), lin (LinInit) and sample (SampleInit).
+// It returns error if no function is registered under the name.
+func ParseCbInitFunc(name string) (CbInitFunc, error) {
+	cbInitMu.RLock()
+	defer cbInitMu.RUnlock()
+	fn, ok := cbInitFns[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported codebook init function: %s", name)
+	}
+	return fn, nil
+}
+
+// CbInitFuncs returns sorted names of registered codebook initialization functions
+func CbInitFuncs() []string {
+	cbInitMu.RLock()
+	defer cbInitMu.RUnlock()
+	names := make([]string, 0, len(cbInitFns))
+	for name := range cbInitFns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // validateLinInit checks whether you can initialize SOM given the provided parameters
 // It returns error if at least one of the mandatory conditions fails to be satisfied
 func validateLinInit(data *mat.Dense, dims []int) error {
@@ -267,15 +706,10 @@ func getBaseVecs(data *mat.Dense, mapDim int) (*mat.Dense, error) {
 	samples, dataDim := data.Dims()
 	if dataDim > 1 && (dataDim >= mapDim) {
 		// calculate Principal components of the input data
-		var pc stat.PC
-		ok := pc.PrincipalComponents(data, nil)
-		if !ok {
-			return nil, fmt.Errorf("could not determine Principal Components")
+		vecs, vals, err := principalComponents(data, mapDim)
+		if err != nil {
+			return nil, err
 		}
-		// principal components and their eigen values
-		vecs := new(mat.Dense)
-		pc.VectorsTo(vecs)
-		vals := pc.VarsTo(nil)
 		// normalize the eigenvectors
 		for i := 0; i < mapDim; i++ {
 			vec := vecs.ColView(i).(*mat.VecDense)
@@ -294,6 +728,132 @@ func getBaseVecs(data *mat.Dense, mapDim int) (*mat.Dense, error) {
 	return baseVecs, nil
 }
 
+// randPCAMinDim is the minimum data dimension whose principal components are computed by randomized PCA
+const randPCAMinDim = 256
+
+// principalComponents returns at least k principal directions of data in matrix columns along with their variances.
+// Wide data with at least randPCAMinDim columns only have their top k components computed by randomized PCA.
+// It returns error if the principal components could not be determined.
+func principalComponents(data *mat.Dense, k int) (*mat.Dense, []float64, error) {
+	if _, dataDim := data.Dims(); dataDim >= randPCAMinDim {
+		return randPCA(data, k)
+	}
+	var pc stat.PC
+	if ok := pc.PrincipalComponents(data, nil); !ok {
+		return nil, nil, fmt.Errorf("could not determine Principal Components")
+	}
+	vecs := new(mat.Dense)
+	pc.VectorsTo(vecs)
+	return vecs, pc.VarsTo(nil), nil
+}
+
+const (
+	// randPCAOversample is the number of extra random projections which improve randomized PCA accuracy
+	randPCAOversample = 10
+	// randPCAPowerIters is the number of power iterations which sharpen the randomized PCA subspace
+	randPCAPowerIters = 2
+)
+
+// randPCA returns top k principal directions of data in matrix columns along with their variances.
+// It computes them by randomized range finder with power iterations (Halko et al.): data is projected
+// onto a few random directions, the projections are orthonormalized and the principal directions are
+// found by SVD of the small projected matrix. Data is centered implicitly, so the data matrix is not copied;
+// matrix products run in parallel BLAS routines. The random projections are seeded, so the results are repeatable.
+// It returns error if the SVD of the projected data fails.
+func randPCA(data *mat.Dense, k int) (*mat.Dense, []float64, error) {
+	samples, dataDim := data.Dims()
+	l := k + randPCAOversample
+	if l > dataDim {
+		l = dataDim
+	}
+	if l > samples {
+		l = samples
+	}
+	mean := make([]float64, dataDim)
+	for i := 0; i < samples; i++ {
+		floats.Add(mean, data.RawRowView(i))
+	}
+	floats.Scale(1/float64(samples), mean)
+
+	r := rand.New(rand.NewSource(1))
+	omega := mat.NewDense(dataDim, l, nil)
+	for i := 0; i < dataDim; i++ {
+		for j := 0; j < l; j++ {
+			omega.Set(i, j, r.NormFloat64())
+		}
+	}
+	y := centeredMul(data, mean, omega)
+	for q := 0; q < randPCAPowerIters; q++ {
+		orthonormalize(y)
+		z := centeredTMul(data, mean, y)
+		orthonormalize(z)
+		y = centeredMul(data, mean, z)
+	}
+	orthonormalize(y)
+	// principal directions are the left singular vectors of projected data transpose
+	var svd mat.SVD
+	if ok := svd.Factorize(centeredTMul(data, mean, y), mat.SVDThin); !ok {
+		return nil, nil, fmt.Errorf("could not determine Principal Components")
+	}
+	vecs := new(mat.Dense)
+	svd.UTo(vecs)
+	vals := svd.Values(nil)
+	for i := range vals {
+		vals[i] = vals[i] * vals[i] / float64(samples-1)
+	}
+
+	return vecs, vals, nil
+}
+
+// centeredMul returns the product of data centered by mean and m
+func centeredMul(data *mat.Dense, mean []float64, m *mat.Dense) *mat.Dense {
+	p := new(mat.Dense)
+	p.Mul(data, m)
+	// subtract mean projection from every row
+	meanProj := make([]float64, p.RawMatrix().Cols)
+	mat.NewVecDense(len(meanProj), meanProj).MulVec(m.T(), mat.NewVecDense(len(mean), mean))
+	rows, _ := p.Dims()
+	for i := 0; i < rows; i++ {
+		floats.Sub(p.RawRowView(i), meanProj)
+	}
+	return p
+}
+
+// centeredTMul returns the product of transposed data centered by mean and m
+func centeredTMul(data *mat.Dense, mean []float64, m *mat.Dense) *mat.Dense {
+	p := new(mat.Dense)
+	p.Mul(data.T(), m)
+	// subtract outer product of mean and m column sums
+	rows, cols := m.Dims()
+	sums := make([]float64, cols)
+	for i := 0; i < rows; i++ {
+		floats.Add(sums, m.RawRowView(i))
+	}
+	for i, mu := range mean {
+		floats.AddScaled(p.RawRowView(i), -mu, sums)
+	}
+	return p
+}
+
+// orthonormalize orthonormalizes m columns in place by modified Gram-Schmidt process.
+// Columns which are linearly dependent on the preceding columns are zeroed.
+func orthonormalize(m *mat.Dense) {
+	_, cols := m.Dims()
+	for j := 0; j < cols; j++ {
+		col := m.ColView(j).(*mat.VecDense)
+		for i := 0; i < j; i++ {
+			prev := m.ColView(i)
+			col.AddScaledVec(col, -mat.Dot(prev, col), prev)
+		}
+		norm := mat.Norm(col, 2)
+		if norm < 1e-12 {
+			col.Zero()
+			continue
+		}
+		col.ScaleVec(1/norm, col)
+	}
+}
+
 // getLinMapCoords calculates map coordinates and normalizes them to unit values
 // It returns error if it can't calculate coordinates
 func getLinMapCoords(mapDim int, dims []int) (*mat.Dense, error) {
@@ -337,6 +897,15 @@ func getLinMapCoords(mapDim int, dims []int) (*mat.Dense, error) {
 // GridCoords returns a matrix which contains coordinates of all SOM units stored row by row.
 // dims specify the size of the Grid, so the returned matrix has as many rows as is the
 // product of the numbers stored in dims slice and as many columns as is the length of dims slice.
+// 2D grids have [rows, cols] dimensions and 3D grids [rows, cols, layers] dimensions: 3D grids are
+// stacks of layers, i.e. 2D grids of the same size placed in parallel planes 1 apart.
+// Units are ordered column by column in every layer and layer by layer, so the unit in a given row,
+// column and layer has index row + col*rows + layer*rows*cols. Its x coordinate is its column,
+// y coordinate its row and z coordinate its layer. Hexagon grids offset x coordinates of every
+// other row by 0.5 and place the rows sqrt(0.75) apart in every layer, so all the six neighbours of
+// a unit in its layer are at unit distance; layers are 1 apart regardless of the unit shape.
+// Units of 1D grids i.e. grids of [1, n] or [n, 1] dimensions are placed on a straight line
+// regardless of the unit shape, so the distance between i-th and j-th unit of the chain is |i-j|.
 // GridCoords fails with error if the requested unit shape is unsupported or if the incorrect
 // dimensions are supplied: dims slice can't be nil nor can its length be bigger than 3
 func GridCoords(uShape string, dims []int) (*mat.Dense, error) {
@@ -344,51 +913,36 @@ func GridCoords(uShape string, dims []int) (*mat.Dense, error) {
 	if err := validateGridCoords(uShape, dims); err != nil {
 		return nil, err
 	}
-	mDims := len(dims)
-	// turn [n] -> [n,1]
-	if mDims == 1 {
-		dims = append(dims, 1)
-	}
-	// We need coordinates tuples to populate coords matrix
-	// cumprod will give us counts/length of the tuples of same numbers in each sequence
-	// dims will give us the the upper bound of the tuple sequence
-	// i.e. cumprod = 2, dim = 2 means we will have [2, 2] tubple in the sequence
-	counts := utils.IntCumProduct(dims)
-	counts = append([]int{1}, counts...)
-	// init grid coordinates matrix
-	mUnits := utils.IntProduct(dims)
-	coords := mat.NewDense(mUnits, mDims, nil)
-	for i := 0; i < mDims; i++ {
-		seq := makeSeq(mUnits/counts[i+1], dims[i], counts[i])
-		coords.SetCol(i, seq)
-	}
-	// retrieve x and y coords
-	x := mat.Col(make([]float64, mUnits), 0, coords)
-	y := mat.Col(make([]float64, mUnits), 1, coords)
-	// swaps x and y coordinates: ij notation to xy
-	if mDims >= 2 {
-		coords.SetCol(1, x)
-		coords.SetCol(0, y)
-	}
-	// This will offset x-coordinates of every other unit by 0.5.
+	// [n] grids are [n, 1] chains with a single coordinate
+	rows, cols, layers := dims[0], 1, 1
+	if len(dims) > 1 {
+		cols = dims[1]
+	}
+	if len(dims) > 2 {
+		layers = dims[2]
+	}
+	// This will offset x-coordinates of every other row by 0.5.
 	// This will make distances of a unit to all its six neighbors equal
-	if strings.EqualFold(uShape, "hexagon") {
-		x = mat.Col(x, 0, coords)
-		y = mat.Col(y, 1, coords)
-		// dims[1] was y-dim, before we swapped it for x-dim
-		xDim := dims[1]
-		repCount := mUnits / xDim
-		for i := 0; i < xDim; i++ {
-			for j := repCount*i + 1; j < repCount*(i+1); j += 2 {
-				x[j] += 0.5
+	hexagon := strings.EqualFold(uShape, "hexagon") && len(dims) > 1 && !isChain(dims)
+	coords := mat.NewDense(rows*cols*layers, len(dims), nil)
+	for i := 0; i < rows*cols*layers; i++ {
+		row, col, layer := i%rows, (i/rows)%cols, i/(rows*cols)
+		x, y := float64(col), float64(row)
+		if hexagon {
+			if row%2 == 1 {
+				x += 0.5
 			}
+			y *= math.Sqrt(0.75)
 		}
-		// y-coordinates are multiplied by sqrt(0.75)
-		for i := 0; i < mUnits; i++ {
-			y[i] *= math.Sqrt(0.75)
+		unit := coords.RawRowView(i)
+		switch len(dims) {
+		case 1:
+			unit[0] = y
+		case 2:
+			unit[0], unit[1] = x, y
+		case 3:
+			unit[0], unit[1], unit[2] = x, y, float64(layer)
 		}
-		coords.SetCol(0, x)
-		coords.SetCol(1, y)
 	}
 	return coords, nil
 }
@@ -415,33 +969,5 @@ func validateGridCoords(uShape string, dims []int) error {
 	if mDims > 3 {
 		return fmt.Errorf("unsupported dimensions requested: %d", mDims)
 	}
-	// can't use hexagon with dims > 2
-	if strings.EqualFold(uShape, "hexagon") {
-		if mDims > 2 {
-			return fmt.Errorf("Exceeded allowed hexagon dims: %d", mDims)
-		}
-	}
 	return nil
 }
-
-// makeSeq makes a sequence of numbers and returns it in a slice
-// The length of the returned slice is equal to the product of all function parameters:
-// reps specifies number of sequence repetitions
-// bound specifies an upper bound of sequence
-// count specifies number of times the number in a sequence should be repeated
-func makeSeq(reps, bound, count int) []float64 {
-	seq := make([]float64, count*bound*reps)
-	// # repeats of the same sequence
-	idx := 0
-	for r := 0; r < reps; r++ {
-		// which numbers should be in the sequence
-		for i := 0; i < bound; i++ {
-			// how many times each number repeats
-			for j := 0; j < count; j++ {
-				seq[idx] = float64(i)
-				idx++
-			}
-		}
-	}
-	return seq
-}
@@ -1,10 +1,13 @@
 package som
 
 import (
+	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
 )
 
 func TestNewGrid(t *testing.T) {
@@ -30,6 +33,15 @@ func TestNewGrid(t *testing.T) {
 	rows, cols := coords.Dims()
 	assert.Equal(cols, len(gCfg.Size))
 	assert.Equal(rows, gCfg.Size[0]*gCfg.Size[1])
+	assert.Equal(rows, g.Units())
+	// coords are generated only once
+	assert.True(coords == g.Coords())
+	// test unit coords
+	for i := 0; i < rows; i++ {
+		assert.Equal(mat.Row(nil, i, coords), g.UnitCoords(i, nil))
+	}
+	dst := make([]float64, cols)
+	assert.Equal(mat.Row(nil, 3, coords), g.UnitCoords(3, dst))
 	// test error cases
 	origDims := gCfg.Size
 	gCfg.Size = []int{1}
@@ -49,6 +61,160 @@ func TestNewGrid(t *testing.T) {
 	gCfg.Size = origDims
 }
 
+func TestGridUnitDist(t *testing.T) {
+	assert := assert.New(t)
+
+	// planar grid distances are euclidean distances between grid coordinates
+	g, err := NewGrid(&GridConfig{Size: []int{4, 4}, Type: "planar", UShape: "rectangle"})
+	assert.NoError(err)
+	assert.Equal("planar", g.Type())
+	expDist, err := DistanceMx(Euclidean, g.coordinates())
+	assert.NoError(err)
+	assert.True(mat.Equal(expDist, g.UnitDist()))
+	// index of unit in row y and column x
+	unit := func(x, y int) int { return x*4 + y }
+	testCases := []struct {
+		gtype  string
+		uShape string
+		a, b   int
+		dist   float64
+	}{
+		{"toroid", "rectangle", unit(0, 0), unit(3, 0), 1.0},
+		{"toroid", "rectangle", unit(0, 0), unit(0, 3), 1.0},
+		{"toroid", "rectangle", unit(0, 0), unit(3, 3), math.Sqrt2},
+		{"toroid", "hexagon", unit(0, 0), unit(3, 0), 1.0},
+		{"toroid", "hexagon", unit(0, 0), unit(0, 3), 1.0},
+		{"toroid", "hexagon", unit(0, 0), unit(0, 2), 2 * math.Sqrt(0.75)},
+		{"cylinder", "rectangle", unit(0, 0), unit(3, 0), 1.0},
+		{"cylinder", "rectangle", unit(0, 0), unit(0, 3), 3.0},
+		{"planar", "rectangle", unit(0, 0), unit(3, 0), 3.0},
+	}
+	for _, tc := range testCases {
+		g, err := NewGrid(&GridConfig{Size: []int{4, 4}, Type: tc.gtype, UShape: tc.uShape})
+		assert.NoError(err)
+		dist := g.UnitDist()
+		assert.InDelta(tc.dist, dist.At(tc.a, tc.b), 1e-9, "%s %s", tc.gtype, tc.uShape)
+		assert.InDelta(tc.dist, dist.At(tc.b, tc.a), 1e-9, "%s %s", tc.gtype, tc.uShape)
+	}
+	// hexagon toroid requires even number of rows
+	g, err = NewGrid(&GridConfig{Size: []int{3, 4}, Type: "toroid", UShape: "hexagon"})
+	assert.Nil(g)
+	assert.Error(err)
+}
+
+func TestGridAdjacent(t *testing.T) {
+	assert := assert.New(t)
+
+	// index of unit in row y and column x of 4x4 grid
+	unit := func(x, y int) int { return x*4 + y }
+	testCases := []struct {
+		gtype  string
+		uShape string
+		a, b   int
+		adj    bool
+	}{
+		{"planar", "rectangle", unit(0, 0), unit(0, 0), false},
+		{"planar", "rectangle", unit(0, 0), unit(1, 0), true},
+		{"planar", "rectangle", unit(0, 0), unit(1, 1), true},
+		{"planar", "rectangle", unit(0, 0), unit(2, 0), false},
+		{"planar", "rectangle", unit(0, 0), unit(3, 0), false},
+		{"toroid", "rectangle", unit(0, 0), unit(3, 0), true},
+		{"toroid", "rectangle", unit(0, 0), unit(3, 3), true},
+		// hexagon units have six neighbours
+		{"planar", "hexagon", unit(1, 1), unit(0, 1), true},
+		{"planar", "hexagon", unit(1, 1), unit(2, 1), true},
+		{"planar", "hexagon", unit(1, 1), unit(1, 0), true},
+		{"planar", "hexagon", unit(1, 1), unit(2, 0), true},
+		{"planar", "hexagon", unit(1, 1), unit(1, 2), true},
+		{"planar", "hexagon", unit(1, 1), unit(2, 2), true},
+		{"planar", "hexagon", unit(1, 1), unit(0, 0), false},
+		{"planar", "hexagon", unit(1, 1), unit(0, 2), false},
+		{"planar", "hexagon", unit(1, 1), unit(1, 3), false},
+		{"cylinder", "hexagon", unit(0, 0), unit(3, 0), true},
+		{"cylinder", "hexagon", unit(0, 0), unit(0, 3), false},
+	}
+	for _, tc := range testCases {
+		g, err := NewGrid(&GridConfig{Size: []int{4, 4}, Type: tc.gtype, UShape: tc.uShape})
+		assert.NoError(err)
+		assert.Equal(tc.adj, g.Adjacent(tc.a, tc.b), "%s %s %d %d", tc.gtype, tc.uShape, tc.a, tc.b)
+		assert.Equal(tc.adj, g.Adjacent(tc.b, tc.a), "%s %s %d %d", tc.gtype, tc.uShape, tc.b, tc.a)
+	}
+}
+
+func TestGrid3D(t *testing.T) {
+	assert := assert.New(t)
+
+	rows, cols, layers := 4, 3, 2
+	// index of unit in row y, column x and layer z
+	unit := func(x, y, z int) int { return y + x*rows + z*rows*cols }
+	for _, uShape := range []string{"rectangle", "hexagon"} {
+		coords, err := GridCoords(uShape, []int{rows, cols, layers})
+		assert.NoError(err)
+		plane, err := GridCoords(uShape, []int{rows, cols})
+		assert.NoError(err)
+		// layers are 2D grids stacked along z axis
+		for z := 0; z < layers; z++ {
+			for i := 0; i < rows*cols; i++ {
+				c := coords.RawRowView(i + z*rows*cols)
+				assert.Equal(plane.RawRowView(i), c[:2])
+				assert.Equal(float64(z), c[2])
+			}
+		}
+	}
+
+	testCases := []struct {
+		gtype  string
+		uShape string
+		a, b   int
+		dist   float64
+		adj    bool
+	}{
+		{"planar", "rectangle", unit(1, 1, 0), unit(1, 1, 1), 1.0, true},
+		{"planar", "rectangle", unit(1, 1, 0), unit(2, 2, 1), math.Sqrt(3), true},
+		{"planar", "rectangle", unit(0, 0, 0), unit(2, 0, 1), math.Sqrt(5), false},
+		{"planar", "hexagon", unit(1, 1, 0), unit(1, 1, 1), 1.0, true},
+		{"planar", "hexagon", unit(1, 1, 0), unit(2, 1, 1), math.Sqrt2, false},
+		{"planar", "hexagon", unit(1, 1, 1), unit(2, 0, 1), 1.0, true},
+		// layers wrap around grid borders, but the stack of layers does not
+		{"toroid", "rectangle", unit(0, 0, 0), unit(2, 3, 0), math.Sqrt2, true},
+		{"toroid", "rectangle", unit(0, 0, 0), unit(2, 3, 1), math.Sqrt(3), true},
+		{"cylinder", "rectangle", unit(0, 0, 0), unit(0, 3, 1), math.Sqrt(10), false},
+	}
+	for _, tc := range testCases {
+		g, err := NewGrid(&GridConfig{Size: []int{rows, cols, layers}, Type: tc.gtype, UShape: tc.uShape})
+		assert.NoError(err)
+		assert.InDelta(tc.dist, g.Dist(tc.a, tc.b), 1e-9, "%s %s %d %d", tc.gtype, tc.uShape, tc.a, tc.b)
+		assert.InDelta(tc.dist, g.UnitDist().At(tc.a, tc.b), 1e-9, "%s %s %d %d", tc.gtype, tc.uShape, tc.a, tc.b)
+		assert.Equal(tc.adj, g.Adjacent(tc.a, tc.b), "%s %s %d %d", tc.gtype, tc.uShape, tc.a, tc.b)
+	}
+
+	// interior rectangle units have 26 neighbours and hexagon units 8
+	for uShape, neighbs := range map[string]int{"rectangle": 26, "hexagon": 8} {
+		g, err := NewGrid(&GridConfig{Size: []int{3, 3, 3}, Type: "planar", UShape: uShape})
+		assert.NoError(err)
+		count := 0
+		for i := 0; i < g.Units(); i++ {
+			if g.Adjacent(13, i) {
+				count++
+			}
+		}
+		assert.Equal(neighbs, count, uShape)
+	}
+
+	// layers are drawn side by side, one column apart
+	g, err := NewGrid(&GridConfig{Size: []int{rows, cols, layers}, Type: "planar", UShape: "rectangle"})
+	assert.NoError(err)
+	proj, dims, uShape := g.display()
+	assert.Equal([]int{rows, 2*cols + 1}, dims)
+	assert.Equal("rectangle", uShape)
+	assert.Equal([]float64{1, 2}, proj.RawRowView(unit(1, 2, 0)))
+	assert.Equal([]float64{5, 2}, proj.RawRowView(unit(1, 2, 1)))
+
+	// 4D grids are not supported
+	_, err = NewGrid(&GridConfig{Size: []int{2, 2, 2, 2}, Type: "planar", UShape: "rectangle"})
+	assert.Error(err)
+}
+
 func TestGridSize(t *testing.T) {
 	assert := assert.New(t)
 
@@ -85,6 +251,129 @@ func TestGridSize(t *testing.T) {
 	assert.Error(err)
 }
 
+func TestGridSizeWith(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(6, 4, []float64{
+		5.1, 3.5, 1.4, 0.2,
+		4.9, 3.0, 1.4, 0.2,
+		4.7, 3.2, 1.3, 0.2,
+		4.6, 3.1, 1.5, 0.2,
+		5.0, 3.6, 1.4, 0.2,
+		5.4, 3.9, 1.7, 0.4,
+	})
+	// invalid configuration
+	for _, c := range []*SizeConfig{{Scale: -1}, {Units: -1}, {MaxUnits: -1}, {Ratio: -1}} {
+		dims, err := GridSizeWith(data, "rectangle", c)
+		assert.Nil(dims)
+		assert.Error(err)
+	}
+	// default configuration is the same as GridSize
+	dims, err := GridSizeWith(data, "rectangle", &SizeConfig{})
+	assert.NoError(err)
+	assert.EqualValues([]int{4, 3}, dims)
+	// bigger scale creates bigger map
+	dims, err = GridSizeWith(data, "rectangle", &SizeConfig{Scale: 20})
+	assert.NoError(err)
+	assert.True(dims[0]*dims[1] > 12)
+	// explicit number of units with forced ratio
+	dims, err = GridSizeWith(data, "rectangle", &SizeConfig{Units: 100, Ratio: 4})
+	assert.NoError(err)
+	assert.EqualValues([]int{20, 5}, dims)
+	dims, err = GridSizeWith(data, "rectangle", &SizeConfig{Units: 100, Ratio: 1})
+	assert.NoError(err)
+	assert.EqualValues([]int{10, 10}, dims)
+	// unsupported grid type
+	dims, err = GridSizeWith(data, "rectangle", &SizeConfig{Type: "foobar"})
+	assert.Nil(dims)
+	assert.Error(err)
+	// toroid dimensions are more balanced than planar ones
+	elongated := mat.NewDense(8, 2, []float64{
+		0, 0, 1, 1, 2, 0, 3, 1, 4, 0, 5, 1, 6, 0, 7, 1,
+	})
+	planar, err := GridSizeWith(elongated, "rectangle", &SizeConfig{Units: 64})
+	assert.NoError(err)
+	toroid, err := GridSizeWith(elongated, "rectangle", &SizeConfig{Units: 64, Type: "toroid"})
+	assert.NoError(err)
+	assert.True(float64(toroid[0])/float64(toroid[1]) < float64(planar[0])/float64(planar[1]))
+	// hexagon toroid has even number of rows
+	for _, units := range []int{30, 45, 64, 99} {
+		dims, err = GridSizeWith(elongated, "hexagon", &SizeConfig{Units: units, Type: "toroid"})
+		assert.NoError(err)
+		assert.Equal(0, dims[0]%2)
+	}
+	// the number of units is capped, but explicit number of units is not
+	dims, err = GridSizeWith(data, "rectangle", &SizeConfig{Scale: 20, MaxUnits: 12})
+	assert.NoError(err)
+	assert.True(dims[0]*dims[1] <= 12)
+	dims, err = GridSizeWith(data, "rectangle", &SizeConfig{Units: 100, Ratio: 1, MaxUnits: 12})
+	assert.NoError(err)
+	assert.EqualValues([]int{10, 10}, dims)
+}
+
+func TestEstimateGridSize(t *testing.T) {
+	assert := assert.New(t)
+
+	elongated := mat.NewDense(8, 2, []float64{
+		0, 0, 1, 1, 2, 0, 3, 1, 4, 0, 5, 1, 6, 0, 7, 1,
+	})
+	est, err := EstimateGridSize(elongated, "rectangle", &SizeConfig{Units: 64})
+	assert.NoError(err)
+	dims, err := GridSizeWith(elongated, "rectangle", &SizeConfig{Units: 64})
+	assert.NoError(err)
+	assert.Equal(dims, est.Dims)
+	assert.Equal(64, est.Units)
+	// the spectrum is sorted and explained variance ratios sum up to one
+	assert.Len(est.Eigenvalues, 2)
+	assert.True(est.Eigenvalues[0] > est.Eigenvalues[1])
+	assert.InDelta(1.0, est.Explained[0]+est.Explained[1], 1e-12)
+	assert.InDelta(est.Eigenvalues[0]/est.Eigenvalues[1], est.Explained[0]/est.Explained[1], 1e-9)
+	assert.InDelta(math.Sqrt(est.Eigenvalues[0]/est.Eigenvalues[1]), est.Ratio, 1e-12)
+	// forced ratio is not estimated from data
+	est, err = EstimateGridSize(elongated, "rectangle", &SizeConfig{Units: 100, Ratio: 4})
+	assert.NoError(err)
+	assert.Nil(est.Eigenvalues)
+	assert.Equal(4.0, est.Ratio)
+	assert.EqualValues([]int{20, 5}, est.Dims)
+	// capped number of units
+	est, err = EstimateGridSize(elongated, "rectangle", &SizeConfig{Scale: 100, MaxUnits: 50})
+	assert.NoError(err)
+	assert.Equal(50, est.Units)
+
+	// invalid data and configuration
+	_, err = EstimateGridSize(nil, "rectangle", &SizeConfig{})
+	assert.Error(err)
+	_, err = EstimateGridSize(elongated, "rectangle", &SizeConfig{MaxUnits: -1})
+	assert.Error(err)
+}
+
+func TestSphereLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(12, SphereUnits(0))
+	assert.Equal(42, SphereUnits(1))
+	assert.Equal(162, SphereUnits(2))
+
+	data := mat.NewDense(2, 1, []float64{2, 3})
+	level, err := SphereLevel(nil, &SizeConfig{})
+	assert.Equal(-1, level)
+	assert.Error(err)
+	level, err = SphereLevel(data, &SizeConfig{Units: -1})
+	assert.Equal(-1, level)
+	assert.Error(err)
+	// 5*sqrt(2) rounds up to 8 units
+	level, err = SphereLevel(data, &SizeConfig{})
+	assert.NoError(err)
+	assert.Equal(0, level)
+	level, err = SphereLevel(data, &SizeConfig{Units: 100})
+	assert.NoError(err)
+	assert.Equal(2, level)
+	// capped levels don't have more units than the cap
+	level, err = SphereLevel(data, &SizeConfig{Scale: 100, MaxUnits: 100})
+	assert.NoError(err)
+	assert.Equal(1, level)
+}
+
 func TestRandInit(t *testing.T) {
 	assert := assert.New(t)
 
@@ -124,6 +413,77 @@ func TestRandInit(t *testing.T) {
 	assert.Error(err)
 }
 
+func TestSampleInit(t *testing.T) {
+	assert := assert.New(t)
+
+	inMx := mat.NewDense(3, 2, []float64{
+		1.0, 2.0,
+		3.0, 4.0,
+		5.0, 6.0,
+	})
+	// fewer units than rows picks distinct rows
+	sampleMx, err := SampleInit(inMx, []int{1, 3})
+	assert.NoError(err)
+	r, c := sampleMx.Dims()
+	assert.Equal(3, r)
+	assert.Equal(2, c)
+	picked := make(map[float64]bool)
+	for i := 0; i < r; i++ {
+		row := sampleMx.RawRowView(i)
+		assert.Equal(row[0]+1.0, row[1])
+		picked[row[0]] = true
+	}
+	assert.Len(picked, 3)
+	// more units than rows
+	sampleMx, err = SampleInit(inMx, []int{2, 3})
+	assert.NoError(err)
+	r, _ = sampleMx.Dims()
+	assert.Equal(6, r)
+
+	// nil input matrix
+	_, err = SampleInit(nil, []int{2, 2})
+	assert.Error(err)
+	// nil dimensions
+	_, err = SampleInit(inMx, nil)
+	assert.Error(err)
+	// negative number of rows
+	_, err = SampleInit(inMx, []int{-4, 3})
+	assert.Error(err)
+}
+
+func TestCbInitFuncs(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, name := range []string{"rand", "lin", "sample"} {
+		fn, err := ParseCbInitFunc(name)
+		assert.NoError(err)
+		assert.NotNil(fn)
+	}
+	_, err := ParseCbInitFunc("custom")
+	assert.Error(err)
+	// register custom function
+	custom := func(data *mat.Dense, dims []int) (*mat.Dense, error) {
+		_, cols := data.Dims()
+		return mat.NewDense(dims[0]*dims[1], cols, nil), nil
+	}
+	assert.NoError(RegisterCbInitFunc("custom", custom))
+	defer func() {
+		cbInitMu.Lock()
+		delete(cbInitFns, "custom")
+		cbInitMu.Unlock()
+	}()
+	fn, err := ParseCbInitFunc("custom")
+	assert.NoError(err)
+	cb, err := fn(dataMx, []int{2, 2})
+	assert.NoError(err)
+	assert.Equal(0.0, mat.Max(cb))
+	assert.Equal([]string{"custom", "lin", "rand", "sample"}, CbInitFuncs())
+	// invalid registrations
+	assert.Error(RegisterCbInitFunc("custom", custom))
+	assert.Error(RegisterCbInitFunc("", custom))
+	assert.Error(RegisterCbInitFunc("other", nil))
+}
+
 func TestLinInit(t *testing.T) {
 	assert := assert.New(t)
 
@@ -164,6 +524,48 @@ func TestLinInit(t *testing.T) {
 	assert.Error(err)
 }
 
+func TestRandPCA(t *testing.T) {
+	assert := assert.New(t)
+
+	// wide data spread along two directions with small noise
+	r := rand.New(rand.NewSource(42))
+	samples, dim := 100, randPCAMinDim+44
+	u, v := make([]float64, dim), make([]float64, dim)
+	for j := 0; j < dim; j++ {
+		u[j], v[j] = r.NormFloat64(), r.NormFloat64()
+	}
+	data := mat.NewDense(samples, dim, nil)
+	for i := 0; i < samples; i++ {
+		a, b := 10*r.NormFloat64(), 5*r.NormFloat64()
+		row := data.RawRowView(i)
+		for j := range row {
+			row[j] = 3.0 + a*u[j] + b*v[j] + 0.01*r.NormFloat64()
+		}
+	}
+	vecs, vals, err := randPCA(data, 2)
+	assert.NoError(err)
+	var pc stat.PC
+	assert.True(pc.PrincipalComponents(data, nil))
+	expVecs := new(mat.Dense)
+	pc.VectorsTo(expVecs)
+	expVals := pc.VarsTo(nil)
+	for i := 0; i < 2; i++ {
+		// principal directions are unique up to their sign
+		assert.InDelta(1.0, math.Abs(mat.Dot(vecs.ColView(i), expVecs.ColView(i))), 1e-6)
+		assert.InDelta(expVals[i], vals[i], 1e-6*expVals[i])
+	}
+	// repeated runs give the same results
+	vecs2, _, err := randPCA(data, 2)
+	assert.NoError(err)
+	assert.True(mat.Equal(vecs, vecs2))
+	// wide data initializes codebook by randomized PCA
+	linMx, err := LinInit(data, []int{3, 2})
+	assert.NoError(err)
+	rows, cols := linMx.Dims()
+	assert.Equal(6, rows)
+	assert.Equal(dim, cols)
+}
+
 func TestGridCoords(t *testing.T) {
 	assert := assert.New(t)
 
@@ -199,6 +601,14 @@ func TestGridCoords(t *testing.T) {
 	assert.NotNil(coords)
 	assert.NoError(err)
 	assert.True(mat.EqualApprox(coords, expMx, 0.01))
+	// 1D hexagon chains are straight lines
+	for _, dims := range [][]int{{1, 3}, {3, 1}} {
+		coords, err = GridCoords("hexagon", dims)
+		assert.NoError(err)
+		rect, err := GridCoords("rectangle", dims)
+		assert.NoError(err)
+		assert.True(mat.Equal(rect, coords))
+	}
 	// incorrect units shape
 	coords, err = GridCoords("fooshape", []int{2, 2})
 	assert.Nil(coords)
@@ -0,0 +1,102 @@
+package som
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// UnitIndex is an inverted index of data rows by their BMUs: it lists the rows mapped to each map unit,
+// so interactive tools can list the records of a unit without finding the BMUs of the whole data set again.
+type UnitIndex struct {
+	// Samples is the number of indexed data rows
+	Samples int `json:"samples"`
+	// Fingerprint is the fingerprint of the indexed data returned by Fingerprint
+	Fingerprint string `json:"fingerprint"`
+	// Units holds ascending indices of the data rows mapped to each map unit
+	Units [][]int `json:"units"`
+}
+
+// UnitIndex builds the inverted index of data rows mapped to map units.
+// It fails with error if data is nil or the BMUs could not be computed.
+func (m *Map) UnitIndex(data *mat.Dense) (*UnitIndex, error) {
+	bmus, err := m.BMUs(data)
+	if err != nil {
+		return nil, err
+	}
+	ix := &UnitIndex{
+		Samples:     len(bmus),
+		Fingerprint: Fingerprint(data),
+		Units:       make([][]int, m.grid.Units()),
+	}
+	for unit := range ix.Units {
+		ix.Units[unit] = []int{}
+	}
+	for row, bmu := range bmus {
+		ix.Units[bmu] = append(ix.Units[bmu], row)
+	}
+
+	return ix, nil
+}
+
+// Rows returns ascending indices of the data rows mapped to map unit.
+// It fails with error if the unit is not indexed.
+func (ix *UnitIndex) Rows(unit int) ([]int, error) {
+	if unit < 0 || unit >= len(ix.Units) {
+		return nil, fmt.Errorf("invalid unit: %d", unit)
+	}
+	return ix.Units[unit], nil
+}
+
+// Validate checks that every indexed row is listed exactly once, in ascending order within its unit.
+// It returns error if the index is not valid.
+func (ix *UnitIndex) Validate() error {
+	if len(ix.Units) == 0 {
+		return fmt.Errorf("index has no units")
+	}
+	if ix.Samples < 0 {
+		return fmt.Errorf("invalid number of samples: %d", ix.Samples)
+	}
+	seen := make([]bool, ix.Samples)
+	for unit, rows := range ix.Units {
+		for k, row := range rows {
+			if row < 0 || row >= ix.Samples {
+				return fmt.Errorf("invalid row %d of unit %d", row, unit)
+			}
+			if seen[row] {
+				return fmt.Errorf("duplicate row %d of unit %d", row, unit)
+			}
+			if k > 0 && row < rows[k-1] {
+				return fmt.Errorf("unsorted rows of unit %d", unit)
+			}
+			seen[row] = true
+		}
+	}
+	for row, ok := range seen {
+		if !ok {
+			return fmt.Errorf("row %d is not indexed", row)
+		}
+	}
+	return nil
+}
+
+// Encode encodes the index to w in JSON format.
+// It fails with error if the write to w fails.
+func (ix *UnitIndex) Encode(w io.Writer) error {
+	return json.NewEncoder(w).Encode(ix)
+}
+
+// DecodeUnitIndex decodes JSON encoded index from r and returns it.
+// It fails with error if the index can not be decoded or is not valid.
+func DecodeUnitIndex(r io.Reader) (*UnitIndex, error) {
+	ix := new(UnitIndex)
+	if err := json.NewDecoder(r).Decode(ix); err != nil {
+		return nil, err
+	}
+	if err := ix.Validate(); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
@@ -0,0 +1,64 @@
+package som
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	assert.NoError(m.Train(tSom, dataMx, 10))
+	ix, err := m.UnitIndex(dataMx)
+	assert.NoError(err)
+	assert.NoError(ix.Validate())
+	assert.Equal(5, ix.Samples)
+	assert.Equal(Fingerprint(dataMx), ix.Fingerprint)
+	assert.Len(ix.Units, m.Grid().Units())
+	// every row is listed by its BMU
+	bmus, err := m.BMUs(dataMx)
+	assert.NoError(err)
+	hits, err := m.Hits(dataMx)
+	assert.NoError(err)
+	for unit := range ix.Units {
+		rows, err := ix.Rows(unit)
+		assert.NoError(err)
+		assert.Len(rows, hits[unit])
+		for _, row := range rows {
+			assert.Equal(unit, bmus[row])
+		}
+	}
+	_, err = ix.Rows(-1)
+	assert.Error(err)
+	_, err = ix.Rows(len(ix.Units))
+	assert.Error(err)
+
+	// encoded index decodes to the same index; empty units are encoded as empty lists
+	buf := new(bytes.Buffer)
+	assert.NoError(ix.Encode(buf))
+	assert.NotContains(buf.String(), "null")
+	dix, err := DecodeUnitIndex(buf)
+	assert.NoError(err)
+	assert.Equal(ix, dix)
+
+	// invalid data and indices
+	_, err = m.UnitIndex(nil)
+	assert.Error(err)
+	for _, s := range []string{
+		`{`,
+		`{"samples":0,"units":[]}`,
+		`{"samples":-1,"units":[[]]}`,
+		`{"samples":2,"units":[[0],[2]]}`,
+		`{"samples":2,"units":[[0,1],[1]]}`,
+		`{"samples":2,"units":[[1,0]]}`,
+		`{"samples":2,"units":[[0],[]]}`,
+	} {
+		_, err := DecodeUnitIndex(strings.NewReader(s))
+		assert.Error(err, s)
+	}
+}
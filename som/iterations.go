@@ -0,0 +1,41 @@
+package som
+
+import (
+	"fmt"
+	"math"
+)
+
+// SuggestIterations suggests the number of training iterations for a map with munits units
+// trained on a data set with a given number of samples using a given training algorithm.
+// It follows the rules of thumb used by SOM Toolbox: the map is trained for ceil(10*munits/samples)
+// epochs in the rough ordering phase and for ceil(40*munits/samples) epochs in the fine-tuning phase,
+// each phase taking at least one epoch. Batch algorithm processes the whole data set in each iteration,
+// so the suggested number of batch iterations is the number of epochs. Sequential algorithm processes
+// one sample per iteration, so it is suggested to run epochs*samples iterations which amounts to at
+// least 50 times the number of map units.
+// It returns error if samples or munits are not positive or if the algorithm is not supported.
+func SuggestIterations(samples, munits int, algorithm string) (int, error) {
+	if samples <= 0 {
+		return 0, fmt.Errorf("invalid number of samples: %d", samples)
+	}
+
+	if munits <= 0 {
+		return 0, fmt.Errorf("invalid number of map units: %d", munits)
+	}
+
+	if _, ok := trainingAlgs[algorithm]; !ok {
+		return 0, fmt.Errorf("invalid SOM training algorithm: %s", algorithm)
+	}
+
+	// map units per data sample
+	mpd := float64(munits) / float64(samples)
+	rough := math.Max(1.0, math.Ceil(10*mpd))
+	fine := math.Max(1.0, math.Ceil(40*mpd))
+	epochs := int(rough + fine)
+
+	if algorithm == "batch" {
+		return epochs, nil
+	}
+
+	return epochs * samples, nil
+}
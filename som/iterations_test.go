@@ -0,0 +1,38 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestIterations(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		samples   int
+		munits    int
+		algorithm string
+		iters     int
+		expErr    bool
+	}{
+		{0, 10, "seq", 0, true},
+		{10, 0, "seq", 0, true},
+		{10, 10, "foobar", 0, true},
+		{100, 10, "batch", 5, false},
+		{100, 10, "seq", 500, false},
+		{100, 50, "batch", 25, false},
+		{100, 50, "seq", 2500, false},
+		{10, 100, "seq", 5000, false},
+	}
+
+	for _, tc := range testCases {
+		iters, err := SuggestIterations(tc.samples, tc.munits, tc.algorithm)
+		if tc.expErr {
+			assert.Error(err)
+			continue
+		}
+		assert.NoError(err)
+		assert.Equal(tc.iters, iters)
+	}
+}
@@ -0,0 +1,126 @@
+package som
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// kohonenTopos maps unit shapes to R kohonen package grid topologies
+var kohonenTopos = map[string]string{
+	"hexagon":   "hexagonal",
+	"rectangle": "rectangular",
+}
+
+// ExportKohonen writes the map to w as R source code which creates an object of R kohonen package.
+// Sourcing the file in R stores the map in variable som of class kohonen which can be explored with
+// kohonen plotting and mapping functions. The object holds the following kohonen structures:
+// - grid: somgrid with unit coordinates, dimensions, topology and toroidal flag
+// - codes: list with a single codebook matrix
+// - unit.classif and distances: BMU indices and distances of data rows, if data is not nil
+// kohonen orders units row by row, so the units and BMU indices are reordered accordingly and
+// indexed from 1. Unit coordinates keep the layout of gosom grid shifted to start at 1.
+// Neighbourhood function is set to bubble if the map was trained with it, otherwise to gaussian.
+// It returns error if the map grid is cylinder or sphere which kohonen does not support, if BMUs of data
+// could not be found or if the write to w fails.
+func (m *Map) ExportKohonen(w io.Writer, data *mat.Dense) error {
+	if m.grid.Type() == "cylinder" || m.grid.Type() == "sphere" {
+		return fmt.Errorf("unsupported kohonen grid type: %s", m.grid.Type())
+	}
+	// kohonen grid dimensions: x is the number of grid columns
+	ydim, xdim := m.grid.Size()[0], 1
+	if len(m.grid.Size()) > 1 {
+		xdim = m.grid.Size()[1]
+	}
+	topo := kohonenTopos[displayShape(m.grid.UShape(), m.grid.Size())]
+	neighb := "gaussian"
+	if m.meta.Train != nil && m.meta.Train.NeighbFn == "bubble" {
+		neighb = "bubble"
+	}
+	// order maps kohonen units to gosom units: gosom units are ordered column by column
+	units := m.grid.Units()
+	order := make([]int, units)
+	rank := make([]int, units)
+	for u := 0; u < units; u++ {
+		k := u/ydim + (u%ydim)*xdim
+		order[k], rank[u] = u, k
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# SOM exported by gosom %s in R kohonen package format.\n", Version)
+	fmt.Fprintf(bw, "# Run source() on this file to load the map into variable som.\n")
+	fmt.Fprintf(bw, "som <- structure(list(\n")
+	// grid
+	coords := m.grid.coordinates()
+	xs, ys := make([]float64, units), make([]float64, units)
+	for k, u := range order {
+		xs[k], ys[k] = coords.At(u, 0)+1, coords.At(u, 1)+1
+		// hexagonal grid rows are sqrt(0.75) apart
+		if topo == "hexagonal" {
+			ys[k] = coords.At(u, 1) + math.Sqrt(0.75)
+		}
+	}
+	fmt.Fprintf(bw, "  grid = structure(list(\n")
+	fmt.Fprintf(bw, "    pts = matrix(c(%s,\n      %s), ncol = 2, dimnames = list(NULL, c(\"x\", \"y\"))),\n", rVector(xs), rVector(ys))
+	fmt.Fprintf(bw, "    xdim = %dL, ydim = %dL, topo = %q,\n", xdim, ydim, topo)
+	fmt.Fprintf(bw, "    neighbourhood.fct = factor(%q, levels = c(\"bubble\", \"gaussian\")),\n", neighb)
+	fmt.Fprintf(bw, "    toroidal = %s), class = \"somgrid\"),\n", rBool(m.grid.Type() == "toroid"))
+	// codes
+	_, dim := m.codebook.Dims()
+	fmt.Fprintf(bw, "  codes = list(matrix(c(\n")
+	for k, u := range order {
+		sep := ","
+		if k == units-1 {
+			sep = ")"
+		}
+		fmt.Fprintf(bw, "    %s%s\n", rVector(m.codebook.RawRowView(u)), sep)
+	}
+	fmt.Fprintf(bw, "    , nrow = %d, byrow = TRUE, dimnames = list(paste0(\"V\", 1:%d), paste0(\"X\", 1:%d)))),\n", units, units, dim)
+	// data classification
+	if data != nil {
+		bmus, err := bmus(m.measure(), data, m.codebook)
+		if err != nil {
+			return err
+		}
+		classif := make([]float64, len(bmus))
+		dists := make([]float64, len(bmus))
+		for i, bmu := range bmus {
+			classif[i] = float64(rank[bmu] + 1)
+			// no need to check for error: BMUs were found in the codebook
+			dists[i], _ = m.measure().distance(data.RawRowView(i), m.codebook.RawRowView(bmu))
+		}
+		fmt.Fprintf(bw, "  unit.classif = c(%s),\n", rVector(classif))
+		fmt.Fprintf(bw, "  distances = c(%s),\n", rVector(dists))
+	}
+	fmt.Fprintf(bw, "  whatmap = 1L, user.weights = 1, distance.weights = 1, dist.fcts = \"euclidean\",\n")
+	fmt.Fprintf(bw, "  maxNA.fraction = 0), class = \"kohonen\")\n")
+
+	return bw.Flush()
+}
+
+// rVector formats vals as comma separated R numeric values
+func rVector(vals []float64) string {
+	s := make([]string, len(vals))
+	for i, v := range vals {
+		// R spells positive infinity without sign
+		if math.IsInf(v, 1) {
+			s[i] = "Inf"
+			continue
+		}
+		s[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return strings.Join(s, ", ")
+}
+
+// rBool formats b as R logical value
+func rBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
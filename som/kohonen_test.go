@@ -0,0 +1,65 @@
+package som
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestExportKohonen(t *testing.T) {
+	assert := assert.New(t)
+
+	mapCfg := &MapConfig{
+		Grid: &GridConfig{Size: []int{2, 3}, Type: "planar", UShape: "rectangle"},
+		Cb:   &CbConfig{Dim: 1, InitFunc: RandInit},
+	}
+	m, err := NewMap(mapCfg, mat.NewDense(1, 1, []float64{0.0}))
+	assert.NoError(err)
+	// codebook values are equal to gosom unit indices
+	for u := 0; u < 6; u++ {
+		m.codebook.Set(u, 0, float64(u))
+	}
+	data := mat.NewDense(2, 1, []float64{1.0, 4.5})
+	var buf bytes.Buffer
+	assert.NoError(m.ExportKohonen(&buf, data))
+	out := buf.String()
+	// gosom units are ordered column by column, kohonen units row by row
+	assert.Contains(out, "pts = matrix(c(1, 2, 3, 1, 2, 3,\n      1, 1, 1, 2, 2, 2)")
+	assert.Contains(out, `xdim = 3L, ydim = 2L, topo = "rectangular"`)
+	assert.Contains(out, "toroidal = FALSE")
+	assert.Contains(out, "codes = list(matrix(c(\n    0,\n    2,\n    4,\n    1,\n    3,\n    5)\n")
+	assert.Contains(out, "nrow = 6, byrow = TRUE")
+	// BMU of 1.0 is gosom unit 1 which is kohonen unit 4, BMU of 4.5 is gosom unit 4
+	assert.Contains(out, "unit.classif = c(4, 3),")
+	assert.Contains(out, "distances = c(0, 0.5),")
+	assert.True(strings.HasSuffix(out, "class = \"kohonen\")\n"))
+	// no data classification without data
+	buf.Reset()
+	assert.NoError(m.ExportKohonen(&buf, nil))
+	assert.NotContains(buf.String(), "unit.classif")
+	// hexagonal toroid
+	mapCfg.Grid.UShape, mapCfg.Grid.Type = "hexagon", "toroid"
+	m, err = NewMap(mapCfg, mat.NewDense(1, 1, []float64{0.0}))
+	assert.NoError(err)
+	buf.Reset()
+	assert.NoError(m.ExportKohonen(&buf, nil))
+	assert.Contains(buf.String(), `topo = "hexagonal"`)
+	assert.Contains(buf.String(), "toroidal = TRUE")
+	// data dimension mismatch
+	assert.Error(m.ExportKohonen(&buf, mat.NewDense(1, 2, nil)))
+	// cylinder is not supported
+	mapCfg.Grid.Type = "cylinder"
+	m, err = NewMap(mapCfg, mat.NewDense(1, 1, []float64{0.0}))
+	assert.NoError(err)
+	assert.Error(m.ExportKohonen(&buf, nil))
+}
+
+func TestRVector(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("1, -0.5, Inf, -Inf, NaN", rVector([]float64{1.0, -0.5, math.Inf(1), math.Inf(-1), math.NaN()}))
+}
@@ -0,0 +1,131 @@
+package som
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// mahalanobis holds the covariance matrix of Mahalanobis metric
+type mahalanobis struct {
+	// cov is covariance matrix of data features
+	cov *mat.SymDense
+	// prec is precision matrix: the inverse of cov
+	prec *mat.SymDense
+}
+
+// newMahalanobis returns Mahalanobis metric of covariance matrix cov.
+// It fails with error if cov is not positive definite.
+func newMahalanobis(cov *mat.SymDense) (*mahalanobis, error) {
+	var chol mat.Cholesky
+	if ok := chol.Factorize(cov); !ok {
+		return nil, fmt.Errorf("covariance matrix is not positive definite: features may be constant or collinear")
+	}
+	prec := new(mat.SymDense)
+	if err := chol.InverseTo(prec); err != nil {
+		return nil, err
+	}
+	c := mat.NewSymDense(cov.Symmetric(), nil)
+	c.CopySym(cov)
+
+	return &mahalanobis{cov: c, prec: prec}, nil
+}
+
+// distance computes Mahalanobis distance between vectors a and b.
+// The dimensions in which either vector has a missing value are skipped.
+func (mh *mahalanobis) distance(a, b []float64) float64 {
+	// precision matrix is symmetric, so only its upper triangle is summed
+	raw := mh.prec.RawSymmetric()
+	d := 0.0
+	for i := 0; i < len(a); i++ {
+		di := a[i] - b[i]
+		if di != di {
+			continue
+		}
+		row := raw.Data[i*raw.Stride:]
+		s := 0.5 * row[i] * di
+		for j := i + 1; j < len(a); j++ {
+			if dj := a[j] - b[j]; dj == dj {
+				s += row[j] * dj
+			}
+		}
+		d += 2 * di * s
+	}
+
+	// rounding errors must not turn tiny distances negative
+	return math.Sqrt(math.Max(d, 0))
+}
+
+// rows returns the covariance matrix as a slice of its rows
+func (mh *mahalanobis) rows() [][]float64 {
+	n := mh.cov.Symmetric()
+	rows := make([][]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, n)
+		for j := range rows[i] {
+			rows[i][j] = mh.cov.At(i, j)
+		}
+	}
+	return rows
+}
+
+// Covariance estimates the covariance matrix of data columns and returns it.
+// Rows with missing values are left out of the estimate.
+// It fails with error if data is nil or if it has fewer than two rows without missing values.
+func Covariance(data *mat.Dense) (*mat.SymDense, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	rows, cols := data.Dims()
+	complete := make([]float64, 0, rows*cols)
+	for i := 0; i < rows; i++ {
+		if row := data.RawRowView(i); !hasMissing(row) {
+			complete = append(complete, row...)
+		}
+	}
+	if len(complete) < 2*cols {
+		return nil, fmt.Errorf("insufficient data rows without missing values: %d", len(complete)/cols)
+	}
+	cov := mat.NewSymDense(cols, nil)
+	stat.CovarianceMatrix(cov, mat.NewDense(len(complete)/cols, cols, complete), nil)
+
+	return cov, nil
+}
+
+// trainMahalanobis returns Mahalanobis metric of training configured by c on data.
+// The covariance of c takes precedence over the covariance estimated from data; if data is nil,
+// the covariance the map was last trained with is used.
+// It fails with error if there is no covariance or if it is invalid.
+func (m *Map) trainMahalanobis(c *TrainConfig, data *mat.Dense) (*mahalanobis, error) {
+	cov := c.Covariance
+	switch {
+	case cov != nil:
+	case data != nil:
+		var err error
+		if cov, err = Covariance(data); err != nil {
+			return nil, err
+		}
+	case m.mh != nil:
+		return m.mh, nil
+	default:
+		return nil, fmt.Errorf("mahalanobis metric requires covariance matrix")
+	}
+	if _, dim := m.codebook.Dims(); cov.Symmetric() != dim {
+		return nil, fmt.Errorf("covariance and codebook dimension mismatch: %d != %d", cov.Symmetric(), dim)
+	}
+
+	return newMahalanobis(cov)
+}
+
+// Covariance returns the covariance matrix of data features used by the map Mahalanobis metric.
+// It returns nil if the map doesn't use Mahalanobis metric.
+func (m *Map) Covariance() *mat.SymDense {
+	if m.mh == nil {
+		return nil
+	}
+	cov := mat.NewSymDense(m.mh.cov.Symmetric(), nil)
+	cov.CopySym(m.mh.cov)
+	return cov
+}
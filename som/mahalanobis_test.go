@@ -0,0 +1,186 @@
+package som
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// correlatedData returns data whose second column is correlated with the first one
+func correlatedData(rows int) *mat.Dense {
+	rnd := rand.New(rand.NewSource(1))
+	data := mat.NewDense(rows, 3, nil)
+	for i := 0; i < rows; i++ {
+		x := rnd.NormFloat64()
+		data.SetRow(i, []float64{x, 2*x + 0.3*rnd.NormFloat64(), rnd.NormFloat64()})
+	}
+	return data
+}
+
+func TestCovariance(t *testing.T) {
+	assert := assert.New(t)
+
+	data := correlatedData(50)
+	exp := mat.NewSymDense(3, nil)
+	stat.CovarianceMatrix(exp, data, nil)
+	cov, err := Covariance(data)
+	assert.NoError(err)
+	assert.True(mat.EqualApprox(exp, cov, 1e-12))
+	// rows with missing values are left out
+	missing := mat.NewDense(51, 3, nil)
+	missing.Slice(0, 50, 0, 3).(*mat.Dense).Copy(data)
+	missing.SetRow(50, []float64{100, math.NaN(), 100})
+	cov, err = Covariance(missing)
+	assert.NoError(err)
+	assert.True(mat.EqualApprox(exp, cov, 1e-12))
+
+	// invalid data
+	_, err = Covariance(nil)
+	assert.Error(err)
+	_, err = Covariance(mat.NewDense(1, 3, nil))
+	assert.Error(err)
+}
+
+func TestMahalanobisDistance(t *testing.T) {
+	assert := assert.New(t)
+
+	// identity covariance computes euclidean distance
+	mh, err := newMahalanobis(mat.NewSymDense(2, []float64{1, 0, 0, 1}))
+	assert.NoError(err)
+	assert.InDelta(5.0, mh.distance([]float64{0, 0}, []float64{3, 4}), 1e-12)
+	// variances scale the coordinate differences
+	mh, err = newMahalanobis(mat.NewSymDense(2, []float64{4, 0, 0, 1}))
+	assert.NoError(err)
+	assert.InDelta(1.0, mh.distance([]float64{0, 0}, []float64{2, 0}), 1e-12)
+	assert.InDelta(2.0, mh.distance([]float64{0, 0}, []float64{0, 2}), 1e-12)
+	// correlated features
+	cov := mat.NewSymDense(3, []float64{2, 1, 0, 1, 2, 0.5, 0, 0.5, 1})
+	mh, err = newMahalanobis(cov)
+	assert.NoError(err)
+	a, b := []float64{1, 2, 3}, []float64{-1, 0.5, 2}
+	var inv mat.Dense
+	assert.NoError(inv.Inverse(cov))
+	diff := mat.NewVecDense(3, []float64{2, 1.5, 1})
+	exp := math.Sqrt(mat.Inner(diff, &inv, diff))
+	assert.InDelta(exp, mh.distance(a, b), 1e-12)
+	assert.InDelta(exp, mh.distance(b, a), 1e-12)
+	assert.Equal(0.0, mh.distance(a, a))
+	// missing values are skipped as if the coordinates were equal
+	assert.InDelta(mh.distance([]float64{1, 0.5, 3}, b), mh.distance([]float64{1, math.NaN(), 3}, b), 1e-12)
+	assert.Equal(cov.RawSymmetric().Data, mat.NewSymDense(3, flatRows(mh.rows())).RawSymmetric().Data)
+
+	// singular covariance
+	_, err = newMahalanobis(mat.NewSymDense(2, []float64{1, 1, 1, 1}))
+	assert.Error(err)
+}
+
+// flatRows returns matrix rows concatenated in a single slice
+func flatRows(rows [][]float64) []float64 {
+	var data []float64
+	for _, row := range rows {
+		data = append(data, row...)
+	}
+	return data
+}
+
+func TestTrainMahalanobis(t *testing.T) {
+	assert := assert.New(t)
+
+	data := correlatedData(50)
+	mc := &MapConfig{
+		Grid: &GridConfig{Size: []int{3, 4}, Type: "planar", UShape: "hexagon"},
+		Cb:   &CbConfig{Dim: 3, InitFunc: RandInit},
+	}
+	cov, err := Covariance(data)
+	assert.NoError(err)
+	mh, err := newMahalanobis(cov)
+	assert.NoError(err)
+	ms := measure{metric: Mahalanobis, mh: mh}
+	for _, alg := range []string{"seq", "batch"} {
+		m, err := NewMap(mc, data)
+		assert.NoError(err)
+		tc := makeDefaultTrainConfig()
+		tc.Algorithm = alg
+		tc.Metric = Mahalanobis
+		assert.NoError(m.Train(tc, data, 50))
+		assert.Equal(Mahalanobis, m.Metric())
+		assert.Equal("mahalanobis", m.Metadata().Train.Metric)
+		// the covariance is estimated from the training data
+		assert.True(mat.EqualApprox(cov, m.Covariance(), 1e-12))
+		// BMUs and quality measures use the covariance
+		got, err := m.BMUs(data)
+		assert.NoError(err)
+		exp, err := bmus(ms, data, m.codebook)
+		assert.NoError(err)
+		assert.Equal(exp, got, alg)
+		qe, err := m.QuantError(data)
+		assert.NoError(err)
+		expQe, err := quantError(ms, data, m.codebook)
+		assert.NoError(err)
+		assert.InDelta(expQe, qe, 1e-9)
+	}
+
+	// supplied identity covariance finds euclidean BMUs
+	m, err := NewMap(mc, data)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Metric = Mahalanobis
+	tc.Covariance = mat.NewSymDense(3, []float64{1, 0, 0, 0, 1, 0, 0, 0, 1})
+	assert.NoError(m.Train(tc, data, 50))
+	assert.True(mat.Equal(tc.Covariance, m.Covariance()))
+	got, err := m.BMUs(data)
+	assert.NoError(err)
+	exp, err := BMUs(data, m.codebook)
+	assert.NoError(err)
+	assert.Equal(exp, got)
+
+	// the covariance is saved in map models
+	tc.Covariance = cov
+	assert.NoError(m.Train(tc, data, 50))
+	buf := new(bytes.Buffer)
+	_, err = m.MarshalTo("som", buf)
+	assert.NoError(err)
+	um := new(Map)
+	_, err = um.UnmarshalFrom("som", buf)
+	assert.NoError(err)
+	assert.Equal(Mahalanobis, um.Metric())
+	assert.True(mat.EqualApprox(cov, um.Covariance(), 1e-12))
+	exp, err = m.BMUs(data)
+	assert.NoError(err)
+	got, err = um.BMUs(data)
+	assert.NoError(err)
+	assert.Equal(exp, got)
+
+	// streamed training uses the covariance the map was trained with
+	tc.Covariance = nil
+	assert.NoError(m.TrainStream(tc, feed(data, 20), 5, 20))
+	assert.True(mat.EqualApprox(cov, m.Covariance(), 1e-12))
+	fresh, err := NewMap(mc, data)
+	assert.NoError(err)
+	assert.Nil(fresh.Covariance())
+	assert.Error(fresh.TrainStream(tc, feed(data, 20), 5, 20))
+	_, err = NewOnlineTrainer(fresh, tc, 20, 5)
+	assert.Error(err)
+	tc.Covariance = cov
+	_, err = NewOnlineTrainer(fresh, tc, 20, 5)
+	assert.NoError(err)
+
+	// invalid covariance and feature weights
+	tc.Covariance = mat.NewSymDense(2, []float64{1, 0, 0, 1})
+	assert.Error(m.Train(tc, data, 10))
+	tc.Covariance = mat.NewSymDense(3, nil)
+	assert.Error(m.Train(tc, data, 10))
+	tc.Covariance = nil
+	tc.FeatureWeights = []float64{1, 2, 1}
+	assert.Error(m.Train(tc, data, 10))
+	// growing networks don't support mahalanobis metric
+	gc := gngConfig()
+	gc.Metric = Mahalanobis
+	_, err = NewGNG(gc, data)
+	assert.Error(err)
+}
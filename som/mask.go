@@ -0,0 +1,92 @@
+package som
+
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// colMask masks data columns in training: carry columns are left out of BMU search, the other columns
+// count in BMU search by their feature weights and fixed columns are left out of codebook updates
+type colMask struct {
+	// weights scales the differences of columns in BMU search; carried columns have zero weight.
+	// It is nil if all the columns have weight 1.
+	weights []float64
+	// fixed marks the columns which are not updated; it is nil if no column is fixed
+	fixed []bool
+}
+
+// validateColMask validates carry and fixed columns and feature weights of training configuration c
+// of data with given dimension. It returns error if any column is out of range, listed more than once or
+// both carried and fixed, if the feature weights are invalid or if no column takes part in BMU search.
+func validateColMask(c *TrainConfig, dim int) error {
+	seen := make(map[int]bool)
+	for _, cols := range [][]int{c.Carry, c.Fixed} {
+		for _, j := range cols {
+			if j < 0 || j >= dim {
+				return fmt.Errorf("invalid column: %d", j)
+			}
+			if seen[j] {
+				return fmt.Errorf("column masked more than once: %d", j)
+			}
+			seen[j] = true
+		}
+	}
+	if len(c.FeatureWeights) > 0 {
+		if err := validateFeatureWeights(c.FeatureWeights, dim); err != nil {
+			return err
+		}
+	}
+	// BMUs can't be found without any searched column
+	if cm := newColMask(c, dim); cm != nil && cm.weights != nil && floats.Max(cm.weights) == 0 {
+		return fmt.Errorf("no column takes part in BMU search")
+	}
+	return nil
+}
+
+// newColMask returns column mask of training configuration c of data with given dimension.
+// It returns nil if c has no carry or fixed columns nor feature weights. The columns must be in range.
+func newColMask(c *TrainConfig, dim int) *colMask {
+	if len(c.Carry) == 0 && len(c.Fixed) == 0 && len(c.FeatureWeights) == 0 {
+		return nil
+	}
+	cm := &colMask{}
+	if len(c.Carry) > 0 || len(c.FeatureWeights) > 0 {
+		cm.weights = make([]float64, dim)
+		for j := range cm.weights {
+			cm.weights[j] = 1.0
+		}
+		copy(cm.weights, c.FeatureWeights)
+		for _, j := range c.Carry {
+			cm.weights[j] = 0.0
+		}
+	}
+	if len(c.Fixed) > 0 {
+		cm.fixed = make([]bool, dim)
+		for _, j := range c.Fixed {
+			cm.fixed[j] = true
+		}
+	}
+	return cm
+}
+
+// closest returns the index of the closest codebook vector to v in a given measure whose metric differences
+// of columns are scaled by the column weights in the same way as by closestWeighted. Missing values are skipped
+// and ties are broken in the same way as by ClosestVec. Custom distance functions can't be weighted.
+// It's safe to call it on nil mask which searches all the columns with the same weight.
+func (cm *colMask) closest(ms measure, v []float64, codebook *mat.Dense) int {
+	if cm == nil || cm.weights == nil {
+		// no need to check for error here:
+		// v and codebook are not nil and have the same dimension
+		bmu, _ := ms.closest(v, codebook)
+		return bmu
+	}
+	return closestWeighted(ms.metric, v, codebook, cm.weights)
+}
+
+// isFixed returns true if j-th column is not updated.
+// It's safe to call it on nil mask which has no fixed columns.
+func (cm *colMask) isFixed(j int) bool {
+	return cm != nil && cm.fixed != nil && cm.fixed[j]
+}
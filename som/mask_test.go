@@ -0,0 +1,141 @@
+package som
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestValidateColMask(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		carry   []int
+		fixed   []int
+		weights []float64
+		valid   bool
+	}{
+		{nil, nil, nil, true},
+		{[]int{2}, []int{0}, nil, true},
+		{[]int{0, 1}, nil, nil, true},
+		{nil, nil, []float64{2, 0, 0.5}, true},
+		{[]int{3}, nil, nil, false},
+		{nil, []int{-1}, nil, false},
+		{[]int{1, 1}, nil, nil, false},
+		{[]int{1}, []int{1}, nil, false},
+		{[]int{0, 1, 2}, nil, nil, false},
+		{nil, nil, []float64{1, 1}, false},
+		{nil, nil, []float64{1, -1, 1}, false},
+		{nil, nil, []float64{0, 0, 0}, false},
+		// the only weighted column is carried
+		{[]int{0}, nil, []float64{1, 0, 0}, false},
+	}
+	for _, tc := range testCases {
+		err := validateColMask(&TrainConfig{Carry: tc.carry, Fixed: tc.fixed, FeatureWeights: tc.weights}, 3)
+		assert.Equal(tc.valid, err == nil, "carry %v, fixed %v, weights %v", tc.carry, tc.fixed, tc.weights)
+	}
+	assert.Nil(newColMask(&TrainConfig{}, 3))
+}
+
+func TestTrainColMask(t *testing.T) {
+	assert := assert.New(t)
+
+	codebook := []float64{
+		0.0, 0.0, 0.0,
+		0.0, 1.0, 0.0,
+		1.0, 0.0, 0.0,
+		1.0, 1.0, 0.0,
+	}
+	// the last column holds row IDs which would dominate BMU search
+	data := mat.NewDense(3, 3, []float64{
+		0.1, 0.1, 100,
+		0.3, 0.1, 200,
+		0.1, 0.9, 50,
+	})
+	train := func(tc *TrainConfig) (*Map, error) {
+		m, err := NewMap(&MapConfig{
+			Grid: &GridConfig{Size: []int{2, 2}, Type: "planar", UShape: "rectangle"},
+			Cb:   &CbConfig{Dim: 3, InitFunc: RandInit},
+		}, data)
+		if err != nil {
+			return nil, err
+		}
+		m.codebook = mat.NewDense(4, 3, append([]float64(nil), codebook...))
+		return m, m.Train(tc, data, 2)
+	}
+
+	testCases := []struct {
+		carry   []int
+		fixed   []int
+		weights []float64
+		exp     []float64
+	}{
+		// carried IDs are averaged by the units the rows map to by their other columns
+		{[]int{2}, nil, nil, []float64{0.2, 0.1, 150, 0.1, 0.9, 50, 1.0, 0.0, 0.0, 1.0, 1.0, 0.0}},
+		// fixed columns keep their initial values
+		{[]int{2}, []int{0}, nil, []float64{0.0, 0.1, 150, 0.0, 0.9, 50, 1.0, 0.0, 0.0, 1.0, 1.0, 0.0}},
+		// zero feature weight leaves the column out of BMU search like carrying it does
+		{nil, nil, []float64{1, 1, 0}, []float64{0.2, 0.1, 150, 0.1, 0.9, 50, 1.0, 0.0, 0.0, 1.0, 1.0, 0.0}},
+	}
+	for _, tc := range testCases {
+		m, err := train(&TrainConfig{
+			Algorithm:      "batch",
+			Radius:         MinRadius,
+			RDecay:         "lin",
+			NeighbFn:       Bubble,
+			LDecay:         "lin",
+			Workers:        2,
+			Carry:          tc.carry,
+			Fixed:          tc.fixed,
+			FeatureWeights: tc.weights,
+		})
+		assert.NoError(err)
+		assert.InDeltaSlice(tc.exp, m.codebook.RawMatrix().Data, 1e-9, "carry %v, fixed %v", tc.carry, tc.fixed)
+		assert.Equal(tc.carry, m.Metadata().Train.Carry)
+		assert.Equal(tc.weights, m.Metadata().Train.FeatureWeights)
+	}
+
+	// sequential training updates carried columns but not the fixed ones
+	tc := makeDefaultTrainConfig()
+	tc.Carry, tc.Fixed = []int{2}, []int{0}
+	m, err := train(tc)
+	assert.NoError(err)
+	for i := 0; i < 4; i++ {
+		assert.Equal(codebook[3*i], m.codebook.At(i, 0))
+	}
+	assert.True(mat.Max(m.codebook.ColView(2)) > 0)
+	assert.Equal([]int{0}, m.Metadata().Train.Fixed)
+
+	// carried columns are skipped by BMU search
+	cm := newColMask(&TrainConfig{Carry: []int{2}}, 3)
+	cb := mat.NewDense(4, 3, append([]float64(nil), codebook...))
+	cb.Set(3, 2, 100)
+	assert.Equal(3, cm.closest(measure{metric: Euclidean}, []float64{0.9, 0.9, math.NaN()}, cb))
+	assert.Equal(1, cm.closest(measure{metric: Euclidean}, []float64{0.1, 0.9, 100}, cb))
+	assert.Equal(3, (*colMask)(nil).closest(measure{metric: Euclidean}, []float64{0.1, 0.9, 100}, cb))
+	// weights scale absolute differences in the other metrics
+	cm = newColMask(&TrainConfig{FeatureWeights: []float64{4, 1, 0}}, 3)
+	v := []float64{0.3, 0.7, 0}
+	assert.Equal(1, cm.closest(measure{metric: Euclidean}, v, cb))
+	assert.Equal(1, cm.closest(measure{metric: Manhattan}, v, cb))
+	// the weighted difference of the first column dominates, so the first of the tied units wins
+	assert.Equal(0, cm.closest(measure{metric: Chebyshev}, v, cb))
+	// weights scale coordinate products in tanimoto metric
+	exp, best := 0, math.Inf(1)
+	for i := 0; i < 4; i++ {
+		if d := tanimoto(v, cb.RawRowView(i), []float64{4, 1, 0}); d < best {
+			exp, best = i, d
+		}
+	}
+	assert.Equal(exp, cm.closest(measure{metric: Tanimoto}, v, cb))
+
+	// invalid columns
+	tc.Carry = []int{3}
+	_, err = train(tc)
+	assert.Error(err)
+	_, err = NewOnlineTrainer(m, tc, 10, 5)
+	assert.Error(err)
+	assert.Error(m.TrainStream(tc, feed(data, 10), 5, 10))
+}
@@ -0,0 +1,187 @@
+package som
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// DistanceFunc computes distance between vectors a and b of the same dimension.
+// Custom distance functions replace the built-in metrics in training and evaluation of maps;
+// they must return non-negative distances which are zero for equal vectors.
+type DistanceFunc func(a, b []float64) float64
+
+// customMetric is the metric name of custom distance functions recorded in training metadata
+const customMetric = "custom"
+
+// measure computes distances between vectors in a metric or by a custom distance function
+type measure struct {
+	// metric is the distance metric used unless fn is set
+	metric Metric
+	// fn is custom distance function; it is nil if metric is used
+	fn DistanceFunc
+	// mh holds the covariance of Mahalanobis metric; without it the metric computes euclidean distance
+	mh *mahalanobis
+	// weights scales the column differences of the metric in the same way as closestWeighted does;
+	// it is nil if all the columns have weight 1. Custom distance functions and Mahalanobis covariance ignore it.
+	weights []float64
+}
+
+// vec returns the function which computes distances of the measure
+func (ms measure) vec() func(a, b []float64) float64 {
+	if dist := ms.exhaustive(); dist != nil {
+		return dist
+	}
+	if weights := ms.weights; weights != nil {
+		return func(a, b []float64) float64 {
+			return weightedDistance(ms.metric, a, b, weights)
+		}
+	}
+	return metricFunc(ms.metric)
+}
+
+// exhaustive returns the function which computes distances of the measure if its closest vectors can only
+// be found by computing the distances to all the vectors. It returns nil if ClosestVec finds them.
+func (ms measure) exhaustive() func(a, b []float64) float64 {
+	switch {
+	case ms.fn != nil:
+		return ms.fn
+	case ms.metric == Mahalanobis && ms.mh != nil:
+		return ms.mh.distance
+	}
+	return nil
+}
+
+// name returns the metric name of the measure recorded in training metadata
+func (ms measure) name() string {
+	if ms.fn != nil {
+		return customMetric
+	}
+	return ms.metric.String()
+}
+
+// distance calculates the distance between vectors a and b.
+// It returns error if the vectors are either nil or have different dimensions.
+func (ms measure) distance(a, b []float64) (float64, error) {
+	if a == nil || b == nil {
+		return 0.0, fmt.Errorf("invalid vectors supplied. a: %v, b: %v", a, b)
+	}
+	if len(a) != len(b) {
+		return 0.0, fmt.Errorf("incorrect vector dims. a: %d, b: %d", len(a), len(b))
+	}
+
+	return ms.vec()(a, b), nil
+}
+
+// row calculates the distances between the vector stored in the i-th row of matrix mat and all mat rows
+// and stores them in dst. It fails in the same way as DistanceRow.
+func (ms measure) row(i int, mat *mat.Dense, dst []float64) ([]float64, error) {
+	if mat == nil {
+		return nil, fmt.Errorf("invalid matrix supplied: %v", mat)
+	}
+
+	rows, _ := mat.Dims()
+	if i < 0 || i >= rows {
+		return nil, fmt.Errorf("invalid row: %d", i)
+	}
+
+	if dst == nil {
+		dst = make([]float64, rows)
+	}
+
+	if len(dst) != rows {
+		return nil, fmt.Errorf("incorrect dst length: %d", len(dst))
+	}
+
+	a, dist := mat.RawRowView(i), ms.vec()
+	for j := 0; j < rows; j++ {
+		// distance of row to itself is zero
+		if j == i {
+			dst[j] = 0.0
+			continue
+		}
+		dst[j] = dist(a, mat.RawRowView(j))
+	}
+
+	return dst, nil
+}
+
+// closest finds the index of the closest row of mat to v. Metrics are searched by ClosestVec and weighted
+// metrics by closestWeighted; custom distance functions and Mahalanobis metric are evaluated for every row,
+// ties are broken in the same way and rows with NaN distances are never closest. It fails in the same way as ClosestVec.
+func (ms measure) closest(v []float64, mat *mat.Dense) (int, error) {
+	dist := ms.exhaustive()
+	if dist == nil && ms.weights == nil {
+		return ClosestVec(ms.metric, v, mat)
+	}
+	if len(v) == 0 {
+		return -1, fmt.Errorf("invalid vector: %v", v)
+	}
+
+	if mat == nil {
+		return -1, fmt.Errorf("invalid matrix: %v", mat)
+	}
+
+	rows, cols := mat.Dims()
+	if rows > 0 && cols != len(v) {
+		return -1, fmt.Errorf("incorrect vector dims. a: %d, b: %d", len(v), cols)
+	}
+
+	if dist == nil {
+		return closestWeighted(ms.metric, v, mat, ms.weights), nil
+	}
+
+	closest, best := 0, math.Inf(1)
+	for i := 0; i < rows; i++ {
+		if d := dist(v, mat.RawRowView(i)); d < best {
+			closest, best = i, d
+		}
+	}
+
+	return closest, nil
+}
+
+// closestN finds the n closest rows of mat to v. It fails in the same way as ClosestNVec.
+func (ms measure) closestN(n int, v []float64, mat *mat.Dense) ([]int, error) {
+	if len(v) == 0 {
+		return nil, fmt.Errorf("invalid vector: %v", v)
+	}
+
+	if mat == nil {
+		return nil, fmt.Errorf("invalid matrix: %v", mat)
+	}
+
+	rows, _ := mat.Dims()
+	if n <= 0 || n > rows {
+		return nil, fmt.Errorf("invalid number of closest vectors requested: %d", n)
+	}
+
+	closest := make([]int, n)
+
+	switch {
+	case n == 1:
+		idx, err := ms.closest(v, mat)
+		if err != nil {
+			return nil, err
+		}
+		closest[0] = idx
+	default:
+		h, _ := newFloat64Heap(n)
+		for i := 0; i < rows; i++ {
+			d, err := ms.distance(v, mat.RawRowView(i))
+			if err != nil {
+				return nil, err
+			}
+			f := &float64Item{val: d, index: i}
+			heap.Push(h, f)
+		}
+
+		for j := 0; j < n; j++ {
+			closest[j] = (heap.Pop(h).(*float64Item)).index
+		}
+	}
+
+	return closest, nil
+}
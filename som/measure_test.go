@@ -0,0 +1,151 @@
+package som
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// l1 is custom distance function which computes the same distances as manhattan metric
+func l1(a, b []float64) float64 {
+	d := 0.0
+	for i := range a {
+		d += math.Abs(a[i] - b[i])
+	}
+	return d
+}
+
+func TestMeasure(t *testing.T) {
+	assert := assert.New(t)
+
+	custom := measure{metric: Euclidean, fn: l1}
+	manhattan := measure{metric: Manhattan}
+	assert.Equal(customMetric, custom.name())
+	assert.Equal("manhattan", manhattan.name())
+
+	// custom functions replace the metric
+	d, err := custom.distance([]float64{0, 0}, []float64{3, 4})
+	assert.NoError(err)
+	assert.Equal(7.0, d)
+	rows, _ := dataMx.Dims()
+	for i := 0; i < rows; i++ {
+		v := dataMx.RawRowView(i)
+		exp, err := manhattan.closest(v, dataMx)
+		assert.NoError(err)
+		closest, err := custom.closest(v, dataMx)
+		assert.NoError(err)
+		assert.Equal(exp, closest)
+		expN, err := manhattan.closestN(3, v, dataMx)
+		assert.NoError(err)
+		closestN, err := custom.closestN(3, v, dataMx)
+		assert.NoError(err)
+		assert.Equal(expN, closestN)
+		expRow, err := manhattan.row(i, dataMx, nil)
+		assert.NoError(err)
+		row, err := custom.row(i, dataMx, nil)
+		assert.NoError(err)
+		assert.InDeltaSlice(expRow, row, 1e-9)
+	}
+	// rows with NaN distances are never closest
+	nan := measure{fn: func(a, b []float64) float64 {
+		if b[0] == 0 {
+			return math.NaN()
+		}
+		return b[0]
+	}}
+	closest, err := nan.closest([]float64{1}, mat.NewDense(3, 1, []float64{0, 2, 1}))
+	assert.NoError(err)
+	assert.Equal(2, closest)
+
+	// custom functions are validated in the same way as metrics
+	_, err = custom.distance(nil, []float64{1})
+	assert.Error(err)
+	_, err = custom.distance([]float64{1, 2}, []float64{1})
+	assert.Error(err)
+	_, err = custom.closest(nil, dataMx)
+	assert.Error(err)
+	_, err = custom.closest([]float64{1}, nil)
+	assert.Error(err)
+	_, err = custom.closest([]float64{1}, dataMx)
+	assert.Error(err)
+	_, err = custom.closestN(10, dataMx.RawRowView(0), dataMx)
+	assert.Error(err)
+	_, err = custom.row(10, dataMx, nil)
+	assert.Error(err)
+}
+
+func TestTrainDistanceFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	manhattan := measure{metric: Manhattan}
+	for _, alg := range []string{"seq", "batch"} {
+		calls := 0
+		counted := func(a, b []float64) float64 {
+			calls++
+			return l1(a, b)
+		}
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		tc := makeDefaultTrainConfig()
+		tc.Algorithm = alg
+		tc.DistanceFn = counted
+		assert.NoError(m.Train(tc, dataMx, 10))
+		assert.True(calls > 0, alg)
+		assert.NotNil(m.DistanceFunc())
+		assert.Equal(customMetric, m.Metadata().Train.Metric)
+		// BMUs, quality measures and u-matrix use the custom function
+		calls = 0
+		got, err := m.BMUs(dataMx)
+		assert.NoError(err)
+		exp, err := bmus(manhattan, dataMx, m.codebook)
+		assert.NoError(err)
+		assert.Equal(exp, got)
+		qe, err := m.QuantError(dataMx)
+		assert.NoError(err)
+		expQe, err := quantError(manhattan, dataMx, m.codebook)
+		assert.NoError(err)
+		assert.InDelta(expQe, qe, 1e-9)
+		assert.NoError(m.UMatrix(new(bytes.Buffer), dataMx, nil, "svg", "custom"))
+		assert.True(calls > 0, alg)
+	}
+
+	// map configuration distance function is used by evaluation and training
+	mc := *mSom
+	mc.DistanceFn = l1
+	m, err := NewMap(&mc, dataMx)
+	assert.NoError(err)
+	qe, err := m.QuantError(dataMx)
+	assert.NoError(err)
+	expQe, err := quantError(measure{metric: Manhattan}, dataMx, m.codebook)
+	assert.NoError(err)
+	assert.InDelta(expQe, qe, 1e-9)
+	tc := makeDefaultTrainConfig()
+	assert.NoError(m.Train(tc, dataMx, 10))
+	assert.Equal(customMetric, m.Metadata().Train.Metric)
+
+	// custom functions can't be weighted
+	tc.FeatureWeights = []float64{1, 2, 1, 1}
+	assert.Error(m.Train(tc, dataMx, 10))
+	assert.Error(m.TrainStream(tc, feed(dataMx, 10), 5, 10))
+	_, err = NewOnlineTrainer(m, tc, 10, 5)
+	assert.Error(err)
+
+	// custom functions are not saved in models
+	buf := new(bytes.Buffer)
+	_, err = m.MarshalTo("som", buf)
+	assert.NoError(err)
+	um := new(Map)
+	_, err = um.UnmarshalFrom("som", buf)
+	assert.NoError(err)
+	assert.Equal(Euclidean, um.Metric())
+	assert.Nil(um.DistanceFunc())
+	um.SetDistanceFunc(l1)
+	qe, err = um.QuantError(dataMx)
+	assert.NoError(err)
+	expQe, err = quantError(measure{metric: Manhattan}, dataMx, um.codebook)
+	assert.NoError(err)
+	assert.InDelta(expQe, qe, 1e-9)
+}
@@ -0,0 +1,73 @@
+package som
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+
+	"github.com/milosgajdos/gosom/pkg/utils"
+)
+
+// float64Size is the size of float64 in bytes
+const float64Size = 8
+
+// intSize is the size of int in bytes
+const intSize = strconv.IntSize / 8
+
+// Footprint holds estimated memory footprint of SOM in bytes
+type Footprint struct {
+	// Codebook is the size of codebook matrix
+	Codebook int64
+	// Grid is the size of grid coordinates matrix
+	Grid int64
+	// UnitDist is the size of unit distance matrix
+	UnitDist int64
+	// Workers is the size of batch training worker buffers
+	Workers int64
+}
+
+// Total returns total estimated memory footprint in bytes
+func (f *Footprint) Total() int64 {
+	return f.Codebook + f.Grid + f.UnitDist + f.Workers
+}
+
+// EstimateFootprint estimates memory footprint of SOM with grid of a given size and codebook vectors
+// of dim dimension when trained using a given training algorithm. Batch training allocates buffers
+// for each worker; if workers is not positive, the number of CPUs is used as the map training does.
+// The buffers of per-feature neighbourhoods are only allocated when the data have missing values,
+// but they are always included, so the estimate is an upper bound.
+// Unit distance matrix grows quadratically with the number of map units which makes it dominate
+// the footprint of large maps.
+// It returns error if any of the supplied parameters is invalid.
+func EstimateFootprint(size []int, dim int, algorithm string, workers int) (*Footprint, error) {
+	if err := validateGridSize(size); err != nil {
+		return nil, err
+	}
+
+	if dim <= 0 {
+		return nil, fmt.Errorf("invalid codebook dimension: %d", dim)
+	}
+
+	if _, ok := trainingAlgs[algorithm]; !ok {
+		return nil, fmt.Errorf("invalid SOM training algorithm: %s", algorithm)
+	}
+
+	units := int64(utils.IntProduct(size))
+	f := &Footprint{
+		Codebook: units * int64(dim) * float64Size,
+		Grid:     units * int64(len(size)) * float64Size,
+		UnitDist: units * units * float64Size,
+	}
+
+	if algorithm == "batch" {
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		// each worker accumulator holds scaled vectors, per-feature neighbourhoods, neighbourhoods,
+		// hits and wins; one more accumulator collects the results of all workers
+		acc := 2*units*int64(dim)*float64Size + units*float64Size + units + units*intSize
+		f.Workers = int64(workers+1) * acc
+	}
+
+	return f, nil
+}
@@ -0,0 +1,53 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateFootprint(t *testing.T) {
+	assert := assert.New(t)
+
+	// invalid parameters
+	f, err := EstimateFootprint([]int{-1, 2}, 4, "seq", 0)
+	assert.Nil(f)
+	assert.Error(err)
+	f, err = EstimateFootprint([]int{2, 3}, 0, "seq", 0)
+	assert.Nil(f)
+	assert.Error(err)
+	f, err = EstimateFootprint([]int{2, 3}, 4, "foobar", 0)
+	assert.Nil(f)
+	assert.Error(err)
+	// sequential training requires no worker buffers
+	f, err = EstimateFootprint([]int{2, 3}, 4, "seq", 0)
+	assert.NoError(err)
+	assert.Equal(int64(6*4*8), f.Codebook)
+	assert.Equal(int64(6*2*8), f.Grid)
+	assert.Equal(int64(6*6*8), f.UnitDist)
+	assert.Equal(int64(0), f.Workers)
+	assert.Equal(f.Codebook+f.Grid+f.UnitDist, f.Total())
+	// batch training allocates an accumulator per worker
+	f, err = EstimateFootprint([]int{2, 3}, 4, "batch", 2)
+	assert.NoError(err)
+	assert.Equal(int64(3*(2*6*4*8+6*8+6+6*intSize)), f.Workers)
+	// the estimate matches the size of allocated accumulators
+	codebook, err := RandInit(dataMx, []int{2, 3})
+	assert.NoError(err)
+	assert.Equal(f.Workers, accsSize(newBatchAccs(3, codebook)))
+	// 500x500 unit map is dominated by unit distances
+	f, err = EstimateFootprint([]int{500, 500}, 10, "seq", 0)
+	assert.NoError(err)
+	assert.Equal(int64(500*500*500*500*8), f.UnitDist)
+}
+
+// accsSize returns the size of batch accumulators in bytes including their lazily allocated buffers
+func accsSize(accs []*batchAcc) int64 {
+	var size int64
+	for _, acc := range accs {
+		acc.mask()
+		size += int64(len(acc.vecs.RawMatrix().Data)+len(acc.dens.RawMatrix().Data)+len(acc.nghbs)) * float64Size
+		size += int64(len(acc.hits)) + int64(len(acc.wins))*intSize
+	}
+	return size
+}
@@ -0,0 +1,86 @@
+package som
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// hasMissing returns true if any of the values is missing i.e. NaN
+func hasMissing(vals []float64) bool {
+	for _, v := range vals {
+		if math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// dataMissing returns true if any data row has missing values
+func dataMissing(data *mat.Dense) bool {
+	rows, _ := data.Dims()
+	for i := 0; i < rows; i++ {
+		if hasMissing(data.RawRowView(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// colRange returns the minimum and maximum of the values of j-th data column which are not missing.
+// Both are zero if all the values of the column are missing.
+func colRange(data *mat.Dense, j int) (float64, float64) {
+	rows, _ := data.Dims()
+	min, max := math.Inf(1), math.Inf(-1)
+	for i := 0; i < rows; i++ {
+		if x := data.At(i, j); !math.IsNaN(x) {
+			min, max = math.Min(min, x), math.Max(max, x)
+		}
+	}
+	if min > max {
+		return 0.0, 0.0
+	}
+	return min, max
+}
+
+// fillMissing replaces missing values in dst with the means of the values of data columns
+// which are not missing. The values of columns which only hold missing values are set to zero.
+func fillMissing(dst, data *mat.Dense) {
+	rows, cols := dst.Dims()
+	var means []float64
+	for i := 0; i < rows; i++ {
+		row := dst.RawRowView(i)
+		if !hasMissing(row) {
+			continue
+		}
+		if means == nil {
+			means = colsPresentMean(data, cols)
+		}
+		for j, x := range row {
+			if math.IsNaN(x) {
+				row[j] = means[j]
+			}
+		}
+	}
+}
+
+// colsPresentMean returns the means of the values of cols data columns which are not missing
+func colsPresentMean(data *mat.Dense, cols int) []float64 {
+	rows, _ := data.Dims()
+	means := make([]float64, cols)
+	counts := make([]int, cols)
+	for i := 0; i < rows; i++ {
+		for j, x := range data.RawRowView(i)[:cols] {
+			if !math.IsNaN(x) {
+				means[j] += x
+				counts[j]++
+			}
+		}
+	}
+	for j, n := range counts {
+		if n > 0 {
+			means[j] /= float64(n)
+		}
+	}
+	return means
+}
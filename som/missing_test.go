@@ -0,0 +1,102 @@
+package som
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestTrainMissing(t *testing.T) {
+	assert := assert.New(t)
+
+	nan := math.NaN()
+	codebook := []float64{
+		0.0, 0.0,
+		0.0, 1.0,
+		1.0, 0.0,
+		1.0, 1.0,
+	}
+	// the first two rows map to unit 0 and the last one to unit 1 by its present value
+	data := mat.NewDense(3, 2, []float64{
+		0.1, 0.1,
+		0.3, nan,
+		nan, 0.9,
+	})
+	newMap := func() (*Map, error) {
+		m, err := NewMap(&MapConfig{
+			Grid: &GridConfig{Size: []int{2, 2}, Type: "planar", UShape: "rectangle"},
+			Cb:   &CbConfig{Dim: 2, InitFunc: RandInit},
+		}, data)
+		if err != nil {
+			return nil, err
+		}
+		m.codebook = mat.NewDense(4, 2, append([]float64(nil), codebook...))
+		return m, nil
+	}
+
+	// the radius doesn't reach beyond BMUs, so their vectors become the means of their present values;
+	// missing features of all the rows of unit 1 keep their values
+	exp := []float64{0.2, 0.1, 0.0, 0.9, 1.0, 0.0, 1.0, 1.0}
+	for _, workers := range []int{1, 2, 3} {
+		m, err := newMap()
+		assert.NoError(err)
+		tc := &TrainConfig{
+			Algorithm:   "batch",
+			Radius:      MinRadius,
+			RDecay:      "lin",
+			NeighbFn:    Bubble,
+			LDecay:      "lin",
+			Workers:     workers,
+			CheckFinite: true,
+		}
+		assert.NoError(m.Train(tc, data, 2))
+		assert.InDeltaSlice(exp, m.codebook.RawMatrix().Data, 1e-9, "workers %d", workers)
+	}
+
+	// missing sample values don't update their elements
+	m, err := newMap()
+	assert.NoError(err)
+	m.seqUpdateCbVec(3, []float64{nan, 0.5}, 0.5, 1.0, 0.0, Gaussian, nil, nil)
+	assert.Equal([]float64{1.0, 0.75}, m.codebook.RawRowView(3))
+
+	// sequential training keeps the codebook finite
+	m, err = newMap()
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.CheckFinite = true
+	assert.NoError(m.Train(tc, data, 20))
+	qe, err := m.QuantError(data)
+	assert.NoError(err)
+	assert.False(math.IsNaN(qe))
+}
+
+func TestInitMissing(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(4, 3, []float64{
+		1.0, math.NaN(), math.NaN(),
+		2.0, 4.0, math.NaN(),
+		math.NaN(), 2.0, math.NaN(),
+		3.0, 6.0, math.NaN(),
+	})
+	for _, init := range []CbInitFunc{RandInit, LinInit, SampleInit} {
+		codebook, err := init(data, []int{2, 3})
+		assert.NoError(err)
+		assert.True(isFinite(codebook.RawMatrix().Data))
+	}
+	// column ranges leave out the missing values
+	codebook, err := RandInit(data, []int{2, 3})
+	assert.NoError(err)
+	for i := 0; i < 6; i++ {
+		row := codebook.RawRowView(i)
+		assert.True(row[0] >= 1.0 && row[0] <= 3.0)
+		assert.True(row[1] >= 2.0 && row[1] <= 6.0)
+		assert.Equal(0.0, row[2])
+	}
+	// missing values are filled with column means
+	dst := mat.DenseCopyOf(data)
+	fillMissing(dst, data)
+	assert.Equal([]float64{1.0, 4.0, 0.0, 2.0, 4.0, 0.0, 2.0, 2.0, 0.0, 3.0, 6.0, 0.0}, dst.RawMatrix().Data)
+}
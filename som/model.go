@@ -0,0 +1,332 @@
+package som
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	// Version is gosom version recorded in serialized models
+	Version = "0.2.0"
+	// ModelFormatVersion is the current version of som model format
+	ModelFormatVersion = 1
+)
+
+// modelMagic identifies SOM model encoded in som format
+var modelMagic = [4]byte{'G', 'S', 'O', 'M'}
+
+// maxHeaderSize is the maximum size of JSON header of som models in bytes;
+// it keeps corrupted header sizes from allocating nonsensical amounts of memory
+const maxHeaderSize = 1 << 26
+
+// neighbFuncNames maps builtin neighbourhood functions to their names
+var neighbFuncNames = map[uintptr]string{
+	reflect.ValueOf(Gaussian).Pointer():   "gaussian",
+	reflect.ValueOf(Bubble).Pointer():     "bubble",
+	reflect.ValueOf(MexicanHat).Pointer(): "mexican",
+}
+
+// Metadata holds SOM model version and provenance metadata
+type Metadata struct {
+	// FormatVersion is the version of model format
+	FormatVersion int `json:"format_version"`
+	// Version is gosom version which created the model
+	Version string `json:"version"`
+	// Created is the time the map was created
+	Created time.Time `json:"created"`
+	// Metric is the distance metric of the map; custom distance functions are not recorded
+	Metric string `json:"metric,omitempty"`
+	// Trained is the time the map training finished
+	Trained *time.Time `json:"trained,omitempty"`
+	// Train holds the configuration of the last training
+	Train *TrainMetadata `json:"train,omitempty"`
+	// DataFingerprint is SHA-256 fingerprint of the last training data
+	DataFingerprint string `json:"data_fingerprint,omitempty"`
+	// Supervised holds configuration of supervised maps created by NewSupervisedMap
+	Supervised *SupervisedMetadata `json:"supervised,omitempty"`
+}
+
+// TrainMetadata holds serializable SOM training configuration
+type TrainMetadata struct {
+	// Algorithm is training algorithm
+	Algorithm string `json:"algorithm"`
+	// Radius is initial SOM units radius
+	Radius float64 `json:"radius"`
+	// RDecay is radius decay strategy
+	RDecay string `json:"rdecay"`
+	// NeighbFn is the name of neighbourhood function or "custom"
+	NeighbFn string `json:"neighb"`
+	// LRate is initial SOM learning rate
+	LRate float64 `json:"lrate"`
+	// LDecay is learning rate decay strategy
+	LDecay string `json:"ldecay"`
+	// Iterations is the number of training iterations
+	Iterations int `json:"iterations"`
+	// Metric is the name of distance metric used to find BMUs
+	Metric string `json:"metric,omitempty"`
+	// Empty is batch training update of units outside of the radius of all BMUs
+	Empty string `json:"empty,omitempty"`
+	// MinNghb is the minimum neighbourhood sum of batch training updates
+	MinNghb float64 `json:"min_nghb,omitempty"`
+	// Carry holds indices of data columns left out of BMU search
+	Carry []int `json:"carry,omitempty"`
+	// Fixed holds indices of data columns which were not updated
+	Fixed []int `json:"fixed,omitempty"`
+	// FeatureWeights holds weights of data columns in BMU search
+	FeatureWeights []float64 `json:"feature_weights,omitempty"`
+	// Subsample is the fraction of data rows sampled in each epoch
+	Subsample float64 `json:"subsample,omitempty"`
+	// Delay is the number of samples whose sequential updates are applied at once
+	Delay int `json:"delay,omitempty"`
+	// Covariance holds the rows of covariance matrix of Mahalanobis metric
+	Covariance [][]float64 `json:"covariance,omitempty"`
+}
+
+// newTrainMetadata returns training metadata for a given training config and number of iterations
+func newTrainMetadata(c *TrainConfig, iters int) *TrainMetadata {
+	name, ok := neighbFuncNames[reflect.ValueOf(c.NeighbFn).Pointer()]
+	if !ok {
+		name = "custom"
+	}
+	return &TrainMetadata{
+		Algorithm:      c.Algorithm,
+		Radius:         c.Radius,
+		RDecay:         c.RDecay,
+		NeighbFn:       name,
+		LRate:          c.LRate,
+		LDecay:         c.LDecay,
+		Iterations:     iters,
+		Metric:         c.Metric.String(),
+		Empty:          c.Empty,
+		MinNghb:        c.MinNghb,
+		Carry:          append([]int(nil), c.Carry...),
+		Fixed:          append([]int(nil), c.Fixed...),
+		FeatureWeights: append([]float64(nil), c.FeatureWeights...),
+		Subsample:      c.Subsample,
+		Delay:          c.Delay,
+	}
+}
+
+// Fingerprint returns hex encoded SHA-256 fingerprint of data matrix dimensions and values
+func Fingerprint(data mat.Matrix) string {
+	h := sha256.New()
+	rows, cols := data.Dims()
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(rows))
+	h.Write(buf)
+	binary.LittleEndian.PutUint64(buf, uint64(cols))
+	h.Write(buf)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			binary.LittleEndian.PutUint64(buf, math.Float64bits(data.At(i, j)))
+			h.Write(buf)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// modelHeader is SOM model header stored in som format
+type modelHeader struct {
+	// Grid is SOM grid configuration
+	Grid *GridConfig `json:"grid"`
+	// Meta is SOM model metadata
+	Meta *Metadata `json:"meta,omitempty"`
+}
+
+// migrate validates model metadata and migrates it to the current model format version.
+// It fails with error if the model format version is not supported.
+func (h *modelHeader) migrate() error {
+	// models of format version 0 don't carry any metadata
+	if h.Meta == nil {
+		h.Meta = &Metadata{Version: "unknown"}
+	}
+	if h.Meta.FormatVersion > ModelFormatVersion {
+		return fmt.Errorf("unsupported model format version: %d", h.Meta.FormatVersion)
+	}
+	h.Meta.FormatVersion = ModelFormatVersion
+	return nil
+}
+
+// marshalModel encodes SOM in som format and writes it to w.
+// The som format consists of a magic number, a length prefixed JSON header which holds
+// the grid configuration and model metadata and the codebook encoded in gonum binary format.
+// It returns the number of bytes written to w or fails with error.
+func (m *Map) marshalModel(w io.Writer) (int, error) {
+	meta := m.Metadata()
+	header, err := json.Marshal(&modelHeader{
+		Grid: &GridConfig{
+			Size:   m.grid.size,
+			Type:   m.grid.gtype,
+			UShape: m.grid.ushape,
+		},
+		Meta: &meta,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(header) > maxHeaderSize {
+		return 0, fmt.Errorf("model header too large: %d", len(header))
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(modelMagic[:])
+	// no need to check for error: writes to bytes.Buffer never fail
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(header)))
+	buf.Write(header)
+	n, err := w.Write(buf.Bytes())
+	if err != nil {
+		return n, err
+	}
+	c, err := m.codebook.MarshalBinaryTo(w)
+
+	return n + c, err
+}
+
+// unmarshalModel decodes SOM encoded in som format from r into m.
+// It returns the number of bytes read from r or fails with error.
+func (m *Map) unmarshalModel(r io.Reader) (int, error) {
+	var magic [4]byte
+	n, err := io.ReadFull(r, magic[:])
+	if err != nil {
+		return n, err
+	}
+	if magic != modelMagic {
+		return n, fmt.Errorf("invalid model magic: %q", magic[:])
+	}
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return n, err
+	}
+	n += 4
+	if size > maxHeaderSize {
+		return n, fmt.Errorf("invalid model header size: %d", size)
+	}
+	header := make([]byte, size)
+	c, err := io.ReadFull(r, header)
+	n += c
+	if err != nil {
+		return n, err
+	}
+	h := new(modelHeader)
+	if err := json.Unmarshal(header, h); err != nil {
+		return n, err
+	}
+	if h.Grid == nil {
+		return n, fmt.Errorf("missing grid configuration")
+	}
+	if err := h.migrate(); err != nil {
+		return n, err
+	}
+	grid, err := NewGrid(h.Grid)
+	if err != nil {
+		return n, err
+	}
+	codebook := new(mat.Dense)
+	c, err = codebook.UnmarshalBinaryFrom(r)
+	n += c
+	if err != nil {
+		return n, err
+	}
+	units := grid.Units()
+	if rows, _ := codebook.Dims(); rows != units {
+		return n, fmt.Errorf("codebook and grid dimension mismatch: %d != %d", rows, units)
+	}
+	// restore the map metric; models which don't record it use the metric the map was trained with
+	// and custom distance functions are not saved
+	name := h.Meta.Metric
+	if name == "" && h.Meta.Train != nil && h.Meta.Train.Metric != customMetric {
+		name = h.Meta.Train.Metric
+	}
+	metric := Euclidean
+	if name != "" {
+		if metric, err = ParseMetric(name); err != nil {
+			return n, err
+		}
+	}
+	// mahalanobis metric is restored from its covariance; without it the metric computes euclidean distance
+	var mh *mahalanobis
+	if metric == Mahalanobis && h.Meta.Train != nil && h.Meta.Train.Covariance != nil {
+		dim := len(h.Meta.Train.Covariance)
+		if _, cols := codebook.Dims(); dim != cols {
+			return n, fmt.Errorf("invalid covariance dimension: %d", dim)
+		}
+		cov := mat.NewSymDense(dim, nil)
+		for i, row := range h.Meta.Train.Covariance {
+			if len(row) != dim {
+				return n, fmt.Errorf("invalid covariance row dimension: %d", len(row))
+			}
+			for j := i; j < dim; j++ {
+				cov.SetSym(i, j, row[j])
+			}
+		}
+		if mh, err = newMahalanobis(cov); err != nil {
+			return n, err
+		}
+	}
+	// carried columns and feature weights are restored from the training columns mask
+	var cm *colMask
+	if t := h.Meta.Train; t != nil {
+		_, dim := codebook.Dims()
+		tc := &TrainConfig{Carry: t.Carry, Fixed: t.Fixed, FeatureWeights: t.FeatureWeights}
+		if err := validateColMask(tc, dim); err != nil {
+			return n, err
+		}
+		cm = newColMask(tc, dim)
+	}
+	m.codebook = codebook
+	m.grid = grid
+	m.resetBuffers()
+	m.meta = *h.Meta
+	m.metric = metric
+	m.mh = mh
+	m.cm = cm
+
+	return n, nil
+}
+
+// readCodebook reads codebook matrix encoded in gonum binary format from r.
+// If legacy is true or if r does not start with gonum binary header, the codebook
+// is decoded from the legacy mat64 binary format: number of rows and columns
+// encoded as little-endian int64 followed by row-major little-endian float64 data.
+// It returns the number of bytes read from r or fails with error.
+func readCodebook(r io.Reader, legacy bool) (*mat.Dense, int, error) {
+	head := make([]byte, 8)
+	n, err := io.ReadFull(r, head)
+	if err != nil {
+		return nil, n, err
+	}
+	// gonum binary format starts with version 1 followed by 'G', 'F', 'A' type encoding
+	if !legacy && binary.LittleEndian.Uint32(head[:4]) == 1 && bytes.Equal(head[4:7], []byte("GFA")) {
+		codebook := new(mat.Dense)
+		c, err := codebook.UnmarshalBinaryFrom(io.MultiReader(bytes.NewReader(head), r))
+		return codebook, c, err
+	}
+	rows := int64(binary.LittleEndian.Uint64(head))
+	var cols int64
+	if err := binary.Read(r, binary.LittleEndian, &cols); err != nil {
+		return nil, n, err
+	}
+	n += 8
+	// avoid allocating nonsensical amounts of memory on corrupted input
+	const maxElems = math.MaxInt32
+	if rows <= 0 || cols <= 0 || rows > maxElems/cols {
+		return nil, n, fmt.Errorf("invalid legacy codebook dimensions: %d x %d", rows, cols)
+	}
+	data := make([]float64, rows*cols)
+	if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+		return nil, n, err
+	}
+	n += 8 * len(data)
+
+	return mat.NewDense(int(rows), int(cols), data), n, nil
+}
@@ -0,0 +1,214 @@
+package som
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestMarshalUnmarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	// unsupported format
+	buf := new(bytes.Buffer)
+	n, err := m.MarshalTo("foo", buf)
+	assert.Equal(0, n)
+	assert.Error(err)
+	n, err = new(Map).UnmarshalFrom("foo", buf)
+	assert.Equal(0, n)
+	assert.Error(err)
+
+	for _, format := range []string{"gonum", "som"} {
+		buf := new(bytes.Buffer)
+		n, err := m.MarshalTo(format, buf)
+		assert.NoError(err)
+		assert.Equal(buf.Len(), n)
+		um := new(Map)
+		c, err := um.UnmarshalFrom(format, buf)
+		assert.NoError(err)
+		assert.Equal(n, c)
+		assert.True(mat.Equal(m.Codebook(), um.Codebook()))
+		if format == "som" {
+			assert.Equal(m.Grid().Size(), um.Grid().Size())
+			assert.Equal(m.Grid().UShape(), um.Grid().UShape())
+			assert.Equal(m.Grid().Type(), um.Grid().Type())
+			assert.Equal(m.Metric(), um.Metric())
+			assert.True(mat.Equal(m.Grid().Coords(), um.Grid().Coords()))
+		}
+	}
+	// invalid model magic
+	_, err = new(Map).UnmarshalFrom("som", bytes.NewBufferString("FOOBAR"))
+	assert.Error(err)
+	// truncated model
+	buf.Reset()
+	_, err = m.MarshalTo("som", buf)
+	assert.NoError(err)
+	_, err = new(Map).UnmarshalFrom("som", bytes.NewReader(buf.Bytes()[:buf.Len()-8]))
+	assert.Error(err)
+	// huge header size of corrupted model
+	_, err = new(Map).UnmarshalFrom("som", bytes.NewReader(append(modelMagic[:], 0xff, 0xff, 0xff, 0xff)))
+	assert.EqualError(err, fmt.Sprintf("invalid model header size: %d", uint32(math.MaxUint32)))
+
+	// models loaded into preallocated maps of a different size don't reuse their buffers
+	big, err := NewMap(&MapConfig{
+		Grid: &GridConfig{Size: []int{4, 6}, Type: "planar", UShape: "hexagon"},
+		Cb:   mSom.Cb,
+	}, dataMx)
+	assert.NoError(err)
+	buf.Reset()
+	_, err = big.MarshalTo("som", buf)
+	assert.NoError(err)
+	pc := *mSom
+	pc.Prealloc = true
+	pm, err := NewMap(&pc, dataMx)
+	assert.NoError(err)
+	_, err = pm.UnmarshalFrom("som", buf)
+	assert.NoError(err)
+	assert.Nil(pm.unitDist)
+	assert.Nil(pm.accs)
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	assert.NoError(pm.Train(tc, dataMx, 5))
+	assert.Len(pm.Wins(), 24)
+}
+
+func TestModelMetadata(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	meta := m.Metadata()
+	assert.Equal(ModelFormatVersion, meta.FormatVersion)
+	assert.Equal(Version, meta.Version)
+	assert.False(meta.Created.IsZero())
+	assert.Nil(meta.Trained)
+	assert.Nil(meta.Train)
+	// training records provenance
+	err = m.Train(tSom, dataMx, 10)
+	assert.NoError(err)
+	meta = m.Metadata()
+	assert.NotNil(meta.Trained)
+	assert.Equal("gaussian", meta.Train.NeighbFn)
+	assert.Equal(10, meta.Train.Iterations)
+	assert.Equal("euclidean", meta.Train.Metric)
+	assert.Equal(Fingerprint(dataMx), meta.DataFingerprint)
+	// metadata survive serialization
+	buf := new(bytes.Buffer)
+	_, err = m.MarshalTo("som", buf)
+	assert.NoError(err)
+	um := new(Map)
+	_, err = um.UnmarshalFrom("som", buf)
+	assert.NoError(err)
+	umeta := um.Metadata()
+	assert.True(meta.Created.Equal(umeta.Created))
+	assert.True(meta.Trained.Equal(*umeta.Trained))
+	assert.Equal(meta.Train, umeta.Train)
+	assert.Equal(meta.DataFingerprint, umeta.DataFingerprint)
+	assert.Equal("euclidean", umeta.Metric)
+}
+
+func TestModelMetric(t *testing.T) {
+	assert := assert.New(t)
+
+	roundTrip := func(m *Map) *Map {
+		buf := new(bytes.Buffer)
+		_, err := m.MarshalTo("som", buf)
+		assert.NoError(err)
+		um := new(Map)
+		_, err = um.UnmarshalFrom("som", buf)
+		assert.NoError(err)
+		return um
+	}
+	// untrained maps keep the metric of their configuration
+	c := *mSom
+	c.Metric = Manhattan
+	m, err := NewMap(&c, dataMx)
+	assert.NoError(err)
+	assert.Equal("manhattan", m.Metadata().Metric)
+	assert.Equal(Manhattan, roundTrip(m).Metric())
+	// metric set after training replaces the training metric
+	assert.NoError(m.Train(makeDefaultTrainConfig(), dataMx, 10))
+	assert.NoError(m.SetMetric(Chebyshev))
+	assert.Equal(Chebyshev, roundTrip(m).Metric())
+	// mahalanobis metric without covariance computes euclidean distances
+	assert.NoError(m.SetMetric(Mahalanobis))
+	um := roundTrip(m)
+	assert.Equal(Mahalanobis, um.Metric())
+	exp, err := m.QuantError(dataMx)
+	assert.NoError(err)
+	qe, err := um.QuantError(dataMx)
+	assert.NoError(err)
+	assert.Equal(exp, qe)
+	// models which don't record the map metric use the training metric
+	m.meta.Train.Metric = "tanimoto"
+	m.metric = Euclidean
+	buf := new(bytes.Buffer)
+	meta := m.meta
+	b, err := json.Marshal(&modelHeader{Grid: mSom.Grid, Meta: &meta})
+	assert.NoError(err)
+	buf.Write(modelMagic[:])
+	assert.NoError(binary.Write(buf, binary.LittleEndian, uint32(len(b))))
+	buf.Write(b)
+	_, err = m.codebook.MarshalBinaryTo(buf)
+	assert.NoError(err)
+	um = new(Map)
+	_, err = um.UnmarshalFrom("som", buf)
+	assert.NoError(err)
+	assert.Equal(Tanimoto, um.Metric())
+}
+
+func TestModelMigrate(t *testing.T) {
+	assert := assert.New(t)
+
+	// models without metadata are migrated
+	h := &modelHeader{Grid: mSom.Grid}
+	assert.NoError(h.migrate())
+	assert.Equal(ModelFormatVersion, h.Meta.FormatVersion)
+	assert.Equal("unknown", h.Meta.Version)
+	// models of newer format versions are rejected
+	h.Meta.FormatVersion = ModelFormatVersion + 1
+	assert.Error(h.migrate())
+}
+
+func TestFingerprint(t *testing.T) {
+	assert := assert.New(t)
+
+	a := mat.NewDense(2, 2, []float64{1, 2, 3, 4})
+	b := mat.NewDense(1, 4, []float64{1, 2, 3, 4})
+	assert.Len(Fingerprint(a), 64)
+	assert.Equal(Fingerprint(a), Fingerprint(mat.DenseCopyOf(a)))
+	assert.NotEqual(Fingerprint(a), Fingerprint(b))
+}
+
+func TestUnmarshalLegacy(t *testing.T) {
+	assert := assert.New(t)
+
+	codebook := mat.NewDense(2, 3, []float64{1.0, 2.0, 3.0, 4.0, 5.0, 6.0})
+	// legacy mat64 layout: rows, cols and row-major data
+	buf := new(bytes.Buffer)
+	assert.NoError(binary.Write(buf, binary.LittleEndian, int64(2)))
+	assert.NoError(binary.Write(buf, binary.LittleEndian, int64(3)))
+	assert.NoError(binary.Write(buf, binary.LittleEndian, codebook.RawMatrix().Data))
+	legacy := buf.Bytes()
+	for _, format := range []string{"gonum", "mat64"} {
+		m := new(Map)
+		n, err := m.UnmarshalFrom(format, bytes.NewReader(legacy))
+		assert.NoError(err)
+		assert.Equal(len(legacy), n)
+		assert.True(mat.Equal(codebook, m.Codebook()))
+	}
+	// truncated legacy data
+	_, err := new(Map).UnmarshalFrom("mat64", bytes.NewReader(legacy[:len(legacy)-1]))
+	assert.Error(err)
+	// invalid legacy dimensions
+	_, err = new(Map).UnmarshalFrom("mat64", bytes.NewReader(make([]byte, 16)))
+	assert.Error(err)
+}
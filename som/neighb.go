@@ -1,6 +1,9 @@
 package som
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
 
 // Gaussian calculates gaussian neghbourhood
 func Gaussian(distance float64, radius float64) float64 {
@@ -21,3 +24,17 @@ func MexicanHat(distance float64, radius float64) float64 {
 		(1 - (distance*distance)/(radius*radius)) *
 		math.Exp(-(distance*distance)/(2*radius*radius))
 }
+
+// ParseNeighbFunc returns builtin neighbourhood function of a given name: gaussian, bubble or mexican.
+// It returns error if the name is unknown.
+func ParseNeighbFunc(name string) (NeighbFunc, error) {
+	switch name {
+	case "gaussian":
+		return Gaussian, nil
+	case "bubble":
+		return Bubble, nil
+	case "mexican":
+		return MexicanHat, nil
+	}
+	return nil, fmt.Errorf("unsupported neighbourhood function: %s", name)
+}
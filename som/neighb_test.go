@@ -5,6 +5,7 @@ import (
 
 	"math"
 	"math/rand"
+	"reflect"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -55,3 +56,16 @@ func TestBubble(t *testing.T) {
 	assert.Equal(t, 0.0, Bubble(radius+diff, radius))
 	assert.Equal(t, 1.0, Bubble(radius, radius))
 }
+
+func TestParseNeighbFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, name := range []string{"gaussian", "bubble", "mexican"} {
+		fn, err := ParseNeighbFunc(name)
+		assert.NoError(err)
+		assert.Equal(name, neighbFuncNames[reflect.ValueOf(fn).Pointer()])
+	}
+	fn, err := ParseNeighbFunc("foo")
+	assert.Nil(fn)
+	assert.Error(err)
+}
@@ -0,0 +1,148 @@
+package som
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ErrScheduleDone is returned when OnlineTrainer receives a sample after all the iterations of its schedule
+var ErrScheduleDone = errors.New("training schedule done")
+
+// OnlineTrainer trains a map sequentially on samples which are passed to it one at a time,
+// e.g. as they are received from a channel or read from a message queue consumer.
+// Unlike TrainStream, which pulls the samples from a channel until it's closed, OnlineTrainer is driven by
+// its caller and keeps its own decay bookkeeping: learning rate and radius decay over the iterations
+// of its schedule which advances by one iteration with every sample. Epochs, which matter to the epoch
+// schedule, unit freezing and training history, are a given number of samples long.
+// OnlineTrainer methods must not be called concurrently.
+type OnlineTrainer struct {
+	// m is the trained map
+	m *Map
+	// c is training configuration
+	c *TrainConfig
+	// unitDist holds map unit distances
+	unitDist *mat.Dense
+	// s is the training schedule
+	s *schedule
+	// f freezes converged units
+	f *freezer
+	// cm masks data columns
+	cm *colMask
+	// ms measures distances of samples from codebook vectors
+	ms measure
+	// w holds the most recent samples for evaluation
+	w *evalWindow
+	// pt labels and times training phases
+	pt *phaseTimer
+	// next is the next training iteration
+	next int
+}
+
+// NewOnlineTrainer returns online trainer of map m whose schedule has iters iterations grouped in epochs
+// of given number of samples. The training configuration must use sequential algorithm.
+// It returns error if the training configuration is invalid, uses qerror sampling or subsampling which need
+// the whole data set or if the number of iterations or epoch length is not positive.
+func NewOnlineTrainer(m *Map, c *TrainConfig, iters, epoch int) (*OnlineTrainer, error) {
+	if iters <= 0 {
+		return nil, fmt.Errorf("invalid number of iterations: %d", iters)
+	}
+	if epoch <= 0 {
+		return nil, fmt.Errorf("invalid epoch length: %d", epoch)
+	}
+	if err := validateTrainConfig(c); err != nil {
+		return nil, err
+	}
+	if c.Algorithm != "seq" {
+		return nil, fmt.Errorf("algorithm %s unsupported by online training", c.Algorithm)
+	}
+	if c.Sampling == "qerror" {
+		return nil, fmt.Errorf("sampling %s unsupported by online training", c.Sampling)
+	}
+	if c.Subsample > 0 {
+		return nil, fmt.Errorf("subsampling unsupported by online training")
+	}
+	// every partial training iteration must update the map
+	if c.Delay > 1 {
+		return nil, fmt.Errorf("update delay unsupported by online training")
+	}
+	if err := validateStreamEval(c.Eval); err != nil {
+		return nil, err
+	}
+	_, dim := m.codebook.Dims()
+	if err := validateColMask(c, dim); err != nil {
+		return nil, err
+	}
+	ms, err := m.trainMeasure(c, nil)
+	if err != nil {
+		return nil, err
+	}
+	unitDist, err := m.unitDists()
+	if err != nil {
+		return nil, err
+	}
+	s := newSchedule(c, iters, epoch)
+	return &OnlineTrainer{
+		m:        m,
+		c:        c,
+		unitDist: unitDist,
+		s:        s,
+		f:        newFreezer(c.Freeze, m.codebook, s),
+		cm:       newColMask(c, dim),
+		ms:       ms,
+		w:        newEvalWindow(c.Eval, dim),
+		pt:       newPhaseTimer(c.PhaseHook),
+	}, nil
+}
+
+// Partial runs the next training iteration on sample vec and updates the map metadata and training history.
+// Phase times are reported to the configured phase hook after every iteration.
+// It returns ErrScheduleDone if all the iterations of the schedule have been run, ErrTrainInProgress if the map
+// is being trained by another call and error if the sample dimension differs from the codebook dimension
+// or if the sample or updated codebook hold non-finite values when finiteness checks are enabled.
+func (o *OnlineTrainer) Partial(vec []float64) error {
+	// guard against concurrent training which would corrupt the codebook
+	if !atomic.CompareAndSwapInt32(&o.m.training, 0, 1) {
+		return ErrTrainInProgress
+	}
+	defer atomic.StoreInt32(&o.m.training, 0)
+
+	if o.Done() {
+		return ErrScheduleDone
+	}
+	if _, dim := o.m.codebook.Dims(); len(vec) != dim {
+		return fmt.Errorf("invalid sample dimension: %d", len(vec))
+	}
+	i := o.next
+	if o.c.CheckFinite {
+		if err := checkFiniteRow(i, vec); err != nil {
+			return err
+		}
+	}
+	defer o.pt.stop()
+	o.pt.enter(phaseBMU)
+	lRate, radius := o.s.at(i)
+	o.m.seqStep(o.c, o.ms, o.unitDist, vec, lRate, radius, o.f, o.cm, nil, o.pt)
+	o.f.step(i, o.m.codebook, o.s)
+	o.next++
+	o.m.trained(o.c, o.ms, o.s, "")
+	if o.c.CheckFinite {
+		if err := checkFiniteCodebook(o.c, o.m.codebook, i, lRate, radius); err != nil {
+			return err
+		}
+	}
+	o.w.add(vec)
+	return o.w.eval(o.m, o.ms, i)
+}
+
+// Iteration returns the next training iteration
+func (o *OnlineTrainer) Iteration() int {
+	return o.next
+}
+
+// Done returns true if all the iterations of the schedule have been run
+func (o *OnlineTrainer) Done() bool {
+	return o.next >= o.s.iters
+}
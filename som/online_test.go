@@ -0,0 +1,79 @@
+package som
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestOnlineTrainer(t *testing.T) {
+	assert := assert.New(t)
+
+	tc := makeDefaultTrainConfig()
+	tc.Freeze = &FreezeConfig{Threshold: 0.05, Patience: 1}
+	// online training follows the same schedule as stream training
+	exp, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	assert.NoError(exp.TrainStream(tc, feed(dataMx, 30), 4, 30))
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	o, err := NewOnlineTrainer(m, tc, 30, 4)
+	assert.NoError(err)
+	rows, _ := dataMx.Dims()
+	for i := 0; i < 30; i++ {
+		assert.False(o.Done())
+		assert.NoError(o.Partial(dataMx.RawRowView(i % rows)))
+	}
+	assert.True(o.Done())
+	assert.Equal(30, o.Iteration())
+	assert.True(mat.Equal(exp.Codebook(), m.Codebook()))
+	assert.Equal(exp.TrainHistory(), m.TrainHistory())
+	assert.NotNil(m.Metadata().Trained)
+	assert.Equal(ErrScheduleDone, o.Partial(dataMx.RawRowView(0)))
+
+	// evaluation of the most recent samples
+	var results []EvalResult
+	tc = makeDefaultTrainConfig()
+	tc.Eval = &StreamEval{
+		Window: 3,
+		Every:  2,
+		Hook:   func(r EvalResult) { results = append(results, r) },
+	}
+	tc.CheckFinite = true
+	o, err = NewOnlineTrainer(m, tc, 10, 5)
+	assert.NoError(err)
+	for i := 0; i < 5; i++ {
+		assert.NoError(o.Partial(dataMx.RawRowView(i)))
+	}
+	assert.Len(results, 2)
+	assert.Equal(4, results[1].Iteration)
+	assert.Equal(3, results[1].Samples)
+	// invalid samples don't advance the schedule
+	assert.Error(o.Partial([]float64{1}))
+	_, cols := dataMx.Dims()
+	assert.Error(o.Partial(make([]float64, cols-1)))
+	inf := make([]float64, cols)
+	inf[0] = math.Inf(1)
+	assert.Error(o.Partial(inf))
+	assert.Equal(5, o.Iteration())
+
+	// invalid configuration
+	_, err = NewOnlineTrainer(m, tc, 0, 5)
+	assert.Error(err)
+	_, err = NewOnlineTrainer(m, tc, 10, 0)
+	assert.Error(err)
+	for _, configure := range []func(*TrainConfig){
+		func(tc *TrainConfig) { tc.Algorithm = "batch" },
+		func(tc *TrainConfig) { tc.Sampling = "qerror" },
+		func(tc *TrainConfig) { tc.Weights = make([]float64, rows) },
+		func(tc *TrainConfig) { tc.LRate = -1 },
+	} {
+		tc := makeDefaultTrainConfig()
+		configure(tc)
+		_, err = NewOnlineTrainer(m, tc, 10, 5)
+		assert.Error(err)
+	}
+}
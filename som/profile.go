@@ -0,0 +1,97 @@
+package som
+
+import (
+	"context"
+	"runtime/pprof"
+	"time"
+)
+
+// Training phases
+const (
+	// PhaseBMU is the search for Best Match Units of data samples
+	PhaseBMU = "bmu"
+	// PhaseUpdate is the update of codebook vectors in BMU neighbourhoods
+	PhaseUpdate = "update"
+	// PhaseMerge is the merge of batch worker results
+	PhaseMerge = "merge"
+)
+
+// PhaseLabel is the pprof label key which holds the training phase
+const PhaseLabel = "som_phase"
+
+// PhaseHook is called with the time spent in a particular training phase.
+// Phase time is aggregated and reported when a batch of work finishes, so
+// the hook may be called several times for the same phase during training.
+// Batch training workers call the hook concurrently.
+type PhaseHook func(phase string, elapsed time.Duration)
+
+// phases are all training phases
+var phases = []string{PhaseBMU, PhaseUpdate, PhaseMerge}
+
+// phaseCtxs are contexts which carry pprof labels of training phases
+var phaseCtxs = func() []context.Context {
+	ctxs := make([]context.Context, len(phases))
+	for i, phase := range phases {
+		ctxs[i] = pprof.WithLabels(context.Background(), pprof.Labels(PhaseLabel, phase))
+	}
+	return ctxs
+}()
+
+// phase indices into phases
+const (
+	phaseBMU = iota
+	phaseUpdate
+	phaseMerge
+	phaseNone = -1
+)
+
+// phaseTimer labels the calling goroutine with the current training phase
+// and measures time spent in each phase if a hook is provided
+type phaseTimer struct {
+	hook    PhaseHook
+	phase   int
+	start   time.Time
+	elapsed [3]time.Duration
+}
+
+// newPhaseTimer creates a new phaseTimer which reports phase times to hook
+func newPhaseTimer(hook PhaseHook) *phaseTimer {
+	return &phaseTimer{
+		hook:  hook,
+		phase: phaseNone,
+	}
+}
+
+// enter ends the current phase and starts a new one
+func (p *phaseTimer) enter(phase int) {
+	if p.phase == phase {
+		return
+	}
+	pprof.SetGoroutineLabels(phaseCtxs[phase])
+	if p.hook != nil {
+		now := time.Now()
+		if p.phase != phaseNone {
+			p.elapsed[p.phase] += now.Sub(p.start)
+		}
+		p.start = now
+	}
+	p.phase = phase
+}
+
+// stop ends the current phase, removes the phase label and reports phase times to hook
+func (p *phaseTimer) stop() {
+	if p.phase == phaseNone {
+		return
+	}
+	pprof.SetGoroutineLabels(context.Background())
+	if p.hook != nil {
+		p.elapsed[p.phase] += time.Since(p.start)
+		for i, elapsed := range p.elapsed {
+			if elapsed > 0 {
+				p.hook(phases[i], elapsed)
+			}
+			p.elapsed[i] = 0
+		}
+	}
+	p.phase = phaseNone
+}
@@ -0,0 +1,39 @@
+package som
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhaseHook(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+
+	mu := &sync.Mutex{}
+	elapsed := make(map[string]time.Duration)
+	tc := makeDefaultTrainConfig()
+	tc.PhaseHook = func(phase string, d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		elapsed[phase] += d
+	}
+	// sequential training reports BMU search and update
+	err = m.Train(tc, dataMx, 100)
+	assert.NoError(err)
+	assert.Contains(elapsed, PhaseBMU)
+	assert.Contains(elapsed, PhaseUpdate)
+	assert.NotContains(elapsed, PhaseMerge)
+	// batch training reports merges, too
+	tc.Algorithm = "batch"
+	err = m.Train(tc, dataMx, 10)
+	assert.NoError(err)
+	assert.Contains(elapsed, PhaseMerge)
+	for _, d := range elapsed {
+		assert.True(d > 0)
+	}
+}
@@ -13,6 +13,11 @@ import (
 // data vectors could not be calculated. This could be because the dimensions of passed in data and
 // codebook matrix are not the same. When the error is returned, quantization error is set to -1.0
 func QuantError(data, codebook *mat.Dense) (float64, error) {
+	return quantError(measure{metric: Euclidean}, data, codebook)
+}
+
+// quantError computes SOM quantization error using a given measure
+func quantError(ms measure, data, codebook *mat.Dense) (float64, error) {
 	// data can't be nil
 	if data == nil {
 		return -1.0, fmt.Errorf("invalid data supplied: %v", data)
@@ -22,15 +27,14 @@ func QuantError(data, codebook *mat.Dense) (float64, error) {
 		return -1.0, fmt.Errorf("invalid codebook supplied: %v", codebook)
 	}
 	var qErr float64
-	metric := Euclidean
 	rows, _ := data.Dims()
 	for i := 0; i < rows; i++ {
-		bmuIdx, err := ClosestVec(metric, data.RawRowView(i), codebook)
+		bmuIdx, err := ms.closest(data.RawRowView(i), codebook)
 		if err != nil {
 			return -1.0, err
 		}
 		// get the BMU distance -- no need to check for errors here
-		d, err := Distance(metric, data.RawRowView(i), codebook.RawRowView(bmuIdx))
+		d, err := ms.distance(data.RawRowView(i), codebook.RawRowView(bmuIdx))
 		if err != nil {
 			return -1.0, err
 		}
@@ -40,69 +44,154 @@ func QuantError(data, codebook *mat.Dense) (float64, error) {
 	return qErr / float64(rows), nil
 }
 
+// unitQuantErrors computes the average quantization error of data rows mapped to each codebook unit.
+// Units without any mapped rows have zero quantization error.
+// It returns error if data or codebook are nil or if the BMU distances could not be computed.
+func unitQuantErrors(ms measure, data, codebook *mat.Dense) ([]float64, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	if codebook == nil {
+		return nil, fmt.Errorf("invalid codebook supplied: %v", codebook)
+	}
+	units, _ := codebook.Dims()
+	qErrs := make([]float64, units)
+	hits := make([]int, units)
+	rows, _ := data.Dims()
+	for i := 0; i < rows; i++ {
+		bmuIdx, err := ms.closest(data.RawRowView(i), codebook)
+		if err != nil {
+			return nil, err
+		}
+		d, err := ms.distance(data.RawRowView(i), codebook.RawRowView(bmuIdx))
+		if err != nil {
+			return nil, err
+		}
+		qErrs[bmuIdx] += d
+		hits[bmuIdx]++
+	}
+	for i := range qErrs {
+		if hits[i] > 0 {
+			qErrs[i] /= float64(hits[i])
+		}
+	}
+	return qErrs, nil
+}
+
 // TopoProduct calculates topographic product for given codebook and grid.
+// TopoProduct computes unit and codebook distances row by row and accumulates the product
+// in log-space, so it does not run out of memory or numeric range on maps with many units.
 // TopoProduct returns error if either codebook or grid are nil or if number of codebook rows
 // is not the same as the number of grid rows. If any two codebooks turn out to be the same
 // TopoProduct returns +Inf - this can happen when map is trained using batch algorithm.
 func TopoProduct(codebook, grid *mat.Dense) (float64, error) {
-	// codebook can't be nil
-	if codebook == nil {
-		return 0.0, fmt.Errorf("invalid codebook supplied: %v", codebook)
-	}
 	// grid can't be nil
 	if grid == nil {
 		return 0.0, fmt.Errorf("invalid grid supplied: %v", grid)
 	}
-	// if grid and codebook don't match, throw error
 	gRows, _ := grid.Dims()
+	// no need to check for errors here: i is always a valid row
+	uDistRow := func(i int, dst []float64) { DistanceRow(Euclidean, i, grid, dst) }
+	return topoProduct(measure{metric: Euclidean}, codebook, gRows, uDistRow)
+}
+
+// topoProduct calculates topographic product using a given measure in codebook space
+// and uDistRow which computes the distances of a unit to all the units of a grid of gRows units
+func topoProduct(ms measure, codebook *mat.Dense, gRows int, uDistRow func(i int, dst []float64)) (float64, error) {
+	// codebook can't be nil
+	if codebook == nil {
+		return 0.0, fmt.Errorf("invalid codebook supplied: %v", codebook)
+	}
+	// if grid and codebook don't match, throw error
 	cRows, _ := codebook.Dims()
 	if gRows != cRows {
 		return 0.0, fmt.Errorf("Grid and codebook dimension mismatch")
 	}
-	// unit and codebook distance matrices -- no need to check for error here
-	uDistMx, _ := DistanceMx(Euclidean, grid)
-	cDistMx, _ := DistanceMx(Euclidean, codebook)
+	// unit and codebook distances are computed row by row
+	uDist := make([]float64, gRows)
+	cDist := make([]float64, cRows)
+	// unit and codebook neighbours sorted by their distance
+	uNeighb := make([]int, gRows)
+	cNeighb := make([]int, cRows)
 	// tp is the topographic product
 	var tp float64
 	// loop through all neurons
 	for i := 0; i < gRows; i++ {
-		// retrieve unit distance slice and sort it
-		uSlice := newFloat64Slice(uDistMx.RawRowView(i)...)
-		sort.Sort(uSlice)
-		uNeighb := uSlice.index[1:]
-		// retrieve codebook distance slice and sort it
-		cSlice := newFloat64Slice(cDistMx.RawRowView(i)...)
-		sort.Sort(cSlice)
-		cNeighb := cSlice.index[1:]
-		// topgraphic product and partial distortion products
-		p1, p2, p3 := 1.0, 1.0, 1.0
-		for j := 0; j < cRows-1; j++ {
+		uDistRow(i, uDist)
+		ms.row(i, codebook, cDist)
+		// sort neighbours by distance; the closest one is the unit itself
+		sortByDist(uNeighb, uDist)
+		sortByDist(cNeighb, cDist)
+		// P3 is accumulated in log-space to avoid overflows and underflows of the product
+		logP3 := 0.0
+		for j := 1; j < cRows; j++ {
 			// if 2 codebooks are the same, return +Inf or -Inf
-			if cDistMx.At(i, cNeighb[j]) == 0 {
+			if cDist[cNeighb[j]] == 0 {
 				return math.Inf(1), nil
 			}
 
-			if cDistMx.At(i, uNeighb[j]) == 0 {
+			if cDist[uNeighb[j]] == 0 {
 				return math.Inf(-1), nil
 			}
-			// lattice_space / codebook_space
-			q1 := cDistMx.At(i, uNeighb[j]) / cDistMx.At(i, cNeighb[j])
-			q2 := uDistMx.At(i, uNeighb[j]) / uDistMx.At(i, cNeighb[j])
-			// calculate P1, P2, P3
-			p1 *= q1
-			p2 *= q2
-			p3 *= q1 * q2
+			// log of lattice_space / codebook_space distortion products
+			logQ1 := math.Log(cDist[uNeighb[j]]) - math.Log(cDist[cNeighb[j]])
+			logQ2 := math.Log(uDist[uNeighb[j]]) - math.Log(uDist[cNeighb[j]])
+			logP3 += logQ1 + logQ2
 			// the actual P3 has to be square rooted
-			tp += math.Log(math.Pow(p3, 1/float64(2*(j+1))))
+			tp += logP3 / float64(2*j)
 		}
 	}
 
 	return tp / float64(cRows*(cRows-1)), nil
 }
 
+// sortByDist stores indices of dist sorted by their distances in idx.
+// Indices of equal distances are sorted in ascending order.
+func sortByDist(idx []int, dist []float64) {
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool {
+		da, db := dist[idx[a]], dist[idx[b]]
+		if da == db {
+			return idx[a] < idx[b]
+		}
+		return da < db
+	})
+}
+
+// Adjacency reports whether grid units with indices a and b are neighbours on the grid lattice
+type Adjacency func(a, b int) bool
+
 // TopoError calculate topographice error for given data set, codebook and grid and returns it
+// Two grid units are considered neighbours if their distance is smaller than 1.01*sqrt(2),
+// which is the adjacency of units on rectangular planar grids including the diagonal neighbours.
+// Use TopoErrorWith to compute topographic error with a different neighbour criterion.
 // It returns error if either data, codebook or grid are nil or if their dimensions are mismatched.
 func TopoError(data, codebook, grid *mat.Dense) (float64, error) {
+	// grid can't be nil
+	if grid == nil {
+		return -1.0, fmt.Errorf("invalid grid supplied: %v", grid)
+	}
+	// 1.01*math.Sqrt(2) accounts for voronoi cell neighbourhood.
+	// This makes the diagonal lattice units to be considered neighb.
+	adj := func(a, b int) bool {
+		return euclideanVec(grid.RawRowView(a), grid.RawRowView(b)) < 1.01*math.Sqrt(2)
+	}
+
+	return TopoErrorWith(data, codebook, adj)
+}
+
+// TopoErrorWith calculates topographic error for given data set and codebook using adj to decide
+// whether the two best matching units of data samples are neighbours on the map grid.
+// Grid Adjacent method provides adjacency which takes the grid unit shape and type into account.
+// It returns error if either data, codebook or adj are nil or if their dimensions are mismatched.
+func TopoErrorWith(data, codebook *mat.Dense, adj Adjacency) (float64, error) {
+	return topoError(measure{metric: Euclidean}, data, codebook, adj)
+}
+
+// topoError calculates topographic error using a given measure to find BMUs
+func topoError(ms measure, data, codebook *mat.Dense, adj Adjacency) (float64, error) {
 	// data can't be nil
 	if data == nil {
 		return -1.0, fmt.Errorf("invalid data supplied: %v", data)
@@ -111,25 +200,20 @@ func TopoError(data, codebook, grid *mat.Dense) (float64, error) {
 	if codebook == nil {
 		return -1.0, fmt.Errorf("invalid codebook supplied: %v", codebook)
 	}
-	// grid can't be nil
-	if grid == nil {
-		return -1.0, fmt.Errorf("invalid grid supplied: %v", grid)
+	// adjacency can't be nil
+	if adj == nil {
+		return -1.0, fmt.Errorf("invalid adjacency supplied")
 	}
-	// unit distance matrix -- no need to check for error
-	uDistMx, _ := DistanceMx(Euclidean, grid)
 	var te float64
 	// iterate through all data samples
 	rows, _ := data.Dims()
 	for i := 0; i < rows; i++ {
-		closest, err := ClosestNVec(Euclidean, 2, data.RawRowView(i), codebook)
+		closest, err := ms.closestN(2, data.RawRowView(i), codebook)
 		if err != nil {
 			return -1.0, err
 		}
-		// If the 2 BMUS are next to each other on lattice increment te.
-		// 1.01*math.Sqrt(2) accounts for voronoi cell neighbourhood.
-		// This makes the diagonal lattice units to be considered neighb.
-		uDistVec := uDistMx.RawRowView(closest[0])
-		if uDistVec[closest[1]] >= 1.01*math.Sqrt(2) {
+		// If the 2 BMUS are not next to each other on lattice increment te.
+		if !adj(closest[0], closest[1]) {
 			te++
 		}
 	}
@@ -2,6 +2,7 @@ package som
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -45,6 +46,64 @@ func TestQuantError(t *testing.T) {
 	assert.True(qe >= 0.0)
 }
 
+func TestWeightedQuantError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, cols := qData.Dims()
+	ones := make([]float64, cols)
+	for j := range ones {
+		ones[j] = 1.0
+	}
+	// unit weights give the same quantization error
+	for _, metric := range []Metric{Euclidean, Manhattan, Chebyshev, Tanimoto} {
+		exp, err := quantError(measure{metric: metric}, qData, qCbook)
+		assert.NoError(err)
+		qe, err := quantError(measure{metric: metric, weights: ones}, qData, qCbook)
+		assert.NoError(err)
+		assert.InDelta(exp, qe, 1e-9)
+	}
+	// a single weighted feature
+	data := mat.NewDense(2, 2, []float64{0, 1, 4, 0})
+	codebook := mat.NewDense(2, 2, []float64{0, 0, 4, 4})
+	qe, err := quantError(measure{metric: Euclidean, weights: []float64{1, 0}}, data, codebook)
+	assert.NoError(err)
+	assert.Equal(0.0, qe)
+	qe, err = quantError(measure{metric: Euclidean, weights: []float64{0, 4}}, data, codebook)
+	assert.NoError(err)
+	assert.Equal(1.0, qe)
+	qe, err = quantError(measure{metric: Manhattan, weights: []float64{0, 4}}, data, codebook)
+	assert.NoError(err)
+	assert.Equal(2.0, qe)
+	// invalid input
+	_, err = quantError(measure{metric: Euclidean, weights: ones}, nil, codebook)
+	assert.Error(err)
+	_, err = quantError(measure{metric: Euclidean, weights: []float64{1, 1}}, data, qCbook)
+	assert.Error(err)
+}
+
+func TestUnitQuantErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := unitQuantErrors(measure{metric: Euclidean}, nil, qCbook)
+	assert.Error(err)
+	_, err = unitQuantErrors(measure{metric: Euclidean}, qData, nil)
+	assert.Error(err)
+	qErrs, err := unitQuantErrors(measure{metric: Euclidean}, qData, qCbook)
+	assert.NoError(err)
+	assert.Len(qErrs, 3)
+	// average of unit errors weighted by unit hits is the map quantization error
+	hits := []float64{1, 3, 1}
+	var total float64
+	for i, qe := range qErrs {
+		total += hits[i] * qe
+	}
+	qe, err := QuantError(qData, qCbook)
+	assert.NoError(err)
+	assert.InDelta(qe, total/5, 1e-12)
+	// the last codebook vector is a data row mapped only to itself
+	assert.Equal(0.0, qErrs[2])
+}
+
 func TestTopoProduct(t *testing.T) {
 	assert := assert.New(t)
 
@@ -71,6 +130,22 @@ func TestTopoProduct(t *testing.T) {
 	// this should go through without errors
 	_, err = TopoProduct(qCbook, qGrid)
 	assert.NoError(err)
+	// codebook which replicates the grid preserves topology perfectly
+	bGrid, err := GridCoords("hexagon", []int{40, 40})
+	assert.NoError(err)
+	tp, err = TopoProduct(mat.DenseCopyOf(bGrid), bGrid)
+	assert.NoError(err)
+	assert.InDelta(0.0, tp, 1e-9)
+	// distorted codebook of a big map must not overflow the product
+	bCbook := mat.DenseCopyOf(bGrid)
+	rows, _ := bCbook.Dims()
+	for i := 0; i < rows; i++ {
+		bCbook.Set(i, 0, math.Exp(bCbook.At(i, 0)))
+	}
+	tp, err = TopoProduct(bCbook, bGrid)
+	assert.NoError(err)
+	assert.False(math.IsInf(tp, 0) || math.IsNaN(tp))
+	assert.NotEqual(0.0, tp)
 }
 
 func TestTopoError(t *testing.T) {
@@ -104,3 +179,26 @@ func TestTopoError(t *testing.T) {
 	assert.NoError(err)
 	assert.True(te > 0.0)
 }
+
+func TestTopoErrorWith(t *testing.T) {
+	assert := assert.New(t)
+
+	// nil adjacency returns error
+	te, err := TopoErrorWith(qData, qCbook, nil)
+	assert.Error(err)
+	assert.Equal(-1.0, te)
+	// no units are adjacent
+	te, err = TopoErrorWith(qData, qCbook, func(a, b int) bool { return false })
+	assert.NoError(err)
+	assert.Equal(1.0, te)
+	// all units are adjacent
+	te, err = TopoErrorWith(qData, qCbook, func(a, b int) bool { return true })
+	assert.NoError(err)
+	assert.Equal(0.0, te)
+	// grid adjacency
+	g, err := NewGrid(&GridConfig{Size: []int{1, 3}, Type: "planar", UShape: "rectangle"})
+	assert.NoError(err)
+	te, err = TopoErrorWith(qData, qCbook, g.Adjacent)
+	assert.NoError(err)
+	assert.True(te > 0.0)
+}
@@ -0,0 +1,224 @@
+package som
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	// curveWidth is the width of the training curve plot
+	curveWidth = 600.0
+	// curveHeight is the height of the training curve plot
+	curveHeight = 200.0
+)
+
+// reportMetric is a row of the quality metrics table of map report
+type reportMetric struct {
+	name  string
+	value string
+}
+
+// Report writes a self-contained HTML report of the map and its data to w. The report bundles
+// the u-matrix and hit map of data with units labeled by the dominant classes of data rows,
+// component planes which color units by the values of each codebook feature, a table of map
+// quality metrics and the training curve of radius and learning rate recorded in the training history.
+// classes maps data row index to its class; it can be nil or empty if the classes are not known.
+// The report needs no external resources, so it can be shared as a single file.
+// It fails with error if data is nil or its dimension differs from the codebook dimension,
+// if the metrics could not be computed or if the write to w fails.
+func (m *Map) Report(w io.Writer, data *mat.Dense, classes map[int]int) error {
+	if data == nil {
+		return fmt.Errorf("invalid data supplied: %v", data)
+	}
+	units, dim := m.codebook.Dims()
+	if _, cols := data.Dims(); cols != dim {
+		return fmt.Errorf("data and codebook dimension mismatch: %d != %d", cols, dim)
+	}
+	stats, err := m.ClassStats(data, classes)
+	if err != nil {
+		return err
+	}
+	metrics, err := m.reportMetrics(data, stats, len(classes) > 0)
+	if err != nil {
+		return err
+	}
+	hits, err := m.hitMap(data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprint(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>SOM report</title>\n"+
+		"<style>body{font-family:sans-serif}td,th{padding:2px 8px;text-align:left}figure{display:inline-block}</style>\n"+
+		"</head>\n<body>\n<h1>SOM report</h1>\n<h2>Quality metrics</h2>\n<table>\n"); err != nil {
+		return err
+	}
+	for _, r := range metrics {
+		if _, err := fmt.Fprintf(w, "<tr><th>%s</th><td>%s</td></tr>\n", html.EscapeString(r.name), html.EscapeString(r.value)); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprint(w, "</table>\n<h2>U-Matrix</h2>\n"); err != nil {
+		return err
+	}
+	dominant := stats.Dominant()
+	if err := writeSVGElement(w, m.umatrixMap(), dominant); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprint(w, "\n<h2>Hit map</h2>\n"); err != nil {
+		return err
+	}
+	if err := writeSVGElement(w, hits, dominant); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprint(w, "\n<h2>Component planes</h2>\n"); err != nil {
+		return err
+	}
+	for j := 0; j < dim; j++ {
+		u := m.umatrixMap()
+		u.values = make([]float64, units)
+		mat.Col(u.values, j, m.codebook)
+		if _, err := fmt.Fprintf(w, "<figure>\n<figcaption>Feature %d</figcaption>\n", j); err != nil {
+			return err
+		}
+		if err := writeSVGElement(w, u, nil); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprint(w, "\n</figure>\n"); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprint(w, "<h2>Training curve</h2>\n"); err != nil {
+		return err
+	}
+	if len(m.history) == 0 {
+		if _, err := fmt.Fprint(w, "<p>Training history is not available.</p>\n"); err != nil {
+			return err
+		}
+	} else if err := writeSVG(w, trainingCurve(m.history)); err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, "\n</body>\n</html>\n")
+	return err
+}
+
+// reportMetrics returns map quality metrics of data with class statistics stats.
+// The class purity is only reported if the data have classes.
+func (m *Map) reportMetrics(data *mat.Dense, stats *ClassStats, classes bool) ([]reportMetric, error) {
+	qe, err := m.QuantError(data)
+	if err != nil {
+		return nil, err
+	}
+	te, err := m.TopoError(data)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := m.TopoProduct()
+	if err != nil {
+		return nil, err
+	}
+	units, dim := m.codebook.Dims()
+	rows, _ := data.Dims()
+	metrics := []reportMetric{
+		{"Grid size", fmt.Sprint(m.grid.Size())},
+		{"Unit shape", m.grid.UShape()},
+		{"Units", fmt.Sprint(units)},
+		{"Dimension", fmt.Sprint(dim)},
+		{"Data rows", fmt.Sprint(rows)},
+		{"Quantization error", fmt.Sprintf("%f", qe)},
+		{"Topographic error", fmt.Sprintf("%f", te)},
+		{"Topographic product", fmt.Sprintf("%f", tp)},
+	}
+	if classes {
+		metrics = append(metrics, reportMetric{"Class purity", fmt.Sprintf("%f", stats.Purity())})
+	}
+	if t := m.meta.Train; t != nil {
+		metrics = append(metrics,
+			reportMetric{"Training algorithm", t.Algorithm},
+			reportMetric{"Training iterations", fmt.Sprint(t.Iterations)},
+			reportMetric{"Initial radius", fmt.Sprintf("%f", t.Radius)},
+			reportMetric{"Initial learning rate", fmt.Sprintf("%f", t.LRate)},
+		)
+	}
+	return metrics, nil
+}
+
+// writeSVGElement writes the svg element of displayed map u with units labeled by classes to w
+func writeSVGElement(w io.Writer, u *umatrixMap, classes map[int]int) error {
+	svgElem, err := u.svgElement(classes, &SVGConfig{})
+	if err != nil {
+		return err
+	}
+	return writeSVG(w, svgElem)
+}
+
+// writeSVG encodes svg element to w
+func writeSVG(w io.Writer, svgElem svgElement) error {
+	xmlEncoder := xml.NewEncoder(w)
+	if err := xmlEncoder.Encode(svgElem); err != nil {
+		return err
+	}
+	return xmlEncoder.Flush()
+}
+
+// trainingCurve returns svg element which plots the radius and learning rate of training history steps.
+// Both curves are scaled to their largest values, so they start at the top of the plot and decay towards its bottom.
+func trainingCurve(steps []ScheduleStep) svgElement {
+	last := steps[len(steps)-1].Iteration
+	maxRadius, maxLRate := 0.0, 0.0
+	for _, s := range steps {
+		maxRadius, maxLRate = math.Max(maxRadius, s.Radius), math.Max(maxLRate, s.LRate)
+	}
+	x := func(i int) float64 {
+		if last == 0 {
+			return gridOffset
+		}
+		return gridOffset + (curveWidth-2*gridOffset)*float64(i)/float64(last)
+	}
+	y := func(v, max float64) float64 {
+		if max == 0 {
+			return curveHeight - gridOffset
+		}
+		return curveHeight - gridOffset - (curveHeight-4*gridOffset)*v/max
+	}
+	var radius, lRate string
+	for _, s := range steps {
+		radius += fmt.Sprintf("%f,%f ", x(s.Iteration), y(s.Radius, maxRadius))
+		lRate += fmt.Sprintf("%f,%f ", x(s.Iteration), y(s.LRate, maxLRate))
+	}
+	elems := []interface{}{
+		polyline{
+			Points: []byte(fmt.Sprintf("%f,%f %f,%f %f,%f", gridOffset, 2*gridOffset, gridOffset, curveHeight-gridOffset,
+				curveWidth-gridOffset, curveHeight-gridOffset)),
+			Style: "fill:none;stroke:black;stroke-width:1",
+		},
+		polyline{
+			Points: []byte(radius),
+			Style:  "fill:none;stroke:rgb(255,0,0);stroke-width:2",
+		},
+		labelElement{X: 2 * gridOffset, Y: 1.5 * gridOffset, FontSize: defaultFontSize,
+			TextAnchor: "start", Text: fmt.Sprintf("radius (max %g)", maxRadius)},
+	}
+	// batch training has no learning rate
+	if maxLRate > 0 {
+		elems = append(elems,
+			polyline{
+				Points: []byte(lRate),
+				Style:  "fill:none;stroke:rgb(0,0,255);stroke-width:2",
+			},
+			labelElement{X: curveWidth / 2, Y: 1.5 * gridOffset, FontSize: defaultFontSize,
+				TextAnchor: "start", Text: fmt.Sprintf("learning rate (max %g)", maxLRate)},
+		)
+	}
+	elems = append(elems, labelElement{X: curveWidth - gridOffset, Y: curveHeight, FontSize: defaultFontSize,
+		TextAnchor: "end", Text: fmt.Sprintf("iteration %d", last)})
+	return svgElement{
+		Width:    curveWidth,
+		Height:   curveHeight + gridOffset,
+		Polygons: elems,
+	}
+}
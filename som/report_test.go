@@ -0,0 +1,49 @@
+package som
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestReport(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	_, dim := dataMx.Dims()
+
+	// untrained map has no training curve
+	var buf bytes.Buffer
+	assert.NoError(m.Report(&buf, dataMx, nil))
+	out := buf.String()
+	assert.True(strings.HasPrefix(out, "<!DOCTYPE html>"))
+	assert.True(strings.HasSuffix(out, "</html>\n"))
+	assert.Equal(2+dim, strings.Count(out, "<svg"))
+	assert.Contains(out, "Training history is not available")
+	assert.Contains(out, "Quantization error")
+	assert.NotContains(out, "Class purity")
+
+	tc := makeDefaultTrainConfig()
+	assert.NoError(m.Train(tc, dataMx, 20))
+	buf.Reset()
+	classes := map[int]int{0: 1, 1: 1, 2: 2, 3: 2, 4: 3}
+	assert.NoError(m.Report(&buf, dataMx, classes))
+	out = buf.String()
+	assert.Equal(3+dim, strings.Count(out, "<svg"))
+	assert.Contains(out, "radius (max")
+	assert.Contains(out, "learning rate (max")
+	assert.Contains(out, "Class purity")
+	assert.Contains(out, "Training algorithm</th><td>seq")
+	for j := 0; j < dim; j++ {
+		assert.Contains(out, fmt.Sprintf("Feature %d", j))
+	}
+
+	// invalid data
+	assert.Error(m.Report(&buf, nil, nil))
+	assert.Error(m.Report(&buf, mat.NewDense(2, dim+1, nil), nil))
+}
@@ -0,0 +1,108 @@
+package som
+
+import (
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// samplings maps supported sequential training sampling strategies
+var samplings = map[string]bool{
+	"":        true,
+	"uniform": true,
+	"qerror":  true,
+}
+
+// sampler draws data rows sequential training iterations are run on
+type sampler struct {
+	// r is random number generator
+	r *rand.Rand
+	// data is training data
+	data *mat.Dense
+	// ms is used to compute row quantization errors
+	ms measure
+	// epoch is the number of iterations between quantization error estimates
+	epoch int
+	// weighted requests quantization error weighted sampling
+	weighted bool
+	// cum holds cumulative row quantization errors; it is nil if rows are drawn uniformly
+	cum []float64
+	// size is the number of rows sampled in each epoch
+	size int
+	// subset holds sorted indices of the rows sampled in the current epoch; it is nil unless data are subsampled
+	subset []int
+	// sub holds the rows of subset copied for batch training; it is allocated on first use
+	sub *mat.Dense
+	// subWeights holds the weights of subset rows
+	subWeights []float64
+}
+
+// newSampler returns sampler of data rows configured by c whose schedule s defines the epochs.
+// Rows are drawn uniformly by default; qerror sampling draws rows with probability proportional to their
+// quantization error which is re-estimated at the start of each epoch, so the rows which are already
+// well represented by the map are drawn less often. Row quantization errors are computed using measure ms.
+func newSampler(c *TrainConfig, ms measure, data *mat.Dense, s *schedule, r *rand.Rand) *sampler {
+	rows, _ := data.Dims()
+	return &sampler{
+		r:        r,
+		data:     data,
+		ms:       ms,
+		epoch:    s.epoch,
+		weighted: c.Sampling == "qerror",
+		size:     subsampleSize(c, rows),
+	}
+}
+
+// next returns the data row drawn in the i-th iteration.
+// Subsampling sampler draws the rows of each epoch at its start and weighted sampler then
+// re-estimates row quantization errors of codebook.
+func (s *sampler) next(i int, codebook *mat.Dense) []float64 {
+	if i%s.epoch == 0 {
+		s.draw()
+		if s.weighted {
+			s.estimate(codebook)
+		}
+	}
+	rows, _ := s.data.Dims()
+	if s.cum == nil {
+		if s.subset != nil {
+			return s.data.RawRowView(s.subset[s.r.Intn(len(s.subset))])
+		}
+		return s.data.RawRowView(s.r.Intn(rows))
+	}
+	x := s.r.Float64() * s.cum[rows-1]
+	row := sort.Search(rows, func(j int) bool { return s.cum[j] > x })
+	// guard against rounding of the last cumulative error
+	if row == rows {
+		row = rows - 1
+	}
+	return s.data.RawRowView(row)
+}
+
+// estimate computes cumulative quantization errors of data rows mapped to codebook.
+// The rows which are not sampled in the current epoch have zero errors, so they are never drawn.
+// If all the rows are represented perfectly, rows are drawn uniformly until the next estimate.
+func (s *sampler) estimate(codebook *mat.Dense) {
+	rows, _ := s.data.Dims()
+	if s.cum == nil {
+		s.cum = make([]float64, rows)
+	}
+	total, k := 0.0, 0
+	for i := 0; i < rows; i++ {
+		// subset is sorted, so its next row is the only one which can match
+		if s.subset == nil || (k < len(s.subset) && s.subset[k] == i) {
+			// no need to check for errors here:
+			// data rows and codebook are not nil and have the same dimension
+			row := s.data.RawRowView(i)
+			bmu, _ := s.ms.closest(row, codebook)
+			d, _ := s.ms.distance(row, codebook.RawRowView(bmu))
+			total += d
+			k++
+		}
+		s.cum[i] = total
+	}
+	if total == 0 {
+		s.cum = nil
+	}
+}
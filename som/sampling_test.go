@@ -0,0 +1,54 @@
+package som
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSampler(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(3, 2, []float64{
+		0.0, 0.0,
+		0.0, 0.0,
+		3.0, 4.0,
+	})
+	codebook := mat.NewDense(2, 2, nil)
+	tc := makeDefaultTrainConfig()
+	tc.Sampling = "qerror"
+	s := newSchedule(tc, 30, 3)
+	smp := newSampler(tc, measure{metric: tc.Metric}, data, s, rand.New(rand.NewSource(1)))
+	// only the row which is not represented by the codebook is drawn
+	for i := 0; i < 3; i++ {
+		assert.Equal([]float64{3.0, 4.0}, smp.next(i, codebook))
+	}
+	assert.Equal([]float64{0, 0, 5}, smp.cum)
+	// perfectly represented rows are drawn uniformly after re-estimation
+	codebook.SetRow(1, []float64{3.0, 4.0})
+	smp.next(3, codebook)
+	assert.Nil(smp.cum)
+	// uniform sampling never estimates quantization errors
+	tc.Sampling = "uniform"
+	smp = newSampler(tc, measure{metric: tc.Metric}, data, s, rand.New(rand.NewSource(1)))
+	smp.next(0, codebook)
+	assert.Nil(smp.cum)
+}
+
+func TestTrainSampling(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Sampling = "qerror"
+	assert.NoError(m.Train(tc, dataMx, 20))
+	// weighted sampling is only supported by sequential training
+	tc.Algorithm = "batch"
+	assert.Error(m.Train(tc, dataMx, 20))
+	tc.Algorithm = "seq"
+	tc.Sampling = "foo"
+	assert.Error(m.Train(tc, dataMx, 20))
+}
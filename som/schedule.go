@@ -0,0 +1,81 @@
+package som
+
+// ScheduleStep holds the neighbourhood radius and learning rate in effect at a given training iteration
+type ScheduleStep struct {
+	// Iteration is the training iteration
+	Iteration int `json:"iteration"`
+	// Radius is the neighbourhood radius
+	Radius float64 `json:"radius"`
+	// LRate is the learning rate; it is zero in batch training which has no learning rate
+	LRate float64 `json:"lrate"`
+	// Frozen is the number of units frozen by the end of the epoch which starts at Iteration
+	Frozen int `json:"frozen,omitempty"`
+	// Empty is the number of units which were not within the radius of any BMU in batch training Iteration
+	Empty int `json:"empty,omitempty"`
+	// Sparse is the number of units whose neighbourhood sum was below the minimum in batch training Iteration
+	Sparse int `json:"sparse,omitempty"`
+	// Samples is the number of data rows the epoch which starts at Iteration trains on; it is only set if data are subsampled
+	Samples int `json:"samples,omitempty"`
+}
+
+// schedule computes the radius and learning rate of training iterations and records
+// them in the training history at the start of every epoch
+type schedule struct {
+	// tc is training configuration
+	tc *TrainConfig
+	// iters is the number of training iterations
+	iters int
+	// epoch is the number of iterations in an epoch
+	epoch int
+	// steps holds the recorded training history
+	steps []ScheduleStep
+}
+
+// newSchedule returns a schedule of iters training iterations which are grouped into epochs of given length.
+// Batch training iterations process the whole data set, so their epochs are a single iteration long.
+func newSchedule(tc *TrainConfig, iters, epoch int) *schedule {
+	if tc.Algorithm == "batch" || epoch <= 0 {
+		epoch = 1
+	}
+	return &schedule{
+		tc:    tc,
+		iters: iters,
+		epoch: epoch,
+		steps: make([]ScheduleStep, 0, (iters+epoch-1)/epoch),
+	}
+}
+
+// at returns the learning rate and radius of i-th training iteration.
+// Unless the schedule is updated per epoch, the parameters decay with every iteration.
+func (s *schedule) at(i int) (float64, float64) {
+	step, steps := i, s.iters
+	if s.tc.Schedule == "epoch" {
+		step, steps = i/s.epoch, (s.iters+s.epoch-1)/s.epoch
+	}
+	// no need to check for errors:
+	// LRate and Radius are checked by config validation
+	var lRate float64
+	if s.tc.Algorithm != "batch" {
+		lRate, _ = LRate(step, steps, s.tc.LDecay, s.tc.LRate)
+	}
+	radius, _ := Radius(step, steps, s.tc.RDecay, s.tc.Radius)
+	if i%s.epoch == 0 {
+		s.steps = append(s.steps, ScheduleStep{Iteration: i, Radius: radius, LRate: lRate})
+	}
+	return lRate, radius
+}
+
+// samples records the number of data rows the last recorded epoch trains on
+func (s *schedule) samples(n int) {
+	if len(s.steps) > 0 {
+		s.steps[len(s.steps)-1].Samples = n
+	}
+}
+
+// units records the number of empty and sparse units of the last batch training iteration
+func (s *schedule) units(empty, sparse int) {
+	if len(s.steps) > 0 {
+		s.steps[len(s.steps)-1].Empty = empty
+		s.steps[len(s.steps)-1].Sparse = sparse
+	}
+}
@@ -0,0 +1,67 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	tc := makeDefaultTrainConfig()
+	// parameters decay with every iteration and are recorded once per epoch
+	s := newSchedule(tc, 10, 4)
+	for i := 0; i < 10; i++ {
+		lRate, radius := s.at(i)
+		expLRate, _ := LRate(i, 10, tc.LDecay, tc.LRate)
+		expRadius, _ := Radius(i, 10, tc.RDecay, tc.Radius)
+		assert.Equal(expLRate, lRate)
+		assert.Equal(expRadius, radius)
+	}
+	assert.Len(s.steps, 3)
+	assert.Equal([]int{0, 4, 8}, []int{s.steps[0].Iteration, s.steps[1].Iteration, s.steps[2].Iteration})
+
+	// parameters are constant during epochs
+	tc.Schedule = "epoch"
+	s = newSchedule(tc, 10, 4)
+	for i := 0; i < 10; i++ {
+		lRate, radius := s.at(i)
+		expLRate, _ := LRate(i/4, 3, tc.LDecay, tc.LRate)
+		expRadius, _ := Radius(i/4, 3, tc.RDecay, tc.Radius)
+		assert.Equal(expLRate, lRate)
+		assert.Equal(expRadius, radius)
+	}
+	assert.Len(s.steps, 3)
+
+	// batch epochs are single iterations without learning rate
+	tc.Algorithm = "batch"
+	s = newSchedule(tc, 5, 100)
+	for i := 0; i < 5; i++ {
+		lRate, _ := s.at(i)
+		assert.Equal(0.0, lRate)
+	}
+	assert.Len(s.steps, 5)
+}
+
+func TestTrainHistory(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	assert.Nil(m.TrainHistory())
+	tc := makeDefaultTrainConfig()
+	// 5 data rows make 4 epochs of 20 iterations
+	assert.NoError(m.Train(tc, dataMx, 20))
+	history := m.TrainHistory()
+	assert.Len(history, 4)
+	assert.Equal(15, history[3].Iteration)
+	assert.True(history[3].Radius < history[0].Radius)
+	assert.True(history[3].LRate < history[0].LRate)
+	tc.Algorithm = "batch"
+	assert.NoError(m.Train(tc, dataMx, 3))
+	assert.Len(m.TrainHistory(), 3)
+	// unsupported schedule
+	tc.Schedule = "foo"
+	assert.Error(m.Train(tc, dataMx, 3))
+}
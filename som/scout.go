@@ -0,0 +1,159 @@
+package som
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/milosgajdos/gosom/pkg/utils"
+	"gonum.org/v1/gonum/mat"
+)
+
+// scoutScales scale the estimated number of map units of the default scout map sizes
+var scoutScales = []float64{0.5, 1.0, 2.0}
+
+// ScoutConfig holds configuration of map size scouting
+type ScoutConfig struct {
+	// Sizes are candidate grid dimensions. If empty, the candidates are derived from the
+	// GridSizeWith estimate: maps with half, the same and double the estimated number of units,
+	// each both with the estimated dimensions ratio and square.
+	Sizes [][]int
+	// Grid configures grid type and unit shape of scout maps; its Size is ignored
+	Grid *GridConfig
+	// Train configures training of scout maps. Radius of each scout map is
+	// set to half of its largest grid dimension
+	Train *TrainConfig
+	// Iters is the number of training iterations of each scout map.
+	// If Iters is 0, a fifth of the SuggestIterations is used
+	Iters int
+}
+
+// SizeScore holds evaluation of a scout map
+type SizeScore struct {
+	// Size holds scout map grid dimensions
+	Size []int
+	// QuantError is scout map quantization error
+	QuantError float64
+	// TopoError is scout map topographic error
+	TopoError float64
+	// Score is the scout map score; the lower the better
+	Score float64
+}
+
+// ScoutSize trains short scout maps of several candidate sizes on data and picks the map size.
+// Bigger maps have smaller quantization errors, so the quantization error of each scout map
+// is normalized by the unit count: it is multiplied by square root of the number of units,
+// which is how quantization error of 2D maps shrinks with the map size. The score of each scout
+// map is its normalized quantization error relative to the smallest one plus its topographic error.
+// ScoutSize returns the size of the scout map with the lowest score along with the scores of all scouts.
+// It fails with error if data is nil, the configuration is invalid or any scout map fails to train.
+func ScoutSize(data *mat.Dense, c *ScoutConfig) ([]int, []SizeScore, error) {
+	if data == nil {
+		return nil, nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	if c == nil || c.Grid == nil || c.Train == nil {
+		return nil, nil, fmt.Errorf("invalid scout configuration: %v", c)
+	}
+	if c.Iters < 0 {
+		return nil, nil, fmt.Errorf("invalid number of iterations: %d", c.Iters)
+	}
+
+	sizes := c.Sizes
+	if len(sizes) == 0 {
+		var err error
+		if sizes, err = scoutSizes(data, c.Grid); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	scores := make([]SizeScore, len(sizes))
+	minQE := math.MaxFloat64
+	for i, size := range sizes {
+		qe, te, err := scout(data, size, c)
+		if err != nil {
+			return nil, nil, err
+		}
+		// quantization error normalized by the unit count
+		nqe := qe * math.Sqrt(float64(utils.IntProduct(size)))
+		minQE = math.Min(minQE, nqe)
+		scores[i] = SizeScore{Size: size, QuantError: qe, TopoError: te, Score: nqe}
+	}
+
+	best := 0
+	for i := range scores {
+		if minQE > 0 {
+			scores[i].Score /= minQE
+		}
+		scores[i].Score += scores[i].TopoError
+		if scores[i].Score < scores[best].Score {
+			best = i
+		}
+	}
+
+	return scores[best].Size, scores, nil
+}
+
+// scoutSizes returns default scout map sizes for data and grid configuration
+func scoutSizes(data *mat.Dense, gc *GridConfig) ([][]int, error) {
+	var sizes [][]int
+	seen := make(map[string]bool)
+	for _, scale := range scoutScales {
+		for _, ratio := range []float64{0, 1} {
+			size, err := GridSizeWith(data, gc.UShape, &SizeConfig{
+				Scale: scale * DefaultSizeScale,
+				Ratio: ratio,
+				Type:  gc.Type,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if key := fmt.Sprint(size); !seen[key] {
+				seen[key] = true
+				sizes = append(sizes, size)
+			}
+		}
+	}
+	return sizes, nil
+}
+
+// scout trains a scout map of a given size and returns its quantization and topographic errors
+func scout(data *mat.Dense, size []int, c *ScoutConfig) (float64, float64, error) {
+	rows, dim := data.Dims()
+	m, err := NewMap(&MapConfig{
+		Grid: &GridConfig{
+			Size:   size,
+			Type:   c.Grid.Type,
+			UShape: c.Grid.UShape,
+		},
+		Cb: &CbConfig{
+			Dim:      dim,
+			InitFunc: RandInit,
+		},
+	}, data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tc := *c.Train
+	tc.Radius = math.Max(MinRadius, math.Max(float64(size[0]), float64(size[1]))/2.0)
+	iters := c.Iters
+	if iters == 0 {
+		if iters, err = SuggestIterations(rows, m.grid.Units(), tc.Algorithm); err != nil {
+			return 0, 0, err
+		}
+		iters = int(math.Max(1, float64(iters/5)))
+	}
+	if err := m.Train(&tc, data, iters); err != nil {
+		return 0, 0, err
+	}
+
+	qe, err := m.QuantError(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	te, err := m.TopoError(data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return qe, te, nil
+}
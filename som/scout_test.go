@@ -0,0 +1,73 @@
+package som
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestScoutSize(t *testing.T) {
+	assert := assert.New(t)
+
+	// elongated 2D data set
+	data := make([]float64, 0, 200)
+	for i := 0; i < 100; i++ {
+		data = append(data, float64(i%25), float64(i/25)*0.5)
+	}
+	mx := mat.NewDense(100, 2, data)
+	c := &ScoutConfig{
+		Grid: &GridConfig{
+			Type:   "planar",
+			UShape: "hexagon",
+		},
+		Train: &TrainConfig{
+			Algorithm: "batch",
+			RDecay:    "exp",
+			NeighbFn:  Gaussian,
+			LRate:     0.5,
+			LDecay:    "exp",
+		},
+	}
+	size, scores, err := ScoutSize(mx, c)
+	assert.NoError(err)
+	assert.True(len(scores) > 1)
+	// the picked size has the lowest score
+	found := false
+	for _, s := range scores {
+		if fmt.Sprint(size) == fmt.Sprint(s.Size) {
+			found = true
+			for _, o := range scores {
+				assert.True(s.Score <= o.Score)
+			}
+		}
+	}
+	assert.True(found)
+	// explicit candidate sizes
+	c.Sizes = [][]int{{2, 2}, {4, 10}}
+	c.Iters = 5
+	size, scores, err = ScoutSize(mx, c)
+	assert.NoError(err)
+	assert.Len(scores, 2)
+	assert.Contains(c.Sizes, size)
+	// normalized quantization errors are relative to the smallest one
+	for _, s := range scores {
+		assert.True(s.Score >= s.TopoError+1.0-1e-9)
+	}
+	// invalid candidate size
+	c.Sizes = [][]int{{-2, 2}}
+	_, _, err = ScoutSize(mx, c)
+	assert.Error(err)
+	// invalid configurations
+	c.Sizes, c.Iters = nil, -1
+	_, _, err = ScoutSize(mx, c)
+	assert.Error(err)
+	_, _, err = ScoutSize(mx, &ScoutConfig{Grid: c.Grid})
+	assert.Error(err)
+	_, _, err = ScoutSize(mx, nil)
+	assert.Error(err)
+	// nil data
+	_, _, err = ScoutSize(nil, c)
+	assert.Error(err)
+}
@@ -0,0 +1,96 @@
+package som
+
+import (
+	"fmt"
+	"io"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Sub subtracts codebook of map other from the codebook of map m and returns the per-unit differences
+// of codebook vectors in matrix rows. Comparing maps trained on data of different periods, e.g. last month
+// and this month, shows how the represented data has changed. The maps must have identical grids.
+// It fails with error if other is nil or if the map grids or codebook dimensions differ.
+func (m *Map) Sub(other *Map) (*mat.Dense, error) {
+	if err := m.checkComparable(other); err != nil {
+		return nil, err
+	}
+	diff := new(mat.Dense)
+	diff.Sub(m.codebook, other.codebook)
+	return diff, nil
+}
+
+// UnitShifts returns the distance between codebook vectors of each unit of maps m and other.
+// The distances are computed using the metric of map m. The maps must have identical grids.
+// It fails with error if other is nil or if the map grids or codebook dimensions differ.
+func (m *Map) UnitShifts(other *Map) ([]float64, error) {
+	if err := m.checkComparable(other); err != nil {
+		return nil, err
+	}
+	units, _ := m.codebook.Dims()
+	shifts := make([]float64, units)
+	for i := range shifts {
+		d, err := m.measure().distance(m.codebook.RawRowView(i), other.codebook.RawRowView(i))
+		if err != nil {
+			return nil, err
+		}
+		shifts[i] = d
+	}
+	return shifts, nil
+}
+
+// ShiftMap generates change heatmap of maps m and other in a given format and writes the output to w.
+// Units are colored by UnitShifts in the same way as UMatrixStats colors u-matrix values:
+// the darker the unit, the more its codebook vector has moved.
+// It fails with error if unsupported format is requested, if the maps can't be compared or if the write to w fails.
+func (m *Map) ShiftMap(w io.Writer, other *Map, stats *ClassStats, format, title string) error {
+	u, err := m.shiftMap(other)
+	if err != nil {
+		return err
+	}
+	return u.write(w, stats.Dominant(), format, title)
+}
+
+// ShiftMapSVG generates SVG representation of change heatmap of maps m and other configured by c and writes it to w.
+// See ShiftMap and UMatrixSVG for the description of the output.
+// It fails with error if the maps can't be compared or if the write to w fails.
+func (m *Map) ShiftMapSVG(w io.Writer, other *Map, stats *ClassStats, title string, c *SVGConfig) error {
+	u, err := m.shiftMap(other)
+	if err != nil {
+		return err
+	}
+	return u.svgWith(title, w, stats.Dominant(), c)
+}
+
+// shiftMap returns displayed map whose units hold codebook vector shifts between maps m and other
+func (m *Map) shiftMap(other *Map) (*umatrixMap, error) {
+	shifts, err := m.UnitShifts(other)
+	if err != nil {
+		return nil, err
+	}
+	u := m.umatrixMap()
+	u.values = shifts
+	return u, nil
+}
+
+// checkComparable checks if the units of maps m and other can be compared with each other.
+// It returns error if other is nil or if the map grids or codebook dimensions differ.
+func (m *Map) checkComparable(other *Map) error {
+	if other == nil {
+		return fmt.Errorf("invalid map supplied: %v", other)
+	}
+	g, og := m.grid, other.grid
+	sameSize := len(g.size) == len(og.size)
+	for i := 0; sameSize && i < len(g.size); i++ {
+		sameSize = g.size[i] == og.size[i]
+	}
+	if !sameSize || g.gtype != og.gtype || g.ushape != og.ushape {
+		return fmt.Errorf("mismatched map grids: %s %s %v, %s %s %v", g.gtype, g.ushape, g.size, og.gtype, og.ushape, og.size)
+	}
+	_, dim := m.codebook.Dims()
+	_, odim := other.codebook.Dims()
+	if dim != odim {
+		return fmt.Errorf("mismatched codebook dimensions: %d, %d", dim, odim)
+	}
+	return nil
+}
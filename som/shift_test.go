@@ -0,0 +1,56 @@
+package som
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapShifts(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	other, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	other.codebook.Copy(m.codebook)
+	other.codebook.Set(1, 0, m.codebook.At(1, 0)+3.0)
+	other.codebook.Set(1, 1, m.codebook.At(1, 1)+4.0)
+
+	diff, err := m.Sub(other)
+	assert.NoError(err)
+	rows, cols := diff.Dims()
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			assert.InDelta(m.codebook.At(i, j)-other.codebook.At(i, j), diff.At(i, j), 1e-9)
+		}
+	}
+	shifts, err := m.UnitShifts(other)
+	assert.NoError(err)
+	assert.Len(shifts, rows)
+	for i, s := range shifts {
+		if i == 1 {
+			assert.InDelta(5.0, s, 1e-9)
+			continue
+		}
+		assert.Equal(0.0, s)
+	}
+	var buf bytes.Buffer
+	assert.NoError(m.ShiftMap(&buf, other, NewClassStats(), "svg", "Shift"))
+	assert.Contains(buf.String(), "<svg")
+	buf.Reset()
+	assert.NoError(m.ShiftMapSVG(&buf, other, NewClassStats(), "Shift", nil))
+	assert.Contains(buf.String(), "Shift")
+	assert.Error(m.ShiftMap(&buf, other, NewClassStats(), "pdf", "Shift"))
+	// incomparable maps
+	_, err = m.Sub(nil)
+	assert.Error(err)
+	c := *mSom
+	c.Grid = &GridConfig{Size: []int{2, 2}, Type: "planar", UShape: "rectangle"}
+	small, err := NewMap(&c, dataMx)
+	assert.NoError(err)
+	_, err = m.UnitShifts(small)
+	assert.Error(err)
+	assert.Error(m.ShiftMap(&buf, small, NewClassStats(), "svg", "Shift"))
+}
@@ -0,0 +1,89 @@
+package som
+
+import "fmt"
+
+// classSmoothing configures smoothing of dominant unit classes over the grid lattice
+type classSmoothing struct {
+	// grid is the lattice of map units
+	grid *Grid
+	// minPurity is the minimum purity of units which keep their own dominant class
+	minPurity float64
+}
+
+// Smooth turns on smoothing of the dominant classes returned by Dominant over the lattice of grid g.
+// Units whose purity is at least minPurity keep their dominant class; units without any samples and
+// units with lower purity inherit the most frequent class of their labeled lattice neighbours.
+// The labels spread from unit to unit until every unit connected to a labeled one has a class,
+// so all the units of the map are labeled even if only a few of them receive labeled samples.
+// If there are several most frequent neighbour classes, the smallest one is inherited; low purity
+// units which have no labeled neighbours keep their own dominant class.
+// Smoothing only changes Dominant, histograms and purities still describe the recorded samples.
+// Nil grid turns the smoothing off. It fails with error if minPurity is not within [0, 1].
+func (s *ClassStats) Smooth(g *Grid, minPurity float64) error {
+	if minPurity < 0 || minPurity > 1 {
+		return fmt.Errorf("invalid minimum purity: %f", minPurity)
+	}
+	if g == nil {
+		s.smooth = nil
+		return nil
+	}
+	s.smooth = &classSmoothing{grid: g, minPurity: minPurity}
+	return nil
+}
+
+// classes returns the dominant classes of stats s smoothed over the grid lattice
+func (c *classSmoothing) classes(s *ClassStats) map[int]int {
+	units := c.grid.Units()
+	classes := make(map[int]int)
+	for unit, hist := range s.hists {
+		if unit < units && s.UnitPurity(unit) >= c.minPurity {
+			classes[unit], _ = dominantClass(hist)
+		}
+	}
+	neighbours := c.neighbours()
+	// units inherit the classes labeled in the previous round, so the labels don't depend on unit order
+	for {
+		inherited := make(map[int]int)
+		for u := 0; u < units; u++ {
+			if _, ok := classes[u]; ok {
+				continue
+			}
+			hist := make(map[int]int)
+			for _, v := range neighbours[u] {
+				if class, ok := classes[v]; ok {
+					hist[class]++
+				}
+			}
+			if len(hist) > 0 {
+				inherited[u], _ = dominantClass(hist)
+			}
+		}
+		if len(inherited) == 0 {
+			break
+		}
+		for u, class := range inherited {
+			classes[u] = class
+		}
+	}
+	for unit, hist := range s.hists {
+		if _, ok := classes[unit]; !ok {
+			classes[unit], _ = dominantClass(hist)
+		}
+	}
+	return classes
+}
+
+// neighbours returns the indices of lattice neighbours of every grid unit
+func (c *classSmoothing) neighbours() [][]int {
+	units := c.grid.Units()
+	neighbours := make([][]int, units)
+	for u := 0; u < units; u++ {
+		for v := u + 1; v < units; v++ {
+			if c.grid.Adjacent(u, v) {
+				neighbours[u] = append(neighbours[u], v)
+				neighbours[v] = append(neighbours[v], u)
+			}
+		}
+	}
+	return neighbours
+}
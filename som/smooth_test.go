@@ -0,0 +1,69 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassStatsSmooth(t *testing.T) {
+	assert := assert.New(t)
+
+	// chain of 7 units: 0 1 2 3 4 5 6
+	g, err := NewGrid(&GridConfig{Size: []int{1, 7}, Type: "planar", UShape: "rectangle"})
+	assert.NoError(err)
+	s := NewClassStats()
+	s.Add(0, 1)
+	s.Add(0, 1)
+	// low purity unit
+	s.Add(2, 1)
+	s.Add(2, 2)
+	s.Add(2, 2)
+	s.Add(5, 2)
+	raw := map[int]int{0: 1, 2: 2, 5: 2}
+	assert.Equal(raw, s.Dominant())
+
+	// all the sampled units keep their classes and the empty units inherit the labels of their neighbours;
+	// unit 1 has two neighbour classes, so it inherits the smallest one
+	assert.NoError(s.Smooth(g, 0))
+	assert.Equal(map[int]int{0: 1, 1: 1, 2: 2, 3: 2, 4: 2, 5: 2, 6: 2}, s.Dominant())
+	// low purity unit 2 is relabeled by the labels spreading from its neighbours
+	assert.NoError(s.Smooth(g, 0.7))
+	assert.Equal(map[int]int{0: 1, 1: 1, 2: 1, 3: 2, 4: 2, 5: 2, 6: 2}, s.Dominant())
+	// histograms and purities are not smoothed
+	assert.Equal([]int{0, 2, 5}, s.Units())
+	assert.InDelta(5.0/6.0, s.Purity(), 1e-9)
+	// smoothing follows incrementally added samples
+	s.Add(3, 2)
+	assert.Equal(map[int]int{0: 1, 1: 1, 2: 2, 3: 2, 4: 2, 5: 2, 6: 2}, s.Dominant())
+
+	// low purity units without labeled neighbours keep their classes
+	s = NewClassStats()
+	s.Add(3, 0)
+	s.Add(3, 1)
+	assert.NoError(s.Smooth(g, 1))
+	assert.Equal(map[int]int{3: 0}, s.Dominant())
+	// every unit of a map is labeled
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	stats, err := m.ClassStats(dataMx, map[int]int{0: 3})
+	assert.NoError(err)
+	assert.NoError(stats.Smooth(m.Grid(), 0.5))
+	dominant := stats.Dominant()
+	assert.Len(dominant, m.Grid().Units())
+	for _, class := range dominant {
+		assert.Equal(3, class)
+	}
+
+	// nil grid turns the smoothing off
+	s = NewClassStats()
+	s.Add(0, 1)
+	assert.NoError(s.Smooth(g, 0.5))
+	assert.Len(s.Dominant(), 7)
+	assert.NoError(s.Smooth(nil, 0))
+	assert.Equal(map[int]int{0: 1}, s.Dominant())
+	// invalid purities
+	for _, p := range []float64{-0.1, 1.1} {
+		assert.Error(s.Smooth(g, p))
+	}
+}
@@ -1,14 +1,17 @@
 package som
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"math/rand"
+	"math"
 	"runtime"
-	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/milosgajdos/gosom/pkg/matrix"
 	"gonum.org/v1/gonum/mat"
 )
 
@@ -20,8 +23,35 @@ type Map struct {
 	// grid is a matrix which contains SOM unit coordinates
 	// grid dimensions depend on chosen configuration
 	grid *Grid
+	// tc is the configuration of the last training run
+	tc *TrainConfig
+	// meta holds map metadata
+	meta Metadata
+	// history holds the schedule of the last training
+	history []ScheduleStep
+	// training is set to 1 while the map is being trained
+	training int32
+	// metric is the distance metric used to find BMUs
+	metric Metric
+	// distFn is custom distance function used in place of metric; it is nil if metric is used
+	distFn DistanceFunc
+	// mh holds the covariance of Mahalanobis metric; it is nil if the map uses other metric
+	mh *mahalanobis
+	// cm masks the data columns of the last training; it is nil if no column was masked
+	cm *colMask
+	// unitDist is a preallocated unit distance matrix
+	unitDist *mat.Dense
+	// accs are preallocated batch training accumulators
+	accs []*batchAcc
+	// checkpoint holds the state of the last training stopped when its context was done
+	checkpoint *Checkpoint
+	// wins counts how many times each unit was the BMU of a training sample
+	wins []int
 }
 
+// ErrTrainInProgress is returned when Train is called on a map which is already being trained
+var ErrTrainInProgress = errors.New("map training already in progress")
+
 // NewMap creates a new SOM based on the provided configuration.
 // It creates a map grid and initializes codebook vectors using the provided configuration parameter.
 // NewMap returns error if the provided configuration is not valid or if the data matrix is nil or
@@ -36,6 +66,10 @@ func NewMap(c *MapConfig, data *mat.Dense) (*Map, error) {
 		return nil, err
 	}
 
+	if _, ok := metricNames[c.Metric]; !ok {
+		return nil, fmt.Errorf("unsupported metric: %s", c.Metric)
+	}
+
 	codebook, err := c.Cb.InitFunc(data, c.Grid.Size)
 	if err != nil {
 		return nil, err
@@ -46,41 +80,464 @@ func NewMap(c *MapConfig, data *mat.Dense) (*Map, error) {
 		return nil, err
 	}
 
-	return &Map{
+	m := &Map{
 		codebook: codebook,
 		grid:     grid,
-	}, nil
+		meta:     newMetadata(),
+		metric:   c.Metric,
+		distFn:   c.DistanceFn,
+	}
+
+	if c.Prealloc {
+		if err := m.prealloc(); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// resetBuffers drops the preallocated unit distance matrix and batch training accumulators
+// which no longer match the map once its grid or codebook is replaced
+func (m *Map) resetBuffers() {
+	m.unitDist = nil
+	m.accs = nil
+}
+
+// prealloc allocates unit distance matrix and batch training accumulators
+func (m *Map) prealloc() error {
+	unitDist, err := m.UnitDist()
+	if err != nil {
+		return err
+	}
+	m.unitDist = unitDist
+	// accumulators are preallocated for the default number of workers
+	m.accs = newBatchAccs(runtime.NumCPU()+1, m.codebook)
+
+	return nil
+}
+
+// newMetadata returns metadata of a newly created map
+func newMetadata() Metadata {
+	return Metadata{
+		FormatVersion: ModelFormatVersion,
+		Version:       Version,
+		Created:       time.Now().UTC(),
+	}
 }
 
 // Codebook returns a matrix which contains SOM codebook vectors
-func (m Map) Codebook() mat.Matrix {
+func (m *Map) Codebook() mat.Matrix {
 	return m.codebook
 }
 
 // Grid returns SOM grid
-func (m Map) Grid() *Grid {
+func (m *Map) Grid() *Grid {
 	return m.grid
 }
 
-// UnitDist returns a matrix which contains Euclidean distances between SOM units
-func (m Map) UnitDist() (*mat.Dense, error) {
-	return DistanceMx(Euclidean, m.grid.coords)
+// Metric returns the distance metric of the map: the metric of map configuration or the metric the map was trained with.
+// The metric is used to find BMUs in all evaluation and projection methods unless the map has a custom distance function.
+func (m *Map) Metric() Metric {
+	return m.metric
+}
+
+// SetMetric sets the distance metric used to find BMUs in all evaluation and projection methods
+// and by training configurations which leave their metric Euclidean.
+// Mahalanobis metric computes euclidean distances until the map is trained with it.
+// It fails with error if the metric is not supported.
+func (m *Map) SetMetric(metric Metric) error {
+	if _, ok := metricNames[metric]; !ok {
+		return fmt.Errorf("unsupported metric: %s", metric)
+	}
+	if metric != Mahalanobis {
+		m.mh = nil
+	}
+	m.metric = metric
+	return nil
+}
+
+// DistanceFunc returns custom distance function of the map or nil if the map uses its metric.
+// The function is set by map or training configuration and is used in place of the metric.
+func (m *Map) DistanceFunc() DistanceFunc {
+	return m.distFn
+}
+
+// SetDistanceFunc sets custom distance function fn used in place of the map metric in all evaluation and
+// projection methods. Custom distance functions are not saved in map models, so maps trained with them
+// must have them set again once they are loaded. If fn is nil, the map metric is used.
+func (m *Map) SetDistanceFunc(fn DistanceFunc) {
+	m.distFn = fn
+}
+
+// ColumnWeights returns the weights which scale metric differences of data columns in BMU search of the map:
+// the columns carried by the last training have weight 0 and the other columns its feature weights.
+// It returns nil if all the columns have weight 1.
+func (m *Map) ColumnWeights() []float64 {
+	if m.cm == nil || m.cm.weights == nil {
+		return nil
+	}
+	return append([]float64(nil), m.cm.weights...)
+}
+
+// measure returns the measure of the map distances. The metric differences of columns are scaled by
+// the column weights of the last training, so carried columns are left out of BMU search.
+func (m *Map) measure() measure {
+	ms := measure{metric: m.metric, fn: m.distFn, mh: m.mh}
+	if m.cm != nil {
+		ms.weights = m.cm.weights
+	}
+	return ms
+}
+
+// trainMeasure returns the measure of distances of training configured by c on data.
+// The distance function of c takes precedence over the distance function of the map and the metric
+// of c takes precedence over the map metric unless it is Euclidean.
+// It fails with error if custom distance function or Mahalanobis metric is used along with feature
+// weights or carried columns, which only scale the other built-in metrics, or if the covariance
+// of Mahalanobis metric is missing or invalid.
+func (m *Map) trainMeasure(c *TrainConfig, data *mat.Dense) (measure, error) {
+	fn := c.DistanceFn
+	if fn == nil {
+		fn = m.distFn
+	}
+	metric := c.Metric
+	if metric == Euclidean {
+		metric = m.metric
+	}
+	ms := measure{metric: metric, fn: fn}
+	if (fn != nil || metric == Mahalanobis) && (len(c.Carry) > 0 || len(c.FeatureWeights) > 0) {
+		return ms, fmt.Errorf("custom distance function and mahalanobis metric unsupported with carried columns or feature weights")
+	}
+	if fn == nil && metric == Mahalanobis {
+		mh, err := m.trainMahalanobis(c, data)
+		if err != nil {
+			return ms, err
+		}
+		ms.mh = mh
+	}
+	return ms, nil
+}
+
+// Metadata returns SOM metadata which record its version, metric and provenance
+func (m *Map) Metadata() Metadata {
+	meta := m.meta
+	meta.Metric = m.metric.String()
+	return meta
+}
+
+// UnitDist returns a matrix which contains Euclidean distances between SOM units on the grid.
+// The distances are given by the grid topology, so they don't depend on the map metric. Distances between units of toroid and cylinder grids wrap around the grid borders.
+func (m *Map) UnitDist() (*mat.Dense, error) {
+	return m.grid.UnitDist(), nil
+}
+
+// unitDists returns preallocated unit distance matrix if available or calculates it
+func (m *Map) unitDists() (*mat.Dense, error) {
+	if m.unitDist != nil {
+		return m.unitDist, nil
+	}
+
+	return m.UnitDist()
 }
 
 // BMUs returns a slice which contains indices of Best Match Unit vectors to the map
 // codebook for each vector stored in data rows.
 // It returns error if the data dimension and map codebook dimensions are not the same.
-func (m Map) BMUs(data *mat.Dense) ([]int, error) {
-	return BMUs(data, m.codebook)
+func (m *Map) BMUs(data *mat.Dense) ([]int, error) {
+	return bmus(m.measure(), data, m.codebook)
+}
+
+// BMUDistances returns the BMUs of data rows along with the distances of the rows to their BMUs.
+// The distances are measured the same way the BMUs are found: by the map metric or custom distance function.
+// It returns error if data is nil or if the data dimension and map codebook dimensions are not the same.
+func (m *Map) BMUDistances(data *mat.Dense) ([]int, []float64, error) {
+	if data == nil {
+		return nil, nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	ms := m.measure()
+	rows, _ := data.Dims()
+	bmus := make([]int, rows)
+	dists := make([]float64, rows)
+	for i := 0; i < rows; i++ {
+		bmu, err := ms.closest(data.RawRowView(i), m.codebook)
+		if err != nil {
+			return nil, nil, err
+		}
+		d, err := ms.distance(data.RawRowView(i), m.codebook.RawRowView(bmu))
+		if err != nil {
+			return nil, nil, err
+		}
+		bmus[i], dists[i] = bmu, d
+	}
+
+	return bmus, dists, nil
+}
+
+// Wins returns the number of times each map unit was the BMU of a training sample.
+// The counts are maintained by all training methods as a side effect of their BMU search and they
+// accumulate over training calls until ResetWins is called, so units which never win, i.e. dead units,
+// and the hit map of the training samples are available without another pass over the data.
+// Sequential training adds a win with every iteration and batch training adds the wins of all the data rows
+// with non-zero weight with every iteration. The counts are not saved in map models.
+// It returns nil if the map has not been trained since it was created or the counts were reset.
+func (m *Map) Wins() []int {
+	return m.wins
+}
+
+// ResetWins resets the unit win counts returned by Wins
+func (m *Map) ResetWins() {
+	m.wins = nil
+}
+
+// winCounts returns unit win counts; the counts are allocated if the map has none
+func (m *Map) winCounts() []int {
+	if units, _ := m.codebook.Dims(); len(m.wins) != units {
+		m.wins = make([]int, units)
+	}
+	return m.wins
+}
+
+// Hits returns a slice which contains the number of data samples mapped to each map unit
+// i.e. the number of times each unit is the BMU of some data sample.
+// It returns error if the data dimension and map codebook dimensions are not the same.
+func (m *Map) Hits(data *mat.Dense) ([]int, error) {
+	bmus, err := m.BMUs(data)
+	if err != nil {
+		return nil, err
+	}
+	rows, _ := m.codebook.Dims()
+	hits := make([]int, rows)
+	for _, bmu := range bmus {
+		hits[bmu]++
+	}
+
+	return hits, nil
+}
+
+// VectorAt returns a codebook vector interpolated at an arbitrary grid position given by x and y
+// grid coordinates as returned by Grid Coords. Positions outside of the grid are clamped to the grid border.
+// On rectangle grids the vector is bilinearly interpolated from the four surrounding units.
+// On hexagon grids it is interpolated along the two enclosing unit rows taking the row offsets into account.
+// On sphere grids x and y are coordinates of the equirectangular projection of the sphere drawn by u-matrix
+// and on 3D grids coordinates of the layers drawn side by side by u-matrix; the codebook vector
+// of the closest unit is returned.
+func (m *Map) VectorAt(x, y float64) []float64 {
+	if m.grid.spherical() || m.grid.layered() {
+		proj, _, _ := m.grid.display()
+		// no need to check for error: the projection is never nil
+		unit, _ := ClosestVec(Euclidean, []float64{x, y}, proj)
+		return mat.Row(nil, unit, m.codebook)
+	}
+	rows := m.grid.size[0]
+	// hexagon unit rows are sqrt(0.75) apart
+	if m.grid.hexagonal() {
+		y /= math.Sqrt(0.75)
+	}
+	y = math.Max(0.0, math.Min(y, float64(rows-1)))
+	// pick the two enclosing rows
+	y0 := int(math.Floor(y))
+	y1 := y0
+	if y0 < rows-1 {
+		y1 = y0 + 1
+	}
+	ty := y - float64(y0)
+	// interpolate between rows
+	v0 := m.rowVectorAt(x, y0)
+	v1 := m.rowVectorAt(x, y1)
+	for i := range v0 {
+		v0[i] = (1-ty)*v0[i] + ty*v1[i]
+	}
+
+	return v0
+}
+
+// rowVectorAt returns a codebook vector linearly interpolated at x coordinate of a given grid row
+func (m *Map) rowVectorAt(x float64, row int) []float64 {
+	rows, cols := m.grid.size[0], m.grid.size[1]
+	// every other hexagon row is offset by 0.5
+	if m.grid.hexagonal() && row%2 == 1 {
+		x -= 0.5
+	}
+	x = math.Max(0.0, math.Min(x, float64(cols-1)))
+	// pick the two enclosing units
+	x0 := int(math.Floor(x))
+	x1 := x0
+	if x0 < cols-1 {
+		x1 = x0 + 1
+	}
+	tx := x - float64(x0)
+	a := m.codebook.RawRowView(x0*rows + row)
+	b := m.codebook.RawRowView(x1*rows + row)
+	vec := make([]float64, len(a))
+	for i := range vec {
+		vec[i] = (1-tx)*a[i] + tx*b[i]
+	}
+
+	return vec
+}
+
+// Resize returns a new map with a grid of a given size whose codebook vectors are interpolated
+// from the codebook of m. The new lattice is stretched over the area spanned by the grid of m,
+// so the resized map preserves the ordering learnt by m. The resized map keeps the metric, custom
+// distance function and column weights of m and can be fine-tuned with Train.
+// Resize fails with error if the new grid could not be created or if the map grid is a sphere or 3D grid.
+func (m *Map) Resize(size []int) (*Map, error) {
+	if m.grid.spherical() {
+		return nil, fmt.Errorf("unsupported grid type: %s", m.grid.gtype)
+	}
+	for _, dims := range [][]int{m.grid.size, size} {
+		if len(dims) == 3 {
+			return nil, fmt.Errorf("unsupported number of grid dimensions: %d", len(dims))
+		}
+	}
+	grid, err := NewGrid(&GridConfig{
+		Size:   size,
+		Type:   m.grid.gtype,
+		UShape: m.grid.ushape,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// grid bounds of both old and new grid
+	oldMin, oldMax := coordsBounds(m.grid.coordinates())
+	newMin, newMax := coordsBounds(grid.coordinates())
+	// scale maps new grid coordinate onto the old grid
+	scale := func(v float64, dim int) float64 {
+		if newMax[dim] == newMin[dim] {
+			return (oldMin[dim] + oldMax[dim]) / 2
+		}
+		return oldMin[dim] + (v-newMin[dim])/(newMax[dim]-newMin[dim])*(oldMax[dim]-oldMin[dim])
+	}
+	rows, _ := grid.coordinates().Dims()
+	_, dim := m.codebook.Dims()
+	codebook := mat.NewDense(rows, dim, nil)
+	for i := 0; i < rows; i++ {
+		x := scale(grid.coordinates().At(i, 0), 0)
+		y := scale(grid.coordinates().At(i, 1), 1)
+		codebook.SetRow(i, m.VectorAt(x, y))
+	}
+
+	return &Map{
+		codebook: codebook,
+		grid:     grid,
+		meta:     newMetadata(),
+		metric:   m.metric,
+		distFn:   m.distFn,
+		mh:       m.mh,
+		cm:       m.cm,
+	}, nil
 }
 
-// MarshalTo serializes SOM codebook in a given format to writer w.
-// At the moment only the native gonum binary format is supported.
+// WithUShape returns a view of the map whose grid lattice is laid out in units of shape uShape,
+// so the same trained codebook can be rendered e.g. both in hexagon and rectangle lattice.
+// The view shares the codebook, metric and metadata of m, so training either of them changes both;
+// only its unit coordinates and everything computed from them, such as u-matrix, visualizations
+// and topographic errors, follow the new lattice. Unit indices are the same in both lattices.
+// WithUShape fails with error if the unit shape is not supported or if the map grid is a sphere.
+func (m *Map) WithUShape(uShape string) (*Map, error) {
+	if m.grid.spherical() {
+		return nil, fmt.Errorf("unsupported grid type: %s", m.grid.gtype)
+	}
+	grid, err := NewGrid(&GridConfig{
+		Size:   m.grid.size,
+		Type:   m.grid.gtype,
+		UShape: uShape,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Map{
+		codebook: m.codebook,
+		grid:     grid,
+		tc:       m.tc,
+		meta:     m.meta,
+		history:  m.history,
+		metric:   m.metric,
+		distFn:   m.distFn,
+		mh:       m.mh,
+		cm:       m.cm,
+		wins:     m.wins,
+	}, nil
+}
+
+// coordsBounds returns minimum and maximum values of each column of grid coordinates matrix
+func coordsBounds(coords *mat.Dense) ([]float64, []float64) {
+	_, cols := coords.Dims()
+	// grid coordinates are never empty so no need to check for errors
+	min, _ := matrix.ColsMin(cols, coords)
+	max, _ := matrix.ColsMax(cols, coords)
+	return min, max
+}
+
+// MarshalTo serializes SOM in a given format to writer w.
+// The following formats are supported:
+// gonum - native gonum binary format which encodes SOM codebook only
+// som   - gosom model format which encodes both SOM grid and codebook
+// somoclu - Somoclu text format which encodes SOM codebook with grid dimensions
+// csv   - CSV table with a header row which encodes unit indices, grid coordinates and codebook vectors
+// json  - JSON document which encodes grid parameters and unit indices, coordinates and codebook vectors
+// The csv and json formats are meant for inspecting the map in other tools and can't be unmarshaled.
 // It returns the number of bytes written to w or fails with error.
 func (m *Map) MarshalTo(format string, w io.Writer) (int, error) {
 	switch format {
 	case "gonum":
 		return m.codebook.MarshalBinaryTo(w)
+	case "som":
+		return m.marshalModel(w)
+	case "somoclu":
+		return writeSomocluCodebook(w, m.codebook, m.grid.Size())
+	case "csv", "json":
+		encode := m.csvCodebook
+		if format == "json" {
+			encode = m.jsonCodebook
+		}
+		b, err := encode()
+		if err != nil {
+			return 0, err
+		}
+		return w.Write(b)
+	}
+
+	return 0, fmt.Errorf("unsupported format: %s", format)
+}
+
+// UnmarshalFrom decodes SOM encoded in a given format from reader r into m.
+// See MarshalTo for the list of supported formats except csv and json. Decoding gonum format
+// replaces the map codebook only, som format replaces both SOM grid and codebook.
+// Decoding somoclu format replaces the map codebook; the grid dimensions of the decoded
+// codebook must match the map grid dimensions. ReadSomocluCodebook reads codebooks of any size.
+// Codebooks marshaled by the legacy gonum/matrix mat64 package are detected and decoded
+// when requesting gonum format; they can be also decoded explicitly using mat64 format.
+// It returns the number of bytes read from r or fails with error.
+func (m *Map) UnmarshalFrom(format string, r io.Reader) (int, error) {
+	switch format {
+	case "gonum", "mat64":
+		codebook, n, err := readCodebook(r, format == "mat64")
+		if err != nil {
+			return n, err
+		}
+		m.codebook = codebook
+		m.resetBuffers()
+		// codebooks carry no training metadata, so the columns are no longer masked
+		m.cm = nil
+		return n, nil
+	case "som":
+		return m.unmarshalModel(r)
+	case "somoclu":
+		cr := &countReader{r: r}
+		codebook, dims, err := ReadSomocluCodebook(cr)
+		if err != nil {
+			return cr.n, err
+		}
+		if rows, cols := somocluDims(m.grid.Size()); dims[0] != rows || dims[1] != cols {
+			return cr.n, fmt.Errorf("grid dimension mismatch: %v != %v", dims, m.grid.Size())
+		}
+		m.codebook = codebook
+		m.resetBuffers()
+		m.cm = nil
+		return cr.n, nil
 	}
 
 	return 0, fmt.Errorf("unsupported format: %s", format)
@@ -88,80 +545,233 @@ func (m *Map) MarshalTo(format string, w io.Writer) (int, error) {
 
 // UMatrix generates SOM u-matrix in a given format and writes the output to w.
 // NOTE: if the map has not been trained u-matrix returns seemingly non-sensical results.
-// At the moment only SVG format is supported -- requesting other formats fails with error.
+// The following formats are supported: svg, html and png -- requesting other formats fails with error.
 // It fails with error if the write to w fails.
-func (m Map) UMatrix(w io.Writer, data *mat.Dense, classMap map[int]int, format, title string) error {
-	switch format {
-	case "svg":
-		{
-			// map that contains most frequent BMU class of all of its classes
-			bmuClassMap := make(map[int]int)
-			// only do this if we supply data class map
-			if len(classMap) > 0 {
-				bmuClasses, err := m.mapBMUclasses(data, classMap)
-				if err != nil {
-					return err
-				}
+func (m *Map) UMatrix(w io.Writer, data *mat.Dense, classMap map[int]int, format, title string) error {
+	stats := NewClassStats()
+	// only do this if we supply data class map
+	if len(classMap) > 0 {
+		var err error
+		stats, err = m.ClassStats(data, classMap)
+		if err != nil {
+			return err
+		}
+	}
 
-				// find the most frequent class for each codebook vector
-				for cbi, class := range bmuClasses {
-					sort.Ints(class)
-					count := 1
-					currentIndex := 0
-					for i := 1; i < len(class); i++ {
-						if class[i] == class[currentIndex] {
-							count++
-						} else {
-							count--
-						}
-						if count == 0 {
-							currentIndex = i
-							count = 1
-						}
-					}
-					bmuClassMap[cbi] = class[currentIndex]
-				}
-			}
+	return m.UMatrixStats(w, stats, format, title)
+}
 
-			return UMatrixSVG(m.codebook, m.grid.size, m.grid.ushape, title, w, bmuClassMap)
-		}
+// UMatrixStats generates SOM u-matrix in a given format and writes the output to w.
+// Each unit is labeled with the most frequent class found in the supplied class statistics.
+// This allows to render u-matrix from class statistics maintained incrementally.
+// It fails with error if unsupported format is requested or if the write to w fails.
+func (m *Map) UMatrixStats(w io.Writer, stats *ClassStats, format, title string) error {
+	return m.umatrixMap().write(w, stats.Dominant(), format, title)
+}
+
+// UMatrixSVG generates SOM u-matrix SVG representation configured by c and writes it to w.
+// Each unit is labeled with the most frequent class found in the supplied class statistics.
+// If c is nil, the output is the same as the output of UMatrixStats in svg format.
+// It fails with error if the write to w fails.
+func (m *Map) UMatrixSVG(w io.Writer, stats *ClassStats, title string, c *SVGConfig) error {
+	u := m.umatrixMap()
+	return u.svgWith(title, w, stats.Dominant(), c)
+}
+
+// UnitQuantErrors returns the average quantization error of data rows mapped to each map unit.
+// Units without any mapped rows have zero quantization error; see Hits for the number of mapped rows.
+// It fails with error if data is nil or the distances could not be computed.
+func (m *Map) UnitQuantErrors(data *mat.Dense) ([]float64, error) {
+	return unitQuantErrors(m.measure(), data, m.codebook)
+}
+
+// QuantErrorMap generates quantization error map of data in a given format and writes the output to w.
+// Units are colored by the average quantization error of data rows mapped to them in the same way
+// as UMatrixStats colors u-matrix values: the darker the unit, the worse it represents its data.
+// Units without any mapped rows are drawn in the lightest shade.
+// It fails with error if unsupported format is requested, if quantization errors could not be
+// computed or if the write to w fails.
+func (m *Map) QuantErrorMap(w io.Writer, data *mat.Dense, stats *ClassStats, format, title string) error {
+	u, err := m.quantErrorMap(data)
+	if err != nil {
+		return err
 	}
+	return u.write(w, stats.Dominant(), format, title)
+}
 
-	return fmt.Errorf("unsupported format %s", format)
+// QuantErrorMapSVG generates SVG representation of quantization error map of data configured by c and writes it to w.
+// See QuantErrorMap and UMatrixSVG for the description of the output.
+// It fails with error if quantization errors could not be computed or if the write to w fails.
+func (m *Map) QuantErrorMapSVG(w io.Writer, data *mat.Dense, stats *ClassStats, title string, c *SVGConfig) error {
+	u, err := m.quantErrorMap(data)
+	if err != nil {
+		return err
+	}
+	return u.svgWith(title, w, stats.Dominant(), c)
 }
 
-// mapBMUclasses returns a map which contains a list of classes of which this BMUs input samples are members of.
-// We go through all data samples and add their classes to the list of classes of their respective BMUs.
-func (m Map) mapBMUclasses(data *mat.Dense, classes map[int]int) (map[int][]int, error) {
-	rows, _ := data.Dims()
-	// map of all classes for each BMU
-	bmuClasses := make(map[int][]int)
-	for row := 0; row < rows; row++ {
-		// find BMU
-		cbi, err := ClosestVec(Euclidean, data.RawRowView(row), m.codebook)
-		if err != nil {
+// quantErrorMap returns displayed map whose units hold quantization errors of data
+func (m *Map) quantErrorMap(data *mat.Dense) (*umatrixMap, error) {
+	qErrs, err := m.UnitQuantErrors(data)
+	if err != nil {
+		return nil, err
+	}
+	u := m.umatrixMap()
+	u.values = qErrs
+	return u, nil
+}
+
+// HitMap generates hit map of data in a given format and writes the output to w.
+// Units are colored by the number of data rows mapped to them in the same way as UMatrixStats
+// colors u-matrix values: the darker the unit, the more rows it represents. Hit maps of the rows
+// of a single class selected by ClassRows show how the class occupies the map.
+// If data is nil, units are colored by their win counts recorded during training as returned by Wins.
+// It fails with error if unsupported format is requested, if hits could not be computed or if the write to w fails.
+func (m *Map) HitMap(w io.Writer, data *mat.Dense, stats *ClassStats, format, title string) error {
+	u, err := m.hitMap(data)
+	if err != nil {
+		return err
+	}
+	return u.write(w, stats.Dominant(), format, title)
+}
+
+// HitMapSVG generates SVG representation of hit map of data configured by c and writes it to w.
+// See HitMap and UMatrixSVG for the description of the output.
+// It fails with error if hits could not be computed or if the write to w fails.
+func (m *Map) HitMapSVG(w io.Writer, data *mat.Dense, stats *ClassStats, title string, c *SVGConfig) error {
+	u, err := m.hitMap(data)
+	if err != nil {
+		return err
+	}
+	return u.svgWith(title, w, stats.Dominant(), c)
+}
+
+// hitMap returns displayed map whose units hold the number of data rows mapped to them.
+// If data is nil, the units hold their training win counts.
+func (m *Map) hitMap(data *mat.Dense) (*umatrixMap, error) {
+	hits := m.wins
+	if data != nil {
+		var err error
+		if hits, err = m.Hits(data); err != nil {
 			return nil, err
 		}
-		// lookup input sample class
-		class, ok := classes[row]
-		if ok {
-			classList, ok := bmuClasses[cbi]
-			if !ok {
-				classList = []int{}
-			}
-			// append class to BMU list of classes
-			classList = append(classList, class)
-			bmuClasses[cbi] = classList
-		}
+	} else if hits == nil {
+		return nil, fmt.Errorf("map has no training win counts")
+	}
+	u := m.umatrixMap()
+	u.values = make([]float64, len(hits))
+	for i, h := range hits {
+		u.values[i] = float64(h)
+	}
+	return u, nil
+}
+
+// UMatrixHits generates SOM u-matrix of the units hit by data in a given format and writes the output to w.
+// Only the units which are BMUs of some data rows are drawn; they keep the colors of the full u-matrix,
+// so u-matrices of the rows of different classes selected by ClassRows can be compared with each other.
+// It fails with error if unsupported format is requested, if BMUs could not be found or if the write to w fails.
+func (m *Map) UMatrixHits(w io.Writer, data *mat.Dense, stats *ClassStats, format, title string) error {
+	u, err := m.umatrixHitsMap(data)
+	if err != nil {
+		return err
+	}
+	return u.write(w, stats.Dominant(), format, title)
+}
+
+// UMatrixHitsSVG generates SVG representation of u-matrix of the units hit by data configured by c and writes it to w.
+// See UMatrixHits and UMatrixSVG for the description of the output.
+// It fails with error if BMUs could not be found or if the write to w fails.
+func (m *Map) UMatrixHitsSVG(w io.Writer, data *mat.Dense, stats *ClassStats, title string, c *SVGConfig) error {
+	u, err := m.umatrixHitsMap(data)
+	if err != nil {
+		return err
 	}
+	return u.svgWith(title, w, stats.Dominant(), c)
+}
 
-	return bmuClasses, nil
+// umatrixHitsMap returns displayed u-matrix whose units which are not hit by data are hidden
+func (m *Map) umatrixHitsMap(data *mat.Dense) (*umatrixMap, error) {
+	hits, err := m.Hits(data)
+	if err != nil {
+		return nil, err
+	}
+	u := m.umatrixMap()
+	u.hidden = make([]bool, len(hits))
+	for i, h := range hits {
+		u.hidden[i] = h == 0
+	}
+	return u, nil
+}
+
+// umatrixMap returns displayed map of m. Sphere grids are displayed in equirectangular projection.
+func (m *Map) umatrixMap() *umatrixMap {
+	coords, dims, uShape := m.grid.display()
+	return newUMatrixMap(m.measure(), m.codebook, coords, dims, uShape, m.grid.Adjacent)
 }
 
 // Train runs a SOM training for a given data set and training configuration parameters.
 // It modifies the map codebook vectors based on the chosen training algorithm.
+// The map can only be trained by one goroutine at a time: calling Train while the map is
+// being trained returns ErrTrainInProgress.
 // It returns error if the supplied training configuration is invalid or training fails
 func (m *Map) Train(c *TrainConfig, data *mat.Dense, iters int) error {
+	return m.train(context.Background(), c, data, iters, nil, nil)
+}
+
+// TrainContext runs a SOM training like Train does, but it stops the training once ctx is done,
+// so long trainings can be cancelled or time-bounded. Sequential and batch training check ctx
+// before every iteration and return ctx.Err() when it is done. The codebook keeps the updates of
+// the finished iterations, but the map metadata and training history are not updated.
+// The state of the stopped training is returned by Checkpoint and the training can be finished by Resume.
+// It returns error if the supplied training configuration is invalid, training fails or ctx is done.
+func (m *Map) TrainContext(ctx context.Context, c *TrainConfig, data *mat.Dense, iters int) error {
+	return m.train(ctx, c, data, iters, nil, nil)
+}
+
+// train trains the map; batch training work is dispatched to the pool of trainer t.
+// If t is nil, batch training starts a temporary pool for the duration of the training.
+// If cp is not nil, the training continues from the checkpoint.
+func (m *Map) train(ctx context.Context, c *TrainConfig, data *mat.Dense, iters int, cp *Checkpoint, t *Trainer) error {
+	// guard against concurrent training which would corrupt the codebook
+	if !atomic.CompareAndSwapInt32(&m.training, 0, 1) {
+		return ErrTrainInProgress
+	}
+	defer atomic.StoreInt32(&m.training, 0)
+
+	// validate iterations, data and configuration
+	if err := validateTrain(c, data, iters); err != nil {
+		return err
+	}
+	ms, err := m.trainMeasure(c, data)
+	if err != nil {
+		return err
+	}
+	// resumed training must continue with the same configuration and data
+	if cp != nil {
+		if err := m.validateCheckpoint(c, data, cp); err != nil {
+			return err
+		}
+	}
+	// run the training; sequential training epoch is a pass over all sampled data rows
+	rows, _ := data.Dims()
+	s := newSchedule(c, iters, subsampleSize(c, rows))
+	run := m.newTrainRun(c, ms, data, s, cp)
+	err = m.runTrain(ctx, c, data, run, t)
+	m.checkpoint = nil
+	if err != nil {
+		// remember the state of stopped training so it can be resumed
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			m.checkpoint = run.checkpoint(c, Fingerprint(data))
+		}
+		return err
+	}
+	m.trained(c, ms, s, Fingerprint(data))
+
+	return nil
+}
+
+// validateTrain validates training configured by c of iters iterations on data
+func validateTrain(c *TrainConfig, data *mat.Dense, iters int) error {
 	// number of iterations must be a positive integer
 	if iters <= 0 {
 		return fmt.Errorf("invalid number of iterations: %d", iters)
@@ -174,42 +784,157 @@ func (m *Map) Train(c *TrainConfig, data *mat.Dense, iters int) error {
 	if err := validateTrainConfig(c); err != nil {
 		return err
 	}
-	// run the training
+	// every data row must have its weight
+	rows, cols := data.Dims()
+	if len(c.Weights) > 0 && len(c.Weights) != rows {
+		return fmt.Errorf("weights count mismatch: %d != %d", len(c.Weights), rows)
+	}
+	if err := validateColMask(c, cols); err != nil {
+		return err
+	}
+	// bad input would corrupt the codebook
+	if c.CheckFinite {
+		if err := checkFiniteData(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runTrain runs the iterations of training run with the algorithm configured by c.
+// If t is nil, batch training starts a temporary pool for the duration of the training.
+func (m *Map) runTrain(ctx context.Context, c *TrainConfig, data *mat.Dense, run *trainRun, t *Trainer) error {
 	switch c.Algorithm {
 	case "seq":
-		return m.seqTrain(c, data, iters)
+		return m.seqTrain(ctx, c, data, run)
 	case "batch":
-		return m.batchTrain(c, data, iters)
+		if t == nil {
+			rows, _ := data.Dims()
+			t = newTrainer(batchWorkers(c.Workers, rows))
+			defer t.Close()
+		}
+		return m.batchTrain(ctx, c, data, run, t)
+	}
+	return nil
+}
+
+// trained remembers the training configuration, measure ms and schedule history of a finished
+// training and records its provenance along with the fingerprint of the training data.
+func (m *Map) trained(c *TrainConfig, ms measure, s *schedule, fingerprint string) {
+	// the configuration is copied so that later changes made by the caller don't affect the map
+	tc := *c
+	m.tc = &tc
+	m.metric = ms.metric
+	if c.DistanceFn != nil {
+		m.distFn = c.DistanceFn
 	}
+	m.mh = ms.mh
+	_, dim := m.codebook.Dims()
+	m.cm = newColMask(c, dim)
+	m.history = s.steps
+	trained := time.Now().UTC()
+	m.meta.Trained = &trained
+	m.meta.Train = newTrainMetadata(c, s.iters)
+	m.meta.Train.Metric = m.measure().name()
+	if m.mh != nil {
+		m.meta.Train.Covariance = m.mh.rows()
+	}
+	m.meta.DataFingerprint = fingerprint
+}
 
+// TrainHistory returns the radius and learning rate schedule in effect during the last training.
+// The schedule is recorded at the start of every epoch: once per pass over the data in sequential
+// training and once per iteration in batch training. The history is not saved in map models.
+// It returns nil if the map has not been trained.
+func (m *Map) TrainHistory() []ScheduleStep {
+	return m.history
+}
+
+// Refine continues training of an already ordered map on a given data set.
+// Refine runs a fine-tuning phase: the training parameters are derived from the last training
+// configuration by reducing its radius and learning rate. If the map has not been trained yet,
+// the radius is derived from the grid size and the map is trained using sequential algorithm.
+// Refinements keep the carried and fixed columns, feature weights and workers of the last training
+// and its row weights if data has as many rows. They don't replace the training configuration they
+// are derived from, so repeated Refine calls run the same fine-tuning phase instead of reducing its
+// parameters again. It returns error if the data is nil or if the training fails.
+func (m *Map) Refine(data *mat.Dense, iters int) error {
+	if data == nil {
+		return fmt.Errorf("invalid data supplied: %v", data)
+	}
+	tc := m.tc
+	rows, _ := data.Dims()
+	if err := m.Train(m.refineConfig(rows), data, iters); err != nil {
+		return err
+	}
+	m.tc = tc
 	return nil
 }
 
+// refineConfig returns training configuration used to refine the map on data with given number of rows
+func (m *Map) refineConfig(rows int) *TrainConfig {
+	// fine-tuning radius should not exceed a quarter of the grid span
+	radius := math.Max(MinRadius, m.grid.Span()/4.0)
+	// default fine-tuning configuration
+	c := &TrainConfig{
+		Algorithm: "seq",
+		Radius:    radius,
+		RDecay:    "lin",
+		NeighbFn:  Gaussian,
+		LRate:     0.05,
+		LDecay:    "lin",
+		Metric:    m.metric,
+	}
+	// derive fine-tuning configuration from the last training
+	if m.tc != nil {
+		c.Algorithm = m.tc.Algorithm
+		c.NeighbFn = m.tc.NeighbFn
+		c.Radius = math.Max(MinRadius, math.Min(radius, m.tc.Radius/4.0))
+		c.LRate = math.Max(MinLRate, m.tc.LRate/10.0)
+		// column masks keep refinements consistent with the trained map
+		c.Carry = m.tc.Carry
+		c.Fixed = m.tc.Fixed
+		c.FeatureWeights = m.tc.FeatureWeights
+		c.Workers = m.tc.Workers
+		// row weights only apply to the same data rows of batch training
+		if c.Algorithm == "batch" && len(m.tc.Weights) == rows {
+			c.Weights = m.tc.Weights
+		}
+	}
+
+	return c
+}
+
 // QuantError computes SOM quantization error for the supplied data set
 // It returns the quantization error or fails with error if the passed in data is nil
 // or the distance betweent vectors could not be calculated.
 // When the error is returned, quantization error is set to -1.0.
-func (m Map) QuantError(data *mat.Dense) (float64, error) {
-	return QuantError(data, m.codebook)
+func (m *Map) QuantError(data *mat.Dense) (float64, error) {
+	return quantError(m.measure(), data, m.codebook)
 }
 
 // TopoProduct computes SOM topographic product
+// Lattice distances wrap around the borders of toroid and cylinder grids like UnitDist does.
 // It returns a single number or fails with error if the product could not be computed
-func (m Map) TopoProduct() (float64, error) {
-	return TopoProduct(m.codebook, m.grid.coords)
+func (m *Map) TopoProduct() (float64, error) {
+	return topoProduct(m.measure(), m.codebook, m.grid.Units(), m.grid.distRow)
 }
 
 // TopoError computes SOM topographic error for a given data set.
+// BMU neighbours are determined by the map grid Adjacent method.
 // It returns a single number or fails with error if the error could not be computed
-func (m Map) TopoError(data *mat.Dense) (float64, error) {
-	return TopoError(data, m.codebook, m.grid.coords)
+func (m *Map) TopoError(data *mat.Dense) (float64, error) {
+	return topoError(m.measure(), data, m.codebook, m.grid.Adjacent)
 }
 
 // seqUpdateCbVec updates codebook vector on row cbIdx given the learning rate l,
-// radius r, distance d and neihgbourhood function nFn, provided sample data vector
-func (m *Map) seqUpdateCbVec(cbIdx int, sample []float64, l, r, d float64, nFn NeighbFunc) {
+// radius r, distance d and neihgbourhood function nFn, provided sample data vector.
+// Elements of missing sample values and fixed columns of column mask cm are not updated.
+// If the updates are delayed by du, the update is accumulated in du instead of the codebook.
+func (m *Map) seqUpdateCbVec(cbIdx int, sample []float64, l, r, d float64, nFn NeighbFunc, cm *colMask, du *delayedUpdates) {
 	// pick codebook vector that should be updated
 	cbVec := m.codebook.RawRowView(cbIdx)
+	dst := du.target(cbIdx, cbVec)
 	mul := l
 	// Update codebook vector element by element
 	for i := 0; i < len(cbVec); i++ {
@@ -217,194 +942,441 @@ func (m *Map) seqUpdateCbVec(cbIdx int, sample []float64, l, r, d float64, nFn N
 		if d > 0.0 {
 			mul *= nFn(d, r)
 		}
-		cbVec[i] = cbVec[i] + mul*(sample[i]-cbVec[i])
+		if math.IsNaN(sample[i]) || cm.isFixed(i) {
+			continue
+		}
+		dst[i] += mul * (sample[i] - cbVec[i])
 	}
 }
 
-// seqTrain runs sequential SOM training algorithm on a given data set
-func (m *Map) seqTrain(tc *TrainConfig, data *mat.Dense, iters int) error {
-	rows, _ := data.Dims()
-	// create random number generator
-	src := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(src)
+// seqTrain runs sequential SOM training algorithm on a given data set following the schedule of run until ctx is done.
+// Data rows are drawn uniformly or by their quantization error by the sampler of run.
+func (m *Map) seqTrain(ctx context.Context, tc *TrainConfig, data *mat.Dense, run *trainRun) error {
+	s, smp, f, cm, du := run.s, run.smp, run.f, run.cm, run.du
 	// calculate unit distances
-	unitDist, err := m.UnitDist()
+	unitDist, err := m.unitDists()
 	if err != nil {
 		return err
 	}
-	// retrieve Neighbourhood function
-	nFn := tc.NeighbFn
+	// label and time training phases
+	pt := newPhaseTimer(tc.PhaseHook)
+	defer pt.stop()
 	// perform iters number of learning iterations
-	for i := 0; i < iters; i++ {
+	for i := run.next; i < run.stop; i++ {
+		run.next = i
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pt.enter(phaseBMU)
 		// pick a random sample from dataset
-		sample := data.RawRowView(r.Intn(rows))
-		// no need to check for error here:
-		// sample and codebook are not nil and have the same dimension
-		bmu, _ := ClosestVec(Euclidean, sample, m.codebook)
-		// no need to check for errors:
-		// LRate and Radius are checked by config validation
-		lRate, _ := LRate(i, iters, tc.LDecay, tc.LRate)
-		radius, _ := Radius(i, iters, tc.RDecay, tc.Radius)
-		// pick the bmu unit distance row
-		bmuDists := unitDist.RawRowView(bmu)
-		// find units which are within the radius
-		for i := 0; i < len(bmuDists); i++ {
-			// bmu distance to i-th map unit
-			dist := bmuDists[i]
-			// we are within BMU radius
-			if dist < radius {
-				// update particular codebook vector
-				m.seqUpdateCbVec(i, sample, lRate, radius, dist, nFn)
+		lRate, radius := s.at(i)
+		if smp.subsampled() && i%s.epoch == 0 {
+			s.samples(smp.size)
+		}
+		m.seqStep(tc, run.ms, unitDist, smp.next(i, m.codebook), lRate, radius, f, cm, du, pt)
+		f.step(i, m.codebook, s)
+		if tc.CheckFinite {
+			if err := checkFiniteCodebook(tc, m.codebook, i, lRate, radius); err != nil {
+				return err
 			}
 		}
 	}
+	run.next = run.stop
+	// the updates of the last, possibly incomplete, block are applied once the schedule is done;
+	// the blocks of unfinished schedules continue in the next stage
+	if run.next == s.iters {
+		du.flush(m.codebook)
+	}
 
 	return nil
 }
 
+// seqStep runs a sequential training iteration with given learning rate and radius on a given sample.
+// Units frozen by freezer f are not updated. The BMU is searched for using measure ms and the units
+// are updated in the columns of column mask cm, or their updates are accumulated by du if they are delayed.
+func (m *Map) seqStep(tc *TrainConfig, ms measure, unitDist *mat.Dense, sample []float64, lRate, radius float64,
+	f *freezer, cm *colMask, du *delayedUpdates, pt *phaseTimer) {
+	bmu := cm.closest(ms, sample, m.codebook)
+	m.winCounts()[bmu]++
+	pt.enter(phaseUpdate)
+	// pick the bmu unit distance row
+	bmuDists := unitDist.RawRowView(bmu)
+	// find units which are within the radius
+	for j := 0; j < len(bmuDists); j++ {
+		// bmu distance to j-th map unit
+		dist := bmuDists[j]
+		// we are within BMU radius
+		if dist < radius && !f.isFrozen(j) {
+			// update particular codebook vector
+			m.seqUpdateCbVec(j, sample, lRate, radius, dist, tc.NeighbFn, cm, du)
+		}
+	}
+	du.step(m.codebook)
+}
+
 // batchConfig holds batch training configuration
 type batchConfig struct {
 	// tc is SOM training configuration
 	tc *TrainConfig
-	// iters is a number of batch iterations
-	iters int
+	// weights holds data row weights; it is nil if rows are not weighted
+	weights []float64
+	// f freezes converged units; it is nil if units are not frozen
+	f *freezer
+	// cm masks data columns; it is nil if columns are not masked
+	cm *colMask
+	// ms measures distances of data rows from codebook vectors
+	ms measure
 }
 
-// batchResult holds results of batch algorithm for a particular data input batch
-type batchResult struct {
-	// vecs is a slice of nghb scaled data vectors
-	vecs [][]float64
+// batchAcc accumulates neighbourhood scaled data vectors of batch algorithm
+type batchAcc struct {
+	// vecs is a matrix of nghb scaled data vectors: units x data features
+	vecs *mat.Dense
 	// nghbs is a slice of BMU neighbourhoods
 	nghbs []float64
+	// hits marks units which were within radius of some BMU
+	hits []bool
+	// wins counts how many times each unit was the BMU of a data row
+	wins []int
+	// dens is a matrix of BMU neighbourhoods of the data features which are not missing: units x data features.
+	// It's only accumulated when masked is set; the neighbourhoods of all features are the same as nghbs otherwise.
+	dens *mat.Dense
+	// masked is set when some accumulated data row has missing values
+	masked bool
+}
+
+// newBatchAccs allocates n batch accumulators for a given codebook
+func newBatchAccs(n int, codebook *mat.Dense) []*batchAcc {
+	units, dim := codebook.Dims()
+	accs := make([]*batchAcc, n)
+	for i := range accs {
+		accs[i] = &batchAcc{
+			vecs:  mat.NewDense(units, dim, nil),
+			nghbs: make([]float64, units),
+			hits:  make([]bool, units),
+			wins:  make([]int, units),
+		}
+	}
+
+	return accs
+}
+
+// reset zeroes all accumulated values
+func (a *batchAcc) reset() {
+	a.vecs.Zero()
+	for i := range a.nghbs {
+		a.nghbs[i] = 0.0
+		a.hits[i] = false
+		a.wins[i] = 0
+	}
+	a.masked = false
+}
+
+// mask starts accumulating the neighbourhoods of data features separately.
+// The neighbourhoods accumulated so far are the same for all the features.
+func (a *batchAcc) mask() {
+	if a.masked {
+		return
+	}
+	if a.dens == nil {
+		units, dim := a.vecs.Dims()
+		a.dens = mat.NewDense(units, dim, nil)
+	}
+	for k, nghb := range a.nghbs {
+		den := a.dens.RawRowView(k)
+		for l := range den {
+			den[l] = nghb
+		}
+	}
+	a.masked = true
 }
 
-// processRow processes data rows and sends tehm down the results channel
-func (m Map) processBatch(res chan<- *batchResult, wg *sync.WaitGroup,
-	bc *batchConfig, unitDist, data *mat.Dense, from, count, iter int) {
-	// We pre-allocate a slice for all potential BMU neihbour vectors
-	// NOTE: maxLen is equal to the number of model vectors i.e. gridWidth * gridHeight
-	// Go provides zero-allocation so even if the vecs array is sparse the "empty" items
-	// won't have any effect on the result, but this will give us much better performance!
-	maxLen, _ := unitDist.Dims()
-	vecs := make([][]float64, maxLen)
-	nghbs := make([]float64, maxLen)
+// add adds accumulated values of b to a
+func (a *batchAcc) add(b *batchAcc) {
+	if b.masked {
+		a.mask()
+	}
+	for k := range b.hits {
+		a.wins[k] += b.wins[k]
+		if !b.hits[k] {
+			continue
+		}
+		vec, bvec := a.vecs.RawRowView(k), b.vecs.RawRowView(k)
+		for l := range vec {
+			vec[l] += bvec[l]
+		}
+		if a.masked {
+			den := a.dens.RawRowView(k)
+			if b.masked {
+				for l, x := range b.dens.RawRowView(k) {
+					den[l] += x
+				}
+			} else {
+				for l := range den {
+					den[l] += b.nghbs[k]
+				}
+			}
+		}
+		a.nghbs[k] += b.nghbs[k]
+		a.hits[k] = true
+	}
+}
+
+// processBatch processes data rows and accumulates the results in acc
+func (m *Map) processBatch(acc *batchAcc, wg *sync.WaitGroup,
+	bc *batchConfig, unitDist, data *mat.Dense, from, count int, radius float64) {
+	defer wg.Done()
+	acc.reset()
 
 	// retrieve Neighbourhood function
 	nFn := bc.tc.NeighbFn
+	// label and time training phases
+	pt := newPhaseTimer(bc.tc.PhaseHook)
+	defer pt.stop()
 
 	for i := from; i < count+from; i++ {
+		pt.enter(phaseBMU)
 		row := data.RawRowView(i)
+		// weighted row contributes as many times as its weight;
+		// rows with zero weight must not mark units as hit
+		weight := 1.0
+		if bc.weights != nil {
+			if weight = bc.weights[i]; weight == 0 {
+				continue
+			}
+		}
+		if hasMissing(row) {
+			acc.mask()
+		}
 		// find codebook BMU for this data row
-		bmu, _ := ClosestVec(Euclidean, row, m.codebook)
-		// calculate radius for this iteration
-		radius, _ := Radius(iter, bc.iters, bc.tc.RDecay, bc.tc.Radius)
+		bmu := bc.cm.closest(bc.ms, row, m.codebook)
+		acc.wins[bmu]++
+		pt.enter(phaseUpdate)
 		// pick the BMU's distance row
 		bmuDists := unitDist.RawRowView(bmu)
 		for j := 0; j < len(bmuDists); j++ {
 			// bmu distance to i-th map unit
 			dist := bmuDists[j]
-			// when in BMU radius, scale and add to all neighbourhood vecs
-			if dist < radius {
+			// when in BMU radius, scale and add to all neighbourhood vecs;
+			// frozen units are never hit so they are not updated
+			if dist < radius && !bc.f.isFrozen(j) {
 				// calculate neighbourhood function
-				nghb := nFn(dist, radius)
-				if vecs[j] != nil {
-					for k := 0; k < len(vecs[j]); k++ {
-						vecs[j][k] += nghb * row[k]
+				nghb := weight * nFn(dist, radius)
+				vec := acc.vecs.RawRowView(j)
+				if acc.masked {
+					// missing values add nothing to their features
+					den := acc.dens.RawRowView(j)
+					for k, x := range row {
+						if !math.IsNaN(x) {
+							vec[k] += nghb * x
+							den[k] += nghb
+						}
 					}
 				} else {
-					vecs[j] = make([]float64, len(row))
-					for k := 0; k < len(vecs[j]); k++ {
-						vecs[j][k] = nghb * row[k]
+					for k := 0; k < len(vec); k++ {
+						vec[k] += nghb * row[k]
 					}
 				}
-				nghbs[j] += nghb
+				acc.nghbs[j] += nghb
+				acc.hits[j] = true
 			}
 		}
 	}
-	// send batchResult down results channel
-	res <- &batchResult{vecs: vecs, nghbs: nghbs}
-	wg.Done()
 }
 
-// batchTrain runs batch SOM training on a given data set
-func (m *Map) batchTrain(tc *TrainConfig, data *mat.Dense, iters int) error {
-	cbRows, _ := m.codebook.Dims()
-	rows, _ := data.Dims()
+// maskedUpdate updates codebook vector of unit k to the neighbourhood scaled sum of data vectors vec
+// divided by the neighbourhoods of each feature dens. The features missing in all data rows within
+// the unit neighbourhood and fixed columns of column mask cm keep their values; minNghb applies to the
+// neighbourhood of each feature.
+func (m *Map) maskedUpdate(k int, vec, dens []float64, minNghb float64, cm *colMask) {
+	cbVec := m.codebook.RawRowView(k)
+	for l, x := range cbVec {
+		if cm.isFixed(l) {
+			continue
+		}
+		den := dens[l]
+		if minNghb > 0 && den < minNghb {
+			vec[l] += (minNghb - den) * x
+			den = minNghb
+		}
+		if den > 0 {
+			cbVec[l] = vec[l] / den
+		}
+	}
+}
+
+// batchWorkers returns the number of batch training workers.
+// It defaults to the number of CPUs and never exceeds the number of data rows
+// so that no worker holds an accumulator without any data to process.
+// Each worker accumulates its results in a buffer of SOM units x data features
+// floats which are merged once all workers finish, so more workers trade memory
+// for throughput.
+func batchWorkers(workers, rows int) int {
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > rows {
+		workers = rows
+	}
+
+	return workers
+}
+
+// batchTrain runs batch SOM training on a given data set following the schedule of run using the worker pool of trainer t
+// until ctx is done
+func (m *Map) batchTrain(ctx context.Context, tc *TrainConfig, data *mat.Dense, run *trainRun, t *Trainer) error {
+	// subsampled iterations split only the sampled rows between workers
+	rows, s := run.smp.size, run.s
 
 	// batchConfig holds training config and number of iterations
 	bc := &batchConfig{
-		tc:    tc,
-		iters: iters,
+		tc:      tc,
+		weights: tc.Weights,
+		f:       run.f,
+		cm:      run.cm,
+		ms:      run.ms,
 	}
 
 	// calculate unit distances
-	unitDist, err := m.UnitDist()
+	unitDist, err := m.unitDists()
 	if err != nil {
 		return err
 	}
-	r, _ := unitDist.Dims()
 
 	// evenly distribute batches between workers
-	workers := runtime.NumCPU()
+	workers := tc.Workers
+	if workers == 0 {
+		workers = t.workers
+	}
+	workers = batchWorkers(workers, rows)
+
+	// one accumulator per worker and one for collecting their results
+	accs := m.accs
+	if len(accs) != workers+1 {
+		accs = newBatchAccs(workers+1, m.codebook)
+	}
+
+	// label and time merge and update phases
+	pt := newPhaseTimer(tc.PhaseHook)
+
+	for i := run.next; i < run.stop; i++ {
+		run.next = i
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// radius is the same for all data rows of the iteration
+		_, radius := s.at(i)
+		sub, weights := run.smp.batch(i, tc.Weights)
+		if run.smp.subsampled() {
+			s.samples(run.smp.size)
+		}
+		bc.weights = weights
+		s.units(m.batchStep(bc, unitDist, sub, radius, accs, t, pt))
+		bc.f.step(i, m.codebook, s)
+		if tc.CheckFinite {
+			if err := checkFiniteCodebook(tc, m.codebook, i, 0.0, radius); err != nil {
+				return err
+			}
+		}
+	}
+	run.next = run.stop
+
+	return nil
+}
+
+// batchStep runs batch training iteration with given radius on a given data set using the worker pool of trainer t.
+// The data rows are split between as many workers as there are accumulators in accs less one;
+// the last accumulator collects the results of the workers. It returns the number of empty units which were
+// not within the radius of any BMU and the number of units whose neighbourhood sum was below the minimum.
+// Units whose data rows sum to a smaller neighbourhood than the minimum are moved only part of the way towards
+// the mean of the rows as if their own codebook vectors made up the rest of the sum, which prevents unstable
+// updates of units at the edge of BMU radius.
+func (m *Map) batchStep(bc *batchConfig, unitDist, data *mat.Dense, radius float64, accs []*batchAcc, t *Trainer, pt *phaseTimer) (int, int) {
+	cbRows, _ := m.codebook.Dims()
+	rows, _ := data.Dims()
+	workers := len(accs) - 1
 	batchSize := rows / workers
+	total := accs[workers]
+
+	// reset from index and input count
+	from := 0
+	count := batchSize
+	wg := &sync.WaitGroup{}
+	// dispatch batches to pool workers
+	for j := 0; j < workers; j++ {
+		// from is data matrix row pointer
+		from = j * batchSize
+		// last worker will work through the batch reminder
+		if j == workers-1 {
+			count += rows % workers
+		}
+		// if we go over the number of rows adjust bSamples
+		if from+count > rows {
+			count = rows - from
+		}
+		wg.Add(1)
+		t.jobs <- &batchJob{
+			m:        m,
+			acc:      accs[j],
+			wg:       wg,
+			bc:       bc,
+			unitDist: unitDist,
+			data:     data,
+			from:     from,
+			count:    count,
+			radius:   radius,
+		}
+	}
+	wg.Wait()
 
-	for i := 0; i < iters; i++ {
-		// reset from index and input count
-		from := 0
-		count := batchSize
-		// create batch results channel
-		results := make(chan *batchResult, workers*40)
-		wg := &sync.WaitGroup{}
-		// start worker goroutines
-		for j := 0; j < workers; j++ {
-			// from is data matrix row pointer
-			from = j * batchSize
-			// last worker will work through the batch reminder
-			if j == workers-1 {
-				count += rows % workers
+	// collect batch results from all workers
+	pt.enter(phaseMerge)
+	total.reset()
+	for j := 0; j < workers; j++ {
+		total.add(accs[j])
+	}
+
+	// update codebook vectors
+	pt.enter(phaseUpdate)
+	wins := m.winCounts()
+	minNghb := bc.tc.MinNghb
+	var empty []int
+	sparse := 0
+	for k := 0; k < cbRows; k++ {
+		wins[k] += total.wins[k]
+		if !total.hits[k] {
+			// frozen units are never hit
+			if !bc.f.isFrozen(k) {
+				empty = append(empty, k)
 			}
-			// if we go over the number of rows adjust bSamples
-			if from+count > rows {
-				count = rows - from
+			continue
+		}
+		vec := total.vecs.RawRowView(k)
+		nghb := total.nghbs[k]
+		if total.masked {
+			if minNghb > 0 && nghb < minNghb {
+				sparse++
 			}
-			wg.Add(1)
-			go m.processBatch(results, wg, bc, unitDist, data, from, count, i)
-		}
-
-		// wait for workers to finish and close the result channel
-		go func() {
-			wg.Wait()
-			close(results)
-		}()
-
-		// collect batch results from all workers
-		vecs := make([][]float64, r)
-		nghbs := make([]float64, r)
-		for result := range results {
-			for k := 0; k < len(result.vecs); k++ {
-				if result.vecs[k] != nil {
-					if vecs[k] != nil {
-						for l := 0; l < len(vecs[k]); l++ {
-							vecs[k][l] += result.vecs[k][l]
-						}
-					} else {
-						vecs[k] = result.vecs[k]
-					}
-					nghbs[k] += result.nghbs[k]
-				}
+			m.maskedUpdate(k, vec, total.dens.RawRowView(k), minNghb, bc.cm)
+			continue
+		}
+		// codebook vector makes up the missing neighbourhood sum
+		if minNghb > 0 && nghb < minNghb {
+			for l, x := range m.codebook.RawRowView(k) {
+				vec[l] += (minNghb - nghb) * x
 			}
+			nghb = minNghb
+			sparse++
 		}
-
-		// update codebook vectors
-		for k := 0; k < cbRows; k++ {
-			if vecs[k] != nil {
-				for l := 0; l < len(vecs[k]); l++ {
-					vecs[k][l] = vecs[k][l] / nghbs[k]
-				}
-				m.codebook.SetRow(k, vecs[k])
+		cbVec := m.codebook.RawRowView(k)
+		for l := 0; l < len(vec); l++ {
+			if !bc.cm.isFixed(l) {
+				cbVec[l] = vec[l] / nghb
 			}
 		}
 	}
+	m.updateEmpty(bc, data, empty)
+	pt.stop()
 
-	return nil
+	return len(empty), sparse
 }
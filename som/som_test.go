@@ -1,10 +1,16 @@
 package som
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/milosgajdos/gosom/pkg/utils"
 	"github.com/stretchr/testify/assert"
@@ -96,6 +102,20 @@ func TestNewMap(t *testing.T) {
 	assert.Nil(m)
 	assert.Error(err)
 	mSom.Cb.Dim = origDim
+	// preallocated map
+	mSom.Prealloc = true
+	m, err = NewMap(mSom, dataMx)
+	assert.NoError(err)
+	assert.NotNil(m.unitDist)
+	assert.NotEmpty(m.accs)
+	mSom.Prealloc = false
+	// preallocated map can be trained with both algorithms
+	err = m.Train(tSom, dataMx, 10)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	err = m.Train(tc, dataMx, 10)
+	assert.NoError(err)
 }
 
 func TestCodebook(t *testing.T) {
@@ -144,6 +164,41 @@ func TestUnitDist(t *testing.T) {
 	assert.Equal(mapUnits, cbCols)
 }
 
+func TestPeriodicMap(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, gtype := range []string{"toroid", "cylinder"} {
+		c := &MapConfig{
+			Grid: &GridConfig{Size: []int{4, 4}, Type: gtype, UShape: "hexagon"},
+			Cb:   &CbConfig{Dim: 4, InitFunc: RandInit},
+		}
+		m, err := NewMap(c, dataMx)
+		assert.NoError(err)
+		unitDist, err := m.UnitDist()
+		assert.NoError(err)
+		assert.True(mat.Equal(m.Grid().UnitDist(), unitDist))
+		// units on the opposite borders of the grid are neighbours
+		assert.InDelta(1.0, unitDist.At(0, 12), 1e-9)
+		// topographic product uses the wrapped unit distances
+		tp, err := m.TopoProduct()
+		assert.NoError(err)
+		units, _ := unitDist.Dims()
+		exp, err := topoProduct(m.measure(), m.codebook, units, func(i int, dst []float64) { mat.Row(dst, i, unitDist) })
+		assert.NoError(err)
+		assert.Equal(exp, tp)
+		for _, alg := range []string{"seq", "batch"} {
+			tc := makeDefaultTrainConfig()
+			tc.Algorithm = alg
+			err = m.Train(tc, dataMx, 10)
+			assert.NoError(err)
+		}
+		// resized map keeps grid type
+		r, err := m.Resize([]int{6, 6})
+		assert.NoError(err)
+		assert.Equal(gtype, r.Grid().Type())
+	}
+}
+
 func TestMapBmus(t *testing.T) {
 	assert := assert.New(t)
 
@@ -156,6 +211,292 @@ func TestMapBmus(t *testing.T) {
 	assert.NotNil(bmus)
 	rows, _ := dataMx.Dims()
 	assert.Equal(rows, len(bmus))
+	// BMU distances are measured by the map distance function
+	m.SetDistanceFunc(l1)
+	bmus, err = m.BMUs(dataMx)
+	assert.NoError(err)
+	dbmus, dists, err := m.BMUDistances(dataMx)
+	assert.NoError(err)
+	assert.Equal(bmus, dbmus)
+	qe := 0.0
+	for i, bmu := range dbmus {
+		assert.InDelta(l1(dataMx.RawRowView(i), m.codebook.RawRowView(bmu)), dists[i], 1e-12)
+		qe += dists[i]
+	}
+	expQe, err := m.QuantError(dataMx)
+	assert.NoError(err)
+	assert.InDelta(expQe, qe/float64(rows), 1e-12)
+	_, _, err = m.BMUDistances(nil)
+	assert.Error(err)
+}
+
+func TestMapHits(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	hits, err := m.Hits(dataMx)
+	assert.NoError(err)
+	assert.Len(hits, utils.IntProduct(mSom.Grid.Size))
+	rows, _ := dataMx.Dims()
+	total := 0
+	for _, h := range hits {
+		total += h
+	}
+	assert.Equal(rows, total)
+	// nil data
+	hits, err = m.Hits(nil)
+	assert.Nil(hits)
+	assert.Error(err)
+}
+
+func TestMapWins(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	assert.Nil(m.Wins())
+	// the first batch iteration finds the BMUs of the initial codebook
+	hits, err := m.Hits(dataMx)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	tc.Workers = 2
+	assert.NoError(m.Train(tc, dataMx, 1))
+	assert.Equal(hits, m.Wins())
+	// wins accumulate over training calls
+	rows, _ := dataMx.Dims()
+	assert.NoError(m.Train(makeDefaultTrainConfig(), dataMx, 10))
+	total := 0
+	for _, w := range m.Wins() {
+		total += w
+	}
+	assert.Equal(rows+10, total)
+	// hit map of training wins
+	assert.NoError(m.HitMap(ioutil.Discard, nil, NewClassStats(), "svg", "Wins"))
+	m.ResetWins()
+	assert.Nil(m.Wins())
+	assert.Error(m.HitMap(ioutil.Discard, nil, NewClassStats(), "svg", "Wins"))
+}
+
+func TestMapCarry(t *testing.T) {
+	assert := assert.New(t)
+
+	// the carried column dominates the distances if it is not left out of BMU search
+	data := mat.NewDense(20, 3, nil)
+	for i := 0; i < 20; i++ {
+		data.SetRow(i, []float64{float64(i % 5), float64(i / 5), float64(100 * (i * 7 % 11))})
+	}
+	c := &MapConfig{
+		Grid: mSom.Grid,
+		Cb:   &CbConfig{Dim: 3, InitFunc: RandInit},
+	}
+	m, err := NewMap(c, data)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	tc.Carry = []int{2}
+	assert.NoError(m.Train(tc, data, 10))
+	// hits of the trained map are found in the same way as the training wins
+	hits, err := m.Hits(data)
+	assert.NoError(err)
+	m.ResetWins()
+	assert.NoError(m.Train(tc, data, 1))
+	assert.Equal(hits, m.Wins())
+	// carried columns are restored from the map model
+	exp, err := m.BMUs(data)
+	assert.NoError(err)
+	buf := new(bytes.Buffer)
+	_, err = m.MarshalTo("som", buf)
+	assert.NoError(err)
+	l := new(Map)
+	_, err = l.UnmarshalFrom("som", buf)
+	assert.NoError(err)
+	bmus, err := l.BMUs(data)
+	assert.NoError(err)
+	assert.Equal(exp, bmus)
+	assert.Equal([]float64{1, 1, 0}, m.ColumnWeights())
+	assert.Equal(m.ColumnWeights(), l.ColumnWeights())
+	qe, err := m.QuantError(data)
+	assert.NoError(err)
+	lqe, err := l.QuantError(data)
+	assert.NoError(err)
+	assert.Equal(qe, lqe)
+	// carried columns don't count in the quantization error
+	assert.True(qe < 10)
+}
+
+func TestMapFeatureWeights(t *testing.T) {
+	assert := assert.New(t)
+
+	weights := []float64{4, 1, 0.5, 0}
+	for _, metric := range []Metric{Euclidean, Manhattan} {
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		tc := makeDefaultTrainConfig()
+		tc.Metric = metric
+		tc.FeatureWeights = weights
+		assert.NoError(m.Train(tc, dataMx, 10))
+		// the trained map finds BMUs and their distances with the training feature weights
+		ms := measure{metric: metric, weights: weights}
+		exp, err := bmus(ms, dataMx, m.codebook)
+		assert.NoError(err)
+		idx, err := m.BMUs(dataMx)
+		assert.NoError(err)
+		assert.Equal(exp, idx)
+		expQe, err := quantError(ms, dataMx, m.codebook)
+		assert.NoError(err)
+		qe, err := m.QuantError(dataMx)
+		assert.NoError(err)
+		assert.Equal(expQe, qe)
+		// the weights are restored from the map model
+		buf := new(bytes.Buffer)
+		_, err = m.MarshalTo("som", buf)
+		assert.NoError(err)
+		l := new(Map)
+		_, err = l.UnmarshalFrom("som", buf)
+		assert.NoError(err)
+		assert.Equal(metric, l.Metric())
+		idx, err = l.BMUs(dataMx)
+		assert.NoError(err)
+		assert.Equal(exp, idx)
+		qe, err = l.QuantError(dataMx)
+		assert.NoError(err)
+		assert.Equal(expQe, qe)
+	}
+}
+
+func TestVectorAt(t *testing.T) {
+	assert := assert.New(t)
+
+	grid := &GridConfig{
+		Size:   []int{2, 3},
+		Type:   "planar",
+		UShape: "rectangle",
+	}
+	cb := &CbConfig{
+		Dim:      4,
+		InitFunc: RandInit,
+	}
+	m, err := NewMap(&MapConfig{Grid: grid, Cb: cb}, dataMx)
+	assert.NotNil(m)
+	assert.NoError(err)
+	// grid unit positions return their codebook vectors
+	coords := m.Grid().Coords()
+	rows, _ := coords.Dims()
+	for i := 0; i < rows; i++ {
+		vec := m.VectorAt(coords.At(i, 0), coords.At(i, 1))
+		assert.InDeltaSlice(m.codebook.RawRowView(i), vec, 1e-9)
+	}
+	// half way between two neighbouring units returns their mean
+	a, b := m.codebook.RawRowView(0), m.codebook.RawRowView(2)
+	vec := m.VectorAt(0.5, 0.0)
+	for i := range vec {
+		assert.InDelta((a[i]+b[i])/2, vec[i], 1e-9)
+	}
+	// positions outside grid are clamped
+	assert.InDeltaSlice(m.codebook.RawRowView(0), m.VectorAt(-10.0, -10.0), 1e-9)
+	// hexagon grid unit positions return their codebook vectors
+	m, err = NewMap(mSom, dataMx)
+	assert.NoError(err)
+	coords = m.Grid().Coords()
+	for i := 0; i < rows; i++ {
+		vec := m.VectorAt(coords.At(i, 0), coords.At(i, 1))
+		assert.InDeltaSlice(m.codebook.RawRowView(i), vec, 1e-9)
+	}
+}
+
+func TestResize(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NotNil(m)
+	assert.NoError(err)
+	// invalid grid size
+	r, err := m.Resize([]int{-1, 2})
+	assert.Nil(r)
+	assert.Error(err)
+	// grow the map
+	size := []int{4, 6}
+	r, err = m.Resize(size)
+	assert.NotNil(r)
+	assert.NoError(err)
+	assert.Equal(size, r.Grid().Size())
+	assert.Equal(m.Grid().UShape(), r.Grid().UShape())
+	rows, cols := r.Codebook().Dims()
+	assert.Equal(utils.IntProduct(size), rows)
+	_, cbCols := m.Codebook().Dims()
+	assert.Equal(cbCols, cols)
+	// corner units keep their codebook vectors
+	assert.InDeltaSlice(m.codebook.RawRowView(0), r.codebook.RawRowView(0), 1e-9)
+	// resized map can be trained further
+	err = r.Train(tSom, dataMx, 10)
+	assert.NoError(err)
+
+	// resized map keeps the metric and custom distance function
+	tc := makeDefaultTrainConfig()
+	tc.Metric = Manhattan
+	assert.NoError(m.Train(tc, dataMx, 10))
+	m.SetDistanceFunc(l1)
+	r, err = m.Resize(size)
+	assert.NoError(err)
+	assert.Equal(Manhattan, r.Metric())
+	assert.NotNil(r.DistanceFunc())
+	qe, err := r.QuantError(dataMx)
+	assert.NoError(err)
+	expQe, err := quantError(measure{metric: Manhattan}, dataMx, r.codebook)
+	assert.NoError(err)
+	assert.InDelta(expQe, qe, 1e-9)
+	// mahalanobis map keeps its covariance
+	data := correlatedData(50)
+	mm, err := NewMap(&MapConfig{
+		Grid: &GridConfig{Size: []int{3, 4}, Type: "planar", UShape: "hexagon"},
+		Cb:   &CbConfig{Dim: 3, InitFunc: RandInit},
+	}, data)
+	assert.NoError(err)
+	tc.Metric = Mahalanobis
+	assert.NoError(mm.Train(tc, data, 10))
+	r, err = mm.Resize([]int{5, 6})
+	assert.NoError(err)
+	assert.Equal(Mahalanobis, r.Metric())
+	assert.True(mat.Equal(mm.Covariance(), r.Covariance()))
+}
+
+func TestWithUShape(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	assert.NoError(m.Train(tSom, dataMx, 10))
+	v, err := m.WithUShape("rectangle")
+	assert.NoError(err)
+	assert.Equal("rectangle", v.Grid().UShape())
+	assert.Equal(m.Grid().Size(), v.Grid().Size())
+	assert.Equal(m.Grid().Type(), v.Grid().Type())
+	assert.Equal(m.Metadata(), v.Metadata())
+	// the lattices differ but BMUs of the shared codebook don't
+	assert.False(mat.Equal(m.Grid().Coords(), v.Grid().Coords()))
+	exp, err := m.BMUs(dataMx)
+	assert.NoError(err)
+	bmus, err := v.BMUs(dataMx)
+	assert.NoError(err)
+	assert.Equal(exp, bmus)
+	buf := new(bytes.Buffer)
+	assert.NoError(v.UMatrix(buf, dataMx, nil, "svg", "rectangle"))
+	// the view shares the codebook with the map
+	v.codebook.Set(0, 0, 100)
+	assert.Equal(100.0, m.codebook.At(0, 0))
+
+	// invalid unit shape and sphere grids
+	_, err = m.WithUShape("triangle")
+	assert.Error(err)
+	sm, err := NewMap(&MapConfig{
+		Grid: &GridConfig{Size: SphereSize(1), Type: "sphere", UShape: "hexagon"},
+		Cb:   mSom.Cb,
+	}, dataMx)
+	assert.NoError(err)
+	_, err = sm.WithUShape("rectangle")
+	assert.Error(err)
 }
 
 func TestTrain(t *testing.T) {
@@ -189,9 +530,277 @@ func TestTrain(t *testing.T) {
 	tSom.Algorithm = "batch"
 	err = m.Train(tSom, dataMx, iters)
 	assert.NoError(err)
+	// batch training with more workers than data rows
+	tSom.Workers = 16
+	err = m.Train(tSom, dataMx, iters)
+	assert.NoError(err)
+	tSom.Workers = 0
 	tSom.Algorithm = origAlgorithm
 }
 
+func TestRefine(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NotNil(m)
+	assert.NoError(err)
+	// invalid number of iterations and data
+	err = m.Refine(dataMx, -10)
+	assert.Error(err)
+	assert.Error(m.Refine(nil, 10))
+	rows, _ := dataMx.Dims()
+	// untrained map is refined using default config
+	c := m.refineConfig(rows)
+	assert.Equal("seq", c.Algorithm)
+	assert.Equal(MinRadius, c.Radius)
+	err = m.Refine(dataMx, 10)
+	assert.NoError(err)
+	// trained map derives config from the last training
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	err = m.Train(tc, dataMx, 10)
+	assert.NoError(err)
+	c = m.refineConfig(rows)
+	assert.Equal(tc.Algorithm, c.Algorithm)
+	assert.True(c.Radius <= tc.Radius)
+	assert.True(c.LRate < tc.LRate)
+	err = m.Refine(dataMx, 10)
+	assert.NoError(err)
+	// consecutive refinements derive the same config from the last training
+	for i := 0; i < 2; i++ {
+		rc := m.refineConfig(rows)
+		assert.Equal(c.Radius, rc.Radius)
+		assert.Equal(c.LRate, rc.LRate)
+		err = m.Refine(dataMx, 10)
+		assert.NoError(err)
+	}
+	// changes of the training config made after training don't affect the map
+	tc.Algorithm = "seq"
+	tc.LRate = 1.0
+	rc := m.refineConfig(rows)
+	assert.Equal("batch", rc.Algorithm)
+	assert.Equal(c.LRate, rc.LRate)
+}
+
+func TestRefineMasked(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(20, 4, nil)
+	for i := 0; i < 20; i++ {
+		data.SetRow(i, []float64{float64(i % 5), float64(i / 5), float64(100 * (i * 7 % 11)), float64(i)})
+	}
+	c := &MapConfig{
+		Grid: mSom.Grid,
+		Cb:   &CbConfig{Dim: 4, InitFunc: RandInit},
+	}
+	m, err := NewMap(c, data)
+	assert.NoError(err)
+	fixed := mat.Col(nil, 3, m.codebook)
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	tc.Carry = []int{2}
+	tc.Fixed = []int{3}
+	tc.FeatureWeights = []float64{1, 2, 1, 1}
+	assert.NoError(m.Train(tc, data, 10))
+	assert.NoError(m.Refine(data, 10))
+	rc := m.refineConfig(20)
+	assert.Equal(tc.Carry, rc.Carry)
+	assert.Equal(tc.Fixed, rc.Fixed)
+	assert.Equal(tc.FeatureWeights, rc.FeatureWeights)
+	// fixed columns are not updated by refinements
+	assert.Equal(fixed, mat.Col(nil, 3, m.codebook))
+	// carried columns stay out of BMU search of the refined map
+	exp, err := m.BMUs(data)
+	assert.NoError(err)
+	zeroed := mat.DenseCopyOf(data)
+	for i := 0; i < 20; i++ {
+		zeroed.Set(i, 2, 0)
+	}
+	bmus, err := m.BMUs(zeroed)
+	assert.NoError(err)
+	assert.Equal(exp, bmus)
+	// row weights are only kept for the same number of rows
+	tc.Weights = make([]float64, 20)
+	for i := range tc.Weights {
+		tc.Weights[i] = 1
+	}
+	assert.NoError(m.Train(tc, data, 10))
+	assert.Equal(tc.Weights, m.refineConfig(20).Weights)
+	assert.Nil(m.refineConfig(5).Weights)
+	assert.NoError(m.Refine(dataMx.Slice(0, 5, 0, 4).(*mat.Dense), 10))
+}
+
+func TestTrainSeed(t *testing.T) {
+	assert := assert.New(t)
+
+	train := func(seed int64) mat.Matrix {
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		tc := makeDefaultTrainConfig()
+		tc.Seed = seed
+		tc.Sampling = "qerror"
+		assert.NoError(m.Train(tc, dataMx, 50))
+		return m.Codebook()
+	}
+	// the same seed trains the same map
+	assert.True(mat.Equal(train(7), train(7)))
+	assert.False(mat.Equal(train(7), train(8)))
+}
+
+func TestTrainConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	// map which is being trained can't be trained again
+	m.training = 1
+	err = m.Train(tSom, dataMx, 10)
+	assert.Equal(ErrTrainInProgress, err)
+	m.training = 0
+	// concurrent training calls either succeed or fail with ErrTrainInProgress
+	tc := makeDefaultTrainConfig()
+	errs := make(chan error, 4)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- m.Train(tc, dataMx, 100)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(ErrTrainInProgress, err)
+	}
+	assert.True(succeeded > 0)
+	// training flag is cleared after training
+	assert.Equal(int32(0), m.training)
+}
+
+func TestTrainContext(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, alg := range []string{"seq", "batch"} {
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		tc := makeDefaultTrainConfig()
+		tc.Algorithm = alg
+		// cancelled training does not start
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err = m.TrainContext(ctx, tc, dataMx, 10)
+		assert.Equal(context.Canceled, err)
+		assert.Nil(m.Metadata().Trained)
+		// long training is stopped once its deadline passes
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err = m.TrainContext(ctx, tc, dataMx, 200000)
+		cancel()
+		assert.Equal(context.DeadlineExceeded, err)
+		assert.Equal(int32(0), m.training)
+		// training finished in time
+		err = m.TrainContext(context.Background(), tc, dataMx, 10)
+		assert.NoError(err)
+		assert.NotNil(m.Metadata().Trained)
+	}
+	// trainer pool training
+	tr, err := NewTrainer(2)
+	assert.NoError(err)
+	defer tr.Close()
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(context.Canceled, tr.TrainContext(ctx, m, tSom, dataMx, 10))
+}
+
+func TestChainMap(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, uShape := range []string{"rectangle", "hexagon"} {
+		for _, size := range [][]int{{1, 5}, {5, 1}} {
+			c := &MapConfig{
+				Grid: &GridConfig{Size: size, Type: "planar", UShape: uShape},
+				Cb:   &CbConfig{Dim: 4, InitFunc: LinInit},
+			}
+			m, err := NewMap(c, dataMx)
+			assert.NoError(err)
+			// neighbouring units of the chain are 1 apart
+			unitDist, err := m.UnitDist()
+			assert.NoError(err)
+			for i := 0; i < 5; i++ {
+				for j := 0; j < 5; j++ {
+					assert.InDelta(math.Abs(float64(i-j)), unitDist.At(i, j), 1e-9)
+				}
+			}
+			for _, alg := range []string{"seq", "batch"} {
+				tc := makeDefaultTrainConfig()
+				tc.Algorithm = alg
+				err = m.Train(tc, dataMx, 10)
+				assert.NoError(err)
+			}
+			_, err = m.QuantError(dataMx)
+			assert.NoError(err)
+			_, err = m.TopoProduct()
+			assert.NoError(err)
+			_, err = m.TopoError(dataMx)
+			assert.NoError(err)
+			for _, format := range []string{"svg", "html", "png"} {
+				err = m.UMatrix(ioutil.Discard, dataMx, nil, format, "chain")
+				assert.NoError(err)
+			}
+			// chain units are interpolated along the chain
+			a, b := m.codebook.RawRowView(0), m.codebook.RawRowView(1)
+			vec := m.VectorAt(0.5*float64(size[1]-1)/4, 0.5*float64(size[0]-1)/4)
+			for i := range vec {
+				assert.InDelta((a[i]+b[i])/2, vec[i], 1e-9)
+			}
+		}
+	}
+}
+
+func TestLayeredMap(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, gtype := range []string{"planar", "toroid"} {
+		for _, uShape := range []string{"rectangle", "hexagon"} {
+			c := &MapConfig{
+				Grid: &GridConfig{Size: []int{2, 3, 2}, Type: gtype, UShape: uShape},
+				Cb:   &CbConfig{Dim: 4, InitFunc: LinInit},
+			}
+			m, err := NewMap(c, dataMx)
+			assert.NoError(err)
+			// units right above each other are neighbours
+			unitDist, err := m.UnitDist()
+			assert.NoError(err)
+			assert.InDelta(1.0, unitDist.At(0, 6), 1e-9)
+			for _, alg := range []string{"seq", "batch"} {
+				tc := makeDefaultTrainConfig()
+				tc.Algorithm = alg
+				err = m.Train(tc, dataMx, 10)
+				assert.NoError(err)
+			}
+			_, err = m.TopoError(dataMx)
+			assert.NoError(err)
+			for _, format := range []string{"svg", "html", "png"} {
+				err = m.UMatrix(ioutil.Discard, dataMx, nil, format, "layers")
+				assert.NoError(err)
+			}
+			// vectors are picked from the layers drawn side by side
+			assert.Equal(m.codebook.RawRowView(6), m.VectorAt(4, 0))
+			_, err = m.Resize([]int{3, 3})
+			assert.Error(err)
+			_, err = m.MarshalTo("somoclu", ioutil.Discard)
+			assert.Error(err)
+		}
+	}
+}
+
 func TestMapQuantError(t *testing.T) {
 	assert := assert.New(t)
 
@@ -204,3 +813,119 @@ func TestMapQuantError(t *testing.T) {
 	assert.NoError(err)
 	assert.True(qe > 0.0)
 }
+
+func TestTrainMetric(t *testing.T) {
+	assert := assert.New(t)
+
+	rows, _ := dataMx.Dims()
+	for _, metric := range []Metric{Euclidean, Manhattan, Chebyshev, Tanimoto} {
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		tc := makeDefaultTrainConfig()
+		tc.Metric = metric
+		assert.NoError(m.Train(tc, dataMx, 20))
+		assert.Equal(metric, m.Metric())
+		assert.Equal(metric.String(), m.Metadata().Train.Metric)
+		// BMUs and quantization error use the training metric
+		bmus, err := m.BMUs(dataMx)
+		assert.NoError(err)
+		exp := 0.0
+		for i := 0; i < rows; i++ {
+			bmu, err := ClosestVec(metric, dataMx.RawRowView(i), m.codebook)
+			assert.NoError(err)
+			assert.Equal(bmu, bmus[i])
+			d, err := Distance(metric, dataMx.RawRowView(i), m.codebook.RawRowView(bmu))
+			assert.NoError(err)
+			exp += d
+		}
+		qe, err := m.QuantError(dataMx)
+		assert.NoError(err)
+		assert.InDelta(exp/float64(rows), qe, 1e-9, "metric %s", metric)
+	}
+	// unknown metric
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Metric = Metric(1000)
+	assert.Error(m.Train(tc, dataMx, 20))
+}
+
+func TestMapConfigMetric(t *testing.T) {
+	assert := assert.New(t)
+
+	manhattan := measure{metric: Manhattan}
+	mc := *mSom
+	mc.Metric = Manhattan
+	m, err := NewMap(&mc, dataMx)
+	assert.NoError(err)
+	assert.Equal(Manhattan, m.Metric())
+	// untrained map evaluates data with the configured metric
+	got, err := m.BMUs(dataMx)
+	assert.NoError(err)
+	exp, err := bmus(manhattan, dataMx, m.codebook)
+	assert.NoError(err)
+	assert.Equal(exp, got)
+	qe, err := m.QuantError(dataMx)
+	assert.NoError(err)
+	expQe, err := quantError(manhattan, dataMx, m.codebook)
+	assert.NoError(err)
+	assert.InDelta(expQe, qe, 1e-9)
+	te, err := m.TopoError(dataMx)
+	assert.NoError(err)
+	expTe, err := topoError(manhattan, dataMx, m.codebook, m.grid.Adjacent)
+	assert.NoError(err)
+	assert.InDelta(expTe, te, 1e-9)
+
+	// training keeps the map metric unless the training configuration sets another one
+	tc := makeDefaultTrainConfig()
+	assert.NoError(m.Train(tc, dataMx, 20))
+	assert.Equal(Manhattan, m.Metric())
+	assert.Equal("manhattan", m.Metadata().Train.Metric)
+	assert.NoError(m.Refine(dataMx, 10))
+	assert.Equal(Manhattan, m.Metric())
+	tc.Metric = Chebyshev
+	assert.NoError(m.Train(tc, dataMx, 20))
+	assert.Equal(Chebyshev, m.Metric())
+	// the metric can be switched back to euclidean explicitly
+	assert.NoError(m.SetMetric(Euclidean))
+	tc.Metric = Euclidean
+	assert.NoError(m.Train(tc, dataMx, 20))
+	assert.Equal("euclidean", m.Metadata().Train.Metric)
+
+	// unknown metric
+	assert.Error(m.SetMetric(Metric(1000)))
+	mc.Metric = Metric(1000)
+	_, err = NewMap(&mc, dataMx)
+	assert.Error(err)
+}
+
+func TestTrainWeighted(t *testing.T) {
+	assert := assert.New(t)
+
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	tc.Workers = 1
+	// weighted rows train the same map as duplicated rows
+	dup := mat.NewDense(7, 4, nil)
+	weights := []float64{1, 3, 0, 2, 1}
+	for i, k := 0, 0; i < len(weights); i++ {
+		for j := 0; j < int(weights[i]); j++ {
+			dup.SetRow(k, dataMx.RawRowView(i))
+			k++
+		}
+	}
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	wm, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	wm.codebook.Copy(m.codebook)
+	// few iterations keep the radius large enough to avoid BMU ties caused by rounding
+	assert.NoError(m.Train(tc, dup, 5))
+	tc.Weights = weights
+	assert.NoError(wm.Train(tc, dataMx, 5))
+	assert.True(mat.EqualApprox(m.Codebook(), wm.Codebook(), 1e-9))
+	// every row must have a weight
+	assert.Error(wm.Train(tc, dup, 10))
+	// streamed samples can't be weighted
+	assert.Error(wm.TrainStream(tc, feed(dataMx, 0), 5, 10))
+}
@@ -0,0 +1,196 @@
+package som
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Somoclu stores codebooks and BMUs in ESOM compatible text files. Both files start with
+// header lines prefixed with % which hold the number of map rows and columns followed by
+// the codebook dimension or the number of data rows. Codebook (.wts) files store one codebook
+// vector per line with units ordered row by row. BMU (.bm) files store data row index followed
+// by BMU row and column on each line.
+
+// countReader counts the bytes read from reader r
+type countReader struct {
+	r io.Reader
+	n int
+}
+
+// Read reads from the underlying reader and counts the read bytes
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// somocluUnit returns index of gosom unit in given row and column of map with given dimensions.
+// gosom units are ordered column by column.
+func somocluUnit(dims []int, row, col int) int {
+	return row + col*dims[0]
+}
+
+// somocluDims returns map rows and columns of grid with given dimensions
+func somocluDims(dims []int) (int, int) {
+	if len(dims) == 1 {
+		return dims[0], 1
+	}
+	return dims[0], dims[1]
+}
+
+// writeSomocluCodebook writes codebook of map with given dimensions to w in Somoclu format
+// Somoclu maps are 2D, so it fails with error if the grid is 3D.
+func writeSomocluCodebook(w io.Writer, codebook *mat.Dense, dims []int) (int, error) {
+	if len(dims) > 2 {
+		return 0, fmt.Errorf("unsupported number of Somoclu grid dimensions: %d", len(dims))
+	}
+	rows, cols := somocluDims(dims)
+	_, dim := codebook.Dims()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%%%d %d\n%%%d\n", rows, cols, dim)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			for _, v := range codebook.RawRowView(somocluUnit([]int{rows, cols}, row, col)) {
+				buf.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+				buf.WriteByte(' ')
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	return w.Write(buf.Bytes())
+}
+
+// ReadSomocluCodebook reads codebook stored in Somoclu format from r.
+// It returns codebook with units ordered as gosom grid units along with the map dimensions.
+// It fails with error if the header is malformed or the number of codebook vectors or their
+// dimensions do not match the header.
+func ReadSomocluCodebook(r io.Reader) (*mat.Dense, []int, error) {
+	s := bufio.NewScanner(r)
+	head, err := readSomocluHeader(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, cols, dim := head[0], head[1], head[2]
+	dims := []int{rows, cols}
+	codebook := mat.NewDense(rows*cols, dim, nil)
+	units := 0
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if units == rows*cols {
+			return nil, nil, fmt.Errorf("too many codebook vectors")
+		}
+		if len(fields) != dim {
+			return nil, nil, fmt.Errorf("invalid codebook vector %d dimension: %d", units, len(fields))
+		}
+		vec := codebook.RawRowView(somocluUnit(dims, units/cols, units%cols))
+		for i, f := range fields {
+			if vec[i], err = strconv.ParseFloat(f, 64); err != nil {
+				return nil, nil, fmt.Errorf("invalid codebook value: %s", f)
+			}
+		}
+		units++
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+	if units != rows*cols {
+		return nil, nil, fmt.Errorf("invalid number of codebook vectors: %d", units)
+	}
+	return codebook, dims, nil
+}
+
+// WriteSomocluBMUs finds BMUs of data rows and writes them to w in Somoclu format.
+// It fails with error if the map grid is 3D, the BMUs could not be found or the write to w fails.
+func (m *Map) WriteSomocluBMUs(w io.Writer, data *mat.Dense) error {
+	if size := m.grid.Size(); len(size) > 2 {
+		return fmt.Errorf("unsupported number of Somoclu grid dimensions: %d", len(size))
+	}
+	bmus, err := m.BMUs(data)
+	if err != nil {
+		return err
+	}
+	rows, cols := somocluDims(m.grid.Size())
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%%%d %d\n%%%d\n", rows, cols, len(bmus))
+	for i, bmu := range bmus {
+		fmt.Fprintf(bw, "%d %d %d\n", i, bmu%rows, bmu/rows)
+	}
+	return bw.Flush()
+}
+
+// ReadSomocluBMUs reads BMUs stored in Somoclu format from r.
+// It returns the gosom indices of BMU units of data rows along with the map dimensions.
+// It fails with error if the header is malformed or if some data row index or BMU is out of range.
+func ReadSomocluBMUs(r io.Reader) ([]int, []int, error) {
+	s := bufio.NewScanner(r)
+	head, err := readSomocluHeader(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, cols, n := head[0], head[1], head[2]
+	dims := []int{rows, cols}
+	bmus := make([]int, n)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, nil, fmt.Errorf("invalid BMU line: %s", s.Text())
+		}
+		vals := make([]int, 3)
+		for i, f := range fields {
+			if vals[i], err = strconv.Atoi(f); err != nil {
+				return nil, nil, fmt.Errorf("invalid BMU line: %s", s.Text())
+			}
+		}
+		if vals[0] < 0 || vals[0] >= n {
+			return nil, nil, fmt.Errorf("invalid data row index: %d", vals[0])
+		}
+		if vals[1] < 0 || vals[1] >= rows || vals[2] < 0 || vals[2] >= cols {
+			return nil, nil, fmt.Errorf("invalid BMU: %d %d", vals[1], vals[2])
+		}
+		bmus[vals[0]] = somocluUnit(dims, vals[1], vals[2])
+	}
+	if err := s.Err(); err != nil {
+		return nil, nil, err
+	}
+	return bmus, dims, nil
+}
+
+// readSomocluHeader reads Somoclu file header: map rows and columns followed by codebook dimension
+// or the number of data rows. It returns error if the header is malformed.
+func readSomocluHeader(s *bufio.Scanner) ([]int, error) {
+	var fields []string
+	for len(fields) < 3 && s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if !strings.HasPrefix(line, "%") {
+			return nil, fmt.Errorf("invalid header line: %s", line)
+		}
+		fields = append(fields, strings.Fields(strings.TrimPrefix(line, "%"))...)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid header: %v", fields)
+	}
+	head := make([]int, 3)
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid header value: %s", f)
+		}
+		head[i] = v
+	}
+	return head, nil
+}
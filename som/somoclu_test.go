@@ -0,0 +1,99 @@
+package som
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSomocluCodebook(t *testing.T) {
+	assert := assert.New(t)
+
+	mapCfg := &MapConfig{
+		Grid: &GridConfig{Size: []int{2, 3}, Type: "planar", UShape: "rectangle"},
+		Cb:   &CbConfig{Dim: 2, InitFunc: RandInit},
+	}
+	m, err := NewMap(mapCfg, mat.NewDense(1, 2, []float64{0.0, 0.0}))
+	assert.NoError(err)
+	// codebook values encode gosom unit indices
+	for u := 0; u < 6; u++ {
+		m.codebook.SetRow(u, []float64{float64(u), 0.5})
+	}
+	var buf bytes.Buffer
+	n, err := m.MarshalTo("somoclu", &buf)
+	assert.NoError(err)
+	assert.Equal(buf.Len(), n)
+	// units are written row by row
+	assert.Equal("%2 3\n%2\n0 0.5 \n2 0.5 \n4 0.5 \n1 0.5 \n3 0.5 \n5 0.5 \n", buf.String())
+	// read codebook matches the map codebook
+	codebook, dims, err := ReadSomocluCodebook(bytes.NewReader(buf.Bytes()))
+	assert.NoError(err)
+	assert.Equal([]int{2, 3}, dims)
+	assert.True(mat.Equal(m.codebook, codebook))
+	// unmarshal into a map
+	m2, err := NewMap(mapCfg, mat.NewDense(1, 2, []float64{0.0, 0.0}))
+	assert.NoError(err)
+	n, err = m2.UnmarshalFrom("somoclu", bytes.NewReader(buf.Bytes()))
+	assert.NoError(err)
+	assert.Equal(buf.Len(), n)
+	assert.True(mat.Equal(m.codebook, m2.codebook))
+	// mismatched grid dimensions
+	mapCfg.Grid.Size = []int{3, 2}
+	m3, err := NewMap(mapCfg, mat.NewDense(1, 2, []float64{0.0, 0.0}))
+	assert.NoError(err)
+	_, err = m3.UnmarshalFrom("somoclu", bytes.NewReader(buf.Bytes()))
+	assert.Error(err)
+	// malformed codebooks
+	for _, in := range []string{
+		"",
+		"2 3\n%2\n",
+		"%2 x\n%2\n",
+		"%1 1\n%2\n1 2 3\n",
+		"%1 1\n%2\n1 x\n",
+		"%1 2\n%2\n1 2\n",
+		"%1 1\n%2\n1 2\n3 4\n",
+	} {
+		_, _, err := ReadSomocluCodebook(strings.NewReader(in))
+		assert.Error(err, in)
+	}
+}
+
+func TestSomocluBMUs(t *testing.T) {
+	assert := assert.New(t)
+
+	mapCfg := &MapConfig{
+		Grid: &GridConfig{Size: []int{2, 3}, Type: "planar", UShape: "rectangle"},
+		Cb:   &CbConfig{Dim: 1, InitFunc: RandInit},
+	}
+	m, err := NewMap(mapCfg, mat.NewDense(1, 1, []float64{0.0}))
+	assert.NoError(err)
+	for u := 0; u < 6; u++ {
+		m.codebook.Set(u, 0, float64(u))
+	}
+	data := mat.NewDense(3, 1, []float64{0.0, 3.0, 5.0})
+	var buf bytes.Buffer
+	assert.NoError(m.WriteSomocluBMUs(&buf, data))
+	// gosom unit 3 is in row 1 and column 1, unit 5 in row 1 and column 2
+	assert.Equal("%2 3\n%3\n0 0 0\n1 1 1\n2 1 2\n", buf.String())
+	bmus, dims, err := ReadSomocluBMUs(&buf)
+	assert.NoError(err)
+	assert.Equal([]int{2, 3}, dims)
+	assert.Equal([]int{0, 3, 5}, bmus)
+	// invalid BMU files
+	for _, in := range []string{
+		"%2 3\n",
+		"%2 3\n%1\n0 0\n",
+		"%2 3\n%1\n0 0 x\n",
+		"%2 3\n%1\n1 0 0\n",
+		"%2 3\n%1\n0 2 0\n",
+		"%2 3\n%1\n0 0 3\n",
+	} {
+		_, _, err := ReadSomocluBMUs(strings.NewReader(in))
+		assert.Error(err, in)
+	}
+	// data dimension mismatch
+	assert.Error(m.WriteSomocluBMUs(&buf, mat.NewDense(1, 2, nil)))
+}
@@ -0,0 +1,153 @@
+package som
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// sphereNeighbourRadius is the distance within which the units of sphere grid are lattice neighbours.
+// Neighbouring units of geodesic grids are between 0.9 and 1.1 apart, while the closest units
+// which are not neighbours are almost 1.5 apart.
+const sphereNeighbourRadius = 1.3
+
+// icosahedron vertices and faces from which geodesic sphere grids are subdivided
+var (
+	icoVertices = func() [][3]float64 {
+		t := (1.0 + math.Sqrt(5.0)) / 2.0
+		return [][3]float64{
+			{-1, t, 0}, {1, t, 0}, {-1, -t, 0}, {1, -t, 0},
+			{0, -1, t}, {0, 1, t}, {0, -1, -t}, {0, 1, -t},
+			{t, 0, -1}, {t, 0, 1}, {-t, 0, -1}, {-t, 0, 1},
+		}
+	}()
+	icoFaces = [][3]int{
+		{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+		{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+		{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+		{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+	}
+)
+
+// SphereSize returns the size of geodesic sphere grid of a given subdivision level.
+// Sphere grids are closed surfaces without borders, so their units are stored in a single
+// grid row: the returned size is [1, SphereUnits(level)].
+func SphereSize(level int) []int {
+	return []int{1, SphereUnits(level)}
+}
+
+// sphereLevel returns the subdivision level of geodesic sphere grid of given dims.
+// It returns error if dims is not the size of any geodesic sphere grid.
+func sphereLevel(dims []int) (int, error) {
+	if len(dims) == 2 && dims[0] == 1 {
+		for level := 0; SphereUnits(level) <= dims[1]; level++ {
+			if SphereUnits(level) == dims[1] {
+				return level, nil
+			}
+		}
+	}
+	return -1, fmt.Errorf("invalid sphere grid dimensions: %v", dims)
+}
+
+// SphereCoords returns a matrix which contains 3D coordinates of the units of geodesic sphere grid of given
+// dims stored row by row. The units are the vertices of an icosahedron whose faces are subdivided as many
+// times as is the level of the grid; see SphereSize. The sphere radius is chosen so that the average
+// distance between neighbouring units measured along the sphere surface is 1. Unit shape is only used to
+// draw the units and is ignored. It fails with error if dims is not the size of geodesic sphere grid.
+func SphereCoords(uShape string, dims []int) (*mat.Dense, error) {
+	level, err := sphereLevel(dims)
+	if err != nil {
+		return nil, err
+	}
+	vertices := make([][3]float64, len(icoVertices))
+	for i, v := range icoVertices {
+		vertices[i] = normalizeVec3(v)
+	}
+	faces := icoFaces
+	for l := 0; l < level; l++ {
+		vertices, faces = subdivide(vertices, faces)
+	}
+	// scale the sphere so that neighbouring units are 1 apart on average
+	angle := 0.0
+	for _, f := range faces {
+		for i := range f {
+			angle += vecAngle(vertices[f[i]][:], vertices[f[(i+1)%3]][:])
+		}
+	}
+	r := float64(3*len(faces)) / angle
+	coords := mat.NewDense(len(vertices), 3, nil)
+	for i, v := range vertices {
+		coords.SetRow(i, []float64{r * v[0], r * v[1], r * v[2]})
+	}
+	return coords, nil
+}
+
+// subdivide splits each triangle face into four faces by their edge midpoints projected onto the unit sphere.
+// It returns the vertices extended by the new midpoint vertices along with the new faces.
+func subdivide(vertices [][3]float64, faces [][3]int) ([][3]float64, [][3]int) {
+	mids := make(map[[2]int]int)
+	midpoint := func(a, b int) int {
+		key := [2]int{a, b}
+		if a > b {
+			key = [2]int{b, a}
+		}
+		if i, ok := mids[key]; ok {
+			return i
+		}
+		va, vb := vertices[a], vertices[b]
+		vertices = append(vertices, normalizeVec3([3]float64{va[0] + vb[0], va[1] + vb[1], va[2] + vb[2]}))
+		mids[key] = len(vertices) - 1
+		return mids[key]
+	}
+	subFaces := make([][3]int, 0, 4*len(faces))
+	for _, f := range faces {
+		ab, bc, ca := midpoint(f[0], f[1]), midpoint(f[1], f[2]), midpoint(f[2], f[0])
+		subFaces = append(subFaces,
+			[3]int{f[0], ab, ca},
+			[3]int{f[1], bc, ab},
+			[3]int{f[2], ca, bc},
+			[3]int{ab, bc, ca},
+		)
+	}
+	return vertices, subFaces
+}
+
+// normalizeVec3 returns 3D vector v scaled to unit length
+func normalizeVec3(v [3]float64) [3]float64 {
+	n := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+	return [3]float64{v[0] / n, v[1] / n, v[2] / n}
+}
+
+// vecAngle returns the angle between vectors a and b
+func vecAngle(a, b []float64) float64 {
+	dot, na, nb := 0.0, 0.0, 0.0
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	// clamp rounding errors of nearly parallel vectors
+	return math.Acos(math.Max(-1, math.Min(1, dot/math.Sqrt(na*nb))))
+}
+
+// arcDist returns the distance between points a and b on the surface of sphere centred at origin
+func arcDist(a, b []float64) float64 {
+	return math.Sqrt(a[0]*a[0]+a[1]*a[1]+a[2]*a[2]) * vecAngle(a, b)
+}
+
+// sphereProjection returns equirectangular projection of sphere coords into 2D coordinates.
+// The longitude is projected onto x and the latitude onto y axis, both scaled by the sphere
+// radius, so the distances of the units around the equator are preserved; the north pole is at y 0.
+func sphereProjection(coords *mat.Dense) *mat.Dense {
+	rows, _ := coords.Dims()
+	proj := mat.NewDense(rows, 2, nil)
+	for i := 0; i < rows; i++ {
+		v := coords.RawRowView(i)
+		r := math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+		lon, lat := math.Atan2(v[1], v[0]), math.Asin(v[2]/r)
+		proj.Set(i, 0, r*(lon+math.Pi))
+		proj.Set(i, 1, r*(math.Pi/2-lat))
+	}
+	return proj
+}
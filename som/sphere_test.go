@@ -0,0 +1,101 @@
+package som
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSphereCoords(t *testing.T) {
+	assert := assert.New(t)
+
+	for level := 0; level < 3; level++ {
+		coords, err := SphereCoords("hexagon", SphereSize(level))
+		assert.NoError(err)
+		rows, cols := coords.Dims()
+		assert.Equal(SphereUnits(level), rows)
+		assert.Equal(3, cols)
+		// all units lie on the same sphere
+		r := mat.Norm(coords.RowView(0), 2)
+		for i := 1; i < rows; i++ {
+			assert.InDelta(r, mat.Norm(coords.RowView(i), 2), 1e-9)
+		}
+	}
+	// icosahedron units are all 1 apart
+	coords, err := SphereCoords("hexagon", SphereSize(0))
+	assert.NoError(err)
+	assert.InDelta(1.0, arcDist(coords.RawRowView(0), coords.RawRowView(5)), 1e-9)
+
+	for _, dims := range [][]int{{1, 13}, {2, 6}, {12}} {
+		_, err := SphereCoords("hexagon", dims)
+		assert.Error(err)
+	}
+}
+
+func TestSphereGrid(t *testing.T) {
+	assert := assert.New(t)
+
+	g, err := NewGrid(&GridConfig{Size: SphereSize(2), Type: "sphere", UShape: "hexagon"})
+	assert.NoError(err)
+	units := g.Units()
+	assert.Equal(162, units)
+	dist := g.UnitDist()
+	// twelve icosahedron vertices have five neighbours, the other units have six
+	counts := make(map[int]int)
+	for i := 0; i < units; i++ {
+		n := 0
+		for j := 0; j < units; j++ {
+			if g.Adjacent(i, j) {
+				n++
+			}
+			assert.InDelta(g.Dist(i, j), dist.At(i, j), 1e-9)
+		}
+		counts[n]++
+	}
+	assert.Equal(map[int]int{5: 12, 6: 150}, counts)
+	// antipodal units are half circumference apart
+	assert.InDelta(g.Span(), mat.Max(dist), 1e-9)
+	assert.InDelta(math.Pi*mat.Norm(g.coordinates().RowView(0), 2), g.Span(), 1e-9)
+
+	// projection covers the drawing canvas
+	proj, dims, uShape := g.display()
+	assert.Equal("hexagon", uShape)
+	assert.True(mat.Max(proj.ColView(0)) <= float64(dims[1]))
+	assert.True(mat.Max(proj.ColView(1)) <= float64(dims[0]))
+	assert.True(mat.Min(proj) >= 0)
+
+	// sphere grid size must be geodesic
+	_, err = NewGrid(&GridConfig{Size: []int{1, 100}, Type: "sphere", UShape: "hexagon"})
+	assert.Error(err)
+}
+
+func TestSphereMap(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(&MapConfig{
+		Grid: &GridConfig{Size: SphereSize(1), Type: "sphere", UShape: "hexagon"},
+		Cb:   &CbConfig{Dim: 4, InitFunc: RandInit},
+	}, dataMx)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Radius = m.Grid().Span() / 2
+	assert.NoError(m.Train(tc, dataMx, 50))
+	assert.NoError(m.Refine(dataMx, 10))
+	_, err = m.TopoError(dataMx)
+	assert.NoError(err)
+	for _, format := range []string{"svg", "png"} {
+		buf := new(bytes.Buffer)
+		assert.NoError(m.UMatrixStats(buf, NewClassStats(), format, "sphere"))
+		assert.True(buf.Len() > 0)
+	}
+	// projected positions pick the closest unit
+	proj, _, _ := m.Grid().display()
+	assert.Equal(mat.Row(nil, 3, m.Codebook()), m.VectorAt(proj.At(3, 0), proj.At(3, 1)))
+	// spheres can't be resized or exported to kohonen
+	_, err = m.Resize([]int{2, 2})
+	assert.Error(err)
+	assert.Error(m.ExportKohonen(new(bytes.Buffer), nil))
+}
@@ -0,0 +1,97 @@
+package som
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TrainState holds the progress of training whose iterations are run in stages by TrainStage.
+// The stages continue the radius and learning rate decay of a single schedule of Iters iterations,
+// so the map can be trained on data which arrives over time or in several time-bounded runs.
+// TrainState can be encoded as JSON and saved along with the map model between the stages.
+type TrainState struct {
+	// Iters is the total number of training iterations of the schedule
+	Iters int `json:"iters"`
+	// Checkpoint holds the training state after the last stage; it is nil before the first stage
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+}
+
+// NewTrainState returns the state of staged training whose schedule has iters iterations.
+// It fails with error if iters is not positive.
+func NewTrainState(iters int) (*TrainState, error) {
+	if iters <= 0 {
+		return nil, fmt.Errorf("invalid number of iterations: %d", iters)
+	}
+	return &TrainState{Iters: iters}, nil
+}
+
+// Iteration returns the next training iteration
+func (st *TrainState) Iteration() int {
+	if st.Checkpoint == nil {
+		return 0
+	}
+	return st.Checkpoint.Iteration
+}
+
+// Done returns true if all the iterations of the schedule have been run
+func (st *TrainState) Done() bool {
+	return st.Iteration() >= st.Iters
+}
+
+// TrainStage runs the next iters iterations of staged training with state st on data and updates st.
+// Unlike Train which restarts the radius and learning rate schedule with every call, the stages follow
+// a single schedule of st.Iters iterations and continue the state of the random number generator and
+// of unit freezing, so running the stages on the same data trains the same map as a single Train call
+// with the same seed. Every stage may run on different data, e.g. on data which arrived since the last
+// stage; epochs of sequential training are passes over the data of the current stage.
+// The number of iterations is capped by the iterations left in the schedule and the map metadata and
+// training history are updated after every stage; the updates of an unfinished block of delayed training
+// are kept in st until the next stage. If ctx is done, the stage stops and st holds the
+// state of the finished iterations, so the next stage continues where the stopped one ended.
+// It returns error if the training configuration or data is invalid, the configuration differs from
+// the configuration of the previous stages, the schedule is done or if the training fails.
+func (m *Map) TrainStage(ctx context.Context, c *TrainConfig, data *mat.Dense, st *TrainState, iters int) error {
+	// guard against concurrent training which would corrupt the codebook
+	if !atomic.CompareAndSwapInt32(&m.training, 0, 1) {
+		return ErrTrainInProgress
+	}
+	defer atomic.StoreInt32(&m.training, 0)
+
+	if st == nil || st.Iters <= 0 {
+		return fmt.Errorf("invalid training state: %v", st)
+	}
+	if st.Done() {
+		return fmt.Errorf("training schedule of %d iterations is done", st.Iters)
+	}
+	// validate iterations, data and configuration
+	if err := validateTrain(c, data, iters); err != nil {
+		return err
+	}
+	ms, err := m.trainMeasure(c, data)
+	if err != nil {
+		return err
+	}
+	if st.Checkpoint != nil {
+		if err := m.validateCheckpointState(c, st.Checkpoint); err != nil {
+			return err
+		}
+	}
+
+	rows, _ := data.Dims()
+	s := newSchedule(c, st.Iters, subsampleSize(c, rows))
+	run := m.newTrainRun(c, ms, data, s, st.Checkpoint)
+	if run.next+iters < run.stop {
+		run.stop = run.next + iters
+	}
+	err = m.runTrain(ctx, c, data, run, nil)
+	st.Checkpoint = run.checkpoint(c, Fingerprint(data))
+	if err != nil {
+		return err
+	}
+	m.trained(c, ms, s, st.Checkpoint.DataFingerprint)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package som
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestTrainStage(t *testing.T) {
+	assert := assert.New(t)
+
+	configs := map[string]func(*TrainConfig){
+		"seq":    func(tc *TrainConfig) {},
+		"qerror": func(tc *TrainConfig) { tc.Sampling = "qerror"; tc.Schedule = "epoch" },
+		"batch":  func(tc *TrainConfig) { tc.Algorithm = "batch"; tc.Freeze = &FreezeConfig{Threshold: 0.05, Patience: 1} },
+		"delay":  func(tc *TrainConfig) { tc.Delay = 7 },
+	}
+	for name, configure := range configs {
+		tc := makeDefaultTrainConfig()
+		tc.Seed = 7
+		configure(tc)
+		// single training
+		exp, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		assert.NoError(exp.Train(tc, dataMx, 40), name)
+
+		// the same schedule trained in stages with the state saved in between
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		st, err := NewTrainState(40)
+		assert.NoError(err)
+		for _, iters := range []int{12, 13, 100} {
+			assert.False(st.Done())
+			assert.NoError(m.TrainStage(context.Background(), tc, dataMx, st, iters), name)
+			b, err := json.Marshal(st)
+			assert.NoError(err)
+			st = new(TrainState)
+			assert.NoError(json.Unmarshal(b, st))
+		}
+		assert.True(st.Done())
+		assert.Equal(40, st.Iteration())
+		assert.True(mat.Equal(exp.Codebook(), m.Codebook()), name)
+		assert.Equal(exp.TrainHistory(), m.TrainHistory(), name)
+		// finished schedule can't be continued
+		assert.Error(m.TrainStage(context.Background(), tc, dataMx, st, 1))
+	}
+
+	// online training continues the schedule on new data
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	st, err := NewTrainState(30)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Sampling = "qerror"
+	assert.NoError(m.TrainStage(context.Background(), tc, dataMx, st, 7))
+	assert.Equal(7, st.Iteration())
+	rows, cols := dataMx.Dims()
+	more := mat.NewDense(rows-2, cols, nil)
+	more.Copy(dataMx.Slice(2, rows, 0, cols))
+	assert.NoError(m.TrainStage(context.Background(), tc, more, st, 10))
+	assert.Equal(17, st.Iteration())
+	// stages must use the same algorithm
+	batch := makeDefaultTrainConfig()
+	batch.Algorithm = "batch"
+	assert.Error(m.TrainStage(context.Background(), batch, more, st, 10))
+	// stopped stage keeps the state of finished iterations
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(context.Canceled, m.TrainStage(ctx, tc, more, st, 10))
+	assert.Equal(17, st.Iteration())
+
+	// invalid state
+	_, err = NewTrainState(0)
+	assert.Error(err)
+	assert.Error(m.TrainStage(context.Background(), tc, dataMx, nil, 10))
+	assert.Error(m.TrainStage(context.Background(), tc, dataMx, st, 0))
+}
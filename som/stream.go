@@ -0,0 +1,330 @@
+package som
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TrainStream trains the map on samples received from channel samples using a given training configuration.
+// Sequential training runs one iteration per received sample, batch training collects batch samples into
+// a mini-batch and runs one iteration per mini-batch. Training stops once iters iterations are done or
+// samples is closed; learning rate and radius decay over iters iterations in either case.
+// Sequential training epochs, which matter to the epoch schedule and training history, are batch samples long.
+// As the samples are not retained, the map metadata has no training data fingerprint.
+// Calling TrainStream while the map is being trained returns ErrTrainInProgress.
+// It returns error if the training configuration is invalid or has row weights or subsampling, batch size or the number of iterations
+// is not positive or if a received sample dimension differs from the codebook dimension.
+func (m *Map) TrainStream(c *TrainConfig, samples <-chan []float64, batch, iters int) error {
+	return m.trainStream(c, samples, batch, iters, nil)
+}
+
+// TrainStream trains map m on samples received from channel samples using the trainer worker pool.
+// Unless configured, each mini-batch of batch training is split between as many workers as there are pool workers.
+// It returns ErrTrainerClosed if the trainer has been closed, otherwise it fails the same way Map TrainStream does.
+func (t *Trainer) TrainStream(m *Map, c *TrainConfig, samples <-chan []float64, batch, iters int) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return ErrTrainerClosed
+	}
+
+	return m.trainStream(c, samples, batch, iters, t)
+}
+
+// trainStream trains the map on streamed samples; batch training work is dispatched to the pool of trainer t.
+// If t is nil, batch training starts a temporary pool for the duration of the training.
+func (m *Map) trainStream(c *TrainConfig, samples <-chan []float64, batch, iters int, t *Trainer) error {
+	// guard against concurrent training which would corrupt the codebook
+	if !atomic.CompareAndSwapInt32(&m.training, 0, 1) {
+		return ErrTrainInProgress
+	}
+	defer atomic.StoreInt32(&m.training, 0)
+
+	if iters <= 0 {
+		return fmt.Errorf("invalid number of iterations: %d", iters)
+	}
+	if batch <= 0 {
+		return fmt.Errorf("invalid batch size: %d", batch)
+	}
+	if samples == nil {
+		return fmt.Errorf("invalid samples channel: %v", samples)
+	}
+	if err := validateTrainConfig(c); err != nil {
+		return err
+	}
+	// streamed samples have no row indices to match weights with
+	if len(c.Weights) > 0 {
+		return fmt.Errorf("row weights unsupported by stream training")
+	}
+	// streamed samples are trained on in the order they arrive
+	if c.Sampling == "qerror" {
+		return fmt.Errorf("sampling %s unsupported by stream training", c.Sampling)
+	}
+	if c.Subsample > 0 {
+		return fmt.Errorf("subsampling unsupported by stream training")
+	}
+	if err := validateStreamEval(c.Eval); err != nil {
+		return err
+	}
+	_, dim := m.codebook.Dims()
+	if err := validateColMask(c, dim); err != nil {
+		return err
+	}
+	ms, err := m.trainMeasure(c, nil)
+	if err != nil {
+		return err
+	}
+	// calculate unit distances
+	unitDist, err := m.unitDists()
+	if err != nil {
+		return err
+	}
+	// label and time training phases
+	pt := newPhaseTimer(c.PhaseHook)
+	defer pt.stop()
+
+	// sequential training epoch is batch samples long
+	s := newSchedule(c, iters, batch)
+	w := newEvalWindow(c.Eval, dim)
+	var done int
+	switch c.Algorithm {
+	case "seq":
+		done, err = m.seqStream(c, ms, unitDist, samples, s, w, pt)
+	case "batch":
+		workers := c.Workers
+		if workers == 0 && t != nil {
+			workers = t.workers
+		}
+		workers = batchWorkers(workers, batch)
+		if t == nil {
+			t = newTrainer(workers)
+			defer t.Close()
+		}
+		done, err = m.batchStream(c, ms, unitDist, samples, batch, workers, s, w, t, pt)
+	}
+	if err != nil {
+		return err
+	}
+	if done > 0 {
+		m.trained(c, ms, s, "")
+	}
+
+	return nil
+}
+
+// seqStream runs sequential training iterations following schedule s on samples received from channel samples
+// measuring their distances by ms. The samples are kept in evaluation window w.
+// It returns the number of finished iterations.
+func (m *Map) seqStream(c *TrainConfig, ms measure, unitDist *mat.Dense, samples <-chan []float64, s *schedule,
+	w *evalWindow, pt *phaseTimer) (int, error) {
+	_, dim := m.codebook.Dims()
+	f := newFreezer(c.Freeze, m.codebook, s)
+	cm, du := newColMask(c, dim), newDelayedUpdates(c.Delay, m.codebook)
+	defer du.flush(m.codebook)
+	i := 0
+	for ; i < s.iters; i++ {
+		sample, ok := <-samples
+		if !ok {
+			break
+		}
+		if len(sample) != dim {
+			return i, fmt.Errorf("invalid sample dimension: %d", len(sample))
+		}
+		if c.CheckFinite {
+			if err := checkFiniteRow(i, sample); err != nil {
+				return i, err
+			}
+		}
+		pt.enter(phaseBMU)
+		lRate, radius := s.at(i)
+		m.seqStep(c, ms, unitDist, sample, lRate, radius, f, cm, du, pt)
+		f.step(i, m.codebook, s)
+		if c.CheckFinite {
+			if err := checkFiniteCodebook(c, m.codebook, i, lRate, radius); err != nil {
+				return i, err
+			}
+		}
+		w.add(sample)
+		if err := w.eval(m, ms, i); err != nil {
+			return i, err
+		}
+	}
+
+	return i, nil
+}
+
+// batchStream runs batch training iterations following schedule s on mini-batches of samples received
+// from channel samples measuring their distances by ms. Each mini-batch is split between a given number of workers of trainer t.
+// The samples are kept in evaluation window w. It returns the number of finished iterations.
+func (m *Map) batchStream(c *TrainConfig, ms measure, unitDist *mat.Dense, samples <-chan []float64,
+	batch, workers int, s *schedule, w *evalWindow, t *Trainer, pt *phaseTimer) (int, error) {
+	_, dim := m.codebook.Dims()
+	bc := &batchConfig{
+		tc: c,
+		f:  newFreezer(c.Freeze, m.codebook, s),
+		cm: newColMask(c, dim),
+		ms: ms,
+	}
+	// one accumulator per worker and one for collecting their results
+	accs := m.accs
+	if len(accs) != workers+1 {
+		accs = newBatchAccs(workers+1, m.codebook)
+	}
+	data := mat.NewDense(batch, dim, nil)
+	i := 0
+	for ; i < s.iters; i++ {
+		rows := 0
+		for sample := range samples {
+			if len(sample) != dim {
+				return i, fmt.Errorf("invalid sample dimension: %d", len(sample))
+			}
+			if c.CheckFinite {
+				if err := checkFiniteRow(i*batch+rows, sample); err != nil {
+					return i, err
+				}
+			}
+			data.SetRow(rows, sample)
+			w.add(sample)
+			if rows++; rows == batch {
+				break
+			}
+		}
+		if rows == 0 {
+			break
+		}
+		mb := data.Slice(0, rows, 0, dim).(*mat.Dense)
+		_, radius := s.at(i)
+		// short last mini-batch can't be split between all the workers
+		if rows < workers {
+			s.units(m.batchStep(bc, unitDist, mb, radius, append(accs[:rows:rows], accs[workers]), t, pt))
+		} else {
+			s.units(m.batchStep(bc, unitDist, mb, radius, accs, t, pt))
+		}
+		bc.f.step(i, m.codebook, s)
+		if c.CheckFinite {
+			if err := checkFiniteCodebook(c, m.codebook, i, 0.0, radius); err != nil {
+				return i, err
+			}
+		}
+		if err := w.eval(m, bc.ms, i); err != nil {
+			return i, err
+		}
+		if rows < batch {
+			i++
+			break
+		}
+	}
+
+	return i, nil
+}
+
+// StreamEval configures evaluation of stream training on a rolling window of the most recent samples,
+// so long-running online maps can be monitored for degradation.
+type StreamEval struct {
+	// Window is the maximum number of recent samples kept for evaluation
+	Window int
+	// Every is the number of training iterations between evaluations
+	Every int
+	// Hook receives the evaluation results
+	Hook EvalHook
+}
+
+// EvalResult holds quality measures of the map evaluated on the window of recent samples
+type EvalResult struct {
+	// Iteration is the number of finished training iterations
+	Iteration int
+	// Samples is the number of samples in the window
+	Samples int
+	// QuantError is quantization error of the window samples
+	QuantError float64
+	// TopoError is topographic error of the window samples
+	TopoError float64
+}
+
+// EvalHook is called with the results of stream training evaluation.
+// It is called by the training goroutine, so training waits for the hook to return.
+type EvalHook func(r EvalResult)
+
+// validateStreamEval validates stream evaluation configuration; nil configuration is valid.
+// It returns error if the window size or evaluation period are not positive or the hook is nil.
+func validateStreamEval(e *StreamEval) error {
+	if e == nil {
+		return nil
+	}
+	if e.Window <= 0 {
+		return fmt.Errorf("invalid evaluation window: %d", e.Window)
+	}
+	if e.Every <= 0 {
+		return fmt.Errorf("invalid evaluation period: %d", e.Every)
+	}
+	if e.Hook == nil {
+		return fmt.Errorf("invalid evaluation hook: %v", e.Hook)
+	}
+	return nil
+}
+
+// evalWindow is a ring buffer of the most recent samples used to evaluate stream training.
+// Its methods do nothing on nil window, so stream training without evaluation needs no checks.
+type evalWindow struct {
+	// e is evaluation configuration
+	e *StreamEval
+	// samples holds window samples in its rows
+	samples *mat.Dense
+	// next is the row which receives the next sample
+	next int
+	// size is the number of samples in the window
+	size int
+}
+
+// newEvalWindow returns evaluation window of samples of given dimension configured by e.
+// It returns nil if e is nil.
+func newEvalWindow(e *StreamEval, dim int) *evalWindow {
+	if e == nil {
+		return nil
+	}
+	return &evalWindow{
+		e:       e,
+		samples: mat.NewDense(e.Window, dim, nil),
+	}
+}
+
+// add copies sample to the window replacing the oldest sample if the window is full
+func (w *evalWindow) add(sample []float64) {
+	if w == nil {
+		return
+	}
+	w.samples.SetRow(w.next, sample)
+	w.next = (w.next + 1) % w.e.Window
+	if w.size < w.e.Window {
+		w.size++
+	}
+}
+
+// eval evaluates map m on the window samples using measure ms if the i-th finished
+// training iteration completes an evaluation period and reports the results to hook.
+// It returns error if the quality measures could not be computed.
+func (w *evalWindow) eval(m *Map, ms measure, i int) error {
+	if w == nil || (i+1)%w.e.Every != 0 || w.size == 0 {
+		return nil
+	}
+	_, dim := w.samples.Dims()
+	data := w.samples.Slice(0, w.size, 0, dim).(*mat.Dense)
+	qe, err := quantError(ms, data, m.codebook)
+	if err != nil {
+		return err
+	}
+	te, err := topoError(ms, data, m.codebook, m.grid.Adjacent)
+	if err != nil {
+		return err
+	}
+	w.e.Hook(EvalResult{
+		Iteration:  i + 1,
+		Samples:    w.size,
+		QuantError: qe,
+		TopoError:  te,
+	})
+	return nil
+}
@@ -0,0 +1,126 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+// feed sends rows of data to a new channel n times and closes it
+func feed(data *mat.Dense, n int) <-chan []float64 {
+	rows, _ := data.Dims()
+	samples := make(chan []float64)
+	go func() {
+		defer close(samples)
+		for i := 0; i < n; i++ {
+			samples <- data.RawRowView(i % rows)
+		}
+	}()
+	return samples
+}
+
+func TestTrainStream(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, algo := range []string{"seq", "batch"} {
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		tc := makeDefaultTrainConfig()
+		tc.Algorithm = algo
+		codebook := mat.DenseCopyOf(m.Codebook())
+		// stream is closed before all iterations are done
+		err = m.TrainStream(tc, feed(dataMx, 47), 5, 100)
+		assert.NoError(err, algo)
+		assert.False(mat.Equal(codebook, m.Codebook()), algo)
+		meta := m.Metadata()
+		assert.NotNil(meta.Trained)
+		assert.Empty(meta.DataFingerprint)
+		// samples of incorrect dimension
+		err = m.TrainStream(tc, feed(mat.NewDense(1, 2, nil), 1), 5, 10)
+		assert.Error(err, algo)
+	}
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	// invalid parameters
+	assert.Error(m.TrainStream(tc, feed(dataMx, 1), 5, 0))
+	assert.Error(m.TrainStream(tc, feed(dataMx, 1), 0, 10))
+	assert.Error(m.TrainStream(tc, nil, 5, 10))
+	tc.Algorithm = "foo"
+	assert.Error(m.TrainStream(tc, feed(dataMx, 1), 5, 10))
+	// empty stream leaves the map untrained
+	tc.Algorithm = "seq"
+	assert.NoError(m.TrainStream(tc, feed(dataMx, 0), 5, 10))
+	assert.Nil(m.Metadata().Trained)
+}
+
+func TestTrainerTrainStream(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrainer(2)
+	assert.NoError(err)
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	// mini-batches smaller than the pool are fine too
+	for _, batch := range []int{1, 4} {
+		err = tr.TrainStream(m, tc, feed(dataMx, 20), batch, 10)
+		assert.NoError(err)
+	}
+	assert.NotNil(m.Metadata().Trained)
+	// closed trainer can't train
+	tr.Close()
+	err = tr.TrainStream(m, tc, feed(dataMx, 1), 4, 10)
+	assert.Equal(ErrTrainerClosed, err)
+}
+
+func TestTrainStreamEval(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, algo := range []string{"seq", "batch"} {
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		tc := makeDefaultTrainConfig()
+		tc.Algorithm = algo
+		var results []EvalResult
+		tc.Eval = &StreamEval{
+			Window: 3,
+			Every:  2,
+			Hook:   func(r EvalResult) { results = append(results, r) },
+		}
+		// 4 sequential iterations or 4 mini-batches of a single sample
+		err = m.TrainStream(tc, feed(dataMx, 4), 1, 4)
+		assert.NoError(err, algo)
+		assert.Len(results, 2, algo)
+		assert.Equal(2, results[0].Iteration)
+		assert.Equal(2, results[0].Samples)
+		assert.Equal(4, results[1].Iteration)
+		// window keeps at most 3 samples
+		assert.Equal(3, results[1].Samples)
+		// the last 3 samples are evaluated
+		qe, err := m.QuantError(dataMx.Slice(1, 4, 0, 4).(*mat.Dense))
+		assert.NoError(err)
+		assert.InDelta(qe, results[1].QuantError, 1e-12, algo)
+		assert.True(results[1].TopoError >= 0.0 && results[1].TopoError <= 1.0)
+	}
+}
+
+func TestValidateStreamEval(t *testing.T) {
+	assert := assert.New(t)
+
+	hook := func(EvalResult) {}
+	assert.NoError(validateStreamEval(nil))
+	assert.NoError(validateStreamEval(&StreamEval{Window: 1, Every: 1, Hook: hook}))
+	assert.Error(validateStreamEval(&StreamEval{Window: 0, Every: 1, Hook: hook}))
+	assert.Error(validateStreamEval(&StreamEval{Window: 1, Every: 0, Hook: hook}))
+	assert.Error(validateStreamEval(&StreamEval{Window: 1, Every: 1}))
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Eval = &StreamEval{Window: -1, Every: 1, Hook: hook}
+	assert.Error(m.TrainStream(tc, feed(dataMx, 0), 1, 1))
+}
@@ -0,0 +1,85 @@
+package som
+
+import (
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// subsampleSize returns the number of data rows sampled in each epoch of training configured by c
+// on data with given number of rows. At least one row is sampled; all the rows are sampled unless
+// the data are subsampled. The rows are drawn at random at the start of every epoch, so configurations
+// can be tried out quickly on large data sets before a full run: sequential training epochs are as many
+// iterations long as there are sampled rows and every batch training iteration runs on its own sample.
+func subsampleSize(c *TrainConfig, rows int) int {
+	if c.Subsample <= 0 || c.Subsample >= 1 {
+		return rows
+	}
+	size := int(math.Round(c.Subsample * float64(rows)))
+	if size < 1 {
+		size = 1
+	}
+	if size > rows {
+		size = rows
+	}
+	return size
+}
+
+// subsampled returns true if the sampler trains on a subset of data rows in each epoch
+func (s *sampler) subsampled() bool {
+	rows, _ := s.data.Dims()
+	return s.size < rows
+}
+
+// draw draws the data rows sampled in a new epoch without replacement.
+// It does nothing unless data are subsampled.
+func (s *sampler) draw() {
+	if !s.subsampled() {
+		return
+	}
+	rows, _ := s.data.Dims()
+	s.subset = s.r.Perm(rows)[:s.size]
+	// sorted rows keep their relative order in batch training data
+	sort.Ints(s.subset)
+}
+
+// validSubset returns true if subset holds the indices of as many distinct data rows as the sampler samples
+func (s *sampler) validSubset(subset []int) bool {
+	rows, _ := s.data.Dims()
+	if !s.subsampled() || len(subset) != s.size {
+		return false
+	}
+	for i, row := range subset {
+		if row < 0 || row >= rows || (i > 0 && row <= subset[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// batch returns the data rows and row weights batch training runs its i-th iteration on.
+// Subsampled iterations draw their rows, which are copied to a buffer reused between iterations.
+// It returns all the data rows and weights unless data are subsampled.
+func (s *sampler) batch(i int, weights []float64) (*mat.Dense, []float64) {
+	if !s.subsampled() {
+		return s.data, weights
+	}
+	if i%s.epoch == 0 {
+		s.draw()
+	}
+	if s.sub == nil {
+		_, cols := s.data.Dims()
+		s.sub = mat.NewDense(s.size, cols, nil)
+	}
+	if weights != nil && s.subWeights == nil {
+		s.subWeights = make([]float64, s.size)
+	}
+	for k, row := range s.subset {
+		s.sub.SetRow(k, s.data.RawRowView(row))
+		if weights != nil {
+			s.subWeights[k] = weights[row]
+		}
+	}
+	return s.sub, s.subWeights
+}
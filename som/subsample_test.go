@@ -0,0 +1,143 @@
+package som
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSubsampleSize(t *testing.T) {
+	assert := assert.New(t)
+
+	testCases := []struct {
+		subsample float64
+		rows      int
+		exp       int
+	}{
+		{0.0, 10, 10},
+		{1.0, 10, 10},
+		{0.5, 10, 5},
+		{0.25, 10, 3},
+		{0.01, 10, 1},
+	}
+	for _, tc := range testCases {
+		assert.Equal(tc.exp, subsampleSize(&TrainConfig{Subsample: tc.subsample}, tc.rows), "%f", tc.subsample)
+	}
+}
+
+func TestSubsampleSampler(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(6, 1, []float64{0, 1, 2, 3, 4, 5})
+	tc := makeDefaultTrainConfig()
+	tc.Subsample = 0.5
+	s := newSchedule(tc, 30, subsampleSize(tc, 6))
+	smp := newSampler(tc, measure{metric: tc.Metric}, data, s, rand.New(rand.NewSource(1)))
+	assert.True(smp.subsampled())
+	// sequential training draws rows from the sample of the current epoch
+	for epoch := 0; epoch < 5; epoch++ {
+		drawn := make(map[int]bool)
+		for i := 0; i < s.epoch; i++ {
+			drawn[int(smp.next(epoch*s.epoch+i, nil)[0])] = true
+		}
+		assert.Len(smp.subset, 3)
+		assert.True(smp.validSubset(smp.subset))
+		for row := range drawn {
+			assert.Contains(smp.subset, row)
+		}
+	}
+	// quantization errors of the rows outside of the sample are zero
+	tc.Sampling = "qerror"
+	smp = newSampler(tc, measure{metric: tc.Metric}, data, s, rand.New(rand.NewSource(1)))
+	codebook := mat.NewDense(1, 1, nil)
+	for i := 0; i < s.epoch; i++ {
+		assert.Contains(smp.subset, int(smp.next(i, codebook)[0]))
+	}
+	prev := 0.0
+	for row, cum := range smp.cum {
+		if contains(smp.subset, row) {
+			assert.Equal(prev+float64(row), cum)
+		} else {
+			assert.Equal(prev, cum)
+		}
+		prev = cum
+	}
+	// batch training copies the sampled rows and their weights
+	weights := []float64{1, 2, 3, 4, 5, 6}
+	sub, subWeights := smp.batch(0, weights)
+	for k, row := range smp.subset {
+		assert.Equal(float64(row), sub.At(k, 0))
+		assert.Equal(weights[row], subWeights[k])
+	}
+	// invalid subsets
+	assert.False(smp.validSubset([]int{0, 1}))
+	assert.False(smp.validSubset([]int{1, 1, 2}))
+	assert.False(smp.validSubset([]int{0, 1, 6}))
+	// data which are not subsampled are returned as they are
+	tc.Subsample = 0
+	smp = newSampler(tc, measure{metric: tc.Metric}, data, s, rand.New(rand.NewSource(1)))
+	assert.False(smp.subsampled())
+	sub, subWeights = smp.batch(0, weights)
+	assert.Equal(data, sub)
+	assert.Equal(weights, subWeights)
+	assert.False(smp.validSubset([]int{0, 1, 2}))
+}
+
+func TestTrainSubsample(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, alg := range []string{"seq", "batch"} {
+		tc := makeDefaultTrainConfig()
+		tc.Algorithm = alg
+		tc.Subsample = 0.6
+		m, err := NewMap(mSom, dataMx)
+		assert.NoError(err)
+		assert.NoError(m.Train(tc, dataMx, 12))
+		// every epoch records the number of its sampled rows
+		for _, step := range m.TrainHistory() {
+			assert.Equal(3, step.Samples, alg)
+		}
+		assert.Equal(0.6, m.Metadata().Train.Subsample)
+	}
+
+	// sequential training epochs are as long as the samples
+	tc := makeDefaultTrainConfig()
+	tc.Subsample = 0.6
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	assert.NoError(m.Train(tc, dataMx, 12))
+	assert.Len(m.TrainHistory(), 4)
+
+	// the same seed trains the same map
+	tc.Seed = 5
+	m1, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	m2, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	m2.codebook.Copy(m1.codebook)
+	assert.NoError(m1.Train(tc, dataMx, 20))
+	assert.NoError(m2.Train(tc, dataMx, 20))
+	assert.True(mat.Equal(m1.Codebook(), m2.Codebook()))
+
+	// invalid fractions and streamed samples
+	for _, subsample := range []float64{-0.1, 1.1} {
+		tc.Subsample = subsample
+		assert.Error(m.Train(tc, dataMx, 10))
+	}
+	tc.Subsample = 0.5
+	assert.Error(m.TrainStream(tc, feed(dataMx, 10), 5, 10))
+	_, err = NewOnlineTrainer(m, tc, 10, 5)
+	assert.Error(err)
+}
+
+// contains returns true if rows contain row
+func contains(rows []int, row int) bool {
+	for _, r := range rows {
+		if r == row {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package som
+
+import (
+	"fmt"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// SupervisedMetadata holds configuration of supervised (X-Y fused) map
+type SupervisedMetadata struct {
+	// Features is the number of data features; codebook vectors hold the features
+	// followed by one-hot encoded class block
+	Features int `json:"features"`
+	// Classes holds classes encoded by the class block columns
+	Classes []int `json:"classes"`
+	// Weight is the weight of the class block; data features are weighted by 1-Weight
+	Weight float64 `json:"weight"`
+}
+
+// NewSupervisedMap creates a new supervised SOM which is trained on data rows concatenated
+// with one-hot encoded classes of the rows, like XYF maps of R kohonen package.
+// The weight balances the two parts of the training vectors: class block is scaled by weight and
+// data features by 1-weight, so the higher the weight, the more the classes drive the map organization.
+// Codebook vectors of the map hold the data features followed by the class block; the codebook dimension
+// of the configuration c is ignored. The map is trained by TrainSupervised and predicts classes via Classify.
+// It fails with error if data is nil, classes is empty, weight is not in (0.0, 1.0) or if the map could not be created.
+func NewSupervisedMap(c *MapConfig, data *mat.Dense, classes map[int]int, weight float64) (*Map, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid input data: %v", data)
+	}
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("invalid classes: %v", classes)
+	}
+	if weight <= 0.0 || weight >= 1.0 {
+		return nil, fmt.Errorf("invalid class weight: %f", weight)
+	}
+	_, cols := data.Dims()
+	sm := &SupervisedMetadata{
+		Features: cols,
+		Weight:   weight,
+	}
+	seen := make(map[int]bool)
+	for _, class := range classes {
+		if !seen[class] {
+			seen[class] = true
+			sm.Classes = append(sm.Classes, class)
+		}
+	}
+	sort.Ints(sm.Classes)
+	fused, err := sm.fuse(data, classes)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.Cb == nil {
+		return nil, fmt.Errorf("invalid map config: %v", c)
+	}
+	mc := *c
+	cb := *c.Cb
+	cb.Dim = cols + len(sm.Classes)
+	mc.Cb = &cb
+	m, err := NewMap(&mc, fused)
+	if err != nil {
+		return nil, err
+	}
+	m.meta.Supervised = sm
+
+	return m, nil
+}
+
+// fuse returns data features scaled by 1-Weight concatenated with class block scaled by Weight.
+// It fails with error if data dimension differs from the number of features or if any row has unknown class.
+func (sm *SupervisedMetadata) fuse(data *mat.Dense, classes map[int]int) (*mat.Dense, error) {
+	if data == nil {
+		return nil, fmt.Errorf("invalid input data: %v", data)
+	}
+	rows, cols := data.Dims()
+	if cols != sm.Features {
+		return nil, fmt.Errorf("invalid data dimension: %d", cols)
+	}
+	fused := mat.NewDense(rows, cols+len(sm.Classes), nil)
+	for i := 0; i < rows; i++ {
+		row := fused.RawRowView(i)
+		for j, v := range data.RawRowView(i) {
+			row[j] = (1 - sm.Weight) * v
+		}
+		class, ok := classes[i]
+		if !ok {
+			return nil, fmt.Errorf("missing class of data row %d", i)
+		}
+		k := sort.SearchInts(sm.Classes, class)
+		if k == len(sm.Classes) || sm.Classes[k] != class {
+			return nil, fmt.Errorf("unknown class of data row %d: %d", i, class)
+		}
+		row[cols+k] = sm.Weight
+	}
+	return fused, nil
+}
+
+// TrainSupervised trains supervised map on data rows concatenated with one-hot encoded classes of the rows.
+// See NewSupervisedMap for the description of the training vectors; otherwise it works like Train.
+// It returns error if the map is not supervised, if any data row has no class or a class which was not
+// known when the map was created, or if the training fails.
+func (m *Map) TrainSupervised(c *TrainConfig, data *mat.Dense, classes map[int]int, iters int) error {
+	if m.meta.Supervised == nil {
+		return fmt.Errorf("map is not supervised")
+	}
+	fused, err := m.meta.Supervised.fuse(data, classes)
+	if err != nil {
+		return err
+	}
+	return m.Train(c, fused, iters)
+}
+
+// Supervised returns supervised map configuration or nil if the map is not supervised
+func (m *Map) Supervised() *SupervisedMetadata {
+	return m.meta.Supervised
+}
+
+// Classify predicts the class of data vector vec by supervised map.
+// The best matching unit of vec is found using the data features of codebook vectors only
+// and the predicted class is the class with the highest value in its class block.
+// It returns error if the map is not supervised or if vec dimension differs from the number of features.
+func (m *Map) Classify(vec []float64) (int, error) {
+	sm := m.meta.Supervised
+	if sm == nil {
+		return -1, fmt.Errorf("map is not supervised")
+	}
+	if len(vec) != sm.Features {
+		return -1, fmt.Errorf("invalid vector dimension: %d", len(vec))
+	}
+	scaled := make([]float64, len(vec))
+	for i, v := range vec {
+		scaled[i] = (1 - sm.Weight) * v
+	}
+	units, _ := m.codebook.Dims()
+	features := m.codebook.Slice(0, units, 0, sm.Features).(*mat.Dense)
+	bmu, err := m.measure().closest(scaled, features)
+	if err != nil {
+		return -1, err
+	}
+	block := m.codebook.RawRowView(bmu)[sm.Features:]
+	best := 0
+	for k, v := range block {
+		if v > block[best] {
+			best = k
+		}
+	}
+	return sm.Classes[best], nil
+}
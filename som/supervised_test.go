@@ -0,0 +1,86 @@
+package som
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSupervisedMap(t *testing.T) {
+	assert := assert.New(t)
+
+	data := mat.NewDense(6, 2, []float64{
+		0.0, 0.1,
+		0.1, 0.0,
+		0.1, 0.1,
+		5.0, 5.1,
+		5.1, 5.0,
+		5.1, 5.1,
+	})
+	classes := map[int]int{0: 3, 1: 3, 2: 3, 3: 7, 4: 7, 5: 7}
+	mc := &MapConfig{
+		Grid: &GridConfig{Size: []int{2, 2}, Type: "planar", UShape: "rectangle"},
+		Cb:   &CbConfig{Dim: 2, InitFunc: RandInit},
+	}
+	m, err := NewSupervisedMap(mc, data, classes, 0.5)
+	assert.NoError(err)
+	_, dim := m.Codebook().Dims()
+	assert.Equal(4, dim)
+	assert.Equal(&SupervisedMetadata{Features: 2, Classes: []int{3, 7}, Weight: 0.5}, m.Supervised())
+	tc := &TrainConfig{
+		Algorithm: "batch",
+		Radius:    1.0,
+		RDecay:    "lin",
+		NeighbFn:  Gaussian,
+		LRate:     0.5,
+		LDecay:    "lin",
+	}
+	assert.NoError(m.TrainSupervised(tc, data, classes, 20))
+	for _, tst := range []struct {
+		vec   []float64
+		class int
+	}{
+		{[]float64{0.05, 0.05}, 3},
+		{[]float64{-1.0, 0.5}, 3},
+		{[]float64{5.05, 5.05}, 7},
+		{[]float64{6.0, 4.0}, 7},
+	} {
+		class, err := m.Classify(tst.vec)
+		assert.NoError(err)
+		assert.Equal(tst.class, class)
+	}
+	_, err = m.Classify([]float64{1.0})
+	assert.Error(err)
+	// supervised configuration survives serialization
+	buf := new(bytes.Buffer)
+	_, err = m.MarshalTo("som", buf)
+	assert.NoError(err)
+	um := new(Map)
+	_, err = um.UnmarshalFrom("som", buf)
+	assert.NoError(err)
+	class, err := um.Classify([]float64{5.05, 5.05})
+	assert.NoError(err)
+	assert.Equal(7, class)
+	// unknown and missing classes
+	assert.Error(m.TrainSupervised(tc, data, map[int]int{0: 3, 1: 3, 2: 3, 3: 7, 4: 7, 5: 9}, 10))
+	assert.Error(m.TrainSupervised(tc, data, map[int]int{0: 3}, 10))
+	assert.Error(m.TrainSupervised(tc, mat.NewDense(2, 3, nil), classes, 10))
+	// invalid parameters
+	_, err = NewSupervisedMap(mc, nil, classes, 0.5)
+	assert.Error(err)
+	_, err = NewSupervisedMap(mc, data, nil, 0.5)
+	assert.Error(err)
+	_, err = NewSupervisedMap(mc, data, classes, 1.0)
+	assert.Error(err)
+	_, err = NewSupervisedMap(nil, data, classes, 0.5)
+	assert.Error(err)
+	// unsupervised map
+	m, err = NewMap(mSom, dataMx)
+	assert.NoError(err)
+	assert.Nil(m.Supervised())
+	_, err = m.Classify(dataMx.RawRowView(0))
+	assert.Error(err)
+	assert.Error(m.TrainSupervised(tSom, dataMx, map[int]int{0: 1}, 10))
+}
@@ -0,0 +1,80 @@
+package som
+
+import (
+	"fmt"
+	"io"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// SweepStep holds the best matching unit of a feature sweep sample
+type SweepStep struct {
+	// Value is the swept feature value of the sample
+	Value float64 `json:"value"`
+	// BMU is the best matching unit of the sample
+	BMU int `json:"bmu"`
+	// Dist is the distance of the sample from the BMU codebook vector
+	Dist float64 `json:"dist"`
+}
+
+// SweepData returns steps samples which hold base values except for a given feature which
+// is swept over evenly spaced values from from to to, both inclusive, in samples rows.
+// It fails with error if base is empty, feature is out of base range or steps is less than 2.
+func SweepData(base []float64, feature int, from, to float64, steps int) (*mat.Dense, error) {
+	if len(base) == 0 {
+		return nil, fmt.Errorf("invalid base values: %v", base)
+	}
+	if feature < 0 || feature >= len(base) {
+		return nil, fmt.Errorf("invalid feature: %d", feature)
+	}
+	if steps < 2 {
+		return nil, fmt.Errorf("invalid number of sweep steps: %d", steps)
+	}
+	samples := mat.NewDense(steps, len(base), nil)
+	for i := 0; i < steps; i++ {
+		samples.SetRow(i, base)
+		samples.Set(i, feature, from+float64(i)*(to-from)/float64(steps-1))
+	}
+	return samples, nil
+}
+
+// FeatureSweep sweeps a given feature of base vector over steps evenly spaced values from from to to
+// while holding the other features at their base values and returns the best matching unit of each sample.
+// The BMU path shows which direction on the map corresponds to the feature; see PathSVG.
+// It fails with error if the sweep is invalid or base dimension differs from the codebook dimension.
+func (m *Map) FeatureSweep(base []float64, feature int, from, to float64, steps int) ([]SweepStep, error) {
+	samples, err := SweepData(base, feature, from, to, steps)
+	if err != nil {
+		return nil, err
+	}
+	sweep := make([]SweepStep, steps)
+	for i := range sweep {
+		sample := samples.RawRowView(i)
+		bmu, err := m.measure().closest(sample, m.codebook)
+		if err != nil {
+			return nil, err
+		}
+		dist, err := m.measure().distance(sample, m.codebook.RawRowView(bmu))
+		if err != nil {
+			return nil, err
+		}
+		sweep[i] = SweepStep{Value: sample[feature], BMU: bmu, Dist: dist}
+	}
+	return sweep, nil
+}
+
+// PathSVG generates SVG representation of u-matrix overlaid by a path connecting given units, such as
+// the BMUs of FeatureSweep, configured by c and writes it to w. The start of the path is marked by a hollow
+// circle and its end by a filled circle. See UMatrixSVG for the description of the remaining parameters.
+// It fails with error if any path unit is not a map unit or if the write to w fails.
+func (m *Map) PathSVG(w io.Writer, path []int, stats *ClassStats, title string, c *SVGConfig) error {
+	units, _ := m.codebook.Dims()
+	for _, unit := range path {
+		if unit < 0 || unit >= units {
+			return fmt.Errorf("invalid path unit: %d", unit)
+		}
+	}
+	u := m.umatrixMap()
+	u.path = path
+	return u.svgWith(title, w, stats.Dominant(), c)
+}
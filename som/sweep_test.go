@@ -0,0 +1,70 @@
+package som
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSweepData(t *testing.T) {
+	assert := assert.New(t)
+
+	samples, err := SweepData([]float64{1.0, 2.0}, 1, 0.0, 1.0, 3)
+	assert.NoError(err)
+	assert.True(mat.Equal(mat.NewDense(3, 2, []float64{
+		1.0, 0.0,
+		1.0, 0.5,
+		1.0, 1.0,
+	}), samples))
+	_, err = SweepData(nil, 0, 0.0, 1.0, 3)
+	assert.Error(err)
+	_, err = SweepData([]float64{1.0}, 1, 0.0, 1.0, 3)
+	assert.Error(err)
+	_, err = SweepData([]float64{1.0}, 0, 0.0, 1.0, 1)
+	assert.Error(err)
+}
+
+func TestFeatureSweep(t *testing.T) {
+	assert := assert.New(t)
+
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	// codebook vectors differ in the first feature only
+	units, dim := m.codebook.Dims()
+	for i := 0; i < units; i++ {
+		for j := 0; j < dim; j++ {
+			m.codebook.Set(i, j, 0.0)
+		}
+		m.codebook.Set(i, 0, float64(i))
+	}
+	sweep, err := m.FeatureSweep([]float64{0.0, 0.0, 0.0, 0.0}, 0, 0.0, 5.0, 6)
+	assert.NoError(err)
+	assert.Len(sweep, 6)
+	for i, s := range sweep {
+		assert.Equal(float64(i), s.Value)
+		assert.Equal(i, s.BMU)
+		assert.Equal(0.0, s.Dist)
+	}
+	// the other features don't move the BMU
+	sweep, err = m.FeatureSweep([]float64{0.0, 0.0, 0.0, 0.0}, 1, 0.0, 5.0, 3)
+	assert.NoError(err)
+	for _, s := range sweep {
+		assert.Equal(0, s.BMU)
+	}
+	_, err = m.FeatureSweep([]float64{0.0}, 0, 0.0, 5.0, 3)
+	assert.Error(err)
+	_, err = m.FeatureSweep([]float64{0.0, 0.0, 0.0, 0.0}, 4, 0.0, 5.0, 3)
+	assert.Error(err)
+	// BMU path overlay
+	var buf bytes.Buffer
+	assert.NoError(m.PathSVG(&buf, []int{0, 1, 2}, NewClassStats(), "Sweep", &SVGConfig{Standalone: true}))
+	assert.Equal(1, strings.Count(buf.String(), "<polyline"))
+	assert.Equal(2, strings.Count(buf.String(), "<circle"))
+	buf.Reset()
+	assert.NoError(m.PathSVG(&buf, nil, NewClassStats(), "Sweep", nil))
+	assert.NotContains(buf.String(), "<polyline")
+	assert.Error(m.PathSVG(&buf, []int{0, units}, NewClassStats(), "Sweep", nil))
+}
@@ -0,0 +1,121 @@
+package som
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// ErrTrainerClosed is returned when Train is called on a closed Trainer
+var ErrTrainerClosed = errors.New("trainer closed")
+
+// Trainer trains SOMs using a persistent pool of batch training workers.
+// The pool is reused across training iterations and Train calls which avoids
+// starting new goroutines for every batch iteration. Trainer can train several
+// maps concurrently; their batches share the pool workers.
+type Trainer struct {
+	// workers is the number of pool workers
+	workers int
+	// jobs is a channel of batch jobs processed by pool workers
+	jobs chan *batchJob
+	// mu guards against closing the pool during training
+	mu sync.RWMutex
+	// closed is set when the pool is closed
+	closed bool
+	// done is used to wait for pool workers to exit
+	done sync.WaitGroup
+}
+
+// batchJob is a batch of data rows processed by a pool worker
+type batchJob struct {
+	m        *Map
+	acc      *batchAcc
+	wg       *sync.WaitGroup
+	bc       *batchConfig
+	unitDist *mat.Dense
+	data     *mat.Dense
+	from     int
+	count    int
+	radius   float64
+}
+
+// NewTrainer creates a new Trainer with a pool of a given number of workers and starts them.
+// If workers is 0, the number of CPUs is used.
+// It returns error if the number of workers is negative.
+func NewTrainer(workers int) (*Trainer, error) {
+	if workers < 0 {
+		return nil, fmt.Errorf("invalid number of workers: %d", workers)
+	}
+
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return newTrainer(workers), nil
+}
+
+// newTrainer creates a Trainer and starts its workers
+func newTrainer(workers int) *Trainer {
+	t := &Trainer{
+		workers: workers,
+		jobs:    make(chan *batchJob, workers),
+	}
+
+	t.done.Add(workers)
+	for i := 0; i < workers; i++ {
+		go t.work()
+	}
+
+	return t
+}
+
+// work processes batch jobs until the jobs channel is closed
+func (t *Trainer) work() {
+	defer t.done.Done()
+	for j := range t.jobs {
+		j.m.processBatch(j.acc, j.wg, j.bc, j.unitDist, j.data, j.from, j.count, j.radius)
+	}
+}
+
+// Workers returns the number of pool workers
+func (t *Trainer) Workers() int {
+	return t.workers
+}
+
+// Train trains map m using the provided configuration on a given data set using the trainer worker pool.
+// Unless configured, batch training splits the data into as many batches as there are pool workers.
+// It returns ErrTrainerClosed if the trainer has been closed, otherwise it fails the same way Map Train does.
+func (t *Trainer) Train(m *Map, c *TrainConfig, data *mat.Dense, iters int) error {
+	return t.TrainContext(context.Background(), m, c, data, iters)
+}
+
+// TrainContext trains map m like Train does, but it stops the training once ctx is done; see Map TrainContext.
+// It returns ErrTrainerClosed if the trainer has been closed, otherwise it fails the same way Map TrainContext does.
+func (t *Trainer) TrainContext(ctx context.Context, m *Map, c *TrainConfig, data *mat.Dense, iters int) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return ErrTrainerClosed
+	}
+
+	return m.train(ctx, c, data, iters, nil, t)
+}
+
+// Close stops the pool workers once all running trainings finish.
+// Close can be called multiple times.
+func (t *Trainer) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return
+	}
+	t.closed = true
+	close(t.jobs)
+	t.done.Wait()
+}
@@ -0,0 +1,48 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTrainer(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrainer(-1)
+	assert.Nil(tr)
+	assert.Error(err)
+	tr, err = NewTrainer(3)
+	assert.NoError(err)
+	assert.Equal(3, tr.Workers())
+	tr.Close()
+	// closing twice is fine
+	tr.Close()
+}
+
+func TestTrainerTrain(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrainer(2)
+	assert.NoError(err)
+	m, err := NewMap(mSom, dataMx)
+	assert.NoError(err)
+	tc := makeDefaultTrainConfig()
+	tc.Algorithm = "batch"
+	// pool is reused across several training calls
+	for i := 0; i < 3; i++ {
+		err = tr.Train(m, tc, dataMx, 10)
+		assert.NoError(err)
+	}
+	assert.NotNil(m.Metadata().Trained)
+	// sequential training does not need the pool
+	err = tr.Train(m, tSom, dataMx, 10)
+	assert.NoError(err)
+	// invalid training parameters
+	err = tr.Train(m, tc, dataMx, -1)
+	assert.Error(err)
+	// closed trainer can't train
+	tr.Close()
+	err = tr.Train(m, tc, dataMx, 10)
+	assert.Equal(ErrTrainerClosed, err)
+}
@@ -0,0 +1,219 @@
+package som
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// TuneSpace defines the space of training parameters searched by Tune.
+// Parameters whose range or list of options is empty are not tuned
+// and their values are taken from the base training configuration.
+type TuneSpace struct {
+	// Radius is the range of initial SOM unit radius
+	Radius [2]float64
+	// LRate is the range of initial SOM learning rate
+	LRate [2]float64
+	// RDecays lists radius decay strategies
+	RDecays []string
+	// LDecays lists learning rate decay strategies
+	LDecays []string
+	// NeighbFns lists neighbourhood functions
+	NeighbFns []NeighbFunc
+}
+
+// TuneConfig holds configuration of training parameters search
+type TuneConfig struct {
+	// Map configures maps trained in search trials
+	Map *MapConfig
+	// Train is the base training configuration whose parameters are tuned
+	Train *TrainConfig
+	// Space is the searched space of training parameters
+	Space *TuneSpace
+	// Trials is the number of sampled training configurations
+	Trials int
+	// Iters is the number of training iterations of each trial
+	Iters int
+	// Halving enables successive halving: all the sampled configurations are trained
+	// with a fraction of Iters, the better half is trained again with twice as many
+	// iterations and so on until the last remaining configurations are trained with Iters
+	// iterations. Halving runs 1+log2(Trials) rounds of training.
+	Halving bool
+	// Seed seeds sampling of training configurations. If Seed is 0, current time is used
+	Seed int64
+}
+
+// Trial holds evaluation of a training configuration
+type Trial struct {
+	// Config is the evaluated training configuration
+	Config *TrainConfig
+	// Iters is the number of training iterations
+	Iters int
+	// QuantError is quantization error of the trained map
+	QuantError float64
+	// TopoError is topographic error of the trained map
+	TopoError float64
+	// Score is the trial score; the lower the better
+	Score float64
+}
+
+// Tune searches training parameters within a budget of a given number of random trials.
+// Each trial trains a new map with a randomly sampled training configuration and evaluates it on data.
+// The score of a trial is the sum of its topographic error and its quantization error relative
+// to the mean distance of data samples from their mean, so that both errors have the same scale.
+// Tune returns the training configuration with the lowest score along with all the evaluated trials
+// in the order in which they were run. It fails with error if data is nil, the configuration is invalid
+// or if any of the trials fails to train.
+func Tune(data *mat.Dense, c *TuneConfig) (*TrainConfig, []Trial, error) {
+	if data == nil {
+		return nil, nil, fmt.Errorf("invalid data supplied: %v", data)
+	}
+	if err := validateTuneConfig(c); err != nil {
+		return nil, nil, err
+	}
+
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(seed))
+	configs := make([]*TrainConfig, c.Trials)
+	for i := range configs {
+		configs[i] = sampleTrainConfig(r, c.Train, c.Space)
+	}
+
+	qe0 := dataSpread(data)
+	// rungs of successive halving; random search runs a single rung
+	rungs := 1
+	if c.Halving {
+		rungs += int(math.Log2(float64(c.Trials)))
+	}
+	var history []Trial
+	for rung := 0; rung < rungs; rung++ {
+		iters := c.Iters >> uint(rungs-1-rung)
+		if iters < 1 {
+			iters = 1
+		}
+		trials := make([]Trial, len(configs))
+		for i, tc := range configs {
+			t, err := runTrial(data, c.Map, tc, iters, qe0)
+			if err != nil {
+				return nil, nil, err
+			}
+			trials[i] = t
+		}
+		history = append(history, trials...)
+		// keep the better half of the configurations
+		sort.SliceStable(trials, func(i, j int) bool { return trials[i].Score < trials[j].Score })
+		keep := (len(trials) + 1) / 2
+		if rung == rungs-1 {
+			keep = 1
+		}
+		configs = configs[:keep]
+		for i := range configs {
+			configs[i] = trials[i].Config
+		}
+	}
+
+	return configs[0], history, nil
+}
+
+// validateTuneConfig validates training parameters search configuration
+func validateTuneConfig(c *TuneConfig) error {
+	if c == nil || c.Map == nil || c.Train == nil || c.Space == nil {
+		return fmt.Errorf("invalid tune configuration: %v", c)
+	}
+	if c.Trials <= 0 {
+		return fmt.Errorf("invalid number of trials: %d", c.Trials)
+	}
+	if c.Iters <= 0 {
+		return fmt.Errorf("invalid number of iterations: %d", c.Iters)
+	}
+	if c.Space.Radius[0] < 0 || c.Space.Radius[1] < c.Space.Radius[0] {
+		return fmt.Errorf("invalid radius range: %v", c.Space.Radius)
+	}
+	if c.Space.LRate[0] < 0 || c.Space.LRate[1] < c.Space.LRate[0] {
+		return fmt.Errorf("invalid learning rate range: %v", c.Space.LRate)
+	}
+	for _, decay := range c.Space.RDecays {
+		if _, ok := decays[decay]; !ok {
+			return fmt.Errorf("unsupported Radius decay strategy: %s", decay)
+		}
+	}
+	for _, decay := range c.Space.LDecays {
+		if _, ok := decays[decay]; !ok {
+			return fmt.Errorf("unsupported Learning rate decay strategy: %s", decay)
+		}
+	}
+	for _, fn := range c.Space.NeighbFns {
+		if fn == nil {
+			return fmt.Errorf("invalid Neighbourhood function: %v", fn)
+		}
+	}
+	return nil
+}
+
+// sampleTrainConfig samples training configuration from space s using base configuration
+func sampleTrainConfig(r *rand.Rand, base *TrainConfig, s *TuneSpace) *TrainConfig {
+	tc := *base
+	if s.Radius[1] > 0 {
+		tc.Radius = s.Radius[0] + r.Float64()*(s.Radius[1]-s.Radius[0])
+	}
+	if s.LRate[1] > 0 {
+		tc.LRate = s.LRate[0] + r.Float64()*(s.LRate[1]-s.LRate[0])
+	}
+	if len(s.RDecays) > 0 {
+		tc.RDecay = s.RDecays[r.Intn(len(s.RDecays))]
+	}
+	if len(s.LDecays) > 0 {
+		tc.LDecay = s.LDecays[r.Intn(len(s.LDecays))]
+	}
+	if len(s.NeighbFns) > 0 {
+		tc.NeighbFn = s.NeighbFns[r.Intn(len(s.NeighbFns))]
+	}
+	return &tc
+}
+
+// runTrial trains a new map with a given training configuration and evaluates it
+func runTrial(data *mat.Dense, mc *MapConfig, tc *TrainConfig, iters int, qe0 float64) (Trial, error) {
+	m, err := NewMap(mc, data)
+	if err != nil {
+		return Trial{}, err
+	}
+	if err := m.Train(tc, data, iters); err != nil {
+		return Trial{}, err
+	}
+	t := Trial{Config: tc, Iters: iters}
+	if t.QuantError, err = m.QuantError(data); err != nil {
+		return Trial{}, err
+	}
+	if t.TopoError, err = m.TopoError(data); err != nil {
+		return Trial{}, err
+	}
+	t.Score = t.TopoError
+	if qe0 > 0 {
+		t.Score += t.QuantError / qe0
+	}
+	return t, nil
+}
+
+// dataSpread returns the mean distance of data samples from their mean,
+// i.e. the quantization error of a single unit map
+func dataSpread(data *mat.Dense) float64 {
+	rows, cols := data.Dims()
+	mean := make([]float64, cols)
+	for i := 0; i < rows; i++ {
+		floats.Add(mean, data.RawRowView(i))
+	}
+	floats.Scale(1/float64(rows), mean)
+	spread := 0.0
+	for i := 0; i < rows; i++ {
+		spread += euclideanVec(data.RawRowView(i), mean)
+	}
+	return spread / float64(rows)
+}
@@ -0,0 +1,86 @@
+package som
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTune(t *testing.T) {
+	assert := assert.New(t)
+
+	base := &TrainConfig{
+		Algorithm: "batch",
+		Radius:    1.0,
+		RDecay:    "lin",
+		NeighbFn:  Gaussian,
+		LRate:     0.5,
+		LDecay:    "lin",
+	}
+	c := &TuneConfig{
+		Map:   mSom,
+		Train: base,
+		Space: &TuneSpace{
+			Radius:    [2]float64{1.0, 3.0},
+			RDecays:   []string{"exp", "inv"},
+			NeighbFns: []NeighbFunc{Gaussian, Bubble},
+		},
+		Trials: 5,
+		Iters:  8,
+		Seed:   42,
+	}
+	// random search
+	best, trials, err := Tune(dataMx, c)
+	assert.NoError(err)
+	assert.Len(trials, 5)
+	bestTrial := trials[0]
+	for _, trial := range trials {
+		assert.Equal(8, trial.Iters)
+		assert.True(trial.Config.Radius >= 1.0 && trial.Config.Radius <= 3.0)
+		assert.Contains([]string{"exp", "inv"}, trial.Config.RDecay)
+		// parameters which are not tuned are taken from the base config
+		assert.Equal(base.LRate, trial.Config.LRate)
+		assert.Equal(base.LDecay, trial.Config.LDecay)
+		if trial.Score < bestTrial.Score {
+			bestTrial = trial
+		}
+	}
+	assert.Equal(bestTrial.Config, best)
+	// the base config is not modified
+	assert.Equal(1.0, base.Radius)
+	// successive halving trains 5, 3 and 2 configurations with 2, 4 and 8 iterations
+	c.Halving = true
+	best, trials, err = Tune(dataMx, c)
+	assert.NoError(err)
+	assert.Len(trials, 10)
+	for i, iters := range []int{2, 2, 2, 2, 2, 4, 4, 4, 8, 8} {
+		assert.Equal(iters, trials[i].Iters)
+	}
+	// the best config has the lowest score in the last round
+	last := trials[8:]
+	if last[0].Score <= last[1].Score {
+		assert.Equal(last[0].Config, best)
+	} else {
+		assert.Equal(last[1].Config, best)
+	}
+	// invalid configurations
+	for _, ic := range []*TuneConfig{
+		nil,
+		{Map: mSom, Train: base},
+		{Map: mSom, Train: base, Space: &TuneSpace{}, Trials: 0, Iters: 1},
+		{Map: mSom, Train: base, Space: &TuneSpace{}, Trials: 1, Iters: 0},
+		{Map: mSom, Train: base, Space: &TuneSpace{Radius: [2]float64{2.0, 1.0}}, Trials: 1, Iters: 1},
+		{Map: mSom, Train: base, Space: &TuneSpace{LRate: [2]float64{-1.0, 1.0}}, Trials: 1, Iters: 1},
+		{Map: mSom, Train: base, Space: &TuneSpace{RDecays: []string{"foo"}}, Trials: 1, Iters: 1},
+		{Map: mSom, Train: base, Space: &TuneSpace{LDecays: []string{"foo"}}, Trials: 1, Iters: 1},
+		{Map: mSom, Train: base, Space: &TuneSpace{NeighbFns: []NeighbFunc{nil}}, Trials: 1, Iters: 1},
+	} {
+		best, trials, err = Tune(dataMx, ic)
+		assert.Nil(best)
+		assert.Nil(trials)
+		assert.Error(err)
+	}
+	// nil data
+	_, _, err = Tune(nil, c)
+	assert.Error(err)
+}